@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	"encoding/json"
+
 	"github.com/go-errors/errors"
 	v1 "k8s.io/api/core/v1"
 )
@@ -35,7 +37,227 @@ func (s StringOrSecretParameter) ConvertToEnvVar(name string) (envVar v1.EnvVar,
 	return
 }
 
+// IsSecretRef is true when the value is read from a Secret
+func (s StringOrSecretParameter) IsSecretRef() bool {
+	return s.ValueFrom != nil && s.ValueFrom.SecretKeyRef != nil
+}
+
 type SecretKeyRef struct {
 	// +optional
 	SecretKeyRef *v1.SecretKeySelector `json:"secretKeyRef,omitempty"`
 }
+
+type ComponentDeviation struct {
+	ComponentType string `json:"componentType"`
+	ComponentName string `json:"componentName"`
+	Message       string `json:"message"`
+
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// +optional
+	Expected string `json:"expected,omitempty"`
+
+	// +optional
+	Actual string `json:"actual,omitempty"`
+}
+
+// ComponentRemediation records how the deviations of a component were handled
+type ComponentRemediation struct {
+	ComponentType string `json:"componentType"`
+	ComponentName string `json:"componentName"`
+
+	// Action is the path that was taken: repair, migrate, refresh or report
+	Action string `json:"action"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DeploymentSpec configures a Deployment created by the operator.
+// Unset fields use the defaults from the xjoin-generic ConfigMap.
+type DeploymentSpec struct {
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// +optional
+	ImageTag string `json:"imageTag,omitempty"`
+
+	// +optional
+	ImagePullPolicy v1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// +optional
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// +optional
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+
+	// +optional
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// +optional
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// XJoinAPISubGraphSpec configures the xjoin-api-subgraph Deployments of an Index
+type XJoinAPISubGraphSpec struct {
+	DeploymentSpec `json:",inline"`
+
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+}
+
+// AutoscalingSpec adds a HorizontalPodAutoscaler to a Deployment, the replicas of the Deployment are then
+// managed by the HorizontalPodAutoscaler
+type AutoscalingSpec struct {
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+}
+
+const (
+	SinkTypeElasticsearch = "elasticsearch"
+	SinkTypeOpenSearch    = "opensearch"
+	SinkTypePostgreSQL    = "postgresql"
+)
+
+// SinkSpec selects the backend the joined records of an Index are written to.
+// Elasticsearch is used when the sink is not set.
+type SinkSpec struct {
+	// +optional
+	// +kubebuilder:validation:Enum=elasticsearch;opensearch;postgresql
+	// +kubebuilder:default:=elasticsearch
+	Type string `json:"type,omitempty"`
+
+	// +optional
+	OpenSearch *OpenSearchSinkSpec `json:"opensearch,omitempty"`
+
+	// +optional
+	PostgreSQL *PostgreSQLSinkSpec `json:"postgresql,omitempty"`
+}
+
+// GetType returns the sink type, defaults to elasticsearch
+func (s *SinkSpec) GetType() string {
+	if s == nil || s.Type == "" {
+		return SinkTypeElasticsearch
+	}
+	return s.Type
+}
+
+// OpenSearchSinkSpec is the connection to an OpenSearch cluster
+type OpenSearchSinkSpec struct {
+	// +kubebuilder:validation:Required
+	URL *StringOrSecretParameter `json:"url,omitempty"`
+
+	// +optional
+	Username *StringOrSecretParameter `json:"username,omitempty"`
+
+	// +optional
+	Password *StringOrSecretParameter `json:"password,omitempty"`
+}
+
+// PostgreSQLSinkSpec is the connection to the PostgreSQL database the Index tables are created in
+type PostgreSQLSinkSpec struct {
+	// +kubebuilder:validation:Required
+	Hostname *StringOrSecretParameter `json:"hostname,omitempty"`
+
+	// +kubebuilder:validation:Required
+	Port *StringOrSecretParameter `json:"port,omitempty"`
+
+	// +kubebuilder:validation:Required
+	Name *StringOrSecretParameter `json:"name,omitempty"`
+
+	// +kubebuilder:validation:Required
+	Username *StringOrSecretParameter `json:"username,omitempty"`
+
+	// +kubebuilder:validation:Required
+	Password *StringOrSecretParameter `json:"password,omitempty"`
+
+	// Schema the tables are created in
+	// +optional
+	// +kubebuilder:default:=public
+	Schema string `json:"schema,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Enum=disable;require;verify-ca;verify-full
+	SSLMode string `json:"sslMode,omitempty"`
+}
+
+const (
+	//DataSourceVersionPolicyActiveOnly uses the active version, the index waits until the data source has one
+	DataSourceVersionPolicyActiveOnly = "activeOnly"
+	//DataSourceVersionPolicyPreferRefreshing uses the refreshing version when there is one, the active one otherwise
+	DataSourceVersionPolicyPreferRefreshing = "preferRefreshing"
+	//DataSourceVersionPolicyLatestValid uses the refreshing version once it's valid, the valid active one otherwise
+	DataSourceVersionPolicyLatestValid = "latestValid"
+	//DataSourceVersionPinned is recorded when the version is pinned by DataSourceVersionSpec.Version
+	DataSourceVersionPinned = "pinned"
+)
+
+// DataSourceVersionSpec selects the version of a data source referenced by an Index.
+// When a data source is not listed, the valid active version is used, then the refreshing version.
+type DataSourceVersionSpec struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Version pins the XJoinDataSourcePipeline version, the policy is ignored when it's set
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Enum=activeOnly;preferRefreshing;latestValid
+	Policy string `json:"policy,omitempty"`
+}
+
+// DataSourceVersion is the version of a data source used by an IndexPipeline
+type DataSourceVersion struct {
+	// Version is the XJoinDataSourcePipeline version
+	Version string `json:"version"`
+
+	// SchemaVersion is the schema registry version of the XJoinDataSourcePipeline's subject
+	// +optional
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+
+	// Policy is the policy that chose the version, empty for the default policy
+	// +optional
+	Policy string `json:"policy,omitempty"`
+}
+
+// UnmarshalJSON accepts the XJoinDataSourcePipeline version as a plain string, the format used by previous releases
+func (d *DataSourceVersion) UnmarshalJSON(data []byte) error {
+	var version string
+	if err := json.Unmarshal(data, &version); err == nil {
+		*d = DataSourceVersion{Version: version}
+		return nil
+	}
+
+	type dataSourceVersion DataSourceVersion
+	var v dataSourceVersion
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.Wrap(err, 0)
+	}
+	*d = DataSourceVersion(v)
+	return nil
+}
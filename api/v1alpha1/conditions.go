@@ -0,0 +1,9 @@
+package v1alpha1
+
+const (
+	ConditionReady             = "Ready"
+	ConditionRefreshing        = "Refreshing"
+	ConditionValidated         = "Validated"
+	ConditionComponentsHealthy = "ComponentsHealthy"
+	ConditionDegraded          = "Degraded"
+)
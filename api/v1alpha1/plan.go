@@ -0,0 +1,22 @@
+package v1alpha1
+
+// PlanAnnotation enables plan mode when set to "true". In plan mode the operator renders the components it would
+// create into a ConfigMap instead of applying changes.
+const PlanAnnotation = "xjoin.cloud.redhat.com/plan"
+
+type PlanStatus struct {
+	// ConfigMapName is the name of the ConfigMap that contains the rendered plan
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	// SpecHash is the hash of the spec that was planned
+	SpecHash string `json:"specHash,omitempty"`
+
+	// State is the state the resource transitions to when the plan is applied e.g. START_REFRESH
+	State string `json:"state,omitempty"`
+
+	// Refresh is true when applying the plan creates a new version
+	Refresh bool `json:"refresh"`
+
+	// +optional
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,98 @@
+package v1alpha1
+
+// compatibility levels between the avro schema of the active version and a new avro schema
+const (
+	SchemaCompatibilityNone     = "none"
+	SchemaCompatibilityBackward = "backward"
+	SchemaCompatibilityForward  = "forward"
+	SchemaCompatibilityFull     = "full"
+)
+
+const (
+	SchemaChangePolicyRefresh             = "refresh"
+	SchemaChangePolicyInPlaceWhenAdditive = "inPlaceWhenAdditive"
+)
+
+// actions taken for a schema change
+const (
+	SchemaChangeActionRefresh = "refresh"
+	SchemaChangeActionInPlace = "inPlace"
+	SchemaChangeActionBlocked = "blocked"
+)
+
+// types of the changes of a field
+const (
+	SchemaFieldAdded   = "added"
+	SchemaFieldRemoved = "removed"
+	SchemaFieldChanged = "changed"
+)
+
+// SchemaChangeSpec configures how a change of the avro schema is applied
+type SchemaChangeSpec struct {
+	// Compatibility is the compatibility required between the schema of the active version and the new schema.
+	// A change that is not compatible is not applied.
+	// +optional
+	// +kubebuilder:validation:Enum=none;backward;forward;full
+	Compatibility string `json:"compatibility,omitempty"`
+
+	// Policy inPlaceWhenAdditive (default) migrates the components of the active version when the only changes are
+	// new fields with a default value. refresh creates a new version for every change.
+	// +optional
+	// +kubebuilder:validation:Enum=refresh;inPlaceWhenAdditive
+	Policy string `json:"policy,omitempty"`
+}
+
+// GetCompatibility returns the required compatibility, defaults to none
+func (s *SchemaChangeSpec) GetCompatibility() string {
+	if s == nil || s.Compatibility == "" {
+		return SchemaCompatibilityNone
+	}
+	return s.Compatibility
+}
+
+// GetPolicy returns the schema change policy, defaults to inPlaceWhenAdditive
+func (s *SchemaChangeSpec) GetPolicy() string {
+	if s == nil || s.Policy == "" {
+		return SchemaChangePolicyInPlaceWhenAdditive
+	}
+	return s.Policy
+}
+
+// SchemaCompatibilityStatus is the compatibility of the spec's avro schema with the schema of the active version
+type SchemaCompatibilityStatus struct {
+	// ActiveVersion is the version the schema is compared with
+	ActiveVersion string `json:"activeVersion"`
+
+	// SpecHash is the hash of the spec that was analyzed
+	SpecHash string `json:"specHash"`
+
+	// Backward is true when the new schema can read data written with the active schema
+	Backward bool `json:"backward"`
+
+	// Forward is true when the active schema can read data written with the new schema
+	Forward bool `json:"forward"`
+
+	// Full is true when the schemas are both backward and forward compatible
+	Full bool `json:"full"`
+
+	// Additive is true when the only changes are new fields with a default value
+	Additive bool `json:"additive"`
+
+	// +optional
+	Changes []SchemaFieldChange `json:"changes,omitempty"`
+
+	// Action is how the change is applied: refresh, inPlace or blocked
+	Action string `json:"action"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SchemaFieldChange is a field that differs between the active and the new schema
+type SchemaFieldChange struct {
+	// Path is the dot separated path of the field, empty for the root record
+	Path string `json:"path"`
+
+	// Type is added, removed or changed
+	Type string `json:"type"`
+}
@@ -0,0 +1,126 @@
+package v1alpha1
+
+// strategies of the native validation
+const (
+	ValidationStrategyFull      = "full"
+	ValidationStrategySampling  = "sampling"
+	ValidationStrategyHashRange = "hashRange"
+	ValidationStrategyCursor    = "cursor"
+)
+
+// ValidationSpec configures how the records of the data sources are compared with the index by the native validation
+type ValidationSpec struct {
+	// Strategy full (default) compares every record. sampling compares a random sample of records, hashRange compares
+	// checksums of id hash ranges and only compares the records of mismatched ranges, cursor compares a batch of
+	// records per reconcile.
+	// +optional
+	// +kubebuilder:validation:Enum=full;sampling;hashRange;cursor
+	Strategy string `json:"strategy,omitempty"`
+
+	// SampleSize is the number of ids sampled from the database and from the index by the sampling strategy
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	SampleSize int `json:"sampleSize,omitempty"`
+
+	// Confidence is the confidence level in percent of the upper bound of the mismatch ratio estimated by the
+	// sampling strategy
+	// +optional
+	// +kubebuilder:validation:Minimum=50
+	// +kubebuilder:validation:Maximum=99
+	Confidence int `json:"confidence,omitempty"`
+
+	// Buckets is the number of id hash ranges of the hashRange strategy
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Buckets int `json:"buckets,omitempty"`
+
+	// BatchSize is the number of records of each data source compared per reconcile by the cursor strategy
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	BatchSize int `json:"batchSize,omitempty"`
+}
+
+// GetStrategy returns the validation strategy, defaults to full
+func (s *ValidationSpec) GetStrategy() string {
+	if s == nil || s.Strategy == "" {
+		return ValidationStrategyFull
+	}
+	return s.Strategy
+}
+
+// GetSampleSize returns the sample size of the sampling strategy, defaults to 1000
+func (s *ValidationSpec) GetSampleSize() int {
+	if s == nil || s.SampleSize == 0 {
+		return 1000
+	}
+	return s.SampleSize
+}
+
+// GetConfidence returns the confidence level of the sampling strategy, defaults to 95
+func (s *ValidationSpec) GetConfidence() int {
+	if s == nil || s.Confidence == 0 {
+		return 95
+	}
+	return s.Confidence
+}
+
+// GetBuckets returns the number of hash ranges of the hashRange strategy, defaults to 1024
+func (s *ValidationSpec) GetBuckets() int {
+	if s == nil || s.Buckets == 0 {
+		return 1024
+	}
+	return s.Buckets
+}
+
+// GetBatchSize returns the batch size of the cursor strategy, defaults to 10000
+func (s *ValidationSpec) GetBatchSize() int {
+	if s == nil || s.BatchSize == 0 {
+		return 10000
+	}
+	return s.BatchSize
+}
+
+// ValidationCursorStatus is the progress of a validation with the cursor strategy, it is kept between reconciles
+type ValidationCursorStatus struct {
+	// +optional
+	DataSources []ValidationCursorPosition `json:"dataSources,omitempty"`
+}
+
+// ValidationCursorPosition is the progress of the cursor through the records of a data source
+type ValidationCursorPosition struct {
+	// Path is the path of the field of the index documents that contains the data source record
+	Path string `json:"path"`
+
+	// DatabaseID is the last id compared from the database, ids are compared in the order of their text value
+	// +optional
+	DatabaseID string `json:"databaseId,omitempty"`
+
+	// DatabaseDone is true when every record of the database was compared
+	// +optional
+	DatabaseDone bool `json:"databaseDone,omitempty"`
+
+	// ElasticsearchSort is the sort value of the last document compared from the index, as json
+	// +optional
+	ElasticsearchSort string `json:"elasticsearchSort,omitempty"`
+
+	// ElasticsearchDone is true when every document of the index was compared
+	// +optional
+	ElasticsearchDone bool `json:"elasticsearchDone,omitempty"`
+
+	// Records is the number of records of the database compared so far
+	Records int `json:"records"`
+
+	// Mismatched is the number of mismatched records found so far
+	Mismatched int `json:"mismatched"`
+}
+
+// Done returns true when every record of each data source was compared
+func (s *ValidationCursorStatus) Done() bool {
+	for _, position := range s.DataSources {
+		if !position.DatabaseDone || !position.ElasticsearchDone {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,21 @@
+package v1alpha1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// ValidationReportStatus references the ConfigMap that contains the mismatch report of a validation run
+type ValidationReportStatus struct {
+	// ConfigMap is the name of the ConfigMap, the report is stored as json in the report.json key
+	ConfigMap string `json:"configMap"`
+
+	// Time is when the validation run completed
+	Time metav1.Time `json:"time"`
+
+	// Result is the result of the validation run, valid or invalid
+	Result string `json:"result"`
+
+	// Mismatched is the number of mismatched records found by the validation run
+	Mismatched int `json:"mismatched"`
+
+	// Entries is the number of mismatched records listed in the report, bounded by validation.report.max.entries
+	Entries int `json:"entries"`
+}
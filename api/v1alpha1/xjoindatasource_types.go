@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -13,6 +14,9 @@ type XJoinDataSourceSpec struct {
 	DatabaseName     *StringOrSecretParameter `json:"databaseName,omitempty"`
 	DatabaseTable    *StringOrSecretParameter `json:"databaseTable,omitempty"`
 
+	// +optional
+	SchemaChange *SchemaChangeSpec `json:"schemaChange,omitempty"`
+
 	// +optional
 	Pause bool `json:"pause,omitempty"`
 }
@@ -23,11 +27,34 @@ type XJoinDataSourceStatus struct {
 	RefreshingVersion        string `json:"refreshingVersion"`
 	RefreshingVersionIsValid bool   `json:"refreshingVersionIsValid"`
 	SpecHash                 string `json:"specHash"`
+
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// +optional
+	Plan *PlanStatus `json:"plan,omitempty"`
+
+	// +optional
+	SchemaCompatibility *SchemaCompatibilityStatus `json:"schemaCompatibility,omitempty"`
+
+	// RetainedVersions are previous versions kept until the IndexPipelines using them have been refreshed
+	// +optional
+	RetainedVersions []string `json:"retainedVersions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:shortName=xjoindatasource,categories=all
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Active",type=string,JSONPath=`.status.activeVersion`
+// +kubebuilder:printcolumn:name="Refreshing",type=string,JSONPath=`.status.refreshingVersion`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 type XJoinDataSource struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -77,6 +104,22 @@ func (in *XJoinDataSource) SetRefreshingVersionIsValid(valid bool) {
 	in.Status.RefreshingVersionIsValid = valid
 }
 
+func (in *XJoinDataSource) GetPhase() string {
+	return in.Status.Phase
+}
+
+func (in *XJoinDataSource) SetPhase(phase string) {
+	in.Status.Phase = phase
+}
+
+func (in *XJoinDataSource) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+func (in *XJoinDataSource) SetCondition(condition metav1.Condition) {
+	meta.SetStatusCondition(&in.Status.Conditions, condition)
+}
+
 // +kubebuilder:object:root=true
 
 type XJoinDataSourceList struct {
@@ -39,6 +39,16 @@ type XJoinDataSourcePipelineSpec struct {
 
 type XJoinDataSourcePipelineStatus struct {
 	ValidationResponse validation.ValidationResponse `json:"validationResponse,omitempty"`
+
+	// +optional
+	Deviations []ComponentDeviation `json:"deviations,omitempty"`
+
+	// +optional
+	Remediations []ComponentRemediation `json:"remediations,omitempty"`
+
+	// AvroSchemaHash is the hash of the avro schema the components were created or last migrated with
+	// +optional
+	AvroSchemaHash string `json:"avroSchemaHash,omitempty"`
 }
 
 // +kubebuilder:object:root=true
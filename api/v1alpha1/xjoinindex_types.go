@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -11,6 +12,27 @@ type XJoinIndexSpec struct {
 	// +optional
 	CustomSubgraphImages []CustomSubgraphImage `json:"customSubgraphImages,omitempty"`
 
+	// +optional
+	XJoinCore *DeploymentSpec `json:"xjoinCore,omitempty"`
+
+	// +optional
+	XJoinAPISubGraph *XJoinAPISubGraphSpec `json:"xjoinAPISubGraph,omitempty"`
+
+	// +optional
+	Sink *SinkSpec `json:"sink,omitempty"`
+
+	// DataSources selects the version of each referenced data source
+	// +optional
+	DataSources []DataSourceVersionSpec `json:"dataSources,omitempty"`
+
+	// Validation configures how the native validation compares the data sources with the index
+	// +optional
+	Validation *ValidationSpec `json:"validation,omitempty"`
+
+	// SchemaChange configures how a change of the avro schema is applied
+	// +optional
+	SchemaChange *SchemaChangeSpec `json:"schemaChange,omitempty"`
+
 	// +optional
 	Pause bool `json:"pause,omitempty"`
 }
@@ -21,11 +43,30 @@ type XJoinIndexStatus struct {
 	RefreshingVersion        string `json:"refreshingVersion"`
 	RefreshingVersionIsValid bool   `json:"refreshingVersionIsValid"`
 	SpecHash                 string `json:"specHash"`
+
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// +optional
+	Plan *PlanStatus `json:"plan,omitempty"`
+
+	// +optional
+	SchemaCompatibility *SchemaCompatibilityStatus `json:"schemaCompatibility,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:shortName=xjoinindex,categories=all
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Active",type=string,JSONPath=`.status.activeVersion`
+// +kubebuilder:printcolumn:name="Refreshing",type=string,JSONPath=`.status.refreshingVersion`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 type XJoinIndex struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -83,6 +124,22 @@ func (in *XJoinIndex) SetRefreshingVersionIsValid(valid bool) {
 	in.Status.RefreshingVersionIsValid = valid
 }
 
+func (in *XJoinIndex) GetPhase() string {
+	return in.Status.Phase
+}
+
+func (in *XJoinIndex) SetPhase(phase string) {
+	in.Status.Phase = phase
+}
+
+func (in *XJoinIndex) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+func (in *XJoinIndex) SetCondition(condition metav1.Condition) {
+	meta.SetStatusCondition(&in.Status.Conditions, condition)
+}
+
 // +kubebuilder:object:root=true
 
 type XJoinIndexList struct {
@@ -18,13 +18,51 @@ type XJoinIndexPipelineSpec struct {
 	// +optional
 	CustomSubgraphImages []CustomSubgraphImage `json:"customSubgraphImages,omitempty"`
 
+	// +optional
+	XJoinCore *DeploymentSpec `json:"xjoinCore,omitempty"`
+
+	// +optional
+	XJoinAPISubGraph *XJoinAPISubGraphSpec `json:"xjoinAPISubGraph,omitempty"`
+
+	// +optional
+	Sink *SinkSpec `json:"sink,omitempty"`
+
+	// DataSources selects the version of each referenced data source
+	// +optional
+	DataSources []DataSourceVersionSpec `json:"dataSources,omitempty"`
+
+	// Validation configures how the native validation compares the data sources with the index
+	// +optional
+	Validation *ValidationSpec `json:"validation,omitempty"`
+
 	// +optional
 	Pause bool `json:"pause,omitempty"`
 }
 
 type XJoinIndexPipelineStatus struct {
 	ValidationResponse validation.ValidationResponse `json:"validationResponse,omitempty"`
-	DataSources        map[string]string             `json:"dataSources,omitempty"`
+
+	// DataSources are the versions of the data sources used by the pipeline keyed by data source name
+	// +optional
+	DataSources map[string]DataSourceVersion `json:"dataSources,omitempty"`
+
+	// +optional
+	Deviations []ComponentDeviation `json:"deviations,omitempty"`
+
+	// +optional
+	Remediations []ComponentRemediation `json:"remediations,omitempty"`
+
+	// AvroSchemaHash is the hash of the avro schema the components were created or last migrated with
+	// +optional
+	AvroSchemaHash string `json:"avroSchemaHash,omitempty"`
+
+	// GraphQLSchemaHash is the hash of the graphql schema generated from the avro schema
+	// +optional
+	GraphQLSchemaHash string `json:"graphQLSchemaHash,omitempty"`
+
+	// ValidationReport links the mismatch report of the latest validation run
+	// +optional
+	ValidationReport *ValidationReportStatus `json:"validationReport,omitempty"`
 
 	// +kubebuilder:validation:Required
 	// +kubebuilder:default:=false
@@ -43,8 +81,13 @@ type XJoinIndexPipeline struct {
 	Status XJoinIndexPipelineStatus `json:"status,omitempty"`
 }
 
+// GetDataSources returns the XJoinDataSourcePipeline version of each data source keyed by data source name
 func (in *XJoinIndexPipeline) GetDataSources() map[string]string {
-	return in.Status.DataSources
+	dataSources := make(map[string]string, len(in.Status.DataSources))
+	for key, value := range in.Status.DataSources {
+		dataSources[key] = value.Version
+	}
+	return dataSources
 }
 
 func (in *XJoinIndexPipeline) GetDataSourceNames() []string {
@@ -58,7 +101,7 @@ func (in *XJoinIndexPipeline) GetDataSourceNames() []string {
 func (in *XJoinIndexPipeline) GetDataSourcePipelineNames() []string {
 	pipelineNames := make([]string, 0, len(in.Status.DataSources))
 	for key, value := range in.Status.DataSources {
-		pipelineNames = append(pipelineNames, key+"."+value)
+		pipelineNames = append(pipelineNames, key+"."+value.Version)
 	}
 	return pipelineNames
 }
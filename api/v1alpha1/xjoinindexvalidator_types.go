@@ -18,6 +18,10 @@ type XJoinIndexValidatorSpec struct {
 	// +kubebuilder:validation:Required
 	IndexName string `json:"indexName,omitempty"`
 
+	// Validation configures how the native validation compares the data sources with the index
+	// +optional
+	Validation *ValidationSpec `json:"validation,omitempty"`
+
 	// +optional
 	Pause bool `json:"pause,omitempty"`
 }
@@ -25,6 +29,18 @@ type XJoinIndexValidatorSpec struct {
 type XJoinIndexValidatorStatus struct {
 	ValidationResponse validation.ValidationResponse `json:"validationResponse,omitempty"`
 	ValidationPodPhase string                        `json:"validationPodPhase,omitempty"`
+
+	// ValidationError describes why the output of the last validation pod was invalid
+	// +optional
+	ValidationError string `json:"validationError,omitempty"`
+
+	// RepairAttempts is the number of consecutive repairs of mismatched records since the last applied validation
+	// +optional
+	RepairAttempts int `json:"repairAttempts,omitempty"`
+
+	// ValidationCursor is the progress of a validation with the cursor strategy
+	// +optional
+	ValidationCursor *ValidationCursorStatus `json:"validationCursor,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -27,6 +27,61 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingSpec) DeepCopyInto(out *AutoscalingSpec) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetCPUUtilizationPercentage != nil {
+		in, out := &in.TargetCPUUtilizationPercentage, &out.TargetCPUUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingSpec.
+func (in *AutoscalingSpec) DeepCopy() *AutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentDeviation) DeepCopyInto(out *ComponentDeviation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentDeviation.
+func (in *ComponentDeviation) DeepCopy() *ComponentDeviation {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentDeviation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentRemediation) DeepCopyInto(out *ComponentRemediation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentRemediation.
+func (in *ComponentRemediation) DeepCopy() *ComponentRemediation {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentRemediation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomSubgraphImage) DeepCopyInto(out *CustomSubgraphImage) {
 	*out = *in
@@ -42,6 +97,227 @@ func (in *CustomSubgraphImage) DeepCopy() *CustomSubgraphImage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSourceVersion) DeepCopyInto(out *DataSourceVersion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSourceVersion.
+func (in *DataSourceVersion) DeepCopy() *DataSourceVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSourceVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSourceVersionSpec) DeepCopyInto(out *DataSourceVersionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSourceVersionSpec.
+func (in *DataSourceVersionSpec) DeepCopy() *DataSourceVersionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSourceVersionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentSpec) DeepCopyInto(out *DeploymentSpec) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentSpec.
+func (in *DeploymentSpec) DeepCopy() *DeploymentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenSearchSinkSpec) DeepCopyInto(out *OpenSearchSinkSpec) {
+	*out = *in
+	if in.URL != nil {
+		in, out := &in.URL, &out.URL
+		*out = new(StringOrSecretParameter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Username != nil {
+		in, out := &in.Username, &out.Username
+		*out = new(StringOrSecretParameter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Password != nil {
+		in, out := &in.Password, &out.Password
+		*out = new(StringOrSecretParameter)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenSearchSinkSpec.
+func (in *OpenSearchSinkSpec) DeepCopy() *OpenSearchSinkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OpenSearchSinkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanStatus) DeepCopyInto(out *PlanStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanStatus.
+func (in *PlanStatus) DeepCopy() *PlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgreSQLSinkSpec) DeepCopyInto(out *PostgreSQLSinkSpec) {
+	*out = *in
+	if in.Hostname != nil {
+		in, out := &in.Hostname, &out.Hostname
+		*out = new(StringOrSecretParameter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(StringOrSecretParameter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Name != nil {
+		in, out := &in.Name, &out.Name
+		*out = new(StringOrSecretParameter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Username != nil {
+		in, out := &in.Username, &out.Username
+		*out = new(StringOrSecretParameter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Password != nil {
+		in, out := &in.Password, &out.Password
+		*out = new(StringOrSecretParameter)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgreSQLSinkSpec.
+func (in *PostgreSQLSinkSpec) DeepCopy() *PostgreSQLSinkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgreSQLSinkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaChangeSpec) DeepCopyInto(out *SchemaChangeSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaChangeSpec.
+func (in *SchemaChangeSpec) DeepCopy() *SchemaChangeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaChangeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaCompatibilityStatus) DeepCopyInto(out *SchemaCompatibilityStatus) {
+	*out = *in
+	if in.Changes != nil {
+		in, out := &in.Changes, &out.Changes
+		*out = make([]SchemaFieldChange, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaCompatibilityStatus.
+func (in *SchemaCompatibilityStatus) DeepCopy() *SchemaCompatibilityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaCompatibilityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaFieldChange) DeepCopyInto(out *SchemaFieldChange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaFieldChange.
+func (in *SchemaFieldChange) DeepCopy() *SchemaFieldChange {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaFieldChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretKeyRef) DeepCopyInto(out *SecretKeyRef) {
 	*out = *in
@@ -62,6 +338,31 @@ func (in *SecretKeyRef) DeepCopy() *SecretKeyRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SinkSpec) DeepCopyInto(out *SinkSpec) {
+	*out = *in
+	if in.OpenSearch != nil {
+		in, out := &in.OpenSearch, &out.OpenSearch
+		*out = new(OpenSearchSinkSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostgreSQL != nil {
+		in, out := &in.PostgreSQL, &out.PostgreSQL
+		*out = new(PostgreSQLSinkSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SinkSpec.
+func (in *SinkSpec) DeepCopy() *SinkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SinkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StringOrSecretParameter) DeepCopyInto(out *StringOrSecretParameter) {
 	*out = *in
@@ -82,13 +383,100 @@ func (in *StringOrSecretParameter) DeepCopy() *StringOrSecretParameter {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValidationCursorPosition) DeepCopyInto(out *ValidationCursorPosition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValidationCursorPosition.
+func (in *ValidationCursorPosition) DeepCopy() *ValidationCursorPosition {
+	if in == nil {
+		return nil
+	}
+	out := new(ValidationCursorPosition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValidationCursorStatus) DeepCopyInto(out *ValidationCursorStatus) {
+	*out = *in
+	if in.DataSources != nil {
+		in, out := &in.DataSources, &out.DataSources
+		*out = make([]ValidationCursorPosition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValidationCursorStatus.
+func (in *ValidationCursorStatus) DeepCopy() *ValidationCursorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ValidationCursorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValidationReportStatus) DeepCopyInto(out *ValidationReportStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValidationReportStatus.
+func (in *ValidationReportStatus) DeepCopy() *ValidationReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ValidationReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValidationSpec) DeepCopyInto(out *ValidationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValidationSpec.
+func (in *ValidationSpec) DeepCopy() *ValidationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ValidationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XJoinAPISubGraphSpec) DeepCopyInto(out *XJoinAPISubGraphSpec) {
+	*out = *in
+	in.DeploymentSpec.DeepCopyInto(&out.DeploymentSpec)
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(AutoscalingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinAPISubGraphSpec.
+func (in *XJoinAPISubGraphSpec) DeepCopy() *XJoinAPISubGraphSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(XJoinAPISubGraphSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *XJoinDataSource) DeepCopyInto(out *XJoinDataSource) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinDataSource.
@@ -249,6 +637,16 @@ func (in *XJoinDataSourcePipelineSpec) DeepCopy() *XJoinDataSourcePipelineSpec {
 func (in *XJoinDataSourcePipelineStatus) DeepCopyInto(out *XJoinDataSourcePipelineStatus) {
 	*out = *in
 	in.ValidationResponse.DeepCopyInto(&out.ValidationResponse)
+	if in.Deviations != nil {
+		in, out := &in.Deviations, &out.Deviations
+		*out = make([]ComponentDeviation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Remediations != nil {
+		in, out := &in.Remediations, &out.Remediations
+		*out = make([]ComponentRemediation, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinDataSourcePipelineStatus.
@@ -294,6 +692,11 @@ func (in *XJoinDataSourceSpec) DeepCopyInto(out *XJoinDataSourceSpec) {
 		*out = new(StringOrSecretParameter)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SchemaChange != nil {
+		in, out := &in.SchemaChange, &out.SchemaChange
+		*out = new(SchemaChangeSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinDataSourceSpec.
@@ -309,6 +712,28 @@ func (in *XJoinDataSourceSpec) DeepCopy() *XJoinDataSourceSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *XJoinDataSourceStatus) DeepCopyInto(out *XJoinDataSourceStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(PlanStatus)
+		**out = **in
+	}
+	if in.SchemaCompatibility != nil {
+		in, out := &in.SchemaCompatibility, &out.SchemaCompatibility
+		*out = new(SchemaCompatibilityStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetainedVersions != nil {
+		in, out := &in.RetainedVersions, &out.RetainedVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinDataSourceStatus.
@@ -327,7 +752,7 @@ func (in *XJoinIndex) DeepCopyInto(out *XJoinIndex) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinIndex.
@@ -447,6 +872,31 @@ func (in *XJoinIndexPipelineSpec) DeepCopyInto(out *XJoinIndexPipelineSpec) {
 		*out = make([]CustomSubgraphImage, len(*in))
 		copy(*out, *in)
 	}
+	if in.XJoinCore != nil {
+		in, out := &in.XJoinCore, &out.XJoinCore
+		*out = new(DeploymentSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.XJoinAPISubGraph != nil {
+		in, out := &in.XJoinAPISubGraph, &out.XJoinAPISubGraph
+		*out = new(XJoinAPISubGraphSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sink != nil {
+		in, out := &in.Sink, &out.Sink
+		*out = new(SinkSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DataSources != nil {
+		in, out := &in.DataSources, &out.DataSources
+		*out = make([]DataSourceVersionSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ValidationSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinIndexPipelineSpec.
@@ -465,11 +915,26 @@ func (in *XJoinIndexPipelineStatus) DeepCopyInto(out *XJoinIndexPipelineStatus)
 	in.ValidationResponse.DeepCopyInto(&out.ValidationResponse)
 	if in.DataSources != nil {
 		in, out := &in.DataSources, &out.DataSources
-		*out = make(map[string]string, len(*in))
+		*out = make(map[string]DataSourceVersion, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
+	if in.Deviations != nil {
+		in, out := &in.Deviations, &out.Deviations
+		*out = make([]ComponentDeviation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Remediations != nil {
+		in, out := &in.Remediations, &out.Remediations
+		*out = make([]ComponentRemediation, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValidationReport != nil {
+		in, out := &in.ValidationReport, &out.ValidationReport
+		*out = new(ValidationReportStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinIndexPipelineStatus.
@@ -490,6 +955,36 @@ func (in *XJoinIndexSpec) DeepCopyInto(out *XJoinIndexSpec) {
 		*out = make([]CustomSubgraphImage, len(*in))
 		copy(*out, *in)
 	}
+	if in.XJoinCore != nil {
+		in, out := &in.XJoinCore, &out.XJoinCore
+		*out = new(DeploymentSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.XJoinAPISubGraph != nil {
+		in, out := &in.XJoinAPISubGraph, &out.XJoinAPISubGraph
+		*out = new(XJoinAPISubGraphSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sink != nil {
+		in, out := &in.Sink, &out.Sink
+		*out = new(SinkSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DataSources != nil {
+		in, out := &in.DataSources, &out.DataSources
+		*out = make([]DataSourceVersionSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ValidationSpec)
+		**out = **in
+	}
+	if in.SchemaChange != nil {
+		in, out := &in.SchemaChange, &out.SchemaChange
+		*out = new(SchemaChangeSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinIndexSpec.
@@ -505,6 +1000,23 @@ func (in *XJoinIndexSpec) DeepCopy() *XJoinIndexSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *XJoinIndexStatus) DeepCopyInto(out *XJoinIndexStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(PlanStatus)
+		**out = **in
+	}
+	if in.SchemaCompatibility != nil {
+		in, out := &in.SchemaCompatibility, &out.SchemaCompatibility
+		*out = new(SchemaCompatibilityStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinIndexStatus.
@@ -522,7 +1034,7 @@ func (in *XJoinIndexValidator) DeepCopyInto(out *XJoinIndexValidator) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -579,6 +1091,11 @@ func (in *XJoinIndexValidatorList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *XJoinIndexValidatorSpec) DeepCopyInto(out *XJoinIndexValidatorSpec) {
 	*out = *in
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ValidationSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinIndexValidatorSpec.
@@ -595,6 +1112,11 @@ func (in *XJoinIndexValidatorSpec) DeepCopy() *XJoinIndexValidatorSpec {
 func (in *XJoinIndexValidatorStatus) DeepCopyInto(out *XJoinIndexValidatorStatus) {
 	*out = *in
 	in.ValidationResponse.DeepCopyInto(&out.ValidationResponse)
+	if in.ValidationCursor != nil {
+		in, out := &in.ValidationCursor, &out.ValidationCursor
+		*out = new(ValidationCursorStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XJoinIndexValidatorStatus.
@@ -0,0 +1,153 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/go-errors/errors"
+)
+
+// restoredAttributes are dropped when a schema is unmarshalled into avro.Type or when avro.Type is marshalled as a
+// plain string. They are required by avro for some types (e.g. name and symbols of an enum, items of an array,
+// values of a map) so they are added back to the index avro schema.
+var restoredAttributes = []string{"name", "items", "values", "logicalType", "precision", "scale", "symbols", "size"}
+
+// typeAttributes contains every attribute of the avro types in the index schema keyed by field path
+// e.g. host.created_on. Array items are keyed by the path of the array followed by [] e.g. host.tags[].
+// avro.Type only contains a subset of the attributes, the others (logicalType, precision, scale, symbols,
+// xjoin.es.*) are read from here.
+type typeAttributes map[string]map[string]interface{}
+
+// get returns the attributes of the type at path, the attributes are empty for fields added by transformations
+func (a typeAttributes) get(path string) map[string]interface{} {
+	if attributes, ok := a[path]; ok {
+		return attributes
+	}
+	return map[string]interface{}{}
+}
+
+// collectFields records the attributes of the type of each field in rawFields, the raw json of avro fields
+func (a typeAttributes) collectFields(rawFields interface{}, path string) {
+	fields, ok := rawFields.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, rawField := range fields {
+		field, ok := rawField.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := field["name"].(string)
+		if !ok {
+			continue
+		}
+		a.collectType(field["type"], joinPath(path, name))
+	}
+}
+
+// collectType records the attributes of rawType. Only the member of a union chosen by resolveUnion is recorded.
+func (a typeAttributes) collectType(rawType interface{}, path string) {
+	switch t := rawType.(type) {
+	case []interface{}:
+		idx := rawUnionIndex(t)
+		if idx >= 0 {
+			a.collectType(t[idx], path)
+		}
+	case map[string]interface{}:
+		a[path] = t
+		a.collectFields(t["fields"], path)
+		a.collectFields(t["xjoin.fields"], path)
+		if items, ok := t["items"]; ok {
+			a.collectType(items, path+"[]")
+		}
+	}
+}
+
+// restore adds the restoredAttributes that were dropped when unmarshalling into avro.Type back to schemaJSON.
+// schemaJSON is returned as is when no attribute is restored to keep the order of its keys.
+func (a typeAttributes) restore(schemaJSON []byte) ([]byte, error) {
+	var schema map[string]interface{}
+	err := json.Unmarshal(schemaJSON, &schema)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	unchanged, err := json.Marshal(schema)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	a.restoreFields(schema["fields"], "")
+
+	restored, err := json.Marshal(schema)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	if bytes.Equal(unchanged, restored) {
+		return schemaJSON, nil
+	}
+	return restored, nil
+}
+
+func (a typeAttributes) restoreFields(rawFields interface{}, path string) {
+	fields, ok := rawFields.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, rawField := range fields {
+		field, ok := rawField.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := field["name"].(string)
+		if !ok {
+			continue
+		}
+		field["type"] = a.restoreType(field["type"], joinPath(path, name))
+	}
+}
+
+func (a typeAttributes) restoreType(rawType interface{}, path string) interface{} {
+	switch t := rawType.(type) {
+	case []interface{}:
+		idx := rawUnionIndex(t)
+		if idx >= 0 {
+			t[idx] = a.restoreType(t[idx], path)
+		}
+		return t
+	case string:
+		restored := a.restoreType(map[string]interface{}{"type": t}, path).(map[string]interface{})
+		if len(restored) == 1 {
+			return t
+		}
+		return restored
+	case map[string]interface{}:
+		//fields added by transformations can have a different type than the original field at the same path
+		attributes := a.get(path)
+		if attributes["type"] != t["type"] {
+			attributes = map[string]interface{}{}
+		}
+		for _, key := range restoredAttributes {
+			if _, exists := t[key]; !exists && attributes[key] != nil {
+				t[key] = attributes[key]
+			}
+		}
+		a.restoreFields(t["fields"], path)
+		a.restoreFields(t["xjoin.fields"], path)
+		if items, ok := t["items"]; ok {
+			t["items"] = a.restoreType(items, path+"[]")
+		}
+		return t
+	default:
+		return rawType
+	}
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,366 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/go-errors/errors"
+	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+)
+
+// SchemaComparison is the result of comparing the raw json of two avro schemas
+type SchemaComparison struct {
+	//Backward is true when the next schema can read data written with the previous schema
+	Backward bool
+
+	//Forward is true when the previous schema can read data written with the next schema
+	Forward bool
+
+	Changes []xjoin.SchemaFieldChange
+}
+
+// Full is true when the schemas are both backward and forward compatible
+func (c SchemaComparison) Full() bool {
+	return c.Backward && c.Forward
+}
+
+// Additive is true when the only changes are new fields that can be read from data written with the previous schema
+func (c SchemaComparison) Additive() bool {
+	if len(c.Changes) == 0 || !c.Backward {
+		return false
+	}
+	for _, change := range c.Changes {
+		if change.Type != xjoin.SchemaFieldAdded {
+			return false
+		}
+	}
+	return true
+}
+
+// Satisfies is true when the schemas meet the compatibility level
+func (c SchemaComparison) Satisfies(compatibility string) bool {
+	switch compatibility {
+	case xjoin.SchemaCompatibilityBackward:
+		return c.Backward
+	case xjoin.SchemaCompatibilityForward:
+		return c.Forward
+	case xjoin.SchemaCompatibilityFull:
+		return c.Full()
+	default:
+		return true
+	}
+}
+
+// CompareSchemas checks the compatibility of two avro schemas following the avro schema resolution rules and lists
+// the fields that differ. Named types that are not defined in the schema e.g. references are compared by name.
+func CompareSchemas(previousSchema string, nextSchema string) (comparison SchemaComparison, err error) {
+	var previous, next interface{}
+	err = json.Unmarshal([]byte(previousSchema), &previous)
+	if err != nil {
+		return comparison, errors.Wrap(fmt.Errorf("unable to parse the previous schema: %w", err), 0)
+	}
+	err = json.Unmarshal([]byte(nextSchema), &next)
+	if err != nil {
+		return comparison, errors.Wrap(fmt.Errorf("unable to parse the new schema: %w", err), 0)
+	}
+
+	previousNames := make(namedRawTypes)
+	previousNames.collect(previous)
+	nextNames := make(namedRawTypes)
+	nextNames.collect(next)
+
+	comparison.Backward = schemaResolver{reader: nextNames, writer: previousNames}.canRead(next, previous)
+	comparison.Forward = schemaResolver{reader: previousNames, writer: nextNames}.canRead(previous, next)
+	diffRawTypes("", previous, next, &comparison.Changes)
+
+	return comparison, nil
+}
+
+// namedRawTypes are the definitions of the named types of a schema by name and full name
+type namedRawTypes map[string]map[string]interface{}
+
+func (n namedRawTypes) collect(rawType interface{}) {
+	_, _ = rewriteRawType(rawType, func(rawType interface{}) (interface{}, bool, error) {
+		t, ok := rawType.(map[string]interface{})
+		if !ok {
+			return rawType, false, nil
+		}
+		switch t["type"] {
+		case "record", "enum", "fixed":
+			name, _ := t["name"].(string)
+			n[name] = t
+			if namespace, ok := t["namespace"].(string); ok && namespace != "" {
+				n[namespace+"."+name] = t
+			}
+		}
+		return rawType, false, nil
+	})
+}
+
+// resolve returns the definition of a named type and unwraps types declared as {"type": <type>}
+func (n namedRawTypes) resolve(rawType interface{}) interface{} {
+	switch t := rawType.(type) {
+	case string:
+		if definition, ok := n[t]; ok {
+			return definition
+		}
+	case map[string]interface{}:
+		if _, isString := t["type"].(string); !isString && t["type"] != nil {
+			return n.resolve(t["type"])
+		} else if name, _ := t["type"].(string); !isAvroType(name) {
+			if definition, ok := n[name]; ok {
+				return definition
+			}
+			return name
+		}
+	}
+	return rawType
+}
+
+func isAvroType(name string) bool {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string",
+		"record", "enum", "array", "map", "fixed":
+		return true
+	}
+	return false
+}
+
+func rawTypeName(rawType interface{}) string {
+	switch t := rawType.(type) {
+	case string:
+		return t
+	case []interface{}:
+		return "union"
+	case map[string]interface{}:
+		name, _ := t["type"].(string)
+		return name
+	}
+	return ""
+}
+
+// schemaResolver checks if data written with the writer schema can be read with the reader schema
+type schemaResolver struct {
+	reader namedRawTypes
+	writer namedRawTypes
+}
+
+func (s schemaResolver) canRead(reader interface{}, writer interface{}) bool {
+	return s.canReadType(reader, writer, make(map[[2]string]bool))
+}
+
+func (s schemaResolver) canReadType(reader interface{}, writer interface{}, visiting map[[2]string]bool) bool {
+	reader = s.reader.resolve(reader)
+	writer = s.writer.resolve(writer)
+
+	//each branch of a written union must be readable
+	if writerUnion, ok := writer.([]interface{}); ok {
+		for _, branch := range writerUnion {
+			if !s.canReadType(reader, branch, visiting) {
+				return false
+			}
+		}
+		return true
+	}
+	if readerUnion, ok := reader.([]interface{}); ok {
+		for _, branch := range readerUnion {
+			if s.canReadType(branch, writer, visiting) {
+				return true
+			}
+		}
+		return false
+	}
+
+	readerType := rawTypeName(reader)
+	writerType := rawTypeName(writer)
+	readerMap, _ := reader.(map[string]interface{})
+	writerMap, _ := writer.(map[string]interface{})
+
+	switch {
+	case readerType == "record" && writerType == "record":
+		//recursive types are compatible while they are being compared
+		key := [2]string{fmt.Sprint(readerMap["name"]), fmt.Sprint(writerMap["name"])}
+		if visiting[key] {
+			return true
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+
+		writerFields := rawFieldsByName(writerMap)
+		readerFields, _ := readerMap["fields"].([]interface{})
+		for _, rawField := range readerFields {
+			field, _ := rawField.(map[string]interface{})
+			name, _ := field["name"].(string)
+			writerField, found := writerFields[name]
+			if !found {
+				for _, alias := range rawAliases(field) {
+					if writerField, found = writerFields[alias]; found {
+						break
+					}
+				}
+			}
+
+			if !found {
+				if _, hasDefault := field["default"]; !hasDefault {
+					return false
+				}
+			} else if !s.canReadType(field["type"], writerField["type"], visiting) {
+				return false
+			}
+		}
+		return true
+	case readerType == "enum" && writerType == "enum":
+		if _, hasDefault := readerMap["default"]; hasDefault {
+			return true
+		}
+		readerSymbols := make(map[interface{}]bool)
+		for _, symbol := range rawSlice(readerMap["symbols"]) {
+			readerSymbols[symbol] = true
+		}
+		for _, symbol := range rawSlice(writerMap["symbols"]) {
+			if !readerSymbols[symbol] {
+				return false
+			}
+		}
+		return true
+	case readerType == "array" && writerType == "array":
+		return s.canReadType(readerMap["items"], writerMap["items"], visiting)
+	case readerType == "map" && writerType == "map":
+		return s.canReadType(readerMap["values"], writerMap["values"], visiting)
+	case readerType == "fixed" && writerType == "fixed":
+		return reflect.DeepEqual(readerMap["size"], writerMap["size"])
+	}
+
+	return readerType == writerType || isPromotion(writerType, readerType)
+}
+
+// isPromotion is true when the avro type written can be promoted to the type read
+func isPromotion(writerType string, readerType string) bool {
+	switch writerType {
+	case "int":
+		return readerType == "long" || readerType == "float" || readerType == "double"
+	case "long":
+		return readerType == "float" || readerType == "double"
+	case "float":
+		return readerType == "double"
+	case "string":
+		return readerType == "bytes"
+	case "bytes":
+		return readerType == "string"
+	}
+	return false
+}
+
+// diffRawTypes appends the fields that differ between two raw avro types
+func diffRawTypes(path string, previous interface{}, next interface{}, changes *[]xjoin.SchemaFieldChange) {
+	previousMap, previousIsMap := previous.(map[string]interface{})
+	nextMap, nextIsMap := next.(map[string]interface{})
+	previousUnion, previousIsUnion := previous.([]interface{})
+	nextUnion, nextIsUnion := next.([]interface{})
+
+	switch {
+	case previousIsMap && nextIsMap:
+		nestedKeys := []string{"type", "fields", "items", "values", "doc"}
+		if !reflect.DeepEqual(withoutKeys(previousMap, nestedKeys), withoutKeys(nextMap, nestedKeys)) {
+			*changes = append(*changes, xjoin.SchemaFieldChange{Path: path, Type: xjoin.SchemaFieldChanged})
+			return
+		}
+
+		_, previousTypeIsString := previousMap["type"].(string)
+		_, nextTypeIsString := nextMap["type"].(string)
+		if previousTypeIsString != nextTypeIsString ||
+			(previousTypeIsString && previousMap["type"] != nextMap["type"]) {
+			*changes = append(*changes, xjoin.SchemaFieldChange{Path: path, Type: xjoin.SchemaFieldChanged})
+			return
+		} else if !previousTypeIsString {
+			diffRawTypes(path, previousMap["type"], nextMap["type"], changes)
+		}
+
+		diffRawFields(path, previousMap, nextMap, changes)
+		for _, key := range []string{"items", "values"} {
+			if previousMap[key] != nil || nextMap[key] != nil {
+				diffRawTypes(path, previousMap[key], nextMap[key], changes)
+			}
+		}
+	case previousIsUnion && nextIsUnion && len(previousUnion) == len(nextUnion):
+		for idx := range previousUnion {
+			diffRawTypes(path, previousUnion[idx], nextUnion[idx], changes)
+		}
+	default:
+		if !reflect.DeepEqual(previous, next) {
+			*changes = append(*changes, xjoin.SchemaFieldChange{Path: path, Type: xjoin.SchemaFieldChanged})
+		}
+	}
+}
+
+func diffRawFields(path string, previous map[string]interface{}, next map[string]interface{},
+	changes *[]xjoin.SchemaFieldChange) {
+
+	previousFields := rawFieldsByName(previous)
+	nextFields := rawFieldsByName(next)
+
+	for _, rawField := range rawSlice(previous["fields"]) {
+		field, _ := rawField.(map[string]interface{})
+		name, _ := field["name"].(string)
+		fieldPath := joinPath(path, name)
+
+		nextField, found := nextFields[name]
+		if !found {
+			*changes = append(*changes, xjoin.SchemaFieldChange{Path: fieldPath, Type: xjoin.SchemaFieldRemoved})
+			continue
+		}
+
+		fieldKeys := []string{"type", "doc"}
+		if !reflect.DeepEqual(withoutKeys(field, fieldKeys), withoutKeys(nextField, fieldKeys)) {
+			*changes = append(*changes, xjoin.SchemaFieldChange{Path: fieldPath, Type: xjoin.SchemaFieldChanged})
+			continue
+		}
+		diffRawTypes(fieldPath, field["type"], nextField["type"], changes)
+	}
+
+	for _, rawField := range rawSlice(next["fields"]) {
+		field, _ := rawField.(map[string]interface{})
+		name, _ := field["name"].(string)
+		if _, found := previousFields[name]; !found {
+			*changes = append(*changes,
+				xjoin.SchemaFieldChange{Path: joinPath(path, name), Type: xjoin.SchemaFieldAdded})
+		}
+	}
+}
+
+func rawFieldsByName(record map[string]interface{}) map[string]map[string]interface{} {
+	fields := make(map[string]map[string]interface{})
+	for _, rawField := range rawSlice(record["fields"]) {
+		if field, ok := rawField.(map[string]interface{}); ok {
+			name, _ := field["name"].(string)
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+func rawAliases(field map[string]interface{}) (aliases []string) {
+	for _, alias := range rawSlice(field["aliases"]) {
+		if name, ok := alias.(string); ok {
+			aliases = append(aliases, name)
+		}
+	}
+	return aliases
+}
+
+func rawSlice(value interface{}) []interface{} {
+	slice, _ := value.([]interface{})
+	return slice
+}
+
+func withoutKeys(rawType map[string]interface{}, keys []string) map[string]interface{} {
+	copied := make(map[string]interface{}, len(rawType))
+	for key, value := range rawType {
+		copied[key] = value
+	}
+	for _, key := range keys {
+		delete(copied, key)
+	}
+	return copied
+}
@@ -0,0 +1,67 @@
+package avro
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+)
+
+// DataSourceField is a field of the index documents that contains the record of a data source
+type DataSourceField struct {
+	Path       string   //dot separated path of the field in the index documents
+	DataSource string   //name of the data source
+	PrimaryKey string   //field of the data source record with xjoin.primary.key
+	Fields     []string //indexed fields of the data source record
+}
+
+// DataSourceFields lists the fields of the index that contain the record of a data source, including the fields of
+// nested records. References inside arrays are skipped because a document contains many records of those data sources.
+func (s IndexAvroSchema) DataSourceFields() (fields []DataSourceField, err error) {
+	err = collectDataSourceFields(s.AvroSchema.Fields, "", &fields)
+	return fields, err
+}
+
+func collectDataSourceFields(avroFields []Field, path string, dataSourceFields *[]DataSourceField) error {
+	for _, avroField := range avroFields {
+		if avroField.XJoinIndex != nil && !*avroField.XJoinIndex {
+			continue
+		}
+
+		fieldPath := joinPath(path, avroField.Name)
+		avroFieldType, err := resolveUnion(avroField.Type)
+		if err != nil {
+			return fmt.Errorf("invalid type of field %s: %w", fieldPath, err)
+		}
+
+		if avroFieldType.XJoinType == "reference" {
+			dataSourceField := DataSourceField{
+				Path:       fieldPath,
+				DataSource: strings.Split(avroFieldType.Name, ".")[0],
+			}
+			for _, recordField := range avroFieldType.Fields {
+				if recordField.XJoinIndex != nil && !*recordField.XJoinIndex {
+					continue
+				}
+				dataSourceField.Fields = append(dataSourceField.Fields, recordField.Name)
+
+				recordFieldType, err := resolveUnion(recordField.Type)
+				if err == nil && recordFieldType.XJoinPrimaryKey && dataSourceField.PrimaryKey == "" {
+					dataSourceField.PrimaryKey = recordField.Name
+				}
+			}
+			if dataSourceField.PrimaryKey == "" {
+				return fmt.Errorf("the record of data source %s in field %s has no xjoin.primary.key field",
+					dataSourceField.DataSource, fieldPath)
+			}
+			*dataSourceFields = append(*dataSourceFields, dataSourceField)
+		} else if avroFieldType.Type == "record" {
+			err = collectDataSourceFields(avroFieldType.Fields, fieldPath, dataSourceFields)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package avro
+
+import (
+	"fmt"
+
+	"github.com/go-errors/errors"
+	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// validateDataSourceSpecs checks each data source listed in the spec is referenced by the avro schema
+func (d *IndexAvroSchemaParser) validateDataSourceSpecs(dataSourceNames []string) error {
+	referenced := make(map[string]bool)
+	for _, name := range dataSourceNames {
+		referenced[name] = true
+	}
+
+	listed := make(map[string]bool)
+	for _, spec := range d.DataSources {
+		if listed[spec.Name] {
+			return fmt.Errorf("data source %s is listed more than once in spec.dataSources", spec.Name)
+		}
+		listed[spec.Name] = true
+
+		if !referenced[spec.Name] {
+			return fmt.Errorf("data source %s in spec.dataSources is not referenced by the avro schema", spec.Name)
+		}
+	}
+	return nil
+}
+
+func (d *IndexAvroSchemaParser) dataSourceSpec(dataSourceName string) xjoin.DataSourceVersionSpec {
+	for _, spec := range d.DataSources {
+		if spec.Name == dataSourceName {
+			return spec
+		}
+	}
+	return xjoin.DataSourceVersionSpec{Name: dataSourceName}
+}
+
+// chooseDataSourceVersion determines which version of a data source is used. A version already used by the
+// IndexPipeline is kept so the pipeline isn't switched to another data source version while it's running.
+func (d *IndexAvroSchemaParser) chooseDataSourceVersion(dataSourceName string) (
+	version xjoin.DataSourceVersion, err error) {
+
+	spec := d.dataSourceSpec(dataSourceName)
+
+	used, ok := d.UsedDataSources[dataSourceName]
+	if ok && used.Version != "" && (spec.Version == "" || spec.Version == used.Version) {
+		return used, nil
+	}
+
+	if spec.Version != "" {
+		return xjoin.DataSourceVersion{Version: spec.Version, Policy: xjoin.DataSourceVersionPinned}, nil
+	}
+
+	//get data source obj from the reference name
+	dataSource := &unstructured.Unstructured{}
+	dataSource.SetGroupVersionKind(common.DataSourceGVK)
+	err = d.Client.Get(d.Context, client.ObjectKey{Name: dataSourceName, Namespace: d.Namespace}, dataSource)
+	if err != nil {
+		return version, errors.Wrap(err, 0)
+	}
+
+	status := dataSource.Object["status"]
+	if status == nil {
+		err = errors.New("status missing from datasource")
+		return version, errors.Wrap(err, 0)
+	}
+	statusMap := status.(map[string]interface{})
+
+	activeVersion, _ := statusMap["activeVersion"].(string)
+	refreshingVersion, _ := statusMap["refreshingVersion"].(string)
+	activeVersionIsValid, _ := statusMap["activeVersionIsValid"].(bool)
+	refreshingVersionIsValid, _ := statusMap["refreshingVersionIsValid"].(bool)
+
+	version.Policy = spec.Policy
+	switch spec.Policy {
+	case "":
+		if activeVersion != "" && (activeVersionIsValid || d.Active) {
+			version.Version = activeVersion
+		} else if refreshingVersion != "" {
+			version.Version = refreshingVersion
+		}
+	case xjoin.DataSourceVersionPolicyActiveOnly:
+		version.Version = activeVersion
+	case xjoin.DataSourceVersionPolicyPreferRefreshing:
+		if refreshingVersion != "" {
+			version.Version = refreshingVersion
+		} else {
+			version.Version = activeVersion
+		}
+	case xjoin.DataSourceVersionPolicyLatestValid:
+		if refreshingVersion != "" && refreshingVersionIsValid {
+			version.Version = refreshingVersion
+		} else if activeVersion != "" && activeVersionIsValid {
+			version.Version = activeVersion
+		}
+	default:
+		return version, fmt.Errorf("unknown version policy %s for data source %s", spec.Policy, dataSourceName)
+	}
+
+	if version.Version == "" {
+		if spec.Policy == "" {
+			return version, errors.Wrap(errors.New(
+				"Datasource ("+dataSourceName+") is not ready yet. It has no active or refreshing version."), 0)
+		}
+		return version, errors.Wrap(fmt.Errorf(
+			"Datasource (%s) has no version matching the %s policy yet.", dataSourceName, spec.Policy), 0)
+	}
+
+	return version, nil
+}
+
+// resolveSchemaVersion returns the schema registry version of a data source subject, the latest version is used
+// when version is 0
+func (d *IndexAvroSchemaParser) resolveSchemaVersion(subject string, version int) (int, error) {
+	schema, err := d.fetchSubject(registrySubject{subject: subject, version: version})
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+	return schema.Version(), nil
+}
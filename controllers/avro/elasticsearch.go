@@ -0,0 +1,429 @@
+package avro
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+)
+
+// Units of numeric date fields
+const (
+	EpochMillis = "millis"
+	EpochMicros = "micros"
+	EpochNanos  = "nanos"
+	EpochDays   = "days"
+)
+
+// Elasticsearch type mappings selected by the elasticsearch.type.mapping parameter
+const (
+	TypeMappingLegacy = "legacy"
+	TypeMappingTyped  = "typed"
+)
+
+// DateField is a numeric date field whose value isn't in epoch millis. Elasticsearch parses numeric dates as epoch
+// millis so the value is converted by the ingest pipeline.
+type DateField struct {
+	Field string `json:"field"`
+	Unit  string `json:"unit"`
+}
+
+// elasticsearchPropertyBuilder builds the elasticsearch mapping of an avro type. attributes contains every
+// attribute of the avro type including the ones missing from avro.Type e.g. logicalType, precision, scale, symbols.
+type elasticsearchPropertyBuilder func(avroType Type, attributes map[string]interface{}) (map[string]interface{}, error)
+
+// legacyElasticsearchTypes is the mapping of the indexes created before the avro types were mapped. Only the
+// xjoin.type of a field is used, every other type is mapped to keyword.
+var legacyElasticsearchTypes = map[string]string{
+	"date_nanos": "date_nanos",
+	"string":     "keyword",
+	"boolean":    "boolean",
+	"json":       "object",
+	"record":     "object",
+	"reference":  "object",
+}
+
+// elasticsearchTypes maps xjoin.type, connect.name, logicalType and avro type names to elasticsearch mappings.
+// A type is looked up by xjoin.type first, then connect.name, then logicalType and finally the avro type.
+// xjoin.type can be set to any key of this table to choose the mapping of a field, other xjoin.type values are
+// rejected. xjoin.es.type overrides the elasticsearch type entirely.
+var elasticsearchTypes = map[string]elasticsearchPropertyBuilder{
+	//xjoin.type
+	"json":       simpleType("object"),
+	"reference":  simpleType("object"),
+	"date_nanos": simpleType("date_nanos"),
+	"byte":       simpleType("byte"),
+	"short":      simpleType("short"),
+	"integer":    simpleType("integer"),
+	"keyword":    simpleType("keyword"),
+	"text":       simpleType("text"),
+
+	//avro types
+	"string":  simpleType("keyword"),
+	"boolean": simpleType("boolean"),
+	"int":     simpleType("integer"),
+	"long":    simpleType("long"),
+	"float":   simpleType("float"),
+	"double":  simpleType("double"),
+	"bytes":   simpleType("binary"),
+	"fixed":   simpleType("binary"),
+	"record":  simpleType("object"),
+	"map":     simpleType("object"),
+	"enum":    enumType,
+
+	//avro logical types
+	"date":                   simpleType("date"),
+	"decimal":                decimalType,
+	"uuid":                   simpleType("keyword"),
+	"time-millis":            simpleType("integer"),
+	"time-micros":            simpleType("long"),
+	"timestamp-millis":       simpleType("date"),
+	"timestamp-micros":       simpleType("date"),
+	"local-timestamp-millis": simpleType("date"),
+	"local-timestamp-micros": simpleType("date"),
+
+	//kafka connect and debezium types
+	"org.apache.kafka.connect.data.Date":      simpleType("date"),
+	"org.apache.kafka.connect.data.Time":      simpleType("integer"),
+	"org.apache.kafka.connect.data.Timestamp": simpleType("date"),
+	"org.apache.kafka.connect.data.Decimal":   decimalType,
+	"io.debezium.time.Date":                   simpleType("date"),
+	"io.debezium.time.Time":                   simpleType("integer"),
+	"io.debezium.time.MicroTime":              simpleType("long"),
+	"io.debezium.time.NanoTime":               simpleType("long"),
+	"io.debezium.time.Timestamp":              simpleType("date"),
+	"io.debezium.time.MicroTimestamp":         simpleType("date"),
+	"io.debezium.time.NanoTimestamp":          simpleType("date"),
+	"io.debezium.time.ZonedTimestamp":         simpleType("date"),
+	"io.debezium.data.Uuid":                   simpleType("keyword"),
+}
+
+// epochUnits are the units of the numeric date types by connect.name and logicalType
+var epochUnits = map[string]string{
+	"date":                                    EpochDays,
+	"timestamp-millis":                        EpochMillis,
+	"timestamp-micros":                        EpochMicros,
+	"local-timestamp-millis":                  EpochMillis,
+	"local-timestamp-micros":                  EpochMicros,
+	"org.apache.kafka.connect.data.Date":      EpochDays,
+	"org.apache.kafka.connect.data.Timestamp": EpochMillis,
+	"io.debezium.time.Date":                   EpochDays,
+	"io.debezium.time.Timestamp":              EpochMillis,
+	"io.debezium.time.MicroTimestamp":         EpochMicros,
+	"io.debezium.time.NanoTimestamp":          EpochNanos,
+}
+
+var avroNamePattern = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+func simpleType(esType string) elasticsearchPropertyBuilder {
+	return func(avroType Type, attributes map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"type": esType}, nil
+	}
+}
+
+// enumType maps an avro enum to a keyword after validating its symbols
+func enumType(avroType Type, attributes map[string]interface{}) (map[string]interface{}, error) {
+	symbols, ok := attributes["symbols"].([]interface{})
+	if !ok || len(symbols) == 0 {
+		return nil, errors.New("enum symbols are missing")
+	}
+
+	unique := make(map[string]bool)
+	for _, s := range symbols {
+		symbol, ok := s.(string)
+		if !ok || !avroNamePattern.MatchString(symbol) {
+			return nil, fmt.Errorf("invalid enum symbol %v, symbols must match %s", s, avroNamePattern.String())
+		}
+		if unique[symbol] {
+			return nil, fmt.Errorf("duplicate enum symbol %s", symbol)
+		}
+		unique[symbol] = true
+	}
+
+	if defaultSymbol, ok := attributes["default"]; ok {
+		if symbol, isString := defaultSymbol.(string); !isString || !unique[symbol] {
+			return nil, fmt.Errorf("enum default %v is not one of the symbols", defaultSymbol)
+		}
+	}
+
+	return map[string]interface{}{"type": "keyword"}, nil
+}
+
+// decimalType maps an avro decimal to a scaled_float which stores the value scaled by 10^scale as a long
+func decimalType(avroType Type, attributes map[string]interface{}) (map[string]interface{}, error) {
+	scale, err := integerAttribute(attributes, "scale")
+	if err != nil {
+		return nil, err
+	}
+
+	precision, err := integerAttribute(attributes, "precision")
+	if err != nil {
+		return nil, err
+	}
+	if precision != nil && *precision < 1 {
+		return nil, fmt.Errorf("decimal precision must be greater than 0, got %d", *precision)
+	}
+
+	scalingFactor := 1.0
+	if scale != nil {
+		if *scale < 0 || (precision != nil && *scale > *precision) {
+			return nil, fmt.Errorf("decimal scale must be between 0 and the precision, got %d", *scale)
+		}
+		scalingFactor = math.Pow10(*scale)
+	}
+
+	return map[string]interface{}{"type": "scaled_float", "scaling_factor": scalingFactor}, nil
+}
+
+// integerAttribute reads an integer attribute, kafka connect decimals store the scale in connect.parameters
+func integerAttribute(attributes map[string]interface{}, name string) (*int, error) {
+	value, ok := attributes[name]
+	if !ok {
+		if connectParameters, isMap := attributes["connect.parameters"].(map[string]interface{}); isMap {
+			value, ok = connectParameters[name]
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case float64:
+		i := int(v)
+		return &i, nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer, got %s", name, v)
+		}
+		return &i, nil
+	default:
+		return nil, fmt.Errorf("%s must be an integer, got %v", name, v)
+	}
+}
+
+// elasticsearchMapping builds the elasticsearch properties of the index avro schema
+type elasticsearchMapping struct {
+	attributes  typeAttributes
+	typeMapping string //legacy or typed
+	jsonFields  []string
+	dateFields  []DateField
+
+	//analysis components and copy_to targets referenced by xjoin.es.* attributes
+	analyzers   map[string]bool
+	normalizers map[string]bool
+	copyTo      map[string]bool
+}
+
+func newElasticsearchMapping(attributes typeAttributes, typeMapping string) (*elasticsearchMapping, error) {
+	if typeMapping != TypeMappingLegacy && typeMapping != TypeMappingTyped {
+		return nil, fmt.Errorf("elasticsearch type mapping must be one of [%s, %s], got %s",
+			TypeMappingLegacy, TypeMappingTyped, typeMapping)
+	}
+
+	return &elasticsearchMapping{
+		attributes:  attributes,
+		typeMapping: typeMapping,
+		analyzers:   make(map[string]bool),
+		normalizers: make(map[string]bool),
+		copyTo:      make(map[string]bool),
+	}, nil
+}
+
+func (m *elasticsearchMapping) parseAvroFields(avroFields []Field, path string) (map[string]interface{}, error) {
+	esProperties := make(map[string]interface{})
+
+	for _, avroField := range avroFields {
+		if avroField.XJoinIndex != nil && !*avroField.XJoinIndex {
+			continue
+		}
+
+		fieldPath := joinPath(path, avroField.Name)
+		avroFieldType, err := resolveUnion(avroField.Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type of field %s: %w", fieldPath, err)
+		}
+
+		esProperty, err := m.typeToProperty(avroFieldType, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+
+		esProperty, err = m.parseXJoinFlags(avroFieldType, m.hintAttributes(fieldPath), esProperty)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %s: %w", fieldPath, err)
+		}
+
+		//find json fields which need to be transformed from a string
+		//fields inside arrays keep the [] of each array in the path e.g. hosts[].facts
+		if avroFieldType.XJoinType == "json" && avroFieldType.Type == "string" {
+			m.jsonFields = append(m.jsonFields, fieldPath)
+		}
+
+		esProperties[avroField.Name] = esProperty
+	}
+
+	return esProperties, nil
+}
+
+// typeToProperty builds the elasticsearch property of the avro type of the field at path
+func (m *elasticsearchMapping) typeToProperty(avroType Type, path string) (map[string]interface{}, error) {
+	attributes := m.attributes.get(path)
+
+	err := validateXJoinType(avroType, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.typeMapping == TypeMappingLegacy {
+		return m.legacyTypeToProperty(avroType, attributes, path)
+	}
+
+	//elasticsearch has no array type, arrays (including arrays of arrays) are mapped to the type of their items
+	if avroType.Type == "array" && (avroType.XJoinType == "" || avroType.XJoinType == "array") {
+		if esType, ok := attributes["xjoin.es.type"].(string); ok && esType != "" {
+			return map[string]interface{}{"type": esType}, nil
+		}
+		if len(avroType.Items) == 0 {
+			return nil, fmt.Errorf("unable to map field %s: items are missing from array", path)
+		}
+		itemType, err := resolveUnion(avroType.Items)
+		if err != nil {
+			return nil, fmt.Errorf("invalid items of array %s: %w", path, err)
+		}
+		return m.typeToProperty(itemType, path+"[]")
+	}
+
+	var esProperty map[string]interface{}
+	if esType, ok := attributes["xjoin.es.type"].(string); ok && esType != "" {
+		esProperty = map[string]interface{}{"type": esType}
+	} else {
+		builder, err := lookupElasticsearchType(avroType, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to map field %s: %w", path, err)
+		}
+		esProperty, err = builder(avroType, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to map field %s: %w", path, err)
+		}
+	}
+
+	err = m.addDateField(avroType, attributes, esProperty, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.addNestedProperties(avroType, esProperty, path)
+}
+
+// legacyTypeToProperty builds the property of the legacy mapping, the type is looked up by xjoin.type only and the
+// types without a legacy mapping are mapped to keyword. Numeric dates are not converted.
+func (m *elasticsearchMapping) legacyTypeToProperty(
+	avroType Type, attributes map[string]interface{}, path string) (map[string]interface{}, error) {
+
+	esType, ok := attributes["xjoin.es.type"].(string)
+	if !ok || esType == "" {
+		typeName := avroType.XJoinType
+		if typeName == "array" && len(avroType.Items) > 0 {
+			typeName = avroType.Items[0].Type
+		}
+
+		esType, ok = legacyElasticsearchTypes[strings.ToLower(typeName)]
+		if !ok {
+			esType = "keyword"
+		}
+	}
+
+	return m.addNestedProperties(avroType, map[string]interface{}{"type": esType}, path)
+}
+
+// addNestedProperties adds the properties of the fields nested in an object type
+func (m *elasticsearchMapping) addNestedProperties(
+	avroType Type, esProperty map[string]interface{}, path string) (map[string]interface{}, error) {
+
+	if esProperty["type"] == "object" {
+		//nested json objects are "type: string", "xjoin.type: json" with xjoin.fields
+		//top level records are "type: record" with standard avro fields
+		var nestedFields []Field
+		if avroType.Fields != nil {
+			nestedFields = avroType.Fields
+		} else if avroType.XJoinFields != nil {
+			nestedFields = avroType.XJoinFields
+		}
+
+		if nestedFields != nil {
+			nestedProperties, err := m.parseAvroFields(nestedFields, path)
+			if err != nil {
+				return nil, err
+			}
+			esProperty["properties"] = nestedProperties
+		}
+	}
+
+	return esProperty, nil
+}
+
+// validateXJoinType returns an error when the xjoin.type of a field has no mapping. Both type mappings reject it so
+// a typo isn't indexed with the mapping of the avro type.
+func validateXJoinType(avroType Type, path string) error {
+	xjoinType := strings.ToLower(avroType.XJoinType)
+	if xjoinType == "" || xjoinType == "array" {
+		return nil
+	}
+	if _, ok := elasticsearchTypes[xjoinType]; ok {
+		return nil
+	}
+	return fmt.Errorf("unable to map field %s: unknown xjoin.type %s", path, avroType.XJoinType)
+}
+
+// lookupElasticsearchType finds the entry of the mapping table for an avro type. Unknown connect.name and logicalType
+// values fall back to the underlying avro type as defined by the avro specification.
+func lookupElasticsearchType(avroType Type, attributes map[string]interface{}) (elasticsearchPropertyBuilder, error) {
+	if builder, ok := elasticsearchTypes[strings.ToLower(avroType.XJoinType)]; ok {
+		return builder, nil
+	}
+
+	logicalType, _ := attributes["logicalType"].(string)
+	for _, name := range []string{avroType.ConnectName, logicalType} {
+		if builder, ok := elasticsearchTypes[name]; ok && name != "" {
+			return builder, nil
+		}
+	}
+
+	builder, ok := elasticsearchTypes[avroType.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported avro type %s", avroType.Type)
+	}
+	return builder, nil
+}
+
+// addDateField records numeric date fields that have to be converted to epoch millis by the ingest pipeline
+func (m *elasticsearchMapping) addDateField(
+	avroType Type, attributes map[string]interface{}, esProperty map[string]interface{}, path string) error {
+
+	if esProperty["type"] != "date" && esProperty["type"] != "date_nanos" {
+		return nil
+	}
+	if avroType.Type != "int" && avroType.Type != "long" {
+		return nil
+	}
+
+	logicalType, _ := attributes["logicalType"].(string)
+	unit, ok := epochUnits[avroType.ConnectName]
+	if !ok {
+		unit = epochUnits[logicalType]
+	}
+	if unit == "" || unit == EpochMillis {
+		return nil
+	}
+
+	if strings.Contains(path, "[]") {
+		return fmt.Errorf("unable to map field %s: dates in %s are not supported inside arrays", path, unit)
+	}
+	m.dateFields = append(m.dateFields, DateField{Field: path, Unit: unit})
+	return nil
+}
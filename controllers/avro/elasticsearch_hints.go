@@ -0,0 +1,311 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+)
+
+// xjoinESPrefix is the prefix of the attributes that tune the elasticsearch mapping of a field
+const xjoinESPrefix = "xjoin.es."
+
+// xjoinESAnalysis is an attribute of the index avro schema that defines custom analysis components
+// e.g. "xjoin.es.analysis": {"analyzer": {"name_search": {"tokenizer": "standard", "filter": ["lowercase"]}}}
+const xjoinESAnalysis = "xjoin.es.analysis"
+
+// elasticsearchHints are the supported xjoin.es.* attributes of a field
+var elasticsearchHints = map[string]bool{
+	"xjoin.es.type":            true,
+	"xjoin.es.analyzer":        true,
+	"xjoin.es.search_analyzer": true,
+	"xjoin.es.normalizer":      true,
+	"xjoin.es.nested":          true,
+	"xjoin.es.copy_to":         true,
+	"xjoin.es.doc_values":      true,
+	"xjoin.es.index":           true,
+	"xjoin.es.ignore_above":    true,
+}
+
+// analysisSections are the analysis components that can be defined in xjoin.es.analysis
+var analysisSections = map[string]bool{
+	"analyzer":    true,
+	"normalizer":  true,
+	"tokenizer":   true,
+	"filter":      true,
+	"char_filter": true,
+}
+
+// builtinAnalysis are the analyzers and normalizers that can be used without defining them in xjoin.es.analysis
+var builtinAnalysis = map[string]map[string]interface{}{
+	"normalizer": {
+		"case_insensitive": map[string]interface{}{"filter": "lowercase"},
+	},
+	"analyzer": {
+		"folding": map[string]interface{}{"tokenizer": "standard", "filter": []interface{}{"lowercase", "asciifolding"}},
+	},
+}
+
+// elasticsearchNormalizers are the normalizers provided by elasticsearch
+var elasticsearchNormalizers = map[string]bool{"lowercase": true}
+
+// hintAttributes returns the attributes of the field at path. The attributes of array items are included so hints
+// can be set on the array or on its items, the attributes of the array take precedence.
+func (m *elasticsearchMapping) hintAttributes(path string) map[string]interface{} {
+	hints := make(map[string]interface{})
+	for attributesPath := path; ; attributesPath = attributesPath + "[]" {
+		attributes, ok := m.attributes[attributesPath]
+		if !ok {
+			return hints
+		}
+		for key, value := range attributes {
+			if _, exists := hints[key]; !exists {
+				hints[key] = value
+			}
+		}
+	}
+}
+
+// parseXJoinFlags applies the xjoin.case and xjoin.es.* attributes of a field to its elasticsearch property
+func (m *elasticsearchMapping) parseXJoinFlags(avroFieldType Type, attributes map[string]interface{},
+	esProperty map[string]interface{}) (map[string]interface{}, error) {
+
+	if avroFieldType.XJoinCase != "" {
+		if avroFieldType.XJoinType != "string" {
+			return nil, errors.Wrap(errors.New("xjoin.case can only be applied to string fields"), 0)
+		}
+
+		if avroFieldType.XJoinCase == "insensitive" {
+			fields := make(map[string]interface{})
+			lowercaseField := make(map[string]interface{})
+			lowercaseField["type"] = "keyword"
+			lowercaseField["normalizer"] = "case_insensitive"
+			fields["lowercase"] = lowercaseField
+			esProperty["fields"] = fields
+			m.normalizers["case_insensitive"] = true
+		} else if avroFieldType.XJoinCase != "sensitive" {
+			return nil, errors.Wrap(errors.New("xjoin.case must be one of [insensitive, sensitive]"), 0)
+		}
+	}
+
+	for key := range attributes {
+		if strings.HasPrefix(key, xjoinESPrefix) && !elasticsearchHints[key] {
+			return nil, fmt.Errorf("unknown attribute %s, must be one of [%s]", key, supportedHints())
+		}
+	}
+
+	esType := esProperty["type"]
+
+	if nested, ok := attributes["xjoin.es.nested"]; ok {
+		isNested, isBool := nested.(bool)
+		if !isBool {
+			return nil, errors.New("xjoin.es.nested must be a boolean")
+		}
+		if isNested {
+			if esType != "object" {
+				return nil, errors.New("xjoin.es.nested can only be applied to records or arrays of records")
+			}
+			esProperty["type"] = "nested"
+		}
+	}
+
+	for _, hint := range []struct {
+		attribute string
+		types     []string
+		analysis  map[string]bool
+	}{
+		{attribute: "xjoin.es.analyzer", types: []string{"text"}, analysis: m.analyzers},
+		{attribute: "xjoin.es.search_analyzer", types: []string{"text"}, analysis: m.analyzers},
+		{attribute: "xjoin.es.normalizer", types: []string{"keyword"}, analysis: m.normalizers},
+	} {
+		value, ok := attributes[hint.attribute]
+		if !ok {
+			continue
+		}
+		name, isString := value.(string)
+		if !isString || name == "" {
+			return nil, fmt.Errorf("%s must be a string", hint.attribute)
+		}
+		if !containsType(hint.types, esType) {
+			return nil, fmt.Errorf("%s can only be applied to %s fields", hint.attribute, strings.Join(hint.types, ", "))
+		}
+		esProperty[strings.TrimPrefix(hint.attribute, xjoinESPrefix)] = name
+		hint.analysis[name] = true
+	}
+
+	if ignoreAbove, ok := attributes["xjoin.es.ignore_above"]; ok {
+		value, isNumber := ignoreAbove.(float64)
+		if !isNumber || value < 1 || value != float64(int(value)) {
+			return nil, errors.New("xjoin.es.ignore_above must be a positive integer")
+		}
+		if esType != "keyword" {
+			return nil, errors.New("xjoin.es.ignore_above can only be applied to keyword fields")
+		}
+		esProperty["ignore_above"] = int(value)
+	}
+
+	for _, hint := range []struct {
+		attribute    string
+		invalidTypes []string
+	}{
+		{attribute: "xjoin.es.doc_values", invalidTypes: []string{"text", "object", "nested"}},
+		{attribute: "xjoin.es.index", invalidTypes: []string{"object", "nested"}},
+	} {
+		value, ok := attributes[hint.attribute]
+		if !ok {
+			continue
+		}
+		if _, isBool := value.(bool); !isBool {
+			return nil, fmt.Errorf("%s must be a boolean", hint.attribute)
+		}
+		if containsType(hint.invalidTypes, esType) {
+			return nil, fmt.Errorf("%s can't be applied to %s fields", hint.attribute, esType)
+		}
+		esProperty[strings.TrimPrefix(hint.attribute, xjoinESPrefix)] = value
+	}
+
+	if copyTo, ok := attributes["xjoin.es.copy_to"]; ok {
+		targets, err := stringOrStrings(copyTo)
+		if err != nil {
+			return nil, fmt.Errorf("xjoin.es.copy_to %w", err)
+		}
+		if esType == "object" || esType == "nested" {
+			return nil, errors.New("xjoin.es.copy_to can't be applied to records")
+		}
+		for _, target := range targets {
+			m.copyTo[target] = true
+		}
+		esProperty["copy_to"] = targets
+	}
+
+	return esProperty, nil
+}
+
+// addCopyToFields adds the copy_to targets that aren't fields of the schema as top level text fields
+func (m *elasticsearchMapping) addCopyToFields(esProperties map[string]interface{}) error {
+	for target := range m.copyTo {
+		if propertyExists(esProperties, target) {
+			continue
+		}
+		if strings.Contains(target, ".") {
+			return fmt.Errorf("copy_to target %s must be a field of the schema or a top level field name", target)
+		}
+		esProperties[target] = map[string]interface{}{"type": "text"}
+	}
+	return nil
+}
+
+// buildAnalysis generates the analysis settings of the index. It contains the components defined in
+// xjoin.es.analysis and the builtinAnalysis components referenced by the fields.
+func (m *elasticsearchMapping) buildAnalysis() (string, error) {
+	analysis := make(map[string]map[string]interface{})
+
+	if custom, ok := m.attributes.get("")[xjoinESAnalysis]; ok {
+		customMap, isMap := custom.(map[string]interface{})
+		if !isMap {
+			return "", errors.New(xjoinESAnalysis + " must be an object")
+		}
+		for section, definitions := range customMap {
+			if !analysisSections[section] {
+				return "", fmt.Errorf("invalid section %s.%s", xjoinESAnalysis, section)
+			}
+			definitionsMap, isMap := definitions.(map[string]interface{})
+			if !isMap {
+				return "", fmt.Errorf("%s.%s must be an object", xjoinESAnalysis, section)
+			}
+			analysis[section] = definitionsMap
+		}
+	}
+
+	for _, referenced := range []struct {
+		section string
+		names   map[string]bool
+	}{
+		{section: "analyzer", names: m.analyzers},
+		{section: "normalizer", names: m.normalizers},
+	} {
+		for name := range referenced.names {
+			if _, defined := analysis[referenced.section][name]; defined {
+				continue
+			}
+
+			builtin, isBuiltin := builtinAnalysis[referenced.section][name]
+			if isBuiltin {
+				if analysis[referenced.section] == nil {
+					analysis[referenced.section] = make(map[string]interface{})
+				}
+				analysis[referenced.section][name] = builtin
+			} else if referenced.section == "normalizer" && !elasticsearchNormalizers[name] {
+				//analyzers that aren't defined are assumed to be provided by elasticsearch e.g. standard, english
+				return "", fmt.Errorf("normalizer %s is not defined, define it in %s", name, xjoinESAnalysis)
+			}
+		}
+	}
+
+	if len(analysis) == 0 {
+		return "", nil
+	}
+
+	analysisJSON, err := json.Marshal(analysis)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return string(analysisJSON), nil
+}
+
+// propertyExists checks if a dotted path e.g. host.display_name is a property of the mapping
+func propertyExists(esProperties map[string]interface{}, path string) bool {
+	name, rest, nested := strings.Cut(path, ".")
+	property, ok := esProperties[name].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if !nested {
+		return true
+	}
+	children, ok := property["properties"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	return propertyExists(children, rest)
+}
+
+func stringOrStrings(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, errors.New("must be a string or an array of strings")
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, errors.New("must be a string or an array of strings")
+	}
+}
+
+func containsType(types []string, esType interface{}) bool {
+	for _, t := range types {
+		if t == esType {
+			return true
+		}
+	}
+	return false
+}
+
+func supportedHints() string {
+	var hints []string
+	for hint := range elasticsearchHints {
+		hints = append(hints, hint)
+	}
+	sort.Strings(hints)
+	return strings.Join(hints, ", ")
+}
@@ -0,0 +1,142 @@
+package avro
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+)
+
+const mappingTestSchema = `{
+  "type": "record",
+  "name": "Value",
+  "fields": [
+    {"name": "id", "type": {"type": "string", "xjoin.type": "string"}},
+    {"name": "count", "type": "int"},
+    {"name": "created_on", "type": {"type": "long", "connect.name": "io.debezium.time.MicroTimestamp"}},
+    {"name": "modified_on", "type": {"type": "long", "xjoin.type": "date_nanos"}},
+    {"name": "enabled", "type": ["null", {"type": "boolean", "xjoin.type": "boolean"}]},
+    {"name": "tags", "type": {"type": "array", "xjoin.type": "array", "items": {"type": "string"}}},
+    {"name": "owner", "type": {"type": "record", "name": "Owner", "xjoin.type": "record", "fields": [
+      {"name": "score", "type": "double"}
+    ]}}
+  ]
+}`
+
+func parseMappingTestSchema(t *testing.T, avroSchema string, typeMapping string) (
+	*elasticsearchMapping, map[string]interface{}, error) {
+
+	t.Helper()
+
+	var schema Schema
+	err := json.Unmarshal([]byte(avroSchema), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rawSchema map[string]interface{}
+	err = json.Unmarshal([]byte(avroSchema), &rawSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attributes := make(typeAttributes)
+	attributes.collectFields(rawSchema["fields"], "")
+
+	mapping, err := newElasticsearchMapping(attributes, typeMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties, err := mapping.parseAvroFields(schema.Fields, "")
+	return mapping, properties, err
+}
+
+func buildMappingTestProperties(t *testing.T, typeMapping string) (map[string]interface{}, []DateField) {
+	t.Helper()
+
+	mapping, properties, err := parseMappingTestSchema(t, mappingTestSchema, typeMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return properties, mapping.dateFields
+}
+
+func esType(esType string) map[string]interface{} {
+	return map[string]interface{}{"type": esType}
+}
+
+func TestLegacyTypeMapping(t *testing.T) {
+	properties, dateFields := buildMappingTestProperties(t, TypeMappingLegacy)
+
+	expected := map[string]interface{}{
+		"id":          esType("keyword"),
+		"count":       esType("keyword"),
+		"created_on":  esType("keyword"),
+		"modified_on": esType("date_nanos"),
+		"enabled":     esType("boolean"),
+		"tags":        esType("keyword"),
+		"owner": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"score": esType("keyword")},
+		},
+	}
+	if !reflect.DeepEqual(properties, expected) {
+		t.Errorf("expected the legacy mapping %v, got %v", expected, properties)
+	}
+	if len(dateFields) != 0 {
+		t.Errorf("expected no converted date fields, got %v", dateFields)
+	}
+}
+
+func TestTypedTypeMapping(t *testing.T) {
+	properties, dateFields := buildMappingTestProperties(t, TypeMappingTyped)
+
+	expected := map[string]interface{}{
+		"id":          esType("keyword"),
+		"count":       esType("integer"),
+		"created_on":  esType("date"),
+		"modified_on": esType("date_nanos"),
+		"enabled":     esType("boolean"),
+		"tags":        esType("keyword"),
+		"owner": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"score": esType("double")},
+		},
+	}
+	if !reflect.DeepEqual(properties, expected) {
+		t.Errorf("expected the typed mapping %v, got %v", expected, properties)
+	}
+	if !reflect.DeepEqual(dateFields, []DateField{{Field: "created_on", Unit: EpochMicros}}) {
+		t.Errorf("expected created_on to be converted from micros, got %v", dateFields)
+	}
+}
+
+func TestUnknownTypeMapping(t *testing.T) {
+	_, err := newElasticsearchMapping(make(typeAttributes), "dynamic")
+	if err == nil {
+		t.Errorf("expected an error for an unknown type mapping")
+	}
+}
+
+func TestUnknownXJoinType(t *testing.T) {
+	avroSchema := `{
+	  "type": "record",
+	  "name": "Value",
+	  "fields": [
+	    {"name": "host", "type": {"type": "record", "name": "Host", "xjoin.type": "record", "fields": [
+	      {"name": "ip", "type": {"type": "string", "xjoin.type": "ip_address"}}
+	    ]}}
+	  ]
+	}`
+
+	for _, typeMapping := range []string{TypeMappingLegacy, TypeMappingTyped} {
+		t.Run(typeMapping, func(t *testing.T) {
+			_, _, err := parseMappingTestSchema(t, avroSchema, typeMapping)
+			if err == nil {
+				t.Fatalf("expected an error for the unknown xjoin.type")
+			}
+			if expected := "unable to map field host.ip: unknown xjoin.type ip_address"; err.Error() != expected {
+				t.Errorf("expected the error %s, got %s", expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package avro
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+)
+
+// graphQLFederationLink imports the federation directives used by the generated schema
+const graphQLFederationLink = `extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key"])`
+
+// graphQLScalars maps the elasticsearch type of a field to the graphql type it is served as. Elasticsearch types
+// missing from this table e.g. ip, geo_point are served as a String.
+var graphQLScalars = map[string]string{
+	"keyword":      "String",
+	"text":         "String",
+	"binary":       "String",
+	"boolean":      "Boolean",
+	"byte":         "Int",
+	"short":        "Int",
+	"integer":      "Int",
+	"long":         "Long",
+	"float":        "Float",
+	"half_float":   "Float",
+	"double":       "Float",
+	"scaled_float": "Float",
+	"date":         "Date",
+	"date_nanos":   "Date",
+	"object":       "JSON",
+	"flattened":    "JSON",
+	"nested":       "JSON",
+}
+
+// graphQLCustomScalars are the scalars that are not built into graphql
+var graphQLCustomScalars = map[string]bool{"Long": true, "Date": true, "JSON": true}
+
+// graphQLFilters are the conditions of the filter input of each scalar, a scalar without an entry is not filterable
+var graphQLFilters = map[string][]string{
+	"String":  {"eq: String", "in: [String!]", "matches: String"},
+	"Boolean": {"is: Boolean"},
+	"Int":     {"eq: Int", "gt: Int", "gte: Int", "lt: Int", "lte: Int"},
+	"Long":    {"eq: Long", "gt: Long", "gte: Long", "lt: Long", "lte: Long"},
+	"Float":   {"eq: Float", "gt: Float", "gte: Float", "lt: Float", "lte: Float"},
+	"Date":    {"eq: Date", "gt: Date", "gte: Date", "lt: Date", "lte: Date"},
+}
+
+// graphQLSchema builds the graphql SDL of the xjoin-api-subgraph that serves the index
+type graphQLSchema struct {
+	attributes typeAttributes
+	types      []string //object types in the order they are defined
+	filters    []string //filter inputs of the object types
+	scalars    map[string]bool
+	orderBy    []string
+}
+
+// transformToGraphQL generates the graphql SDL of the index: an object type for each record, a filter input for each
+// object type, an enum of the fields the documents can be ordered by and a query that returns a page of documents.
+// Records with xjoin.primary.key fields are federation entities keyed by those fields.
+// The schema is empty when the index has no fields to serve.
+func (d *IndexAvroSchemaParser) transformToGraphQL(indexAvroSchema *IndexAvroSchema) (err error) {
+	rootName := graphQLTypeName(d.GraphQLName)
+	if rootName == "" {
+		return nil
+	}
+
+	schema := &graphQLSchema{
+		attributes: d.typeAttributes,
+		scalars:    make(map[string]bool),
+	}
+
+	hasFields, filterName, err := schema.parseObject(rootName, indexAvroSchema.AvroSchema.Fields, "", false)
+	if err != nil {
+		return err
+	}
+	if !hasFields {
+		return nil
+	}
+
+	indexAvroSchema.GraphQLSchema = schema.render(rootName, filterName)
+	return nil
+}
+
+// parseObject adds the object type and filter input of a record, the filter name is empty when no field is filterable
+func (g *graphQLSchema) parseObject(typeName string, avroFields []Field, path string, inArray bool) (
+	hasFields bool, filterName string, err error) {
+
+	var fields, filterFields, keys []string
+
+	for _, avroField := range avroFields {
+		if avroField.XJoinIndex != nil && !*avroField.XJoinIndex {
+			continue
+		}
+
+		fieldPath := joinPath(path, avroField.Name)
+		avroFieldType, err := resolveUnion(avroField.Type)
+		if err != nil {
+			return false, "", fmt.Errorf("invalid type of field %s: %w", fieldPath, err)
+		}
+
+		fieldType, fieldFilter, err := g.fieldType(
+			avroFieldType, typeName+graphQLTypeName(avroField.Name), fieldPath, inArray)
+		if err != nil {
+			return false, "", err
+		}
+		if fieldType == "" {
+			continue
+		}
+
+		fields = append(fields, avroField.Name+": "+fieldType)
+		if fieldFilter != "" {
+			filterFields = append(filterFields, avroField.Name+": "+fieldFilter)
+		}
+		if avroFieldType.XJoinPrimaryKey {
+			keys = append(keys, avroField.Name)
+		}
+	}
+
+	if len(fields) == 0 {
+		return false, "", nil
+	}
+
+	declaration := "type " + typeName
+	if len(keys) > 0 {
+		declaration = declaration + fmt.Sprintf(` @key(fields: "%s")`, strings.Join(keys, " "))
+	}
+	g.types = append(g.types, renderGraphQLBlock(declaration, fields))
+
+	if len(filterFields) > 0 {
+		filterName = typeName + "Filter"
+		if path == "" {
+			filterFields = append(filterFields,
+				"AND: ["+filterName+"!]", "OR: ["+filterName+"!]", "NOT: "+filterName)
+		}
+		g.filters = append(g.filters, renderGraphQLBlock("input "+filterName, filterFields))
+	}
+
+	return true, filterName, nil
+}
+
+// fieldType returns the graphql type and filter input of the avro type of the field at path. Nested records are
+// added as object types named nestedName. The type is empty for a record without fields.
+func (g *graphQLSchema) fieldType(avroType Type, nestedName string, path string, inArray bool) (
+	fieldType string, filterName string, err error) {
+
+	attributes := g.attributes.get(path)
+
+	if avroType.Type == "array" && (avroType.XJoinType == "" || avroType.XJoinType == "array") {
+		if esType, ok := attributes["xjoin.es.type"].(string); ok && esType != "" {
+			scalar := g.scalar(esType)
+			return "[" + scalar + "]", g.filter(scalar), nil
+		}
+		if len(avroType.Items) == 0 {
+			return "", "", fmt.Errorf("unable to map field %s: items are missing from array", path)
+		}
+		itemType, err := resolveUnion(avroType.Items)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid items of array %s: %w", path, err)
+		}
+		itemGraphQLType, itemFilter, err := g.fieldType(itemType, nestedName, path+"[]", true)
+		if err != nil || itemGraphQLType == "" {
+			return "", "", err
+		}
+		return "[" + itemGraphQLType + "]", itemFilter, nil
+	}
+
+	esType, err := g.elasticsearchType(avroType, attributes, path)
+	if err != nil {
+		return "", "", err
+	}
+
+	if esType == "object" {
+		nestedFields := avroType.Fields
+		if nestedFields == nil {
+			nestedFields = avroType.XJoinFields
+		}
+		if nestedFields != nil {
+			hasFields, nestedFilter, err := g.parseObject(nestedName, nestedFields, path, inArray)
+			if err != nil || !hasFields {
+				return "", "", err
+			}
+			return nestedName, nestedFilter, nil
+		}
+	}
+
+	scalar := g.scalar(esType)
+
+	//elasticsearch can't sort by text or binary fields, fields inside arrays have multiple values
+	if !inArray && esType != "text" && esType != "binary" && graphQLFilters[scalar] != nil {
+		g.orderBy = append(g.orderBy, strings.ReplaceAll(path, ".", "_"))
+	}
+
+	return scalar, g.filter(scalar), nil
+}
+
+// elasticsearchType resolves the elasticsearch type of an avro type the same way as the elasticsearch mapping
+func (g *graphQLSchema) elasticsearchType(
+	avroType Type, attributes map[string]interface{}, path string) (string, error) {
+
+	if esType, ok := attributes["xjoin.es.type"].(string); ok && esType != "" {
+		return esType, nil
+	}
+
+	builder, err := lookupElasticsearchType(avroType, attributes)
+	if err != nil {
+		return "", fmt.Errorf("unable to map field %s: %w", path, err)
+	}
+	esProperty, err := builder(avroType, attributes)
+	if err != nil {
+		return "", fmt.Errorf("unable to map field %s: %w", path, err)
+	}
+	esType, _ := esProperty["type"].(string)
+	return esType, nil
+}
+
+func (g *graphQLSchema) scalar(esType string) string {
+	scalar, ok := graphQLScalars[esType]
+	if !ok {
+		scalar = "String"
+	}
+	if graphQLCustomScalars[scalar] {
+		g.scalars[scalar] = true
+	}
+	return scalar
+}
+
+// filter returns the name of the filter input of a scalar and records that the input is used
+func (g *graphQLSchema) filter(scalar string) string {
+	if graphQLFilters[scalar] == nil {
+		return ""
+	}
+	g.scalars[scalar+"Filter"] = true
+	return scalar + "Filter"
+}
+
+func (g *graphQLSchema) render(rootName string, filterName string) string {
+	queryName := string(unicode.ToLower(rune(rootName[0]))) + rootName[1:]
+
+	var arguments []string
+	if filterName != "" {
+		arguments = append(arguments, "filter: "+filterName)
+	}
+	if len(g.orderBy) > 0 {
+		arguments = append(arguments, "order_by: "+rootName+"OrderBy", "order_how: OrderDirection")
+	}
+	arguments = append(arguments, "limit: Int = 10", "offset: Int = 0")
+
+	blocks := []string{graphQLFederationLink}
+
+	var scalars []string
+	for name := range g.scalars {
+		if graphQLCustomScalars[name] {
+			scalars = append(scalars, "scalar "+name)
+		}
+	}
+	sort.Strings(scalars)
+	if len(scalars) > 0 {
+		blocks = append(blocks, strings.Join(scalars, "\n"))
+	}
+
+	blocks = append(blocks,
+		renderGraphQLBlock("type Query", []string{
+			fmt.Sprintf("%s(%s): %sCollection!", queryName, strings.Join(arguments, ", "), rootName)}),
+		renderGraphQLBlock("type "+rootName+"Collection", []string{
+			"data: [" + rootName + "!]!", "meta: CollectionMeta!"}),
+		renderGraphQLBlock("type CollectionMeta", []string{"count: Int!", "total: Int!"}))
+
+	//nested types are added before the type that contains them, the root type is defined first
+	for idx := len(g.types) - 1; idx >= 0; idx-- {
+		blocks = append(blocks, g.types[idx])
+	}
+
+	if len(g.orderBy) > 0 {
+		blocks = append(blocks,
+			renderGraphQLBlock("enum "+rootName+"OrderBy", g.orderBy),
+			renderGraphQLBlock("enum OrderDirection", []string{"ASC", "DESC"}))
+	}
+
+	for idx := len(g.filters) - 1; idx >= 0; idx-- {
+		blocks = append(blocks, g.filters[idx])
+	}
+
+	var scalarFilters []string
+	for scalar, conditions := range graphQLFilters {
+		if g.scalars[scalar+"Filter"] {
+			scalarFilters = append(scalarFilters, renderGraphQLBlock("input "+scalar+"Filter", conditions))
+		}
+	}
+	sort.Strings(scalarFilters)
+	blocks = append(blocks, scalarFilters...)
+
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+func renderGraphQLBlock(declaration string, lines []string) string {
+	return declaration + " {\n  " + strings.Join(lines, "\n  ") + "\n}"
+}
+
+// graphQLTypeName converts a name to PascalCase e.g. system_profile -> SystemProfile, test-index -> TestIndex
+func graphQLTypeName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var typeName strings.Builder
+	for _, word := range words {
+		typeName.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return typeName.String()
+}
@@ -0,0 +1,100 @@
+package avro
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-errors/errors"
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+	"github.com/redhatinsights/xjoin-operator/controllers/database"
+)
+
+// PostgreSQLColumnDelimiter joins the names of nested fields into a column name. It must match the delimiter of the
+// Flatten transformation in the PostgreSQL connector template.
+const PostgreSQLColumnDelimiter = "_"
+
+// TransformToPostgreSQL flattens an expanded index avro schema into the columns of a PostgreSQL table.
+// Nested records are flattened into one column per field e.g. host.account -> host_account.
+func TransformToPostgreSQL(avroSchema Schema) (columns []database.Column, err error) {
+	if avroSchema.Fields == nil {
+		return nil, errors.Wrap(errors.New("fields property is missing from avro schema"), 0)
+	}
+
+	return parsePostgreSQLColumns(avroSchema.Fields, "")
+}
+
+func parsePostgreSQLColumns(avroFields []Field, prefix string) (columns []database.Column, err error) {
+	for _, avroField := range avroFields {
+		name := prefix + avroField.Name
+		avroFieldType, err := resolveUnion(avroField.Type)
+		if err != nil {
+			return nil, errors.Wrap(fmt.Errorf("invalid type of field %s: %w",
+				strings.ReplaceAll(name, PostgreSQLColumnDelimiter, "."), err), 0)
+		}
+
+		//nested records are flattened, json strings are stored as jsonb
+		if avroFieldType.Type == "record" || (avroFieldType.XJoinType == "reference" && avroFieldType.Fields != nil) {
+			nestedColumns, err := parsePostgreSQLColumns(avroFieldType.Fields, name+PostgreSQLColumnDelimiter)
+			if err != nil {
+				return nil, errors.Wrap(err, 0)
+			}
+			columns = append(columns, nestedColumns...)
+			continue
+		}
+
+		columnType, err := avroTypeToPostgreSQLType(avroFieldType)
+		if err != nil {
+			return nil, errors.Wrap(fmt.Errorf("unable to map field %s: %w",
+				strings.ReplaceAll(name, PostgreSQLColumnDelimiter, "."), err), 0)
+		}
+		columns = append(columns, database.Column{Name: name, Type: columnType})
+	}
+
+	return columns, nil
+}
+
+func avroTypeToPostgreSQLType(avroType Type) (string, error) {
+	if avroType.Type == "array" {
+		if len(avroType.Items) == 0 {
+			return "", errors.New("items are missing from array")
+		}
+		itemType, err := resolveUnion(avroType.Items)
+		if err != nil {
+			return "", err
+		}
+		if itemType.Type == "record" || itemType.Type == "array" {
+			return "", fmt.Errorf("arrays of type %s are not supported by the postgresql sink", itemType.Type)
+		}
+		columnType, err := avroTypeToPostgreSQLType(itemType)
+		if err != nil {
+			return "", err
+		}
+		return columnType + "[]", nil
+	}
+
+	switch strings.ToLower(avroType.XJoinType) {
+	case "json":
+		return "jsonb", nil
+	case "date_nanos", "date":
+		return "timestamp with time zone", nil
+	}
+
+	switch avroType.Type {
+	case "string", "enum":
+		return "text", nil
+	case "boolean":
+		return "boolean", nil
+	case "int":
+		return "integer", nil
+	case "long":
+		return "bigint", nil
+	case "float":
+		return "real", nil
+	case "double":
+		return "double precision", nil
+	case "bytes", "fixed":
+		return "bytea", nil
+	default:
+		return "", fmt.Errorf("type %s is not supported by the postgresql sink", avroType.Type)
+	}
+}
@@ -0,0 +1,323 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-errors/errors"
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+	"github.com/riferrei/srclient"
+)
+
+// registrySubject identifies a version of a schema registry subject, version 0 is the latest version
+type registrySubject struct {
+	subject string
+	version int
+}
+
+func (s registrySubject) String() string {
+	if s.version == 0 {
+		return s.subject
+	}
+	return fmt.Sprintf("%s (version %d)", s.subject, s.version)
+}
+
+// referenceScope resolves the type names of a schema to schema registry subjects
+type referenceScope struct {
+	references []srclient.Reference
+
+	//only xjoin.type reference types are expanded in the index schema. They reference the version of a data source
+	//subject chosen by parseAvroSchemaReferences. Any type name of a data source schema that matches one of its
+	//references is expanded.
+	index bool
+}
+
+func (s referenceScope) find(typeName string) (registrySubject, bool) {
+	for _, ref := range s.references {
+		if ref.Name == typeName {
+			return registrySubject{subject: ref.Subject, version: ref.Version}, true
+		}
+	}
+	return registrySubject{}, false
+}
+
+// expandReferences retrieves the full schema of each reference. References are expanded at any depth, including
+// inside unions and arrays, and the schemas retrieved from the registry can reference other subjects. The expanded
+// schemas are cached for the lifetime of the parser, i.e. a reconcile.
+func (d *IndexAvroSchemaParser) expandReferences(
+	baseSchema string, references []srclient.Reference) (fullSchema Schema, err error) {
+
+	var rawSchema map[string]interface{}
+	err = json.Unmarshal([]byte(baseSchema), &rawSchema)
+	if err != nil {
+		return fullSchema, errors.Wrap(err, 0)
+	}
+
+	_, err = d.expandRawType(rawSchema, referenceScope{references: references, index: true}, nil)
+	if err != nil {
+		return fullSchema, errors.Wrap(err, 0)
+	}
+
+	expandedSchema, err := json.Marshal(rawSchema)
+	if err != nil {
+		return fullSchema, errors.Wrap(err, 0)
+	}
+	err = json.Unmarshal(expandedSchema, &fullSchema)
+	if err != nil {
+		return fullSchema, errors.Wrap(err, 0)
+	}
+
+	d.typeAttributes = make(typeAttributes)
+	d.typeAttributes[""] = rawSchema
+	d.typeAttributes.collectFields(rawSchema["fields"], "")
+
+	return fullSchema, nil
+}
+
+// expandRawType replaces the references in the raw json of an avro type with the schemas of the subjects they
+// reference. stack contains the subjects being expanded to detect cycles.
+func (d *IndexAvroSchemaParser) expandRawType(
+	rawType interface{}, scope referenceScope, stack []registrySubject) (interface{}, error) {
+
+	return rewriteRawType(rawType, func(rawType interface{}) (interface{}, bool, error) {
+		var typeName string
+		attributes := make(map[string]interface{})
+		switch t := rawType.(type) {
+		case string:
+			typeName = t
+		case map[string]interface{}:
+			typeName, _ = t["type"].(string)
+			attributes = t
+		default:
+			return rawType, false, nil
+		}
+
+		isReference := attributes["xjoin.type"] == "reference"
+		subject, found := scope.find(typeName)
+		if !isReference && (!found || scope.index) {
+			return rawType, false, nil
+		} else if !found {
+			return nil, false, fmt.Errorf("reference %s not found in list of references", typeName)
+		}
+
+		expanded, err := d.expandSubject(subject, stack)
+		if err != nil {
+			return nil, false, err
+		}
+
+		//the attributes of the referencing type e.g. xjoin.type are kept
+		expanded = copyRawSchema(expanded)
+		for key, value := range attributes {
+			if key != "type" {
+				expanded[key] = value
+			}
+		}
+		expanded["name"] = typeName
+		delete(expanded, "namespace")
+		d.referenceNames[typeName] = true
+
+		return expanded, true, nil
+	})
+}
+
+// expandSubject returns the schema of a subject with its own references expanded
+func (d *IndexAvroSchemaParser) expandSubject(
+	subject registrySubject, stack []registrySubject) (map[string]interface{}, error) {
+
+	for idx, s := range stack {
+		if s == subject {
+			var cycle []string
+			for _, c := range append(stack[idx:], subject) {
+				cycle = append(cycle, c.String())
+			}
+			return nil, fmt.Errorf("reference cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	if d.subjects == nil {
+		d.subjects = make(map[registrySubject]map[string]interface{})
+		d.referenceNames = make(map[string]bool)
+	}
+	if expanded, ok := d.subjects[subject]; ok {
+		return expanded, nil
+	}
+
+	schema, err := d.fetchSubject(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawSchema map[string]interface{}
+	err = json.Unmarshal([]byte(schema.Schema()), &rawSchema)
+	if err != nil {
+		return nil, fmt.Errorf("the schema of subject %s must be a record: %w", subject, err)
+	}
+
+	_, err = d.expandRawType(rawSchema, referenceScope{references: schema.References()}, append(stack, subject))
+	if err != nil {
+		return nil, err
+	}
+
+	d.subjects[subject] = rawSchema
+	return rawSchema, nil
+}
+
+// fetchSubject retrieves a version of a subject from the schema registry. The latest version is also cached under
+// its version number so resolving the version of a subject doesn't fetch it twice.
+func (d *IndexAvroSchemaParser) fetchSubject(subject registrySubject) (*srclient.Schema, error) {
+	if d.fetched == nil {
+		d.fetched = make(map[registrySubject]*srclient.Schema)
+	}
+	if schema, ok := d.fetched[subject]; ok {
+		return schema, nil
+	}
+
+	schema, err := d.SchemaRegistry.GetSchemaVersion(subject.subject, subject.version)
+	if err != nil {
+		return nil, err
+	}
+
+	d.fetched[subject] = schema
+	d.fetched[registrySubject{subject: subject.subject, version: schema.Version()}] = schema
+	return schema, nil
+}
+
+// collectReferenceNames returns the type names of the xjoin.type reference types in the raw json of an avro type
+func collectReferenceNames(rawType interface{}) (names []string, err error) {
+	unique := make(map[string]bool)
+	_, err = rewriteRawType(rawType, func(rawType interface{}) (interface{}, bool, error) {
+		t, ok := rawType.(map[string]interface{})
+		if !ok || t["xjoin.type"] != "reference" {
+			return rawType, false, nil
+		}
+
+		name, ok := t["type"].(string)
+		if !ok {
+			return nil, false, errors.New("the type of a reference must be the name of a data source schema")
+		}
+		if !unique[name] {
+			unique[name] = true
+			names = append(names, name)
+		}
+		return rawType, true, nil
+	})
+	return names, err
+}
+
+// defineReferencesOnce replaces each definition of an expanded reference after the first with its name. Avro named
+// types can only be defined once per schema e.g. when two data sources embed the same shared record type.
+// schemaJSON is returned as is when no definition is replaced to keep the order of its keys.
+func (d *IndexAvroSchemaParser) defineReferencesOnce(schemaJSON []byte) ([]byte, error) {
+	var schema map[string]interface{}
+	err := json.Unmarshal(schemaJSON, &schema)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	defined := make(map[string]bool)
+	replaced := false
+	_, err = rewriteRawType(schema, func(rawType interface{}) (interface{}, bool, error) {
+		t, ok := rawType.(map[string]interface{})
+		if !ok {
+			return rawType, false, nil
+		}
+		name, _ := t["name"].(string)
+		if !d.referenceNames[name] {
+			return rawType, false, nil
+		}
+		if defined[name] {
+			replaced = true
+			return name, true, nil
+		}
+		defined[name] = true
+		return rawType, false, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	} else if !replaced {
+		return schemaJSON, nil
+	}
+
+	definedOnce, err := json.Marshal(schema)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return definedOnce, nil
+}
+
+// rewriteRawType walks the raw json of an avro type depth first in the order of the document. rewrite is called with
+// each type and returns its replacement, the children of a type are only visited when it isn't replaced.
+func rewriteRawType(
+	rawType interface{}, rewrite func(rawType interface{}) (interface{}, bool, error)) (interface{}, error) {
+
+	replacement, replaced, err := rewrite(rawType)
+	if err != nil || replaced {
+		return replacement, err
+	}
+
+	switch t := rawType.(type) {
+	case []interface{}:
+		for idx := range t {
+			t[idx], err = rewriteRawType(t[idx], rewrite)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		if _, isString := t["type"].(string); !isString && t["type"] != nil {
+			t["type"], err = rewriteRawType(t["type"], rewrite)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, key := range []string{"fields", "xjoin.fields"} {
+			fields, _ := t[key].([]interface{})
+			for _, rawField := range fields {
+				field, ok := rawField.(map[string]interface{})
+				if !ok || field["type"] == nil {
+					continue
+				}
+				field["type"], err = rewriteRawType(field["type"], rewrite)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for _, key := range []string{"items", "values"} {
+			if child, ok := t[key]; ok {
+				t[key], err = rewriteRawType(child, rewrite)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return rawType, nil
+}
+
+func copyRawSchema(rawSchema map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(rawSchema))
+	for key, value := range rawSchema {
+		copied[key] = copyRawValue(value)
+	}
+	return copied
+}
+
+func copyRawValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyRawSchema(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for idx, item := range v {
+			copied[idx] = copyRawValue(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -1,36 +1,31 @@
 package avro
 
 import (
-	"container/list"
 	"context"
 	"encoding/json"
-	"fmt"
-	"reflect"
 	"strings"
 
 	"github.com/go-errors/errors"
 	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
-	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/log"
 	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
 	"github.com/riferrei/srclient"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const (
-	OBJECT_TO_ARRAY_OF_OBJECTS = "object_to_array_of_objects"
-	OBJECT_TO_ARRAY_OF_STRINGS = "object_to_array_of_strings"
-)
-
 // IndexAvroSchema is a completely parsed representation of a xjoinindex avro schema
 type IndexAvroSchema struct {
 	AvroSchema       Schema
 	AvroSchemaString string
 	References       []srclient.Reference
 	ESProperties     string
+	ESAnalysis       string
 	JSONFields       []string
+	DateFields       []DateField
 	SourceTopics     string
+	DataSources      map[string]xjoin.DataSourceVersion
+	GraphQLSchema    string
 }
 
 type IndexAvroSchemaParser struct {
@@ -42,11 +37,21 @@ type IndexAvroSchemaParser struct {
 	Log             log.Log
 	SchemaRegistry  *schemaregistry.ConfluentClient
 	Active          bool
+	DataSources     []xjoin.DataSourceVersionSpec
+	UsedDataSources map[string]xjoin.DataSourceVersion //versions already used by the IndexPipeline
+	GraphQLName     string                             //names the graphql query and types, no graphql schema when empty
+	TypeMapping     string                             //legacy or typed elasticsearch type mapping
+	typeAttributes  typeAttributes                     //set by expandReferences
+
+	//schemas of the referenced subjects and the names of the expanded references, cached by expandReferences
+	subjects       map[registrySubject]map[string]interface{}
+	fetched        map[registrySubject]*srclient.Schema
+	referenceNames map[string]bool
 }
 
 // Parse AvroSchema string into various structures represented by IndexAvroSchema to be used in component creation
 func (d *IndexAvroSchemaParser) Parse() (indexAvroSchema IndexAvroSchema, err error) {
-	indexAvroSchema.References, err = d.parseAvroSchemaReferences()
+	indexAvroSchema.References, indexAvroSchema.DataSources, err = d.parseAvroSchemaReferences()
 	if err != nil {
 		return indexAvroSchema, errors.Wrap(err, 0)
 	}
@@ -63,7 +68,12 @@ func (d *IndexAvroSchemaParser) Parse() (indexAvroSchema IndexAvroSchema, err er
 		return indexAvroSchema, errors.Wrap(err, 0)
 	}
 
-	indexAvroSchema.ESProperties, indexAvroSchema.JSONFields, err = d.transformToES(indexAvroSchema.AvroSchema)
+	err = d.transformToES(&indexAvroSchema)
+	if err != nil {
+		return indexAvroSchema, errors.Wrap(err, 0)
+	}
+
+	err = d.transformToGraphQL(&indexAvroSchema)
 	if err != nil {
 		return indexAvroSchema, errors.Wrap(err, 0)
 	}
@@ -75,348 +85,115 @@ func (d *IndexAvroSchemaParser) Parse() (indexAvroSchema IndexAvroSchema, err er
 	if err != nil {
 		return indexAvroSchema, errors.Wrap(err, 0)
 	}
+	avroSchemaString, err = d.typeAttributes.restore(avroSchemaString)
+	if err != nil {
+		return indexAvroSchema, errors.Wrap(err, 0)
+	}
+	avroSchemaString, err = d.defineReferencesOnce(avroSchemaString)
+	if err != nil {
+		return indexAvroSchema, errors.Wrap(err, 0)
+	}
 	indexAvroSchema.AvroSchemaString = string(avroSchemaString)
 
 	return
 }
 
-// applyTransformations adds the fields defined in xjoin.transformations to the Schema
-func (d *IndexAvroSchemaParser) applyTransformations(avroSchema Schema) (transformedAvroSchema Schema, err error) {
-	for _, transformation := range avroSchema.Transformations {
-		switch transformation.Type {
-		case OBJECT_TO_ARRAY_OF_OBJECTS:
-			avroSchema, err = d.applyObjectToArrayOfObjectsTransformation(avroSchema, transformation)
-			if err != nil {
-				return transformedAvroSchema, errors.Wrap(err, 0)
-			}
-		case OBJECT_TO_ARRAY_OF_STRINGS:
-			avroSchema, err = d.applyObjectToArrayOfStringsTransformation(avroSchema, transformation)
-			if err != nil {
-				return transformedAvroSchema, errors.Wrap(err, 0)
-			}
-		}
-	}
-
-	return avroSchema, nil
-}
-
-func (d *IndexAvroSchemaParser) applyObjectToArrayOfStringsTransformation(
-	avroSchema Schema, transformation Transformation) (Schema, error) {
-
-	stringType := Type{
-		Type: "string",
-	}
+// ParseAvroSchemaReferences parses the Index's Avro Schema JSON to build a list of srclient.References and the
+// version of each data source they reference
+func (d *IndexAvroSchemaParser) parseAvroSchemaReferences() (
+	references []srclient.Reference, dataSources map[string]xjoin.DataSourceVersion, err error) {
 
-	fieldNodes := strings.Split(transformation.OutputField, ".")
-	fieldName := fieldNodes[len(fieldNodes)-1]
-	fieldType := Type{
-		Type:      "array",
-		XJoinType: "array",
-		Items:     []Type{stringType},
-	}
-	field := Field{
-		Name: fieldName,
-		Type: []Type{fieldType},
-	}
-	err := avroSchema.AddField(transformation.OutputField, field)
+	var rawSchema map[string]interface{}
+	err = json.Unmarshal([]byte(d.AvroSchema), &rawSchema)
 	if err != nil {
-		return avroSchema, errors.Wrap(err, 0)
+		d.Log.Error(err, "Unable to parse avro schema as JSON", "Schema", d.AvroSchema)
+		return references, dataSources, errors.Wrap(err, 0)
 	}
 
-	return avroSchema, nil
-}
-
-func (d *IndexAvroSchemaParser) applyObjectToArrayOfObjectsTransformation(
-	avroSchema Schema, transformation Transformation) (Schema, error) {
-
-	if transformation.Parameters["keys"] == nil || reflect.TypeOf(transformation.Parameters["keys"]).Kind() != reflect.Slice {
-		return avroSchema, errors.Wrap(errors.New(fmt.Sprintf(
-			"keys field missing from transformation: %s, output_field: %s", OBJECT_TO_ARRAY_OF_OBJECTS, transformation.OutputField)), 0)
+	//references can be at any depth of the schema, each data source is referenced once
+	names, err := collectReferenceNames(rawSchema)
+	if err != nil {
+		return references, dataSources, errors.Wrap(err, 0)
 	}
 
-	var childFields []Field
-
-	for _, key := range transformation.Parameters["keys"].([]interface{}) {
-		if reflect.TypeOf(key).Kind() != reflect.String {
-			return avroSchema, errors.Wrap(errors.New(fmt.Sprintf(
-				"keys field must be an array of strings, output_field: %s", transformation.OutputField)), 0)
-		}
-
-		nullType := Type{
-			Type: "null",
-		}
-
-		childType := Type{
-			Type:      "string",
-			XJoinType: "string",
+	var dataSourceNames []string
+	for _, referenceName := range names {
+		if len(strings.Split(referenceName, ".")) < 2 {
+			return references, dataSources, errors.Wrap(
+				errors.New("unable to parse dataSourceName from avro schema fields"), 0)
 		}
-		childFields = append(childFields, Field{
-			Name: key.(string),
-			Type: []Type{nullType, childType},
-		})
+		dataSourceNames = append(dataSourceNames, strings.Split(referenceName, ".")[0])
 	}
 
-	fieldNodes := strings.Split(transformation.OutputField, ".")
-	fieldName := fieldNodes[len(fieldNodes)-1]
-
-	childType := Type{
-		Type:      "record",
-		XJoinType: "json",
-		Name:      "children",
-		Fields:    childFields,
-	}
-
-	fieldType := Type{
-		Type:      "array",
-		XJoinType: "json",
-		Name:      fieldName,
-		Items:     []Type{childType},
-	}
-
-	field := Field{
-		Name: fieldName,
-		Type: []Type{fieldType},
-	}
-
-	err := avroSchema.AddField(transformation.OutputField, field)
+	err = d.validateDataSourceSpecs(dataSourceNames)
 	if err != nil {
-		return avroSchema, errors.Wrap(err, 0)
+		return references, dataSources, errors.Wrap(err, 0)
 	}
 
-	return avroSchema, nil
-}
-
-// ParseAvroSchemaReferences parses the Index's Avro Schema JSON to build a list of srclient.References
-func (d *IndexAvroSchemaParser) parseAvroSchemaReferences() (references []srclient.Reference, err error) {
-	schemaString := d.AvroSchema
-	var schemaObj Schema
-	err = json.Unmarshal([]byte(schemaString), &schemaObj)
-	if err != nil {
-		d.Log.Error(err, "Unable to parse avro schema as JSON", "Schema", schemaString)
-		return references, errors.Wrap(err, 0)
-	}
+	dataSources = make(map[string]xjoin.DataSourceVersion)
+	for idx, referenceName := range names {
+		dataSourceName := dataSourceNames[idx]
 
-	for _, field := range schemaObj.Fields {
-		if len(field.Type) == 0 || len(strings.Split(field.Type[0].Type, ".")) < 2 {
-			return references, errors.Wrap(errors.New("unable to parse dataSourceName from avro schema fields"), 0)
-		}
-		dataSourceName := strings.Split(field.Type[0].Type, ".")[0]
-
-		//get data source obj from field.Ref
-		dataSource := &unstructured.Unstructured{}
-		dataSource.SetGroupVersionKind(common.DataSourceGVK)
-		err = d.Client.Get(d.Context, client.ObjectKey{Name: dataSourceName, Namespace: d.Namespace}, dataSource)
+		//determine which datasource version to use
+		dataSourceVersion, err := d.chooseDataSourceVersion(dataSourceName)
 		if err != nil {
-			return references, errors.Wrap(err, 0)
-		}
-
-		status := dataSource.Object["status"]
-		if status == nil {
-			err = errors.New("status missing from datasource")
-			return references, errors.Wrap(err, 0)
+			return nil, nil, errors.Wrap(err, 0)
 		}
-		statusMap := status.(map[string]interface{})
 
-		//determine which datasource version to use
-		activeVersion := statusMap["activeVersion"].(string)
-		refreshingVersion := statusMap["refreshingVersion"].(string)
-		activeVersionIsValid := statusMap["activeVersionIsValid"].(bool)
-		var chosenVersion string
-
-		if activeVersion != "" && (activeVersionIsValid || d.Active) {
-			chosenVersion = statusMap["activeVersion"].(string)
-		} else if refreshingVersion != "" {
-			chosenVersion = refreshingVersion
-		} else {
-			return nil, errors.Wrap(errors.New(
-				"Datasource ("+dataSourceName+") is not ready yet. It has no active or refreshing version."), 0)
+		subject := "xjoindatasourcepipeline." + dataSourceName + "." + dataSourceVersion.Version + "-value"
+		dataSourceVersion.SchemaVersion, err = d.resolveSchemaVersion(subject, dataSourceVersion.SchemaVersion)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, 0)
 		}
 
 		ref := srclient.Reference{
-			Name:    field.Type[0].Type,
-			Subject: "xjoindatasourcepipeline." + dataSourceName + "." + chosenVersion + "-value",
-			Version: 1,
+			Name:    referenceName,
+			Subject: subject,
+			Version: dataSourceVersion.SchemaVersion,
 		}
 
 		references = append(references, ref)
+		dataSources[dataSourceName] = dataSourceVersion
 	}
 
 	return
 }
 
-// ParseAvroSchema transforms an avro schema into elasticsearch mapping properties and a list of jsonFields
-func (d *IndexAvroSchemaParser) transformToES(avroSchema Schema) (properties string, jsonFields []string, err error) {
-
-	if avroSchema.Fields == nil {
-		return properties, jsonFields, errors.Wrap(errors.New("fields property is missing from avro schema"), 0)
+// transformToES transforms the avro schema into the elasticsearch mapping properties and analysis settings, a list of
+// jsonFields and a list of numeric dateFields which have to be converted to epoch millis
+func (d *IndexAvroSchemaParser) transformToES(indexAvroSchema *IndexAvroSchema) (err error) {
+	if indexAvroSchema.AvroSchema.Fields == nil {
+		return errors.Wrap(errors.New("fields property is missing from avro schema"), 0)
 	}
 
-	esProperties, jsonFields, err := parseAvroFields(avroSchema.Fields, list.List{})
+	mapping, err := newElasticsearchMapping(d.typeAttributes, d.TypeMapping)
 	if err != nil {
-		return properties, jsonFields, errors.Wrap(err, 0)
+		return errors.Wrap(err, 0)
 	}
-
-	propertiesBytes, err := json.Marshal(esProperties)
+	esProperties, err := mapping.parseAvroFields(indexAvroSchema.AvroSchema.Fields, "")
 	if err != nil {
-		return properties, jsonFields, errors.Wrap(err, 0)
+		return errors.Wrap(err, 0)
 	}
 
-	return string(propertiesBytes), jsonFields, nil
-}
-
-func parseAvroFields(avroFields []Field, parent list.List) (map[string]interface{}, []string, error) {
-	esProperties := make(map[string]interface{})
-	var jsonFields []string
-
-	for _, avroField := range avroFields {
-		esProperty := make(map[string]interface{})
-
-		if avroField.XJoinIndex != nil && !*avroField.XJoinIndex {
-			continue
-		}
-
-		//determine this field's type
-		var avroFieldType Type
-		if len(avroField.Type) > 1 {
-			avroFieldType = avroField.Type[1]
-		} else {
-			avroFieldType = avroField.Type[0]
-		}
-
-		esProperty["type"] = avroTypeToElasticsearchType(avroFieldType)
-		esProperty, err := parseXJoinFlags(avroFieldType, esProperty)
-		if err != nil {
-			return nil, nil, errors.Wrap(err, 0)
-		}
-
-		//find json fields which need to be transformed from a string
-		if avroFieldType.XJoinType == "json" && avroFieldType.Type == "string" {
-			jsonFieldName := ""
-			for parentField := parent.Front(); parentField != nil; parentField = parentField.Next() {
-				jsonFieldName = parentField.Value.(string) + "."
-
-			}
-			jsonFieldName = jsonFieldName + avroField.Name
-			jsonFields = append(jsonFields, jsonFieldName)
-		}
-
-		//recurse through nested object types
-		if esProperty["type"] == "object" {
-			//nested json objects are "type: string", "xjoin.type: json" with xjoin.fields
-			//top level records are "type: record" with standard avro fields
-			var nestedFields []Field
-			if avroFieldType.Fields != nil {
-				nestedFields = avroFieldType.Fields
-			} else if avroFieldType.XJoinFields != nil {
-				nestedFields = avroFieldType.XJoinFields
-			}
-
-			if nestedFields != nil {
-				newParent := parent
-				newParent.PushFront(avroField.Name)
-				nestedProperties, nestedJsonFields, err :=
-					parseAvroFields(nestedFields, newParent)
-				if err != nil {
-					return nil, nil, errors.Wrap(err, 0)
-				}
-				esProperty["properties"] = nestedProperties
-				jsonFields = append(jsonFields, nestedJsonFields...)
-			}
-		}
-
-		esProperties[avroField.Name] = esProperty
-	}
-	return esProperties, jsonFields, nil
-}
-
-func avroTypeToElasticsearchType(avroType Type) (esType string) {
-	typeString := avroType.XJoinType
-	if avroType.XJoinType == "array" {
-		typeString = avroType.Items[0].Type //TODO: handle multiple items?
-	}
-
-	switch strings.ToLower(typeString) {
-	case "date_nanos":
-		esType = "date_nanos"
-	case "string":
-		esType = "keyword"
-	case "boolean":
-		esType = "boolean"
-	case "json":
-		esType = "object"
-	case "record":
-		esType = "object"
-	case "reference":
-		esType = "object"
-	default:
-		esType = "keyword" //TODO should this be default or error?
-	}
-
-	return
-}
-
-func parseXJoinFlags(avroFieldType Type, esProperty map[string]interface{}) (map[string]interface{}, error) {
-	if avroFieldType.XJoinCase != "" {
-		if avroFieldType.XJoinType != "string" {
-			return nil, errors.Wrap(errors.New("xjoin.case can only be applied to string fields"), 0)
-		}
-
-		if avroFieldType.XJoinCase == "insensitive" {
-			fields := make(map[string]interface{})
-			lowercaseField := make(map[string]interface{})
-			lowercaseField["type"] = "keyword"
-			lowercaseField["normalizer"] = "case_insensitive"
-			fields["lowercase"] = lowercaseField
-			esProperty["fields"] = fields
-		} else if avroFieldType.XJoinCase != "sensitive" {
-			return nil, errors.Wrap(errors.New("xjoin.case must be one of [insensitive, sensitive]"), 0)
-		}
+	err = mapping.addCopyToFields(esProperties)
+	if err != nil {
+		return errors.Wrap(err, 0)
 	}
 
-	return esProperty, nil
-}
-
-// ExpandReferences retrieves the full schema for each xjoinref field
-func (d *IndexAvroSchemaParser) expandReferences(baseSchema string, references []srclient.Reference) (fullSchema Schema, err error) {
-	err = json.Unmarshal([]byte(baseSchema), &fullSchema)
+	propertiesBytes, err := json.Marshal(esProperties)
 	if err != nil {
-		return fullSchema, errors.Wrap(err, 0)
+		return errors.Wrap(err, 0)
 	}
 
-	//TODO handle type array instead of assuming type[0]
-	for idx, field := range fullSchema.Fields {
-		if field.Type[0].XJoinType == "reference" {
-			ref, err := findReferenceByType(references, field.Type[0].Type)
-			if err != nil {
-				return fullSchema, errors.Wrap(err, 0)
-			}
-			refSchemaString, err := d.SchemaRegistry.GetSchema(ref.Subject)
-			if err != nil {
-				return fullSchema, errors.Wrap(err, 0)
-			}
-
-			var refSchemaType Type
-			err = json.Unmarshal([]byte(refSchemaString), &refSchemaType)
-			if err != nil {
-				return fullSchema, errors.Wrap(err, 0)
-			}
-
-			refSchemaType.XJoinType = field.Type[0].XJoinType
-			refSchemaType.Name = ref.Name
-			fullSchema.Fields[idx].Type = []Type{refSchemaType}
-		}
+	indexAvroSchema.ESAnalysis, err = mapping.buildAnalysis()
+	if err != nil {
+		return errors.Wrap(err, 0)
 	}
 
-	return
-}
-
-func findReferenceByType(references []srclient.Reference, refType string) (srclient.Reference, error) {
-	for _, ref := range references {
-		if ref.Name == refType {
-			return ref, nil
-		}
-	}
-	return srclient.Reference{}, errors.Wrap(errors.New("reference "+refType+"not found in list of references"), 0)
+	indexAvroSchema.ESProperties = string(propertiesBytes)
+	indexAvroSchema.JSONFields = mapping.jsonFields
+	indexAvroSchema.DateFields = mapping.dateFields
+	return nil
 }
 
 func (d *IndexAvroSchemaParser) AvroSubjectToKafkaTopic(avroSubject string) (kafkaTopic string) {
@@ -0,0 +1,525 @@
+package avro
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+)
+
+// Transformations defined in xjoin.transformations. The transformations are performed by xjoin-core, the operator
+// adds the output fields to the index avro schema so the elasticsearch mapping contains them.
+const (
+	OBJECT_TO_ARRAY_OF_OBJECTS = "object_to_array_of_objects"
+	OBJECT_TO_ARRAY_OF_STRINGS = "object_to_array_of_strings"
+	RENAME                     = "rename"
+	ALIAS                      = "alias"
+	CONCATENATE                = "concatenate"
+	JSON_PATH                  = "json_path"
+	MAP_TO_KEY_VALUE_ARRAY     = "map_to_key_value_array"
+	TEMPLATE                   = "template"
+	DROP                       = "drop"
+)
+
+// jsonPathTypes are the avro types of the output field of a json_path transformation
+var jsonPathTypes = map[string]bool{
+	"string": true, "boolean": true, "int": true, "long": true, "float": true, "double": true,
+}
+
+var jsonPathPattern = regexp.MustCompile(`^\$(\.[A-Za-z0-9_]+|\[[0-9]+\])+$`)
+
+// templatePlaceholderPattern matches the fields of a template e.g. {{host.display_name}}
+var templatePlaceholderPattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// applyTransformations applies each transformation defined in xjoin.transformations to the Schema in order, so a
+// transformation can use the output field of a previous one. The attributes of renamed, aliased and dropped fields
+// are updated as well so the elasticsearch mapping follows the avro schema.
+func (d *IndexAvroSchemaParser) applyTransformations(avroSchema Schema) (transformedAvroSchema Schema, err error) {
+	for _, transformation := range avroSchema.Transformations {
+		switch transformation.Type {
+		case OBJECT_TO_ARRAY_OF_OBJECTS:
+			err = d.applyObjectToArrayOfObjectsTransformation(&avroSchema, transformation)
+		case OBJECT_TO_ARRAY_OF_STRINGS:
+			err = d.applyObjectToArrayOfStringsTransformation(&avroSchema, transformation)
+		case RENAME:
+			err = d.applyRenameTransformation(&avroSchema, transformation, false)
+		case ALIAS:
+			err = d.applyRenameTransformation(&avroSchema, transformation, true)
+		case CONCATENATE:
+			err = d.applyConcatenateTransformation(&avroSchema, transformation)
+		case JSON_PATH:
+			err = d.applyJSONPathTransformation(&avroSchema, transformation)
+		case MAP_TO_KEY_VALUE_ARRAY:
+			err = d.applyMapToKeyValueArrayTransformation(&avroSchema, transformation)
+		case TEMPLATE:
+			err = d.applyTemplateTransformation(&avroSchema, transformation)
+		case DROP:
+			err = d.applyDropTransformation(&avroSchema, transformation)
+		default:
+			err = fmt.Errorf("unknown transformation type, must be one of [%s]", strings.Join(transformationTypes(), ", "))
+		}
+
+		if err != nil {
+			return transformedAvroSchema, errors.Wrap(fmt.Errorf(
+				"invalid transformation %s, input.field: %s, output.field: %s: %w",
+				transformation.Type, transformation.InputField, transformation.OutputField, err), 0)
+		}
+	}
+
+	return avroSchema, nil
+}
+
+func (d *IndexAvroSchemaParser) applyObjectToArrayOfStringsTransformation(
+	avroSchema *Schema, transformation Transformation) error {
+
+	stringType := Type{
+		Type: "string",
+	}
+
+	fieldType := Type{
+		Type:      "array",
+		XJoinType: "array",
+		Items:     []Type{stringType},
+	}
+	return addField(avroSchema, transformation.OutputField, Field{Type: []Type{fieldType}})
+}
+
+func (d *IndexAvroSchemaParser) applyObjectToArrayOfObjectsTransformation(
+	avroSchema *Schema, transformation Transformation) error {
+
+	if transformation.Parameters["keys"] == nil || reflect.TypeOf(transformation.Parameters["keys"]).Kind() != reflect.Slice {
+		return errors.New("keys field missing from transformation")
+	}
+
+	var childFields []Field
+
+	for _, key := range transformation.Parameters["keys"].([]interface{}) {
+		if reflect.TypeOf(key).Kind() != reflect.String {
+			return errors.New("keys field must be an array of strings")
+		}
+
+		nullType := Type{
+			Type: "null",
+		}
+
+		childType := Type{
+			Type:      "string",
+			XJoinType: "string",
+		}
+		childFields = append(childFields, Field{
+			Name: key.(string),
+			Type: []Type{nullType, childType},
+		})
+	}
+
+	_, fieldName := splitFieldPath(transformation.OutputField)
+
+	childType := Type{
+		Type:      "record",
+		XJoinType: "json",
+		Name:      "children",
+		Fields:    childFields,
+	}
+
+	fieldType := Type{
+		Type:      "array",
+		XJoinType: "json",
+		Name:      fieldName,
+		Items:     []Type{childType},
+	}
+	return addField(avroSchema, transformation.OutputField, Field{Type: []Type{fieldType}})
+}
+
+// applyRenameTransformation moves the input field to the output field. When alias is true the input field is kept
+// and the output field is a copy of it.
+func (d *IndexAvroSchemaParser) applyRenameTransformation(
+	avroSchema *Schema, transformation Transformation, alias bool) error {
+
+	field, fieldType, err := findField(avroSchema, transformation.InputField)
+	if err != nil {
+		return err
+	}
+
+	if alias {
+		//avro named types can only be defined once per schema
+		if name := namedType(fieldType); name != "" {
+			return fmt.Errorf("unable to alias a field that defines the named type %s", name)
+		}
+	} else {
+		_, err = removeField(avroSchema, transformation.InputField)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = addField(avroSchema, transformation.OutputField, field)
+	if err != nil {
+		return err
+	}
+	d.typeAttributes.move(transformation.InputField, transformation.OutputField, alias)
+	return nil
+}
+
+// applyConcatenateTransformation adds a string field which joins the values of the fields parameter with the
+// separator parameter
+func (d *IndexAvroSchemaParser) applyConcatenateTransformation(
+	avroSchema *Schema, transformation Transformation) error {
+
+	if transformation.Parameters["fields"] == nil {
+		return errors.New("fields parameter is missing")
+	}
+	fields, err := stringOrStrings(transformation.Parameters["fields"])
+	if err != nil {
+		return fmt.Errorf("fields parameter %w", err)
+	}
+	if len(fields) < 2 {
+		return errors.New("fields parameter must contain at least two fields")
+	}
+
+	_, err = optionalStringParameter(transformation, "separator", "")
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		err = checkScalarField(avroSchema, field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return addField(avroSchema, transformation.OutputField, Field{Type: nullable(xjoinStringType)})
+}
+
+// applyJSONPathTransformation adds a field containing the value at the path parameter of the json input field. The
+// type parameter is the avro type of the value, string by default.
+func (d *IndexAvroSchemaParser) applyJSONPathTransformation(avroSchema *Schema, transformation Transformation) error {
+	_, inputType, err := findField(avroSchema, transformation.InputField)
+	if err != nil {
+		return err
+	}
+	if inputType.XJoinType != "json" {
+		return errors.New("input.field must be a json field")
+	}
+
+	path, err := stringParameter(transformation, "path")
+	if err != nil {
+		return err
+	}
+	if !jsonPathPattern.MatchString(path) {
+		return fmt.Errorf("path parameter %s is invalid, it must be a json path e.g. $.system_profile.arch", path)
+	}
+
+	outputType, err := optionalStringParameter(transformation, "type", "string")
+	if err != nil {
+		return err
+	}
+	if !jsonPathTypes[outputType] {
+		var types []string
+		for t := range jsonPathTypes {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return fmt.Errorf("type parameter %s is invalid, must be one of [%s]", outputType, strings.Join(types, ", "))
+	}
+
+	return addField(avroSchema, transformation.OutputField, Field{Type: nullable(Type{Type: outputType})})
+}
+
+// applyMapToKeyValueArrayTransformation adds an array of {key, value} records with an item for each entry of the
+// input map or json field. The array is mapped as nested unless the nested parameter is false, so a query matches
+// the key and the value of the same entry.
+func (d *IndexAvroSchemaParser) applyMapToKeyValueArrayTransformation(
+	avroSchema *Schema, transformation Transformation) error {
+
+	_, inputType, err := findField(avroSchema, transformation.InputField)
+	if err != nil {
+		return err
+	}
+	if inputType.Type != "map" && inputType.XJoinType != "json" {
+		return errors.New("input.field must be a map or a json field")
+	}
+
+	nested, err := boolParameter(transformation, "nested", true)
+	if err != nil {
+		return err
+	}
+
+	entryType := Type{
+		Type: "record",
+		Name: strings.ReplaceAll(transformation.OutputField, ".", "_") + "_entry",
+		Fields: []Field{{
+			Name: "key",
+			Type: []Type{xjoinStringType},
+		}, {
+			Name: "value",
+			Type: nullable(xjoinStringType),
+		}},
+	}
+
+	fieldType := Type{
+		Type:      "array",
+		XJoinType: "array",
+		Items:     []Type{entryType},
+	}
+	err = addField(avroSchema, transformation.OutputField, Field{Type: []Type{fieldType}})
+	if err != nil {
+		return err
+	}
+
+	if nested {
+		d.typeAttributes[transformation.OutputField] = map[string]interface{}{"type": "array", "xjoin.es.nested": true}
+	}
+	return nil
+}
+
+// applyTemplateTransformation adds a string field computed from the template parameter. The fields of the template
+// are referenced by their path e.g. "{{host.display_name}} ({{host.id}})".
+func (d *IndexAvroSchemaParser) applyTemplateTransformation(avroSchema *Schema, transformation Transformation) error {
+	template, err := stringParameter(transformation, "template")
+	if err != nil {
+		return err
+	}
+
+	placeholders := templatePlaceholderPattern.FindAllStringSubmatch(template, -1)
+	if len(placeholders) == 0 {
+		return errors.New("template parameter must reference at least one field e.g. {{host.id}}")
+	}
+
+	for _, placeholder := range placeholders {
+		err = checkScalarField(avroSchema, placeholder[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return addField(avroSchema, transformation.OutputField, Field{Type: nullable(xjoinStringType)})
+}
+
+// applyDropTransformation removes the input field from the schema
+func (d *IndexAvroSchemaParser) applyDropTransformation(avroSchema *Schema, transformation Transformation) error {
+	_, err := removeField(avroSchema, transformation.InputField)
+	if err != nil {
+		return err
+	}
+	d.typeAttributes.move(transformation.InputField, "", false)
+	return nil
+}
+
+// recordFields returns the fields of the record at path, the fields of the schema when path is empty
+func recordFields(avroSchema *Schema, path string) (*[]Field, error) {
+	fields := &avroSchema.Fields
+	if path == "" {
+		return fields, nil
+	}
+
+	for _, name := range strings.Split(path, ".") {
+		idx := fieldIndex(*fields, name)
+		if idx < 0 {
+			return nil, fmt.Errorf("field %s not found in schema", path)
+		}
+
+		typeIdx, err := resolveUnionIndex((*fields)[idx].Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type of field %s: %w", path, err)
+		}
+
+		//the type slice is shared with the schema so the fields of the record can be modified through it
+		recordType := &(*fields)[idx].Type[typeIdx]
+		if recordType.Type != "record" {
+			return nil, fmt.Errorf("field %s is not a record", path)
+		}
+		fields = &recordType.Fields
+	}
+
+	return fields, nil
+}
+
+// findField returns the field at path and its type resolved by resolveUnion
+func findField(avroSchema *Schema, path string) (Field, Type, error) {
+	if path == "" {
+		return Field{}, Type{}, errors.New("field is missing")
+	}
+
+	parent, name := splitFieldPath(path)
+	fields, err := recordFields(avroSchema, parent)
+	if err != nil {
+		return Field{}, Type{}, err
+	}
+
+	idx := fieldIndex(*fields, name)
+	if idx < 0 {
+		return Field{}, Type{}, fmt.Errorf("field %s not found in schema", path)
+	}
+
+	field := (*fields)[idx]
+	fieldType, err := resolveUnion(field.Type)
+	if err != nil {
+		return Field{}, Type{}, fmt.Errorf("invalid type of field %s: %w", path, err)
+	}
+	return field, fieldType, nil
+}
+
+// addField adds field at path, the parent of the field must be a record
+func addField(avroSchema *Schema, path string, field Field) error {
+	if path == "" {
+		return errors.New("output.field is missing")
+	}
+
+	parent, name := splitFieldPath(path)
+	if !avroNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid field name %s, names must match %s", name, avroNamePattern.String())
+	}
+
+	fields, err := recordFields(avroSchema, parent)
+	if err != nil {
+		return err
+	}
+	if fieldIndex(*fields, name) >= 0 {
+		return fmt.Errorf("field %s already exists", path)
+	}
+
+	field.Name = name
+	*fields = append(*fields, field)
+	return nil
+}
+
+// removeField removes the field at path from the schema and returns it
+func removeField(avroSchema *Schema, path string) (Field, error) {
+	field, _, err := findField(avroSchema, path)
+	if err != nil {
+		return Field{}, err
+	}
+
+	parent, name := splitFieldPath(path)
+	fields, err := recordFields(avroSchema, parent)
+	if err != nil {
+		return Field{}, err
+	}
+
+	idx := fieldIndex(*fields, name)
+	*fields = append((*fields)[:idx:idx], (*fields)[idx+1:]...)
+	return field, nil
+}
+
+// checkScalarField validates the field at path exists and contains a single value which can be converted to a string
+func checkScalarField(avroSchema *Schema, path string) error {
+	_, fieldType, err := findField(avroSchema, path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fieldType.XJoinType == "json" || fieldType.XJoinType == "reference":
+		return fmt.Errorf("field %s must not be a %s field", path, fieldType.XJoinType)
+	case fieldType.Type == "record" || fieldType.Type == "array" || fieldType.Type == "map":
+		return fmt.Errorf("field %s must not be a %s", path, fieldType.Type)
+	}
+	return nil
+}
+
+// namedType returns the name of the first record, enum or fixed type defined by t
+func namedType(t Type) string {
+	switch t.Type {
+	case "record", "enum", "fixed":
+		return t.Name
+	}
+
+	for _, item := range t.Items {
+		if name := namedType(item); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// move moves the attributes of the type at path and its children to newPath, the attributes are copied when keep
+// is true and deleted when newPath is empty
+func (a typeAttributes) move(path string, newPath string, keep bool) {
+	moved := make(typeAttributes)
+	for attributesPath, attributes := range a {
+		if attributesPath != path && !strings.HasPrefix(attributesPath, path+".") &&
+			!strings.HasPrefix(attributesPath, path+"[]") {
+			continue
+		}
+
+		if newPath != "" {
+			moved[newPath+strings.TrimPrefix(attributesPath, path)] = attributes
+		}
+		if !keep {
+			delete(a, attributesPath)
+		}
+	}
+
+	for attributesPath, attributes := range moved {
+		a[attributesPath] = attributes
+	}
+}
+
+var xjoinStringType = Type{Type: "string", XJoinType: "string"}
+
+func nullable(t Type) TypeWrapper {
+	return []Type{{Type: "null"}, t}
+}
+
+func fieldIndex(fields []Field, name string) int {
+	for idx, field := range fields {
+		if field.Name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// splitFieldPath splits a dotted path into the path of the parent record and the name of the field
+func splitFieldPath(path string) (parent string, name string) {
+	idx := strings.LastIndex(path, ".")
+	if idx < 0 {
+		return "", path
+	}
+	return path[:idx], path[idx+1:]
+}
+
+func stringParameter(transformation Transformation, name string) (string, error) {
+	if _, ok := transformation.Parameters[name]; !ok {
+		return "", fmt.Errorf("%s parameter is missing", name)
+	}
+	return optionalStringParameter(transformation, name, "")
+}
+
+func optionalStringParameter(transformation Transformation, name string, defaultValue string) (string, error) {
+	value, ok := transformation.Parameters[name]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	s, isString := value.(string)
+	if !isString {
+		return "", fmt.Errorf("%s parameter must be a string", name)
+	}
+	return s, nil
+}
+
+func boolParameter(transformation Transformation, name string, defaultValue bool) (bool, error) {
+	value, ok := transformation.Parameters[name]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	b, isBool := value.(bool)
+	if !isBool {
+		return false, fmt.Errorf("%s parameter must be a boolean", name)
+	}
+	return b, nil
+}
+
+func transformationTypes() []string {
+	types := []string{OBJECT_TO_ARRAY_OF_OBJECTS, OBJECT_TO_ARRAY_OF_STRINGS, RENAME, ALIAS, CONCATENATE, JSON_PATH,
+		MAP_TO_KEY_VALUE_ARRAY, TEMPLATE, DROP}
+	sort.Strings(types)
+	return types
+}
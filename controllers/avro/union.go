@@ -0,0 +1,73 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-errors/errors"
+	. "github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+)
+
+// resolveUnion returns the member of a union that is indexed. Null members are ignored. When more than one member
+// remains the union is ambiguous, it is resolved by setting xjoin.type on exactly one of the members
+// e.g. ["null", "long", {"type": "string", "xjoin.type": "string"}].
+// A type that isn't a union is a union with a single member.
+func resolveUnion(types TypeWrapper) (Type, error) {
+	idx, err := resolveUnionIndex(types)
+	if err != nil {
+		return Type{}, err
+	}
+	return types[idx], nil
+}
+
+func resolveUnionIndex(types TypeWrapper) (int, error) {
+	var members []int
+	for idx, t := range types {
+		if t.Type != "null" {
+			members = append(members, idx)
+		}
+	}
+
+	if len(members) == 0 {
+		return -1, errors.New("the type must contain at least one member that isn't null")
+	} else if len(members) == 1 {
+		return members[0], nil
+	}
+
+	var memberNames []string
+	var xjoinMembers []int
+	for _, idx := range members {
+		memberNames = append(memberNames, types[idx].Type)
+		if types[idx].XJoinType != "" {
+			xjoinMembers = append(xjoinMembers, idx)
+		}
+	}
+
+	if len(xjoinMembers) != 1 {
+		return -1, fmt.Errorf(
+			"ambiguous union [%s], set xjoin.type on exactly one of the members to choose the indexed type",
+			strings.Join(memberNames, ", "))
+	}
+	return xjoinMembers[0], nil
+}
+
+// rawUnionIndex resolves a union in its raw json form the same way as resolveUnion, -1 when it can't be resolved
+func rawUnionIndex(union []interface{}) int {
+	unionJSON, err := json.Marshal(union)
+	if err != nil {
+		return -1
+	}
+
+	var types TypeWrapper
+	err = json.Unmarshal(unionJSON, &types)
+	if err != nil || len(types) != len(union) {
+		return -1
+	}
+
+	idx, err := resolveUnionIndex(types)
+	if err != nil {
+		return -1
+	}
+	return idx
+}
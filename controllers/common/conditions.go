@@ -0,0 +1,245 @@
+package common
+
+import (
+	"fmt"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	logger "github.com/redhatinsights/xjoin-operator/controllers/log"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+const (
+	ReasonActiveVersionValid   = "ActiveVersionValid"
+	ReasonActiveVersionInvalid = "ActiveVersionInvalid"
+	ReasonNoActiveVersion      = "NoActiveVersion"
+	ReasonRemoved              = "Removed"
+	ReasonRefreshInProgress    = "RefreshInProgress"
+	ReasonNotRefreshing        = "NotRefreshing"
+	ReasonValidationSucceeded  = "ValidationSucceeded"
+	ReasonValidationFailed     = "ValidationFailed"
+	ReasonValidationPending    = "ValidationPending"
+	ReasonNotValidated         = "NotValidated"
+	ReasonPipelinesPresent     = "PipelinesPresent"
+	ReasonPipelineMissing      = "PipelineMissing"
+	ReasonPipelineDeleting     = "PipelineDeleting"
+	ReasonComponentDeviation   = "ComponentDeviation"
+	ReasonAsExpected           = "AsExpected"
+	ReasonReconcileError       = "ReconcileError"
+)
+
+const (
+	validResult        = "valid"
+	invalidResult      = "invalid"
+	notValidatedResult = "notValidated"
+)
+
+// ChildPipelineStatus is the subset of an XJoinIndexPipeline/XJoinDataSourcePipeline status
+// used to derive the conditions of the parent XJoinIndex/XJoinDataSource
+type ChildPipelineStatus struct {
+	Version            string
+	Exists             bool
+	Deleting           bool
+	ValidationResponse validation.ValidationResponse
+	Deviations         []xjoin.ComponentDeviation
+}
+
+type ChildPipelineStatuses map[string]ChildPipelineStatus
+
+func (c ChildPipelineStatuses) get(version string) (status ChildPipelineStatus, found bool) {
+	if version == "" {
+		return status, false
+	}
+	status, found = c[version]
+	return
+}
+
+// AddCreatedVersions adds the pipelines of the active and refreshing versions that were created by the state
+// transition of the reconcile. These weren't fetched before the transition and may not be in the cache yet.
+func (c ChildPipelineStatuses) AddCreatedVersions(instance XJoinObject) {
+	for _, version := range []string{instance.GetActiveVersion(), instance.GetRefreshingVersion()} {
+		if _, found := c.get(version); version != "" && !found {
+			c[version] = ChildPipelineStatus{Version: version, Exists: true}
+		}
+	}
+}
+
+// SetStatusConditions updates the Ready, Refreshing, Validated, ComponentsHealthy and Degraded conditions
+// of instance. It should be called after the reconciler has run so the conditions reflect the new versions.
+func SetStatusConditions(instance XJoinObject, pipelines ChildPipelineStatuses) {
+	generation := instance.GetGeneration()
+	activeVersion := instance.GetActiveVersion()
+	refreshingVersion := instance.GetRefreshingVersion()
+	active, activeFound := pipelines.get(activeVersion)
+	refreshing, refreshingFound := pipelines.get(refreshingVersion)
+
+	//Ready
+	ready := metav1.Condition{Type: xjoin.ConditionReady, ObservedGeneration: generation}
+	if instance.GetDeletionTimestamp() != nil {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = ReasonRemoved
+		ready.Message = "Resource is being deleted"
+	} else if activeVersion == "" {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = ReasonNoActiveVersion
+		ready.Message = "Waiting for the initial sync to complete"
+	} else if instance.GetActiveVersionIsValid() {
+		ready.Status = metav1.ConditionTrue
+		ready.Reason = ReasonActiveVersionValid
+		ready.Message = fmt.Sprintf("Active version %s is valid", activeVersion)
+	} else {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = ReasonActiveVersionInvalid
+		ready.Message = fmt.Sprintf("Active version %s is invalid", activeVersion)
+	}
+	instance.SetCondition(ready)
+
+	//Refreshing
+	refreshingCondition := metav1.Condition{Type: xjoin.ConditionRefreshing, ObservedGeneration: generation}
+	if refreshingVersion != "" {
+		refreshingCondition.Status = metav1.ConditionTrue
+		refreshingCondition.Reason = ReasonRefreshInProgress
+		refreshingCondition.Message = fmt.Sprintf("Refreshing version %s", refreshingVersion)
+	} else {
+		refreshingCondition.Status = metav1.ConditionFalse
+		refreshingCondition.Reason = ReasonNotRefreshing
+		refreshingCondition.Message = "No refresh in progress"
+	}
+	instance.SetCondition(refreshingCondition)
+
+	//Validated reflects the version that is serving data, or the initial sync when there is no active version
+	validated := metav1.Condition{Type: xjoin.ConditionValidated, ObservedGeneration: generation}
+	validatedVersion := activeVersion
+	validatedPipeline, validatedFound := active, activeFound
+	if activeVersion == "" {
+		validatedVersion = refreshingVersion
+		validatedPipeline, validatedFound = refreshing, refreshingFound
+	}
+	switch {
+	case validatedFound && validatedPipeline.ValidationResponse.Result == validResult:
+		validated.Status = metav1.ConditionTrue
+		validated.Reason = ReasonValidationSucceeded
+		validated.Message = validationMessage(validatedVersion, validatedPipeline.ValidationResponse)
+	case validatedFound && validatedPipeline.ValidationResponse.Result == invalidResult:
+		validated.Status = metav1.ConditionFalse
+		validated.Reason = ReasonValidationFailed
+		validated.Message = validationMessage(validatedVersion, validatedPipeline.ValidationResponse)
+	case validatedFound && validatedPipeline.ValidationResponse.Result == notValidatedResult:
+		validated.Status = metav1.ConditionUnknown
+		validated.Reason = ReasonNotValidated
+		validated.Message = validationMessage(validatedVersion, validatedPipeline.ValidationResponse)
+	default:
+		validated.Status = metav1.ConditionUnknown
+		validated.Reason = ReasonValidationPending
+		validated.Message = "Validation has not completed yet"
+	}
+	instance.SetCondition(validated)
+
+	//ComponentsHealthy
+	healthy := metav1.Condition{
+		Type:               xjoin.ConditionComponentsHealthy,
+		ObservedGeneration: generation,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonPipelinesPresent,
+		Message:            "All pipelines are present",
+	}
+	for _, version := range []string{activeVersion, refreshingVersion} {
+		if version == "" {
+			continue
+		}
+		pipeline, found := pipelines.get(version)
+		if !found || !pipeline.Exists {
+			healthy.Status = metav1.ConditionUnknown
+			healthy.Reason = ReasonPipelineMissing
+			healthy.Message = fmt.Sprintf("Pipeline for version %s has not been observed yet", version)
+		} else if pipeline.Deleting {
+			healthy.Status = metav1.ConditionFalse
+			healthy.Reason = ReasonPipelineDeleting
+			healthy.Message = fmt.Sprintf("Pipeline for version %s is being deleted", version)
+			break
+		} else if len(pipeline.Deviations) > 0 {
+			healthy.Status = metav1.ConditionFalse
+			healthy.Reason = ReasonComponentDeviation
+			healthy.Message = deviationsMessage(version, pipeline.Deviations)
+			break
+		}
+	}
+	instance.SetCondition(healthy)
+
+	//Degraded
+	degraded := metav1.Condition{
+		Type:               xjoin.ConditionDegraded,
+		ObservedGeneration: generation,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonAsExpected,
+		Message:            "Resource is operating as expected",
+	}
+	if activeVersion != "" && !instance.GetActiveVersionIsValid() {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = ReasonActiveVersionInvalid
+		degraded.Message = fmt.Sprintf("Active version %s is invalid", activeVersion)
+		if refreshingVersion != "" {
+			degraded.Message = degraded.Message + fmt.Sprintf(", refreshing version %s", refreshingVersion)
+		}
+	} else if healthy.Status == metav1.ConditionFalse {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = healthy.Reason
+		degraded.Message = healthy.Message
+	}
+	instance.SetCondition(degraded)
+}
+
+// SetReconcileErrorCondition sets the Degraded condition of instance to the error of a failed reconcile
+func SetReconcileErrorCondition(instance XJoinObject, reconcileErr error) {
+	instance.SetCondition(metav1.Condition{
+		Type:               xjoin.ConditionDegraded,
+		ObservedGeneration: instance.GetGeneration(),
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonReconcileError,
+		Message:            "Reconcile failed: " + reconcileErr.Error(),
+	})
+}
+
+// UpdateConditionsAfterError writes the conditions of original, the instance as it was fetched before the failed
+// reconcile, with the error in the Degraded condition. The other status changes of the failed reconcile are discarded.
+// Conflicts are ignored because the reconcile is retried with the latest instance.
+func UpdateConditionsAfterError(c client.Client, original XJoinObject, pipelines ChildPipelineStatuses,
+	reconcileErr error, log logger.Log) {
+
+	updated, ok := original.DeepCopyObject().(XJoinObject)
+	if !ok || k8errors.IsConflict(reconcileErr) {
+		return
+	}
+	SetStatusConditions(updated, pipelines)
+	SetReconcileErrorCondition(updated, reconcileErr)
+
+	ctx, cancel := utils.DefaultContext()
+	defer cancel()
+	err := c.Status().Patch(ctx, updated, client.MergeFrom(original))
+	if err != nil {
+		log.Error(err, "Unable to update the status conditions after a failed reconcile")
+	}
+}
+
+func validationMessage(version string, response validation.ValidationResponse) string {
+	message := fmt.Sprintf("Version %s is %s", version, response.Result)
+	if response.Reason != "" {
+		message = message + ": " + response.Reason
+	}
+	if response.Message != "" {
+		message = message + ", " + response.Message
+	}
+	return message
+}
+
+func deviationsMessage(version string, deviations []xjoin.ComponentDeviation) string {
+	var messages []string
+	for _, deviation := range deviations {
+		messages = append(messages, fmt.Sprintf(
+			"%s %s: %s", deviation.ComponentType, deviation.ComponentName, deviation.Message))
+	}
+	return fmt.Sprintf("Pipeline for version %s has component deviations: %s", version, strings.Join(messages, "; "))
+}
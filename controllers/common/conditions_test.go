@@ -0,0 +1,189 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type expectedCondition struct {
+	conditionType string
+	status        metav1.ConditionStatus
+	reason        string
+}
+
+func newConditionsIndex(activeVersion string, activeVersionIsValid bool, refreshingVersion string) *xjoin.XJoinIndex {
+	instance := &xjoin.XJoinIndex{}
+	instance.Generation = 2
+	instance.Status.ActiveVersion = activeVersion
+	instance.Status.ActiveVersionIsValid = activeVersionIsValid
+	instance.Status.RefreshingVersion = refreshingVersion
+	return instance
+}
+
+func validatedPipeline(version string, result string) ChildPipelineStatus {
+	return ChildPipelineStatus{
+		Version:            version,
+		Exists:             true,
+		ValidationResponse: validation.ValidationResponse{Result: result},
+	}
+}
+
+func expectConditions(t *testing.T, instance XJoinObject, expected []expectedCondition) {
+	t.Helper()
+	for _, e := range expected {
+		condition := meta.FindStatusCondition(instance.GetConditions(), e.conditionType)
+		if condition == nil {
+			t.Errorf("condition %s is not set", e.conditionType)
+			continue
+		}
+		if condition.Status != e.status || condition.Reason != e.reason {
+			t.Errorf("expected condition %s to be %s/%s, got %s/%s: %s",
+				e.conditionType, e.status, e.reason, condition.Status, condition.Reason, condition.Message)
+		}
+		if condition.ObservedGeneration != instance.GetGeneration() {
+			t.Errorf("expected condition %s to observe generation %d, got %d",
+				e.conditionType, instance.GetGeneration(), condition.ObservedGeneration)
+		}
+	}
+}
+
+func TestSetStatusConditions(t *testing.T) {
+	deletedIndex := newConditionsIndex("1", true, "")
+	deletedIndex.DeletionTimestamp = &metav1.Time{}
+
+	tests := []struct {
+		name      string
+		instance  *xjoin.XJoinIndex
+		pipelines ChildPipelineStatuses
+		expected  []expectedCondition
+	}{{
+		name:      "valid active version",
+		instance:  newConditionsIndex("1", true, ""),
+		pipelines: ChildPipelineStatuses{"1": validatedPipeline("1", validResult)},
+		expected: []expectedCondition{
+			{xjoin.ConditionReady, metav1.ConditionTrue, ReasonActiveVersionValid},
+			{xjoin.ConditionRefreshing, metav1.ConditionFalse, ReasonNotRefreshing},
+			{xjoin.ConditionValidated, metav1.ConditionTrue, ReasonValidationSucceeded},
+			{xjoin.ConditionComponentsHealthy, metav1.ConditionTrue, ReasonPipelinesPresent},
+			{xjoin.ConditionDegraded, metav1.ConditionFalse, ReasonAsExpected},
+		},
+	}, {
+		name:      "initial sync",
+		instance:  newConditionsIndex("", false, "1"),
+		pipelines: ChildPipelineStatuses{"1": {Version: "1", Exists: true}},
+		expected: []expectedCondition{
+			{xjoin.ConditionReady, metav1.ConditionFalse, ReasonNoActiveVersion},
+			{xjoin.ConditionRefreshing, metav1.ConditionTrue, ReasonRefreshInProgress},
+			{xjoin.ConditionValidated, metav1.ConditionUnknown, ReasonValidationPending},
+			{xjoin.ConditionComponentsHealthy, metav1.ConditionTrue, ReasonPipelinesPresent},
+			{xjoin.ConditionDegraded, metav1.ConditionFalse, ReasonAsExpected},
+		},
+	}, {
+		name:     "invalid active version with a refresh in progress",
+		instance: newConditionsIndex("1", false, "2"),
+		pipelines: ChildPipelineStatuses{
+			"1": validatedPipeline("1", invalidResult),
+			"2": {Version: "2", Exists: true},
+		},
+		expected: []expectedCondition{
+			{xjoin.ConditionReady, metav1.ConditionFalse, ReasonActiveVersionInvalid},
+			{xjoin.ConditionRefreshing, metav1.ConditionTrue, ReasonRefreshInProgress},
+			{xjoin.ConditionValidated, metav1.ConditionFalse, ReasonValidationFailed},
+			{xjoin.ConditionComponentsHealthy, metav1.ConditionTrue, ReasonPipelinesPresent},
+			{xjoin.ConditionDegraded, metav1.ConditionTrue, ReasonActiveVersionInvalid},
+		},
+	}, {
+		name:      "sink without a validator",
+		instance:  newConditionsIndex("1", false, ""),
+		pipelines: ChildPipelineStatuses{"1": validatedPipeline("1", notValidatedResult)},
+		expected: []expectedCondition{
+			{xjoin.ConditionValidated, metav1.ConditionUnknown, ReasonNotValidated},
+		},
+	}, {
+		name:      "refreshing pipeline not observed",
+		instance:  newConditionsIndex("1", true, "2"),
+		pipelines: ChildPipelineStatuses{"1": validatedPipeline("1", validResult)},
+		expected: []expectedCondition{
+			{xjoin.ConditionComponentsHealthy, metav1.ConditionUnknown, ReasonPipelineMissing},
+			{xjoin.ConditionDegraded, metav1.ConditionFalse, ReasonAsExpected},
+		},
+	}, {
+		name:     "refreshing pipeline deleting",
+		instance: newConditionsIndex("1", true, "2"),
+		pipelines: ChildPipelineStatuses{
+			"1": validatedPipeline("1", validResult),
+			"2": {Version: "2", Exists: true, Deleting: true},
+		},
+		expected: []expectedCondition{
+			{xjoin.ConditionComponentsHealthy, metav1.ConditionFalse, ReasonPipelineDeleting},
+			{xjoin.ConditionDegraded, metav1.ConditionTrue, ReasonPipelineDeleting},
+		},
+	}, {
+		name:     "component deviation",
+		instance: newConditionsIndex("1", true, ""),
+		pipelines: ChildPipelineStatuses{"1": {
+			Version:            "1",
+			Exists:             true,
+			ValidationResponse: validation.ValidationResponse{Result: validResult},
+			Deviations: []xjoin.ComponentDeviation{{
+				ComponentType: "ElasticsearchPipeline", ComponentName: "xjoinindexpipeline.test.1", Message: "edited"}},
+		}},
+		expected: []expectedCondition{
+			{xjoin.ConditionReady, metav1.ConditionTrue, ReasonActiveVersionValid},
+			{xjoin.ConditionComponentsHealthy, metav1.ConditionFalse, ReasonComponentDeviation},
+			{xjoin.ConditionDegraded, metav1.ConditionTrue, ReasonComponentDeviation},
+		},
+	}, {
+		name:      "deleted",
+		instance:  deletedIndex,
+		pipelines: ChildPipelineStatuses{"1": validatedPipeline("1", validResult)},
+		expected: []expectedCondition{
+			{xjoin.ConditionReady, metav1.ConditionFalse, ReasonRemoved},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetStatusConditions(test.instance, test.pipelines)
+			expectConditions(t, test.instance, test.expected)
+		})
+	}
+}
+
+func TestAddCreatedVersions(t *testing.T) {
+	instance := newConditionsIndex("1", false, "2")
+	pipelines := ChildPipelineStatuses{"1": validatedPipeline("1", invalidResult)}
+
+	pipelines.AddCreatedVersions(instance)
+	SetStatusConditions(instance, pipelines)
+
+	if pipelines["1"].ValidationResponse.Result != invalidResult {
+		t.Errorf("expected the fetched pipeline to be kept")
+	}
+	expectConditions(t, instance, []expectedCondition{
+		{xjoin.ConditionRefreshing, metav1.ConditionTrue, ReasonRefreshInProgress},
+		{xjoin.ConditionComponentsHealthy, metav1.ConditionTrue, ReasonPipelinesPresent},
+		{xjoin.ConditionDegraded, metav1.ConditionTrue, ReasonActiveVersionInvalid},
+	})
+}
+
+func TestSetReconcileErrorCondition(t *testing.T) {
+	instance := newConditionsIndex("1", true, "")
+	SetStatusConditions(instance, ChildPipelineStatuses{"1": validatedPipeline("1", validResult)})
+
+	SetReconcileErrorCondition(instance, errors.New("unable to connect"))
+
+	expectConditions(t, instance, []expectedCondition{
+		{xjoin.ConditionReady, metav1.ConditionTrue, ReasonActiveVersionValid},
+		{xjoin.ConditionDegraded, metav1.ConditionTrue, ReasonReconcileError},
+	})
+	degraded := meta.FindStatusCondition(instance.GetConditions(), xjoin.ConditionDegraded)
+	if degraded.Message != "Reconcile failed: unable to connect" {
+		t.Errorf("unexpected message: %s", degraded.Message)
+	}
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// WriteOwnedConfigMap creates or updates a ConfigMap that is owned by the instance.
+// The ConfigMap is garbage collected when the instance is deleted.
+func (i *Iteration) WriteOwnedConfigMap(
+	name string, ownerGVK schema.GroupVersionKind, data map[string]string) (err error) {
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: i.Instance.GetNamespace(),
+		},
+	}
+
+	ctx, cancel := utils.DefaultContext()
+	defer cancel()
+	_, err = controllerutil.CreateOrUpdate(ctx, i.Client, configMap, func() error {
+		blockOwnerDeletion := true
+		controller := true
+		configMap.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion:         ownerGVK.GroupVersion().String(),
+			Kind:               ownerGVK.Kind,
+			Name:               i.Instance.GetName(),
+			UID:                i.Instance.GetUID(),
+			Controller:         &controller,
+			BlockOwnerDeletion: &blockOwnerDeletion,
+		}})
+		configMap.SetLabels(map[string]string{COMPONENT_NAME_LABEL: i.Instance.GetName()})
+		configMap.Data = data
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	return nil
+}
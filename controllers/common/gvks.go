@@ -43,3 +43,9 @@ var ServiceGVK = schema.GroupVersionKind{
 	Kind:    "Service",
 	Version: "v1",
 }
+
+var HorizontalPodAutoscalerGVK = schema.GroupVersionKind{
+	Group:   "autoscaling",
+	Kind:    "HorizontalPodAutoscaler",
+	Version: "v2",
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// PlanConfigMapName returns the name of the ConfigMap that contains the rendered plan of a resource
+func PlanConfigMapName(kind string, name string) string {
+	return strings.ToLower(kind) + "." + name + ".plan"
+}
+
+// WritePlan creates or updates the plan ConfigMap of the instance
+func (i *Iteration) WritePlan(ownerGVK schema.GroupVersionKind, data map[string]string) (name string, err error) {
+	name = PlanConfigMapName(ownerGVK.Kind, i.Instance.GetName())
+	err = i.WriteOwnedConfigMap(name, ownerGVK, data)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return name, nil
+}
+
+// DeletePlan deletes the plan ConfigMap of the instance. No-op if the ConfigMap doesn't exist.
+func (i *Iteration) DeletePlan(ownerGVK schema.GroupVersionKind) (err error) {
+	configMap := &v1.ConfigMap{}
+	ctx, cancel := utils.DefaultContext()
+	defer cancel()
+
+	err = i.Client.Get(ctx, client.ObjectKey{
+		Name:      PlanConfigMapName(ownerGVK.Kind, i.Instance.GetName()),
+		Namespace: i.Instance.GetNamespace(),
+	}, configMap)
+	if k8errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = i.Client.Delete(ctx, configMap)
+	if err != nil && !k8errors.IsNotFound(err) {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
@@ -20,9 +20,10 @@ type ReconcilerMethods interface {
 }
 
 type Reconciler struct {
-	methods  ReconcilerMethods
-	instance XJoinObject
-	log      logger.Log
+	methods        ReconcilerMethods
+	instance       XJoinObject
+	log            logger.Log
+	holdSpecChange bool
 }
 
 func NewReconciler(methods ReconcilerMethods, instance XJoinObject, log logger.Log) *Reconciler {
@@ -33,6 +34,11 @@ func NewReconciler(methods ReconcilerMethods, instance XJoinObject, log logger.L
 	}
 }
 
+// HoldSpecChange keeps the current versions when the spec changed e.g. when the new schema is not compatible
+func (r *Reconciler) HoldSpecChange() {
+	r.holdSpecChange = true
+}
+
 func (r *Reconciler) Version() string {
 	return strconv.FormatInt(time.Now().UnixNano(), 10)
 }
@@ -66,7 +72,7 @@ func (r *Reconciler) getState(specHash string) string {
 	} else if (r.instance.GetActiveVersion() != "" &&
 		!r.instance.GetActiveVersionIsValid() &&
 		r.instance.GetRefreshingVersion() == "") ||
-		(r.instance.GetSpecHash() != "" && r.instance.GetSpecHash() != specHash) {
+		(!r.holdSpecChange && r.instance.GetSpecHash() != "" && r.instance.GetSpecHash() != specHash) {
 		return START_REFRESH
 	} else if r.instance.GetActiveVersion() == "" && r.instance.GetRefreshingVersion() == "" {
 		return NEW
@@ -90,6 +96,15 @@ func (r *Reconciler) getState(specHash string) string {
 	}
 }
 
+// PlannedState returns the state the next call to Reconcile will transition to without applying it
+func (r *Reconciler) PlannedState() (state string, err error) {
+	specHash, err := k8sUtils.SpecHash(r.instance.GetSpec())
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return r.getState(specHash), nil
+}
+
 func (r *Reconciler) Reconcile(forceRefresh bool) (err error) {
 	//Scrub orphaned resources
 	errs := r.methods.Scrub()
@@ -110,6 +125,7 @@ func (r *Reconciler) Reconcile(forceRefresh bool) (err error) {
 	if state != REFRESHING && forceRefresh {
 		state = START_REFRESH
 	}
+	r.instance.SetPhase(state)
 
 	switch state {
 	case REMOVED:
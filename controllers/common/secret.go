@@ -0,0 +1,50 @@
+package common
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// WriteOwnedSecret creates or updates a Secret that is owned by the instance.
+// The Secret is garbage collected when the instance is deleted.
+func (i *Iteration) WriteOwnedSecret(
+	name string, ownerGVK schema.GroupVersionKind, data map[string]string) (err error) {
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: i.Instance.GetNamespace(),
+		},
+	}
+
+	ctx, cancel := utils.DefaultContext()
+	defer cancel()
+	_, err = controllerutil.CreateOrUpdate(ctx, i.Client, secret, func() error {
+		blockOwnerDeletion := true
+		controller := true
+		secret.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion:         ownerGVK.GroupVersion().String(),
+			Kind:               ownerGVK.Kind,
+			Name:               i.Instance.GetName(),
+			UID:                i.Instance.GetUID(),
+			Controller:         &controller,
+			BlockOwnerDeletion: &blockOwnerDeletion,
+		}})
+		secret.SetLabels(map[string]string{COMPONENT_NAME_LABEL: i.Instance.GetName()})
+		secret.Type = v1.SecretTypeOpaque
+		secret.Data = make(map[string][]byte)
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	return nil
+}
@@ -18,4 +18,8 @@ type XJoinObject interface {
 	SetRefreshingVersionIsValid(valid bool)
 	GetSpecHash() string
 	GetSpec() interface{}
+	GetPhase() string
+	SetPhase(phase string)
+	GetConditions() []metav1.Condition
+	SetCondition(condition metav1.Condition)
 }
@@ -3,6 +3,7 @@ package components
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/go-errors/errors"
@@ -78,7 +79,66 @@ func (as *AvroSchema) DeleteByVersion(version string) (err error) {
 	return
 }
 
-func (as *AvroSchema) CheckDeviation() (problem, err error) {
+// Repair makes the expected schema the latest version of the subject. Registering a schema that is already a
+// version of the subject returns the existing version without making it the latest, so the versions registered
+// after the expected schema are deleted instead. The expected schema is registered when it's not a version yet.
+func (as *AvroSchema) Repair() (err error) {
+	schema, err := as.SetSchemaNameNamespace()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	as.registry.Client.ResetCache()
+	versions, err := as.registry.Client.GetSchemaVersions(as.Name())
+	if err != nil {
+		srerr, isSrerr := err.(srclient.Error)
+		if !isSrerr || srerr.Code != 40401 {
+			return errors.Wrap(err, 0)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	var deviatingVersions []int
+	for _, version := range versions {
+		srschema, err := as.registry.Client.GetSchemaByVersion(as.Name(), version)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if srschema.Schema() != schema {
+			deviatingVersions = append(deviatingVersions, version)
+			continue
+		}
+
+		for _, deviatingVersion := range deviatingVersions {
+			err = as.registry.Client.DeleteSubjectByVersion(as.Name(), deviatingVersion, false)
+			if err != nil {
+				return errors.Wrap(err, 0)
+			}
+		}
+		as.id = srschema.ID()
+		return nil
+	}
+
+	err = as.Create()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (as *AvroSchema) Render() (rendered interface{}, err error) {
+	schema, err := as.SetSchemaNameNamespace()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	rendered, err = parseRenderedJSON(schema)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (as *AvroSchema) CheckDeviation() (problems []Problem, err error) {
 	schema, err := as.SetSchemaNameNamespace()
 	if err != nil {
 		return nil, errors.Wrap(err, 0)
@@ -90,13 +150,14 @@ func (as *AvroSchema) CheckDeviation() (problem, err error) {
 		srerr, isSrerr := err.(srclient.Error)
 		if isSrerr && srerr.Code == 40401 {
 			// Error code 40401 – Subject not found
-			return fmt.Errorf("schema for subject %s not found in registry", as.Name()), nil
+			return append(problems, newMissingProblem(
+				fmt.Sprintf("schema for subject %s not found in registry", as.Name()))), nil
 		}
 		return nil, errors.Wrap(err, 0)
 	}
 
 	if schema != srschema.Schema() {
-		problem = fmt.Errorf("schema in registry changed for subject %s", as.Name())
+		problems = append(problems, newProblem("schema", schema, srschema.Schema()))
 	}
 
 	return
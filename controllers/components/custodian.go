@@ -30,7 +30,14 @@ func (c *Custodian) AddComponent(component Component) {
 //
 //	When a removal fails it continues scrubbing.
 //	Each error is returned when the scrubbing is complete.
+//	The connections of the components are closed once the scrubbing is complete.
 func (c *Custodian) Scrub() (allErrors []error) {
+	defer func() {
+		if err := closeComponents(c.components); err != nil {
+			allErrors = append(allErrors, errors.Wrap(err, 0))
+		}
+	}()
+
 	for _, component := range c.components {
 		installedVersions, err := component.ListInstalledVersions()
 		if err != nil {
@@ -1,8 +1,10 @@
 package components
 
 import (
+	"fmt"
 	"github.com/go-errors/errors"
 	"github.com/redhatinsights/xjoin-operator/controllers/kafka"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	"strings"
 )
 
@@ -26,13 +28,20 @@ func (dc *DebeziumConnector) Name() string {
 	return dc.name + "." + dc.version
 }
 
-func (dc *DebeziumConnector) Create() (err error) {
-	m := dc.TemplateParameters
+func (dc *DebeziumConnector) templateParameters() map[string]interface{} {
+	//copy the parameters because the map is shared with components that are created in parallel
+	m := make(map[string]interface{})
+	for key, value := range dc.TemplateParameters {
+		m[key] = value
+	}
 	m["DatabaseServerName"] = dc.Name()
 	m["ReplicationSlotName"] = strings.ReplaceAll(dc.Name(), ".", "_")
 	m["TopicName"] = dc.Name()
+	return m
+}
 
-	err = dc.KafkaClient.CreateGenericDebeziumConnector(dc.Name(), dc.Template, m)
+func (dc *DebeziumConnector) Create() (err error) {
+	err = dc.KafkaClient.CreateGenericDebeziumConnector(dc.Name(), dc.Template, dc.templateParameters())
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
@@ -47,7 +56,52 @@ func (dc *DebeziumConnector) Delete() (err error) {
 	return
 }
 
-func (dc *DebeziumConnector) CheckDeviation() (problem, err error) {
+func (dc *DebeziumConnector) Render() (rendered interface{}, err error) {
+	connector, err := dc.KafkaClient.BuildGenericDebeziumConnector(dc.Name(), dc.Template, dc.templateParameters())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return connector.Object, nil
+}
+
+func (dc *DebeziumConnector) CheckDeviation() (problems []Problem, err error) {
+	connector, err := dc.KafkaClient.GetConnector(dc.Name())
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			return append(problems, newMissingProblem(fmt.Sprintf("connector %s not found", dc.Name()))), nil
+		}
+		return nil, errors.Wrap(err, 0)
+	}
+
+	expected, err := dc.KafkaClient.BuildGenericDebeziumConnector(dc.Name(), dc.Template, dc.templateParameters())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	problems, err = checkConnectorDeviation(expected, connector)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (dc *DebeziumConnector) Repair() (err error) {
+	exists, err := dc.Exists()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if !exists {
+		return dc.Create()
+	}
+
+	connector, err := dc.KafkaClient.BuildGenericDebeziumConnector(dc.Name(), dc.Template, dc.templateParameters())
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	err = dc.KafkaClient.UpdateGenericConnector(connector)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
 	return
 }
 
@@ -0,0 +1,87 @@
+package components
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// applyDeploymentSpec sets the replicas and pod settings of spec on a deployment built by a component.
+// The resources and imagePullPolicy are set on every container.
+func applyDeploymentSpec(deployment *unstructured.Unstructured, spec v1alpha1.DeploymentSpec) (err error) {
+	object, err := toJSONValue(deployment.Object)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	deployment.Object = object.(map[string]interface{})
+
+	if spec.Replicas != nil {
+		err = unstructured.SetNestedField(deployment.Object, int64(*spec.Replicas), "spec", "replicas")
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+
+	if len(spec.PodAnnotations) > 0 {
+		err = unstructured.SetNestedStringMap(
+			deployment.Object, spec.PodAnnotations, "spec", "template", "metadata", "annotations")
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+
+	podSpec := map[string]interface{}{
+		"nodeSelector":     spec.NodeSelector,
+		"tolerations":      spec.Tolerations,
+		"affinity":         spec.Affinity,
+		"imagePullSecrets": spec.ImagePullSecrets,
+	}
+	if spec.ServiceAccountName != "" {
+		podSpec["serviceAccountName"] = spec.ServiceAccountName
+	}
+	for key, value := range podSpec {
+		jsonValue, err := toJSONValue(value)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if jsonValue == nil {
+			continue
+		}
+		err = unstructured.SetNestedField(deployment.Object, jsonValue, "spec", "template", "spec", key)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+
+	containers, _, err := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	resources, err := toJSONValue(spec.Resources)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	for _, container := range containers {
+		containerMap := container.(map[string]interface{})
+		if spec.ImagePullPolicy != "" {
+			containerMap["imagePullPolicy"] = string(spec.ImagePullPolicy)
+		}
+		if resources != nil {
+			containerMap["resources"] = resources
+		}
+	}
+	err = unstructured.SetNestedSlice(deployment.Object, containers, "spec", "template", "spec", "containers")
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	return nil
+}
+
+// deploymentImage joins the image and tag of spec, the image is returned as is when there is no tag
+func deploymentImage(spec v1alpha1.DeploymentSpec) string {
+	if spec.ImageTag == "" {
+		return spec.Image
+	}
+	return spec.Image + ":" + spec.ImageTag
+}
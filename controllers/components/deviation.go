@@ -0,0 +1,605 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-errors/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+)
+
+// maxProblemValueLength limits the size of the expected/actual values stored in a CR status
+const maxProblemValueLength = 256
+
+const redactedValue = "<redacted>"
+
+// Problem is a single difference between the expected and actual state of a component
+type Problem struct {
+	Path     string
+	Expected string
+	Actual   string
+	Message  string
+}
+
+func newProblem(path string, expected interface{}, actual interface{}) Problem {
+	expectedString := truncateProblemValue(problemValueToString(expected))
+	actualString := truncateProblemValue(problemValueToString(actual))
+
+	//avoid leaking credentials into the CR status
+	if strings.Contains(strings.ToLower(path), "password") {
+		expectedString = redactedValue
+		actualString = redactedValue
+	}
+
+	return Problem{
+		Path:     path,
+		Expected: expectedString,
+		Actual:   actualString,
+		Message:  fmt.Sprintf("%s expected %s, found %s", path, expectedString, actualString),
+	}
+}
+
+func newMissingProblem(message string) Problem {
+	return Problem{Message: message}
+}
+
+func problemValueToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		valueJson, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(valueJson)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func truncateProblemValue(value string) string {
+	if len(value) > maxProblemValueLength {
+		return value[:maxProblemValueLength] + "..."
+	}
+	return value
+}
+
+// compareValues recursively compares the values in expected to actual.
+// Keys that only exist in actual are ignored because external systems (e.g. Kubernetes, Elasticsearch) add defaults.
+// Scalars are compared by their string representation because the external systems don't preserve types
+// e.g. Elasticsearch returns all settings as strings.
+func compareValues(path string, expected interface{}, actual interface{}) (problems []Problem) {
+	return compareValuesOmitting(path, expected, actual, nil)
+}
+
+// compareValuesOmitting compares like compareValues. Keys that only exist in expected are also ignored when their
+// value is a zero value and their path is in omitted, see omitEmptyPaths.
+func compareValuesOmitting(
+	path string, expected interface{}, actual interface{}, omitted map[string]bool) (problems []Problem) {
+
+	switch expectedValue := expected.(type) {
+	case map[string]interface{}:
+		actualMap, ok := actual.(map[string]interface{})
+		if !ok {
+			return append(problems, newProblem(path, expected, actual))
+		}
+
+		keys := make([]string, 0, len(expectedValue))
+		for key := range expectedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := joinProblemPath(path, key)
+			actualChild, exists := actualMap[key]
+			if !exists && omitted[childPath] && isZeroValue(expectedValue[key]) {
+				continue
+			} else if !exists {
+				problems = append(problems, newProblem(childPath, expectedValue[key], nil))
+				continue
+			}
+			problems = append(problems, compareValuesOmitting(childPath, expectedValue[key], actualChild, omitted)...)
+		}
+	case []interface{}:
+		actualSlice, ok := actual.([]interface{})
+		if !ok || len(actualSlice) != len(expectedValue) {
+			return append(problems, newProblem(path, expected, actual))
+		}
+
+		for index := range expectedValue {
+			childPath := fmt.Sprintf("%s[%v]", path, index)
+			problems = append(problems, compareValuesOmitting(
+				childPath, expectedValue[index], actualSlice[index], omitted)...)
+		}
+	default:
+		//a scalar is equivalent to a single element list e.g. Elasticsearch analyzer filters
+		if actualSlice, ok := actual.([]interface{}); ok && len(actualSlice) == 1 {
+			actual = actualSlice[0]
+		}
+
+		if fmt.Sprint(expected) != fmt.Sprint(actual) {
+			problems = append(problems, newProblem(path, expected, actual))
+		}
+	}
+
+	return problems
+}
+
+// omitEmptyPaths returns the paths of the omitempty fields of a struct type and of its nested structs. A resource
+// written by a typed client drops these fields when they are set to their zero value. Anywhere else a missing field
+// is a deviation e.g. a missing replicas: 0.
+func omitEmptyPaths(path string, structType reflect.Type) map[string]bool {
+	paths := make(map[string]bool)
+	addOmitEmptyPaths(paths, path, structType)
+	return paths
+}
+
+func addOmitEmptyPaths(paths map[string]bool, path string, structType reflect.Type) {
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			if field.Anonymous {
+				addOmitEmptyPaths(paths, path, field.Type) //embedded struct fields are inlined
+				continue
+			}
+			name = field.Name
+		}
+
+		fieldPath := joinProblemPath(path, name)
+		for _, option := range tag[1:] {
+			if option == "omitempty" {
+				paths[fieldPath] = true
+			}
+		}
+		addOmitEmptyPaths(paths, fieldPath, field.Type)
+	}
+}
+
+// isZeroValue is true for the json values omitted by omitempty
+func isZeroValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	default:
+		return fmt.Sprint(v) == "0"
+	}
+}
+
+func joinProblemPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// parseRenderedJSON parses a rendered json document, an empty document is returned as nil
+func parseRenderedJSON(document string) (value interface{}, err error) {
+	if strings.TrimSpace(document) == "" {
+		return nil, nil
+	}
+	err = json.Unmarshal([]byte(document), &value)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+// redactCredentials replaces the values of password fields and password env vars in a json value
+func redactCredentials(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		envName, isEnvVar := v["name"].(string)
+		for key, child := range v {
+			if strings.Contains(strings.ToLower(key), "password") && child != nil {
+				v[key] = redactedValue
+			} else if key == "value" && isEnvVar && strings.Contains(strings.ToLower(envName), "password") {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactCredentials(child)
+			}
+		}
+		return v
+	case []interface{}:
+		for index := range v {
+			v[index] = redactCredentials(v[index])
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// toJSONValue converts a struct or map into the generic json representation used by compareValues
+func toJSONValue(in interface{}) (out interface{}, err error) {
+	inJson, err := json.Marshal(in)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	err = json.Unmarshal(inJson, &out)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+func unstructuredToDeployment(obj *unstructured.Unstructured) (deployment appsv1.Deployment, err error) {
+	objJson, err := json.Marshal(obj.Object)
+	if err != nil {
+		return deployment, errors.Wrap(err, 0)
+	}
+	err = json.Unmarshal(objJson, &deployment)
+	if err != nil {
+		return deployment, errors.Wrap(err, 0)
+	}
+	return
+}
+
+// checkDeploymentDeviation compares the replicas, pod settings and the image, imagePullPolicy, env and resources
+// of each container in the expected deployment with the actual deployment
+func checkDeploymentDeviation(expected *unstructured.Unstructured, actual *unstructured.Unstructured) (
+	problems []Problem, err error) {
+
+	expectedDeployment, err := unstructuredToDeployment(expected)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	actualDeployment, err := unstructuredToDeployment(actual)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	if expectedDeployment.Spec.Replicas != nil &&
+		(actualDeployment.Spec.Replicas == nil || *expectedDeployment.Spec.Replicas != *actualDeployment.Spec.Replicas) {
+		var actualReplicas interface{}
+		if actualDeployment.Spec.Replicas != nil {
+			actualReplicas = *actualDeployment.Spec.Replicas
+		}
+		problems = append(problems, newProblem("spec.replicas", *expectedDeployment.Spec.Replicas, actualReplicas))
+	}
+
+	podProblems, err := comparePodSettings(
+		"spec.template", expectedDeployment.Spec.Template, actualDeployment.Spec.Template)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	problems = append(problems, podProblems...)
+
+	actualContainers := make(map[string]v1.Container)
+	for _, container := range actualDeployment.Spec.Template.Spec.Containers {
+		actualContainers[container.Name] = container
+	}
+
+	for _, expectedContainer := range expectedDeployment.Spec.Template.Spec.Containers {
+		path := "spec.template.spec.containers[" + expectedContainer.Name + "]"
+		actualContainer, exists := actualContainers[expectedContainer.Name]
+		if !exists {
+			problems = append(problems, newMissingProblem(fmt.Sprintf("%s is missing", path)))
+			continue
+		}
+
+		if expectedContainer.Image != actualContainer.Image {
+			problems = append(problems, newProblem(path+".image", expectedContainer.Image, actualContainer.Image))
+		}
+		if expectedContainer.ImagePullPolicy != "" && expectedContainer.ImagePullPolicy != actualContainer.ImagePullPolicy {
+			problems = append(problems, newProblem(
+				path+".imagePullPolicy", expectedContainer.ImagePullPolicy, actualContainer.ImagePullPolicy))
+		}
+
+		envProblems, err := compareEnv(path+".env", expectedContainer.Env, actualContainer.Env)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		problems = append(problems, envProblems...)
+
+		problems = append(problems, compareResourceList(
+			path+".resources.limits", expectedContainer.Resources.Limits, actualContainer.Resources.Limits)...)
+		problems = append(problems, compareResourceList(
+			path+".resources.requests", defaultedRequests(expectedContainer.Resources), actualContainer.Resources.Requests)...)
+	}
+
+	return problems, nil
+}
+
+// comparePodSettings compares the scheduling settings, service account, image pull secrets and annotations
+// of the expected pod template with the actual pod template. Annotations added by other controllers are ignored.
+func comparePodSettings(path string, expected v1.PodTemplateSpec, actual v1.PodTemplateSpec) (
+	problems []Problem, err error) {
+
+	for key, value := range expected.Annotations {
+		if actual.Annotations[key] != value {
+			var actualValue interface{}
+			if actualAnnotation, exists := actual.Annotations[key]; exists {
+				actualValue = actualAnnotation
+			}
+			problems = append(problems, newProblem(path+".metadata.annotations."+key, value, actualValue))
+		}
+	}
+
+	if expected.Spec.ServiceAccountName != "" && expected.Spec.ServiceAccountName != actual.Spec.ServiceAccountName {
+		problems = append(problems, newProblem(
+			path+".spec.serviceAccountName", expected.Spec.ServiceAccountName, actual.Spec.ServiceAccountName))
+	}
+
+	fields := []struct {
+		name     string
+		expected interface{}
+		actual   interface{}
+	}{
+		{name: "nodeSelector", expected: expected.Spec.NodeSelector, actual: actual.Spec.NodeSelector},
+		{name: "tolerations", expected: expected.Spec.Tolerations, actual: actual.Spec.Tolerations},
+		{name: "affinity", expected: expected.Spec.Affinity, actual: actual.Spec.Affinity},
+		{name: "imagePullSecrets", expected: expected.Spec.ImagePullSecrets, actual: actual.Spec.ImagePullSecrets},
+	}
+	for _, field := range fields {
+		expectedValue, err := toJSONValue(field.expected)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		actualValue, err := toJSONValue(field.actual)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		if isEmptyJSONValue(expectedValue) && isEmptyJSONValue(actualValue) {
+			continue
+		}
+		problems = append(problems, compareValues(path+".spec."+field.name, expectedValue, actualValue)...)
+	}
+
+	return problems, nil
+}
+
+// isEmptyJSONValue is true for null and for empty json objects and arrays
+func isEmptyJSONValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// repairDeployment re-applies the labels, replicas and pod template of the expected deployment
+// to the existing deployment.
+// The deployment is created when it doesn't exist.
+func repairDeployment(ctx context.Context, c client.Client, expected *unstructured.Unstructured) error {
+	deployment := &unstructured.Unstructured{}
+	deployment.SetGroupVersionKind(expected.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKey{Name: expected.GetName(), Namespace: expected.GetNamespace()}, deployment)
+	if k8errors.IsNotFound(err) {
+		err = c.Create(ctx, expected)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	expectedTemplate, found, err := unstructured.NestedFieldNoCopy(expected.Object, "spec", "template")
+	if err != nil {
+		return errors.Wrap(err, 0)
+	} else if !found {
+		return errors.Wrap(errors.New("spec.template is missing from deployment "+expected.GetName()), 0)
+	}
+	expectedTemplateValue, err := toJSONValue(expectedTemplate)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	err = unstructured.SetNestedField(deployment.Object, expectedTemplateValue, "spec", "template")
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	expectedReplicas, found, err := unstructured.NestedFieldNoCopy(expected.Object, "spec", "replicas")
+	if err != nil {
+		return errors.Wrap(err, 0)
+	} else if found {
+		err = unstructured.SetNestedField(deployment.Object, runtime.DeepCopyJSONValue(expectedReplicas), "spec", "replicas")
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+
+	labels := deployment.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for key, value := range expected.GetLabels() {
+		labels[key] = value
+	}
+	deployment.SetLabels(labels)
+
+	err = c.Update(ctx, deployment)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+func compareEnv(path string, expected []v1.EnvVar, actual []v1.EnvVar) (problems []Problem, err error) {
+	actualEnv := make(map[string]v1.EnvVar)
+	for _, envVar := range actual {
+		actualEnv[envVar.Name] = envVar
+	}
+
+	expectedNames := make(map[string]bool)
+	for _, expectedVar := range expected {
+		expectedNames[expectedVar.Name] = true
+		envPath := path + "[" + expectedVar.Name + "]"
+		actualVar, exists := actualEnv[expectedVar.Name]
+		if !exists {
+			problems = append(problems, newMissingProblem(fmt.Sprintf("%s is missing", envPath)))
+			continue
+		}
+
+		if expectedVar.Value != actualVar.Value {
+			problems = append(problems, newProblem(envPath+".value", expectedVar.Value, actualVar.Value))
+		}
+
+		expectedFrom, err := toJSONValue(expectedVar.ValueFrom)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		actualFrom, err := toJSONValue(actualVar.ValueFrom)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		if expectedFrom != nil || actualFrom != nil {
+			problems = append(problems, compareValues(envPath+".valueFrom", expectedFrom, actualFrom)...)
+		}
+	}
+
+	for _, actualVar := range actual {
+		if !expectedNames[actualVar.Name] {
+			problems = append(problems, newMissingProblem(
+				fmt.Sprintf("%s[%s] is not expected", path, actualVar.Name)))
+		}
+	}
+
+	return problems, nil
+}
+
+func compareResourceList(path string, expected v1.ResourceList, actual v1.ResourceList) (problems []Problem) {
+	var names []string
+	for name := range expected {
+		names = append(names, string(name))
+	}
+	for name := range actual {
+		if _, exists := expected[name]; !exists {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		expectedQuantity, expectedExists := expected[v1.ResourceName(name)]
+		actualQuantity, actualExists := actual[v1.ResourceName(name)]
+		if !expectedExists || !actualExists || expectedQuantity.Cmp(actualQuantity) != 0 {
+			var expectedValue, actualValue interface{}
+			if expectedExists {
+				expectedValue = expectedQuantity.String()
+			}
+			if actualExists {
+				actualValue = actualQuantity.String()
+			}
+			problems = append(problems, newProblem(path+"."+name, expectedValue, actualValue))
+		}
+	}
+
+	return problems
+}
+
+// defaultedRequests returns the requests of resources, the API server defaults a missing request to its limit
+func defaultedRequests(resources v1.ResourceRequirements) v1.ResourceList {
+	requests := resources.Requests.DeepCopy()
+	for name, limit := range resources.Limits {
+		if _, exists := requests[name]; !exists {
+			if requests == nil {
+				requests = make(v1.ResourceList)
+			}
+			requests[name] = limit.DeepCopy()
+		}
+	}
+	return requests
+}
+
+// expandDottedKeys converts keys like "index.number_of_shards" into nested maps
+func expandDottedKeys(in map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for key, value := range in {
+		if valueMap, ok := value.(map[string]interface{}); ok {
+			value = expandDottedKeys(valueMap)
+		}
+
+		parts := strings.Split(key, ".")
+		current := out
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := current[part].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				current[part] = next
+			}
+			current = next
+		}
+
+		last := parts[len(parts)-1]
+		existing, existingIsMap := current[last].(map[string]interface{})
+		valueMap, valueIsMap := value.(map[string]interface{})
+		if existingIsMap && valueIsMap {
+			for k, v := range valueMap {
+				existing[k] = v
+			}
+		} else {
+			current[last] = value
+		}
+	}
+	return out
+}
+
+// checkConnectorDeviation compares the labels and spec of the expected KafkaConnector with the actual KafkaConnector
+func checkConnectorDeviation(expected *unstructured.Unstructured, actual *unstructured.Unstructured) (
+	problems []Problem, err error) {
+
+	expectedLabels, err := toJSONValue(expected.GetLabels())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	actualLabels, err := toJSONValue(actual.GetLabels())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	problems = append(problems, compareValues("metadata.labels", expectedLabels, actualLabels)...)
+
+	expectedSpec, err := toJSONValue(expected.Object["spec"])
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	actualSpec, err := toJSONValue(actual.Object["spec"])
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	//pause is managed at runtime
+	if expectedSpecMap, ok := expectedSpec.(map[string]interface{}); ok {
+		delete(expectedSpecMap, "pause")
+	}
+	problems = append(problems, compareValues("spec", expectedSpec, actualSpec)...)
+
+	return problems, nil
+}
@@ -0,0 +1,354 @@
+package components
+
+import (
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	"testing"
+)
+
+func problemPaths(problems []Problem) (paths []string) {
+	for _, problem := range problems {
+		if problem.Path != "" {
+			paths = append(paths, problem.Path)
+		} else {
+			paths = append(paths, problem.Message)
+		}
+	}
+	return
+}
+
+func equalPaths(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected interface{}
+		actual   interface{}
+		omitted  map[string]bool
+		problems []string
+	}{{
+		name:     "equal",
+		expected: map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": true}},
+		actual:   map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": true}},
+	}, {
+		name:     "missing field",
+		expected: map[string]interface{}{"a": "1", "b": "2"},
+		actual:   map[string]interface{}{"a": "1"},
+		problems: []string{"b"},
+	}, {
+		name:     "extra field",
+		expected: map[string]interface{}{"a": "1"},
+		actual:   map[string]interface{}{"a": "1", "b": "2"},
+	}, {
+		name:     "different value",
+		expected: map[string]interface{}{"a": map[string]interface{}{"b": "1"}},
+		actual:   map[string]interface{}{"a": map[string]interface{}{"b": "2"}},
+		problems: []string{"a.b"},
+	}, {
+		name:     "missing zero number",
+		expected: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(0)}},
+		actual:   map[string]interface{}{"spec": map[string]interface{}{}},
+		problems: []string{"spec.replicas"},
+	}, {
+		name:     "missing false",
+		expected: map[string]interface{}{"spec": map[string]interface{}{"enabled": false}},
+		actual:   map[string]interface{}{"spec": map[string]interface{}{}},
+		problems: []string{"spec.enabled"},
+	}, {
+		name:     "omitted zero value",
+		expected: map[string]interface{}{"spec": map[string]interface{}{"pause": false}},
+		actual:   map[string]interface{}{"spec": map[string]interface{}{}},
+		omitted:  map[string]bool{"spec.pause": true},
+	}, {
+		name:     "missing non zero value of an omitted path",
+		expected: map[string]interface{}{"spec": map[string]interface{}{"pause": true}},
+		actual:   map[string]interface{}{"spec": map[string]interface{}{}},
+		omitted:  map[string]bool{"spec.pause": true},
+		problems: []string{"spec.pause"},
+	}, {
+		name:     "scalars are compared as strings",
+		expected: map[string]interface{}{"shards": int64(1)},
+		actual:   map[string]interface{}{"shards": "1"},
+	}, {
+		name:     "scalar equals single element list",
+		expected: map[string]interface{}{"filter": "lowercase"},
+		actual:   map[string]interface{}{"filter": []interface{}{"lowercase"}},
+	}, {
+		name:     "list length",
+		expected: map[string]interface{}{"a": []interface{}{"1", "2"}},
+		actual:   map[string]interface{}{"a": []interface{}{"1"}},
+		problems: []string{"a"},
+	}, {
+		name:     "list element",
+		expected: map[string]interface{}{"a": []interface{}{"1", "2"}},
+		actual:   map[string]interface{}{"a": []interface{}{"1", "3"}},
+		problems: []string{"a[1]"},
+	}, {
+		name:     "type mismatch",
+		expected: map[string]interface{}{"a": map[string]interface{}{"b": "1"}},
+		actual:   map[string]interface{}{"a": "b"},
+		problems: []string{"a"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := problemPaths(compareValuesOmitting("", test.expected, test.actual, test.omitted))
+			if !equalPaths(problems, test.problems) {
+				t.Errorf("expected problems %v, found %v", test.problems, problems)
+			}
+		})
+	}
+}
+
+func TestOmitEmptyPaths(t *testing.T) {
+	type nested struct {
+		Size     int    `json:"size,omitempty"`
+		Required string `json:"required"`
+	}
+	type spec struct {
+		Name     string  `json:"name"`
+		Pause    bool    `json:"pause,omitempty"`
+		Nested   *nested `json:"nested,omitempty"`
+		Ignored  string  `json:"-"`
+		Untagged bool
+	}
+
+	paths := omitEmptyPaths("spec", reflect.TypeOf(spec{}))
+
+	expected := map[string]bool{"spec.pause": true, "spec.nested": true, "spec.nested.size": true}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected omitted paths %v, found %v", expected, paths)
+	}
+
+	validatorPaths := omitEmptyPaths("spec", reflect.TypeOf(v1alpha1.XJoinIndexValidatorSpec{}))
+	for _, path := range []string{"spec.pause", "spec.validation", "spec.validation.sampleSize"} {
+		if !validatorPaths[path] {
+			t.Errorf("expected %s to be omitted when empty", path)
+		}
+	}
+}
+
+func TestIsZeroValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		zero  bool
+	}{
+		{value: nil, zero: true},
+		{value: false, zero: true},
+		{value: true, zero: false},
+		{value: "", zero: true},
+		{value: "a", zero: false},
+		{value: int64(0), zero: true},
+		{value: float64(0), zero: true},
+		{value: int64(1), zero: false},
+		{value: map[string]interface{}{}, zero: true},
+		{value: map[string]interface{}{"a": "b"}, zero: false},
+		{value: []interface{}{}, zero: true},
+		{value: []interface{}{"a"}, zero: false},
+	}
+
+	for _, test := range tests {
+		if isZeroValue(test.value) != test.zero {
+			t.Errorf("isZeroValue(%#v) expected %v", test.value, test.zero)
+		}
+	}
+}
+
+func TestCompareEnv(t *testing.T) {
+	secretRef := func(name string) *v1.EnvVarSource {
+		return &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: name}, Key: "password"}}
+	}
+
+	tests := []struct {
+		name     string
+		expected []v1.EnvVar
+		actual   []v1.EnvVar
+		problems []string
+	}{{
+		name:     "equal",
+		expected: []v1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", ValueFrom: secretRef("s")}},
+		actual:   []v1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", ValueFrom: secretRef("s")}},
+	}, {
+		name:     "order is ignored",
+		expected: []v1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+		actual:   []v1.EnvVar{{Name: "B", Value: "2"}, {Name: "A", Value: "1"}},
+	}, {
+		name:     "missing",
+		expected: []v1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+		actual:   []v1.EnvVar{{Name: "A", Value: "1"}},
+		problems: []string{"env[B] is missing"},
+	}, {
+		name:     "extra",
+		expected: []v1.EnvVar{{Name: "A", Value: "1"}},
+		actual:   []v1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+		problems: []string{"env[B] is not expected"},
+	}, {
+		name:     "empty value",
+		expected: []v1.EnvVar{{Name: "A", Value: ""}},
+		actual:   []v1.EnvVar{{Name: "A", Value: "1"}},
+		problems: []string{"env[A].value"},
+	}, {
+		name:     "different secret",
+		expected: []v1.EnvVar{{Name: "A", ValueFrom: secretRef("s")}},
+		actual:   []v1.EnvVar{{Name: "A", ValueFrom: secretRef("t")}},
+		problems: []string{"env[A].valueFrom.secretKeyRef.name"},
+	}, {
+		name:     "value instead of secret",
+		expected: []v1.EnvVar{{Name: "A", ValueFrom: secretRef("s")}},
+		actual:   []v1.EnvVar{{Name: "A", Value: "1"}},
+		problems: []string{"env[A].value", "env[A].valueFrom"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := compareEnv("env", test.expected, test.actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+			paths := problemPaths(problems)
+			if !equalPaths(paths, test.problems) {
+				t.Errorf("expected problems %v, found %v", test.problems, paths)
+			}
+		})
+	}
+}
+
+func TestCheckDeploymentDeviation(t *testing.T) {
+	int32Ptr := func(value int32) *int32 {
+		return &value
+	}
+	//the actual deployment is read from the API server which defaults the requests to the limits
+	deployment := func(modify func(deployment *appsv1.Deployment), actual bool) *unstructured.Unstructured {
+		deployment := &appsv1.Deployment{
+			Spec: appsv1.DeploymentSpec{
+				Replicas: int32Ptr(1),
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{
+							Name:  "xjoin-core",
+							Image: "quay.io/cloudservices/xjoin-core:latest",
+							Env:   []v1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+							Resources: v1.ResourceRequirements{
+								Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+							},
+						}},
+					},
+				},
+			},
+		}
+		if actual {
+			deployment.Spec.Template.Spec.Containers[0].Resources.Requests = v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("1")}
+		}
+		modify(deployment)
+		object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deployment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &unstructured.Unstructured{Object: object}
+	}
+
+	tests := []struct {
+		name     string
+		expected func(deployment *appsv1.Deployment)
+		actual   func(deployment *appsv1.Deployment)
+		problems []string
+	}{{
+		name:     "equal",
+		expected: func(deployment *appsv1.Deployment) {},
+		actual:   func(deployment *appsv1.Deployment) {},
+	}, {
+		name:     "defaulted fields",
+		expected: func(deployment *appsv1.Deployment) {},
+		actual: func(deployment *appsv1.Deployment) {
+			container := &deployment.Spec.Template.Spec.Containers[0]
+			container.ImagePullPolicy = v1.PullAlways
+			container.TerminationMessagePath = "/dev/termination-log"
+			deployment.Spec.Template.Spec.ServiceAccountName = "default"
+			deployment.Spec.RevisionHistoryLimit = int32Ptr(10)
+		},
+	}, {
+		name: "zero replicas",
+		expected: func(deployment *appsv1.Deployment) {
+			deployment.Spec.Replicas = int32Ptr(0)
+		},
+		actual: func(deployment *appsv1.Deployment) {
+			deployment.Spec.Replicas = nil
+		},
+		problems: []string{"spec.replicas"},
+	}, {
+		name:     "different replicas",
+		expected: func(deployment *appsv1.Deployment) {},
+		actual: func(deployment *appsv1.Deployment) {
+			deployment.Spec.Replicas = int32Ptr(2)
+		},
+		problems: []string{"spec.replicas"},
+	}, {
+		name:     "env order",
+		expected: func(deployment *appsv1.Deployment) {},
+		actual: func(deployment *appsv1.Deployment) {
+			deployment.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{{Name: "B", Value: "2"}, {Name: "A", Value: "1"}}
+		},
+	}, {
+		name:     "image",
+		expected: func(deployment *appsv1.Deployment) {},
+		actual: func(deployment *appsv1.Deployment) {
+			deployment.Spec.Template.Spec.Containers[0].Image = "quay.io/cloudservices/xjoin-core:old"
+		},
+		problems: []string{"spec.template.spec.containers[xjoin-core].image"},
+	}, {
+		name:     "extra request",
+		expected: func(deployment *appsv1.Deployment) {},
+		actual: func(deployment *appsv1.Deployment) {
+			deployment.Spec.Template.Spec.Containers[0].Resources.Requests = v1.ResourceList{
+				v1.ResourceMemory: resource.MustParse("1Gi")}
+		},
+		problems: []string{
+			"spec.template.spec.containers[xjoin-core].resources.requests.cpu",
+			"spec.template.spec.containers[xjoin-core].resources.requests.memory",
+		},
+	}, {
+		name:     "missing container",
+		expected: func(deployment *appsv1.Deployment) {},
+		actual: func(deployment *appsv1.Deployment) {
+			deployment.Spec.Template.Spec.Containers[0].Name = "other"
+		},
+		problems: []string{"spec.template.spec.containers[xjoin-core] is missing"},
+	}, {
+		name: "missing node selector",
+		expected: func(deployment *appsv1.Deployment) {
+			deployment.Spec.Template.Spec.NodeSelector = map[string]string{"zone": "a"}
+		},
+		actual:   func(deployment *appsv1.Deployment) {},
+		problems: []string{"spec.template.spec.nodeSelector"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := checkDeploymentDeviation(deployment(test.expected, false), deployment(test.actual, true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			paths := problemPaths(problems)
+			if !equalPaths(paths, test.problems) {
+				t.Errorf("expected problems %v, found %v", test.problems, paths)
+			}
+		})
+	}
+}
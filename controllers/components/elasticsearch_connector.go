@@ -1,8 +1,10 @@
 package components
 
 import (
+	"fmt"
 	"github.com/go-errors/errors"
 	"github.com/redhatinsights/xjoin-operator/controllers/kafka"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	"strings"
 )
 
@@ -27,12 +29,19 @@ func (es *ElasticsearchConnector) Name() string {
 	return es.name + "." + es.version
 }
 
-func (es *ElasticsearchConnector) Create() (err error) {
-	m := es.TemplateParameters
+func (es *ElasticsearchConnector) templateParameters() map[string]interface{} {
+	//copy the parameters because the map is shared with components that are created in parallel
+	m := make(map[string]interface{})
+	for key, value := range es.TemplateParameters {
+		m[key] = value
+	}
 	m["Topic"] = es.Topic
 	//m["RenameTopicReplacement"] = fmt.Sprintf("%s.%s", kafka.Parameters.ResourceNamePrefix.String(), pipelineVersion)
+	return m
+}
 
-	err = es.KafkaClient.CreateGenericElasticsearchConnector(es.Name(), es.Template, m)
+func (es *ElasticsearchConnector) Create() (err error) {
+	err = es.KafkaClient.CreateGenericElasticsearchConnector(es.Name(), es.Template, es.templateParameters())
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
@@ -47,7 +56,52 @@ func (es *ElasticsearchConnector) Delete() (err error) {
 	return
 }
 
-func (es *ElasticsearchConnector) CheckDeviation() (problem, err error) {
+func (es *ElasticsearchConnector) Render() (rendered interface{}, err error) {
+	connector, err := es.KafkaClient.BuildGenericElasticsearchConnector(es.Name(), es.Template, es.templateParameters())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return connector.Object, nil
+}
+
+func (es *ElasticsearchConnector) CheckDeviation() (problems []Problem, err error) {
+	connector, err := es.KafkaClient.GetConnector(es.Name())
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			return append(problems, newMissingProblem(fmt.Sprintf("connector %s not found", es.Name()))), nil
+		}
+		return nil, errors.Wrap(err, 0)
+	}
+
+	expected, err := es.KafkaClient.BuildGenericElasticsearchConnector(es.Name(), es.Template, es.templateParameters())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	problems, err = checkConnectorDeviation(expected, connector)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (es *ElasticsearchConnector) Repair() (err error) {
+	exists, err := es.Exists()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if !exists {
+		return es.Create()
+	}
+
+	connector, err := es.KafkaClient.BuildGenericElasticsearchConnector(es.Name(), es.Template, es.templateParameters())
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	err = es.KafkaClient.UpdateGenericConnector(connector)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
 	return
 }
 
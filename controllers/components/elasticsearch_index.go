@@ -1,6 +1,8 @@
 package components
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/go-errors/errors"
 	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
 	"strings"
@@ -11,6 +13,7 @@ type ElasticsearchIndex struct {
 	version              string
 	Template             string
 	Properties           string
+	Analysis             string
 	GenericElasticsearch elasticsearch.GenericElasticsearch
 	WithPipeline         bool
 }
@@ -28,7 +31,19 @@ func (es *ElasticsearchIndex) Name() string {
 }
 
 func (es *ElasticsearchIndex) Create() (err error) {
-	err = es.GenericElasticsearch.CreateIndex(es.Name(), es.Template, es.Properties, es.WithPipeline)
+	err = es.GenericElasticsearch.CreateIndex(
+		es.Name(), es.Template, es.Properties, es.Analysis, es.WithPipeline)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+// Migrate puts the mappings of the new avro schema on the existing index, the index settings e.g. analyzers can't be
+// changed in place
+func (es *ElasticsearchIndex) Migrate() (err error) {
+	err = es.GenericElasticsearch.PutMapping(
+		es.Name(), es.Template, es.Properties, es.Analysis, es.WithPipeline)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
@@ -43,10 +58,77 @@ func (es *ElasticsearchIndex) Delete() (err error) {
 	return
 }
 
-func (es *ElasticsearchIndex) CheckDeviation() (problem, err error) {
+func (es *ElasticsearchIndex) Render() (rendered interface{}, err error) {
+	body, err := es.GenericElasticsearch.RenderIndexTemplate(
+		es.Name(), es.Template, es.Properties, es.Analysis, es.WithPipeline)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	rendered, err = parseRenderedJSON(body)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
 	return
 }
 
+func (es *ElasticsearchIndex) CheckDeviation() (problems []Problem, err error) {
+	exists, err := es.Exists()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	if !exists {
+		return append(problems, newMissingProblem(fmt.Sprintf("index %s not found", es.Name()))), nil
+	}
+
+	expectedBody, err := es.GenericElasticsearch.RenderIndexTemplate(
+		es.Name(), es.Template, es.Properties, es.Analysis, es.WithPipeline)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	var expected map[string]interface{}
+	if expectedBody != "" {
+		err = json.Unmarshal([]byte(expectedBody), &expected)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+	}
+
+	mappings, settings, err := es.GenericElasticsearch.GetIndex(es.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	if expected["mappings"] != nil {
+		problems = append(problems, compareValues("mappings", expected["mappings"], mappings)...)
+	}
+
+	if expectedSettings, ok := expected["settings"].(map[string]interface{}); ok {
+		problems = append(problems, compareValues("settings", normalizeIndexSettings(expectedSettings), settings)...)
+	}
+
+	return
+}
+
+// normalizeIndexSettings converts the settings from the index template into the format returned by
+// Elasticsearch. e.g. {"analysis": {}, "index": {"number_of_shards": 1}} -> {"index": {"analysis": {}, "number_of_shards": 1}}
+func normalizeIndexSettings(settings map[string]interface{}) map[string]interface{} {
+	settings = expandDottedKeys(settings)
+
+	index, ok := settings["index"].(map[string]interface{})
+	if !ok {
+		index = make(map[string]interface{})
+	}
+
+	for key, value := range settings {
+		if key != "index" {
+			index[key] = value
+		}
+	}
+
+	return map[string]interface{}{"index": index}
+}
+
 func (es *ElasticsearchIndex) Exists() (exists bool, err error) {
 	exists, err = es.GenericElasticsearch.IndexExists(es.Name())
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/controllers/avro"
 	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
 	"strings"
 )
@@ -12,6 +13,7 @@ type ElasticsearchPipeline struct {
 	name                 string
 	version              string
 	JsonFields           []string
+	DateFields           []avro.DateField
 	GenericElasticsearch elasticsearch.GenericElasticsearch
 }
 
@@ -47,7 +49,47 @@ func (es *ElasticsearchPipeline) Delete() (err error) {
 	return
 }
 
-func (es *ElasticsearchPipeline) CheckDeviation() (problem, err error) {
+func (es *ElasticsearchPipeline) Render() (rendered interface{}, err error) {
+	pipeline, err := es.jsonFieldsToESPipeline()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	rendered, err = parseRenderedJSON(pipeline)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (es *ElasticsearchPipeline) CheckDeviation() (problems []Problem, err error) {
+	actual, err := es.GenericElasticsearch.GetPipeline(es.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	if actual == nil {
+		return append(problems, newMissingProblem(fmt.Sprintf("pipeline %s not found", es.Name()))), nil
+	}
+
+	pipeline, err := es.jsonFieldsToESPipeline()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	var expected map[string]interface{}
+	err = json.Unmarshal([]byte(pipeline), &expected)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	return compareValues("pipeline", expected, actual), nil
+}
+
+// Repair puts the pipeline again, which replaces the existing pipeline
+func (es *ElasticsearchPipeline) Repair() (err error) {
+	err = es.Create()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
 	return
 }
 
@@ -79,9 +121,22 @@ func (es *ElasticsearchPipeline) jsonFieldsToESPipeline() (pipeline string, err
 	var pipelineObj elasticsearch.Pipeline
 	pipelineObj.Description = "test"
 	for _, jsonField := range es.JsonFields {
+		pipelineObj.Processors = append(pipelineObj.Processors, jsonFieldProcessor(jsonField))
+	}
+
+	//dates are converted after parsing the json fields because they can be nested in a json field
+	for _, dateField := range es.DateFields {
+		expression, ok := epochMillisExpressions[dateField.Unit]
+		if !ok {
+			return pipeline, errors.Wrap(fmt.Errorf("invalid unit %s of date field %s", dateField.Unit, dateField.Field), 0)
+		}
+
 		var processor elasticsearch.PipelineProcessor
-		processor.Json.Field = jsonField
-		processor.Json.If = fmt.Sprintf("ctx.%s != null", jsonField)
+		processor.Script = &elasticsearch.ScriptProcessor{
+			If:     fmt.Sprintf("ctx.%s != null", strings.ReplaceAll(dateField.Field, ".", "?.")),
+			Lang:   "painless",
+			Source: fmt.Sprintf("ctx.%s = ctx.%s %s", dateField.Field, dateField.Field, expression),
+		}
 		pipelineObj.Processors = append(pipelineObj.Processors, processor)
 	}
 
@@ -91,3 +146,39 @@ func (es *ElasticsearchPipeline) jsonFieldsToESPipeline() (pipeline string, err
 	}
 	return string(pipelineJson), nil
 }
+
+// jsonFieldProcessor parses a json field. Fields inside arrays (e.g. hosts[].facts) are parsed by a foreach
+// processor for each array.
+func jsonFieldProcessor(jsonField string) elasticsearch.PipelineProcessor {
+	arrayPath, itemPath, insideArray := strings.Cut(jsonField, "[]")
+	if !insideArray {
+		return elasticsearch.PipelineProcessor{
+			Json: &elasticsearch.JsonProcessor{
+				Field: jsonField,
+				If:    fmt.Sprintf("ctx.%s != null", strings.ReplaceAll(jsonField, ".", "?.")),
+			},
+		}
+	}
+
+	//the items of the array are accessed via _ingest._value, missing or null fields of an item are skipped by
+	//ignoring the failure since the item can't be referenced by the if condition
+	processor := jsonFieldProcessor("_ingest._value" + itemPath)
+	if processor.Json != nil {
+		processor.Json.If = ""
+		processor.Json.IgnoreFailure = true
+	}
+	return elasticsearch.PipelineProcessor{
+		Foreach: &elasticsearch.ForeachProcessor{
+			Field:         arrayPath,
+			IgnoreMissing: true,
+			Processor:     &processor,
+		},
+	}
+}
+
+// epochMillisExpressions convert a numeric date into epoch millis
+var epochMillisExpressions = map[string]string{
+	avro.EpochMicros: "/ 1000L",
+	avro.EpochNanos:  "/ 1000000L",
+	avro.EpochDays:   "* 86400000L",
+}
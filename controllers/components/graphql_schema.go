@@ -1,7 +1,9 @@
 package components
 
 import (
+	"fmt"
 	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
 	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
 	"strings"
 )
@@ -14,13 +16,16 @@ type GraphQLSchema struct {
 	version    string
 	suffix     string
 	active     bool
+	namespace  string
+	subgraph   string
 }
 
 type GraphQLSchemaParameters struct {
-	Schema   string
-	Registry *schemaregistry.RestClient
-	Suffix   string
-	Active   bool
+	Schema    string
+	Registry  *schemaregistry.RestClient
+	Suffix    string
+	Active    bool
+	Namespace string
 }
 
 func NewGraphQLSchema(parameters GraphQLSchemaParameters) *GraphQLSchema {
@@ -29,6 +34,7 @@ func NewGraphQLSchema(parameters GraphQLSchemaParameters) *GraphQLSchema {
 		restClient: parameters.Registry,
 		suffix:     parameters.Suffix,
 		active:     parameters.Active,
+		namespace:  parameters.Namespace,
 	}
 }
 
@@ -41,6 +47,7 @@ func (as *GraphQLSchema) SetName(kind string, name string) {
 	if as.suffix != "" {
 		as.name = as.name + "-" + as.suffix
 	}
+	as.subgraph = subgraphName(name, as.suffix)
 }
 
 func (as *GraphQLSchema) SetVersion(version string) {
@@ -51,8 +58,21 @@ func (as *GraphQLSchema) Name() string {
 	return as.name + "." + as.version
 }
 
+// SubgraphURL is the url of the Service of the xjoin-api-subgraph that serves this schema
+func (as *GraphQLSchema) SubgraphURL() string {
+	return subgraphURL(as.subgraph+"-"+as.version, as.namespace)
+}
+
+// content is the registered schema, the placeholder DefaultGraphQLSchema when no schema was generated
+func (as *GraphQLSchema) content() string {
+	if as.schema == "" {
+		return schemaregistry.DefaultGraphQLSchema
+	}
+	return as.schema
+}
+
 func (as *GraphQLSchema) Create() (err error) {
-	id, err := as.restClient.RegisterGraphQLSchema(as.Name())
+	id, err := as.restClient.RegisterGraphQLSchema(as.Name(), as.SubgraphURL(), as.schema)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
@@ -69,8 +89,55 @@ func (as *GraphQLSchema) DeleteByVersion(version string) (err error) {
 	return as.restClient.DeleteGraphQLSchema(as.name + "." + version)
 }
 
-func (as *GraphQLSchema) CheckDeviation() (problem, err error) {
-	return //TODO
+func (as *GraphQLSchema) Render() (rendered interface{}, err error) {
+	return map[string]interface{}{
+		"name":    as.Name(),
+		"content": as.content(),
+		"labels":  schemaregistry.GraphQLSchemaLabels(as.SubgraphURL()),
+	}, nil
+}
+
+// CheckDeviation compares the version of the artifact registered by the operator. The subgraphs push the schema they
+// serve as newer versions of the same artifact so the latest version is not compared.
+func (as *GraphQLSchema) CheckDeviation() (problems []Problem, err error) {
+	labels, registered, err := as.restClient.GetGraphQLSchemaVersionLabels(as.Name(), as.content())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	if !registered {
+		schema, exists, err := as.restClient.GetGraphQLSchema(as.Name())
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		if !exists {
+			return append(problems, newMissingProblem(fmt.Sprintf("graphql schema %s not found", as.Name()))), nil
+		}
+		return append(problems, newProblem("content", as.content(), schema)), nil
+	}
+
+	for _, expectedLabel := range schemaregistry.GraphQLSchemaLabels(as.SubgraphURL()) {
+		if !utils.ContainsString(labels, expectedLabel) {
+			problems = append(problems, newProblem("labels", expectedLabel, strings.Join(labels, ",")))
+		}
+	}
+
+	return
+}
+
+// Repair registers the schema again then re-applies the enabled/disabled state
+func (as *GraphQLSchema) Repair() (err error) {
+	id, err := as.restClient.UpdateGraphQLSchema(as.Name(), as.SubgraphURL(), as.schema)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	as.id = id
+
+	err = as.Reconcile()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
 }
 
 func (as *GraphQLSchema) Exists() (exists bool, err error) {
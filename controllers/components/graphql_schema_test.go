@@ -0,0 +1,100 @@
+package components
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
+)
+
+type graphQLSchemaVersion struct {
+	content string
+	labels  []string
+}
+
+// newGraphQLRegistry serves the versions of graphql schema artifacts like the apicurio registry
+func newGraphQLRegistry(t *testing.T, artifacts map[string][]graphQLSchemaVersion) *schemaregistry.RestClient {
+	prefix := "/apis/registry/v2/groups/default/artifacts/"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix), "/meta")
+		versions := artifacts[name]
+		if len(versions) == 0 {
+			w.WriteHeader(404)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && !strings.HasSuffix(r.URL.Path, "/meta"):
+			_, _ = w.Write([]byte(versions[len(versions)-1].content))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/meta"):
+			content, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, version := range versions {
+				if version.content == string(content) {
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"labels": version.labels})
+					return
+				}
+			}
+			w.WriteHeader(404)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(500)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return &schemaregistry.RestClient{BaseUrl: server.URL + "/apis/registry/v2", HttpClient: server.Client()}
+}
+
+func TestGraphQLSchemaCheckDeviation(t *testing.T) {
+	schema := NewGraphQLSchema(GraphQLSchemaParameters{Suffix: "custom", Namespace: "test"})
+	schema.SetName("XJoinIndexPipeline", "test")
+	schema.SetVersion("1")
+	labels := schemaregistry.GraphQLSchemaLabels(schema.SubgraphURL())
+	placeholder := schemaregistry.DefaultGraphQLSchema
+	pushed := "type Query {hosts: [Host]}"
+
+	tests := []struct {
+		name     string
+		versions []graphQLSchemaVersion
+		problems []string
+	}{{
+		name:     "registered version is the latest",
+		versions: []graphQLSchemaVersion{{placeholder, labels}},
+	}, {
+		name:     "subgraph pushed its schema",
+		versions: []graphQLSchemaVersion{{placeholder, labels}, {pushed, nil}},
+	}, {
+		name:     "registered version removed",
+		versions: []graphQLSchemaVersion{{pushed, nil}},
+		problems: []string{"content"},
+	}, {
+		name:     "labels removed",
+		versions: []graphQLSchemaVersion{{placeholder, labels[1:]}, {pushed, labels}},
+		problems: []string{"labels"},
+	}, {
+		name:     "artifact removed",
+		problems: []string{"graphql schema xjoinindexpipeline.test-custom.1 not found"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema.restClient = newGraphQLRegistry(t, map[string][]graphQLSchemaVersion{schema.Name(): test.versions})
+
+			problems, err := schema.CheckDeviation()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if paths := problemPaths(problems); !equalPaths(paths, test.problems) {
+				t.Errorf("expected problems %v, found %v", test.problems, paths)
+			}
+		})
+	}
+}
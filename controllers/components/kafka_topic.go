@@ -1,8 +1,11 @@
 package components
 
 import (
+	"fmt"
 	"github.com/go-errors/errors"
 	"github.com/redhatinsights/xjoin-operator/controllers/kafka"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"strings"
 )
 
@@ -41,8 +44,61 @@ func (kt *KafkaTopic) Delete() (err error) {
 	return
 }
 
-func (kt *KafkaTopic) CheckDeviation() (problem, err error) {
-	return //TODO
+func (kt *KafkaTopic) Render() (rendered interface{}, err error) {
+	return kt.KafkaTopics.BuildGenericTopic(kt.Name(), kt.TopicParameters).Object, nil
+}
+
+func (kt *KafkaTopic) CheckDeviation() (problems []Problem, err error) {
+	topic, err := kt.KafkaTopics.GetTopic(kt.Name())
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			return append(problems, newMissingProblem(fmt.Sprintf("topic %s not found", kt.Name()))), nil
+		}
+		return nil, errors.Wrap(err, 0)
+	}
+	actual, ok := topic.(*unstructured.Unstructured)
+	if !ok {
+		return nil, errors.Wrap(fmt.Errorf("unexpected type %T of topic %s", topic, kt.Name()), 0)
+	}
+	expected := kt.KafkaTopics.BuildGenericTopic(kt.Name(), kt.TopicParameters)
+
+	expectedMetadata, err := toJSONValue(map[string]interface{}{"labels": expected.GetLabels()})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	actualMetadata, err := toJSONValue(map[string]interface{}{"labels": actual.GetLabels()})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	problems = append(problems, compareValues("metadata", expectedMetadata, actualMetadata)...)
+
+	expectedSpec, err := toJSONValue(expected.Object["spec"])
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	actualSpec, err := toJSONValue(actual.Object["spec"])
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	problems = append(problems, compareValues("spec", expectedSpec, actualSpec)...)
+
+	return
+}
+
+func (kt *KafkaTopic) Repair() (err error) {
+	exists, err := kt.Exists()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if !exists {
+		return kt.Create()
+	}
+
+	err = kt.KafkaTopics.UpdateGenericTopic(kt.Name(), kt.TopicParameters)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
 }
 
 func (kt *KafkaTopic) Exists() (exists bool, err error) {
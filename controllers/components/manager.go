@@ -1,12 +1,43 @@
 package components
 
-import "github.com/go-errors/errors"
+import (
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"reflect"
+	"strings"
+)
+
+const (
+	DeviationPolicyRefresh = "refresh" //create a new version of the pipeline
+	DeviationPolicyRepair  = "repair"  //repair the component in place, refresh if the repair fails
+	DeviationPolicyReport  = "report"  //only record the deviation in the status
+)
+
+// RemediationMigrate is the remediation of a component that was updated in place for a change of the avro schema
+const RemediationMigrate = "migrate"
+
+func ValidateDeviationPolicy(policy string) error {
+	if policy != DeviationPolicyRefresh && policy != DeviationPolicyRepair && policy != DeviationPolicyReport {
+		return errors.Wrap(fmt.Errorf(
+			"invalid deviation policy: %s, must be one of %s, %s, %s",
+			policy, DeviationPolicyRefresh, DeviationPolicyRepair, DeviationPolicyReport), 0)
+	}
+	return nil
+}
+
+// DeviationsInvalidateVersion returns true when the deviations of a pipeline version should cause the version
+// to be refreshed. With the repair policy the pipeline only reports the deviations that could not be repaired.
+func DeviationsInvalidateVersion(policy string, deviations []v1alpha1.ComponentDeviation) bool {
+	return policy != DeviationPolicyReport && len(deviations) > 0
+}
 
 type Component interface {
 	Name() string
 	Create() error
 	Delete() error
-	CheckDeviation() (error, error)
+	CheckDeviation() ([]Problem, error)
 	Exists() (bool, error)
 	SetName(string, string)
 	SetVersion(string)
@@ -17,41 +48,268 @@ type Component interface {
 	Reconcile() error
 }
 
+// RepairableComponent is implemented by components that can be repaired in place
+// e.g. by re-applying a connector config or re-patching a Deployment. Repair must not delete the component, components
+// storing records (e.g. an Elasticsearch index) don't implement it so their deviations cause a refresh.
+type RepairableComponent interface {
+	Component
+	Repair() error
+}
+
+// MigratableComponent is implemented by components that can apply an additive change of the avro schema in place
+// e.g. by putting the new fields on an index mapping. Components that are rebuilt with the new schema by a repair
+// only implement RepairableComponent.
+type MigratableComponent interface {
+	Component
+	Migrate() error
+}
+
+// RenderableComponent is implemented by components that can render the resource they would create
+// without modifying any external resources
+type RenderableComponent interface {
+	Component
+	Render() (interface{}, error)
+}
+
+// ClosableComponent is implemented by components that keep a connection open across the operations of a reconcile
+// e.g. the database connection of a PostgreSQL table. The connection is closed by the manager at the end of the
+// reconcile.
+type ClosableComponent interface {
+	Component
+	Close() error
+}
+
+// ComponentPlan is the rendered representation of a component
+type ComponentPlan struct {
+	Type         string      `json:"type"`
+	Name         string      `json:"name"`
+	Dependencies []string    `json:"dependencies,omitempty"`
+	Resource     interface{} `json:"resource,omitempty"`
+}
+
+// defaultConcurrency is the number of components created in parallel unless the manager is configured otherwise
+const defaultConcurrency = 4
+
 type ComponentManager struct {
-	components []Component
-	name       string
-	version    string
-	kind       string
+	components   []Component
+	dependencies [][]Component
+	concurrency  int
+	name         string
+	version      string
+	kind         string
 }
 
 func NewComponentManager(kind string, name string, version string) ComponentManager {
 	return ComponentManager{
-		name:    name,
-		version: version,
-		kind:    kind,
+		name:        name,
+		version:     version,
+		kind:        kind,
+		concurrency: defaultConcurrency,
 	}
 }
 
-func (c *ComponentManager) AddComponent(component Component) {
+// AddComponent adds a component to the manager. The component is created after each of its dependencies are created.
+// Dependencies must be added to the manager before the component that depends on them.
+func (c *ComponentManager) AddComponent(component Component, dependencies ...Component) {
 	component.SetName(c.kind, c.name)
 	component.SetVersion(c.version)
 	c.components = append(c.components, component)
+	c.dependencies = append(c.dependencies, dependencies)
+}
+
+// SetConcurrency sets the maximum number of components that are created in parallel, 1 creates them one at a time
+func (c *ComponentManager) SetConcurrency(concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	c.concurrency = concurrency
+}
+
+type createResult struct {
+	index   int
+	created bool
+	err     error
 }
 
 // CreateAll creates all components. No-op if the components are already created.
+// Components are created in parallel, up to the concurrency limit, once their dependencies are created.
+// When a component can't be created the components that were created by this call are deleted.
 func (c *ComponentManager) CreateAll() error {
-	for _, component := range c.components {
+	dependents, remaining, err := c.dependencyGraph()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	var ready []int
+	for index := range c.components {
+		if remaining[index] == 0 {
+			ready = append(ready, index)
+		}
+	}
+
+	results := make(chan createResult)
+	var created []int
+	var createErrors []string
+	running := 0
+
+	for len(ready) > 0 || running > 0 {
+		//stop scheduling new components after a failure, wait for the running components to finish
+		for len(createErrors) == 0 && len(ready) > 0 && running < c.concurrency {
+			index := ready[0]
+			ready = ready[1:]
+			running++
+			go func(index int) {
+				componentCreated, err := createIfNotExists(c.components[index])
+				results <- createResult{index: index, created: componentCreated, err: err}
+			}(index)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+
+		if result.created {
+			created = append(created, result.index)
+		}
+		if result.err != nil {
+			createErrors = append(createErrors, fmt.Sprintf(
+				"unable to create %s %s: %s",
+				componentType(c.components[result.index]), c.components[result.index].Name(), result.err.Error()))
+			continue
+		}
+
+		for _, dependent := range dependents[result.index] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(createErrors) == 0 {
+		return nil
+	}
+
+	//rollback in the reverse order of creation so dependents are deleted before their dependencies
+	for i := len(created) - 1; i >= 0; i-- {
+		component := c.components[created[i]]
 		componentExists, err := component.Exists()
+		if err == nil && !componentExists {
+			continue
+		}
+		if err == nil {
+			err = component.Delete()
+		}
 		if err != nil {
-			return errors.Wrap(err, 0)
+			createErrors = append(createErrors, fmt.Sprintf(
+				"unable to rollback %s %s: %s", componentType(component), component.Name(), err.Error()))
+		}
+	}
+
+	return errors.Wrap(errors.New(strings.Join(createErrors, "; ")), 0)
+}
+
+// createIfNotExists returns true when the component was created
+func createIfNotExists(component Component) (created bool, err error) {
+	componentExists, err := component.Exists()
+	if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+	if componentExists {
+		return false, nil
+	}
+
+	err = component.Create()
+	if k8errors.IsAlreadyExists(err) {
+		//the component was created by someone else since Exists was called, it must not be rolled back
+		return false, errors.Wrap(err, 0)
+	} else if err != nil {
+		//the component may have been partially created
+		return true, errors.Wrap(err, 0)
+	}
+	return true, nil
+}
+
+// dependencyGraph returns the indexes of the components that depend on each component
+// and the number of dependencies of each component
+func (c *ComponentManager) dependencyGraph() (dependents [][]int, remaining []int, err error) {
+	dependents = make([][]int, len(c.components))
+	remaining = make([]int, len(c.components))
+
+	for index, dependencies := range c.dependencies {
+		for _, dependency := range dependencies {
+			dependencyIndex := -1
+			for i := 0; i < index; i++ {
+				if c.components[i] == dependency {
+					dependencyIndex = i
+					break
+				}
+			}
+			if dependencyIndex == -1 {
+				return nil, nil, errors.Wrap(fmt.Errorf(
+					"dependency %s of component %s must be added to the component manager before the component",
+					dependency.Name(), c.components[index].Name()), 0)
+			}
+
+			dependents[dependencyIndex] = append(dependents[dependencyIndex], index)
+			remaining[index]++
+		}
+	}
+
+	return dependents, remaining, nil
+}
+
+// Plan renders each component without creating it. Credentials are redacted from the rendered resources.
+func (c *ComponentManager) Plan() (plans []ComponentPlan, err error) {
+	for index, component := range c.components {
+		plan := ComponentPlan{
+			Type: componentType(component),
+			Name: component.Name(),
 		}
-		if !componentExists {
-			err = component.Create()
+
+		for _, dependency := range c.dependencies[index] {
+			plan.Dependencies = append(plan.Dependencies, dependency.Name())
+		}
+
+		if renderable, ok := component.(RenderableComponent); ok {
+			rendered, err := renderable.Render()
+			if err != nil {
+				return nil, errors.Wrap(err, 0)
+			}
+			resource, err := toJSONValue(rendered)
 			if err != nil {
-				return errors.Wrap(err, 0)
+				return nil, errors.Wrap(err, 0)
+			}
+			plan.Resource = redactCredentials(resource)
+		}
+
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+// Close closes the connections of each ClosableComponent, it is called once the manager is no longer used
+func (c *ComponentManager) Close() error {
+	return closeComponents(c.components)
+}
+
+func closeComponents(components []Component) error {
+	var closeErrors []string
+	for _, component := range components {
+		if closable, ok := component.(ClosableComponent); ok {
+			if err := closable.Close(); err != nil {
+				closeErrors = append(closeErrors, fmt.Sprintf(
+					"unable to close %s %s: %s", componentType(component), component.Name(), err.Error()))
 			}
 		}
 	}
+	if len(closeErrors) > 0 {
+		return errors.Wrap(errors.New(strings.Join(closeErrors, "; ")), 0)
+	}
 	return nil
 }
 
@@ -72,19 +330,154 @@ func (c *ComponentManager) DeleteAll() error {
 	return nil
 }
 
-// CheckForDeviations checks each component's stored value against the expected value, returns true if deviation is found
-func (c *ComponentManager) CheckForDeviations() (problems []error, err error) {
+// CheckForDeviations checks each component's stored value against the expected value, returns a deviation for each
+// component that doesn't match
+func (c *ComponentManager) CheckForDeviations() (deviations []v1alpha1.ComponentDeviation, err error) {
+	for _, component := range c.components {
+		problems, err := component.CheckDeviation()
+		if err != nil {
+			return deviations, errors.Wrap(err, 0)
+		}
+		for _, problem := range problems {
+			deviations = append(deviations, v1alpha1.ComponentDeviation{
+				ComponentType: componentType(component),
+				ComponentName: component.Name(),
+				Message:       problem.Message,
+				Path:          problem.Path,
+				Expected:      problem.Expected,
+				Actual:        problem.Actual,
+			})
+		}
+	}
+
+	return deviations, nil
+}
+
+// HandleDeviations checks for deviations then applies the policy. The deviations that remain are returned along with
+// the remediation that was applied to each deviating component.
+// When migrate is true the avro schema changed and the deviating components are first migrated in place.
+// With the repair policy each deviating component that implements RepairableComponent is repaired in place.
+// Components that can't be repaired, or still deviate after the repair, are escalated to a refresh.
+func (c *ComponentManager) HandleDeviations(policy string, migrate bool) (
+	deviations []v1alpha1.ComponentDeviation, remediations []v1alpha1.ComponentRemediation, err error) {
+
+	err = ValidateDeviationPolicy(policy)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, 0)
+	}
+
+	deviations, err = c.CheckForDeviations()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, 0)
+	}
+	if len(deviations) == 0 {
+		return nil, nil, nil
+	}
+
+	migrationErrors := make(map[string]string)
+	if migrate {
+		deviations, remediations, migrationErrors, err = c.migrate(deviations)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, 0)
+		}
+		if len(deviations) == 0 {
+			return nil, remediations, nil
+		}
+	}
+
+	if policy != DeviationPolicyRepair {
+		for _, component := range c.components {
+			if hasDeviation(component, deviations) {
+				remediations = append(remediations, newRemediation(
+					component, policy, migrationErrors[componentKey(component)]))
+			}
+		}
+		return deviations, remediations, nil
+	}
+
+	repairErrors := make(map[string]string)
+	for _, component := range c.components {
+		if !hasDeviation(component, deviations) {
+			continue
+		}
+
+		repairable, ok := component.(RepairableComponent)
+		if !ok {
+			repairErrors[componentKey(component)] = "component does not support repair"
+			continue
+		}
+
+		err = repairable.Repair()
+		if err != nil {
+			repairErrors[componentKey(component)] = "repair failed: " + err.Error()
+		}
+	}
+
+	previousDeviations := deviations
+	deviations, err = c.CheckForDeviations()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, 0)
+	}
+
+	for _, component := range c.components {
+		if !hasDeviation(component, previousDeviations) {
+			continue
+		}
+
+		if message, failed := repairErrors[componentKey(component)]; failed {
+			remediations = append(remediations, newRemediation(component, DeviationPolicyRefresh, message))
+		} else if hasDeviation(component, deviations) {
+			remediations = append(remediations, newRemediation(
+				component, DeviationPolicyRefresh, "deviations remain after repair"))
+		} else {
+			remediations = append(remediations, newRemediation(component, DeviationPolicyRepair, "repaired in place"))
+		}
+	}
+
+	return deviations, remediations, nil
+}
+
+// migrate applies a change of the avro schema to the deviating components. A component is migrated when it
+// implements MigratableComponent, otherwise it is repaired. The components are migrated in the order they were added
+// so the components storing the records are migrated before the Deployments using them. The deviations that remain
+// are returned along with the reason each component could not be migrated.
+func (c *ComponentManager) migrate(deviations []v1alpha1.ComponentDeviation) (
+	remaining []v1alpha1.ComponentDeviation, remediations []v1alpha1.ComponentRemediation,
+	migrationErrors map[string]string, err error) {
+
+	migrationErrors = make(map[string]string)
 	for _, component := range c.components {
-		problem, err := component.CheckDeviation()
+		if !hasDeviation(component, deviations) {
+			continue
+		}
+
+		if migratable, ok := component.(MigratableComponent); ok {
+			err = migratable.Migrate()
+		} else if repairable, ok := component.(RepairableComponent); ok {
+			err = repairable.Repair()
+		} else {
+			migrationErrors[componentKey(component)] = "component does not support migration"
+			continue
+		}
 		if err != nil {
-			return problems, errors.Wrap(err, 0)
+			migrationErrors[componentKey(component)] = "migration failed: " + err.Error()
 		}
-		if problem != nil {
-			problems = append(problems, problem)
+	}
+
+	remaining, err = c.CheckForDeviations()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, 0)
+	}
+
+	for _, component := range c.components {
+		if hasDeviation(component, deviations) && !hasDeviation(component, remaining) {
+			remediations = append(remediations, newRemediation(component, RemediationMigrate, "migrated in place"))
+		} else if hasDeviation(component, remaining) && migrationErrors[componentKey(component)] == "" {
+			migrationErrors[componentKey(component)] = "deviations remain after migration"
 		}
 	}
 
-	return problems, nil
+	return remaining, remediations, migrationErrors, nil
 }
 
 func (c *ComponentManager) Reconcile() error {
@@ -97,3 +490,30 @@ func (c *ComponentManager) Reconcile() error {
 
 	return nil
 }
+
+func componentType(component Component) string {
+	return reflect.Indirect(reflect.ValueOf(component)).Type().Name()
+}
+
+// componentKey identifies a component, components of different types can have the same name
+func componentKey(component Component) string {
+	return componentType(component) + "/" + component.Name()
+}
+
+func hasDeviation(component Component, deviations []v1alpha1.ComponentDeviation) bool {
+	for _, deviation := range deviations {
+		if deviation.ComponentType == componentType(component) && deviation.ComponentName == component.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+func newRemediation(component Component, action string, message string) v1alpha1.ComponentRemediation {
+	return v1alpha1.ComponentRemediation{
+		ComponentType: componentType(component),
+		ComponentName: component.Name(),
+		Action:        action,
+		Message:       message,
+	}
+}
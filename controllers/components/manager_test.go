@@ -0,0 +1,172 @@
+package components
+
+import (
+	"testing"
+
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+)
+
+// fakeComponent records the calls of the component manager. A deviating component deviates until it is repaired.
+type fakeComponent struct {
+	name      string
+	exists    bool
+	deviating bool
+	deleted   int
+}
+
+func (f *fakeComponent) Name() string {
+	return f.name
+}
+
+func (f *fakeComponent) Create() error {
+	f.exists = true
+	return nil
+}
+
+func (f *fakeComponent) Delete() error {
+	f.deleted++
+	f.exists = false
+	return nil
+}
+
+func (f *fakeComponent) CheckDeviation() ([]Problem, error) {
+	if f.deviating {
+		return []Problem{{Message: "edited"}}, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeComponent) Exists() (bool, error) {
+	return f.exists, nil
+}
+
+func (f *fakeComponent) SetName(string, string) {}
+
+func (f *fakeComponent) SetVersion(string) {}
+
+func (f *fakeComponent) ListInstalledVersions() ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeComponent) Reconcile() error {
+	return nil
+}
+
+// repairableFakeComponent is repaired in place
+type repairableFakeComponent struct {
+	fakeComponent
+	repaired int
+}
+
+func (f *repairableFakeComponent) Repair() error {
+	f.repaired++
+	f.deviating = false
+	return nil
+}
+
+// closableFakeComponent counts the calls to close its connection
+type closableFakeComponent struct {
+	fakeComponent
+	closed int
+}
+
+func (f *closableFakeComponent) Close() error {
+	f.closed++
+	return nil
+}
+
+func remediationActions(remediations []v1alpha1.ComponentRemediation) map[string]string {
+	actions := make(map[string]string)
+	for _, remediation := range remediations {
+		actions[remediation.ComponentName] = remediation.Action
+	}
+	return actions
+}
+
+func TestHandleDeviationsRepair(t *testing.T) {
+	index := &fakeComponent{name: "index", exists: true, deviating: true}
+	connector := &repairableFakeComponent{fakeComponent: fakeComponent{name: "connector", exists: true, deviating: true}}
+	manager := NewComponentManager("XJoinIndexPipeline", "test", "1")
+	manager.AddComponent(index)
+	manager.AddComponent(connector, index)
+
+	deviations, remediations, err := manager.HandleDeviations(DeviationPolicyRepair, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the index stores the records, it is never deleted to repair it
+	if index.deleted != 0 || connector.deleted != 0 {
+		t.Errorf("expected no component to be deleted, index: %d, connector: %d", index.deleted, connector.deleted)
+	}
+	if connector.repaired != 1 {
+		t.Errorf("expected the connector to be repaired once, got %d", connector.repaired)
+	}
+	if len(deviations) != 1 || deviations[0].ComponentName != "index" {
+		t.Errorf("expected only the index to deviate, got %v", deviations)
+	}
+
+	actions := remediationActions(remediations)
+	if actions["index"] != DeviationPolicyRefresh || actions["connector"] != DeviationPolicyRepair {
+		t.Errorf("expected the index to be refreshed and the connector to be repaired, got %v", actions)
+	}
+}
+
+func TestHandleDeviationsPolicies(t *testing.T) {
+	for _, policy := range []string{DeviationPolicyRefresh, DeviationPolicyReport} {
+		t.Run(policy, func(t *testing.T) {
+			index := &fakeComponent{name: "index", exists: true, deviating: true}
+			connector := &repairableFakeComponent{
+				fakeComponent: fakeComponent{name: "connector", exists: true, deviating: true}}
+			manager := NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(index)
+			manager.AddComponent(connector, index)
+
+			deviations, remediations, err := manager.HandleDeviations(policy, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if index.deleted != 0 || connector.deleted != 0 || connector.repaired != 0 {
+				t.Errorf("expected the components to be left as is")
+			}
+			if len(deviations) != 2 {
+				t.Errorf("expected both components to deviate, got %v", deviations)
+			}
+			actions := remediationActions(remediations)
+			if actions["index"] != policy || actions["connector"] != policy {
+				t.Errorf("expected the %s remediation, got %v", policy, actions)
+			}
+		})
+	}
+}
+
+func TestCloseComponents(t *testing.T) {
+	table := &closableFakeComponent{fakeComponent: fakeComponent{name: "table"}}
+	manager := NewComponentManager("XJoinIndexPipeline", "test", "1")
+	manager.AddComponent(table)
+	manager.AddComponent(&fakeComponent{name: "connector"}, table)
+
+	if err := manager.CreateAll(); err != nil {
+		t.Fatal(err)
+	}
+	if table.closed != 0 {
+		t.Errorf("expected the connection to be kept open during the reconcile")
+	}
+
+	if err := manager.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if table.closed != 1 {
+		t.Errorf("expected the connection to be closed once, got %d", table.closed)
+	}
+
+	custodian := NewCustodian("XJoinIndexPipeline", "test", []string{"1"})
+	custodian.AddComponent(table)
+	if errs := custodian.Scrub(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if table.closed != 2 {
+		t.Errorf("expected the custodian to close the connection, got %d", table.closed)
+	}
+}
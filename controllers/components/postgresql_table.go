@@ -0,0 +1,162 @@
+package components
+
+import (
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/controllers/database"
+	"strings"
+)
+
+// PostgreSQLTable is the table the PostgreSQL sink writes the joined records into.
+// The columns are generated from the index avro schema, the primary key is the record key.
+type PostgreSQLTable struct {
+	name       string
+	version    string
+	Database   *database.Database
+	Schema     string
+	PrimaryKey string
+	Columns    []database.Column
+}
+
+func (pt *PostgreSQLTable) SetName(kind string, name string) {
+	pt.name = strings.ToLower(kind + "." + name)
+}
+
+func (pt *PostgreSQLTable) SetVersion(version string) {
+	pt.version = version
+}
+
+func (pt *PostgreSQLTable) Name() string {
+	return pt.name + "." + pt.version
+}
+
+// TableName is the name of the table in the database, e.g. xjoinindexpipeline_hosts_1234
+func (pt *PostgreSQLTable) TableName() string {
+	return database.TableName(pt.Name())
+}
+
+// Close closes the connection opened by the operations of the reconcile, the operations are infrequent so the
+// connection isn't kept open between reconciles
+func (pt *PostgreSQLTable) Close() error {
+	return pt.Database.Close()
+}
+
+func (pt *PostgreSQLTable) Create() (err error) {
+	err = pt.Database.Connect()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = pt.Database.CreateTable(pt.Schema, pt.TableName(), pt.PrimaryKey, pt.Columns)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+// Migrate adds the columns of the new fields to the existing table, the type of an existing column isn't changed
+func (pt *PostgreSQLTable) Migrate() (err error) {
+	err = pt.Database.Connect()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = pt.Database.AddColumns(pt.Schema, pt.TableName(), pt.Columns)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (pt *PostgreSQLTable) Delete() (err error) {
+	err = pt.Database.Connect()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = pt.Database.DropTable(pt.Schema, pt.TableName())
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (pt *PostgreSQLTable) Render() (rendered interface{}, err error) {
+	return map[string]interface{}{
+		"schema":  pt.Schema,
+		"table":   pt.TableName(),
+		"columns": pt.expectedColumns(),
+		"ddl":     database.CreateTableQuery(pt.Schema, pt.TableName(), pt.PrimaryKey, pt.Columns),
+	}, nil
+}
+
+func (pt *PostgreSQLTable) expectedColumns() []database.Column {
+	return append([]database.Column{{Name: pt.PrimaryKey, Type: "text"}}, pt.Columns...)
+}
+
+// CheckDeviation compares the type of each column, columns that were added to the table are ignored
+func (pt *PostgreSQLTable) CheckDeviation() (problems []Problem, err error) {
+	exists, err := pt.Exists()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	if !exists {
+		return append(problems, newMissingProblem(fmt.Sprintf("table %s not found", pt.TableName()))), nil
+	}
+
+	err = pt.Database.Connect()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	columns, err := pt.Database.TableColumns(pt.Schema, pt.TableName())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	for _, expected := range pt.expectedColumns() {
+		actual, exists := columns[expected.Name]
+		if !exists {
+			problems = append(problems, newMissingProblem(fmt.Sprintf("column %s is missing", expected.Name)))
+		} else if actual != expected.Type {
+			problems = append(problems, newProblem("columns."+expected.Name, expected.Type, actual))
+		}
+	}
+
+	return
+}
+
+func (pt *PostgreSQLTable) Exists() (exists bool, err error) {
+	err = pt.Database.Connect()
+	if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+
+	exists, err = pt.Database.TableExists(pt.Schema, pt.TableName())
+	if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (pt *PostgreSQLTable) ListInstalledVersions() (versions []string, err error) {
+	err = pt.Database.Connect()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	prefix := database.TableName(pt.name) + "_"
+	tables, err := pt.Database.ListTablesForPrefix(pt.Schema, prefix)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	for _, table := range tables {
+		versions = append(versions, strings.TrimPrefix(table, prefix))
+	}
+	return
+}
+
+func (pt *PostgreSQLTable) Reconcile() (err error) {
+	return nil
+}
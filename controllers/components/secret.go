@@ -0,0 +1,189 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-errors/errors"
+	v1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+)
+
+// keys of the credentials stored in a pipeline's Secret
+const (
+	ElasticSearchUsernameKey = "elasticsearch.username"
+	ElasticSearchPasswordKey = "elasticsearch.password"
+	SinkPasswordKey          = "sink.password"
+)
+
+// Secret contains the credentials used by the Deployments and connectors of a pipeline.
+// The Deployments reference the Secret via valueFrom.secretKeyRef so the credentials are not stored in the pod specs.
+// The connectors reference it via the Strimzi config provider, see SecretConfigProviderRef.
+type Secret struct {
+	name      string
+	version   string
+	Client    client.Client
+	Context   context.Context
+	Namespace string
+	Suffix    string //distinguishes multiple Secrets of a pipeline e.g. the sink's Secret
+	Data      map[string]string
+}
+
+func (s *Secret) SetName(kind string, name string) {
+	s.name = strings.ToLower(kind + "." + name)
+	if s.Suffix != "" {
+		s.name = s.name + "-" + s.Suffix
+	}
+}
+
+func (s *Secret) SetVersion(version string) {
+	s.version = version
+}
+
+func (s *Secret) Name() string {
+	return s.name + "." + s.version
+}
+
+// SecretKeyRef builds an env var source that reads key from the Secret
+func (s *Secret) SecretKeyRef(key string) map[string]interface{} {
+	return map[string]interface{}{
+		"secretKeyRef": map[string]interface{}{
+			"name": s.Name(),
+			"key":  key,
+		},
+	}
+}
+
+// SecretConfigProviderRef references key in the Secret via the KubernetesSecretConfigProvider of the Strimzi Kafka
+// Connect cluster. The connector reads the value when it starts so it's not stored in the KafkaConnector spec.
+func (s *Secret) SecretConfigProviderRef(key string) string {
+	return fmt.Sprintf("${secrets:%s/%s:%s}", s.Namespace, s.Name(), key)
+}
+
+func (s *Secret) buildSecret() *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name(),
+			Namespace: s.Namespace,
+			Labels: map[string]string{
+				"xjoin.index": s.name,
+			},
+		},
+		Type:       v1.SecretTypeOpaque,
+		StringData: s.Data,
+	}
+}
+
+func (s *Secret) Create() (err error) {
+	err = s.Client.Create(s.Context, s.buildSecret())
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (s *Secret) Delete() (err error) {
+	secret := &v1.Secret{}
+	err = s.Client.Get(s.Context, client.ObjectKey{Name: s.Name(), Namespace: s.Namespace}, secret)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = s.Client.Delete(s.Context, secret)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+// Render returns the Secret with its values redacted
+func (s *Secret) Render() (rendered interface{}, err error) {
+	secret := s.buildSecret()
+	redacted := make(map[string]string)
+	for key := range secret.StringData {
+		redacted[key] = redactedValue
+	}
+	secret.StringData = redacted
+	return toJSONValue(secret)
+}
+
+// CheckDeviation compares the keys and values of the Secret, the values are redacted in the problems
+func (s *Secret) CheckDeviation() (problems []Problem, err error) {
+	secret := &v1.Secret{}
+	err = s.Client.Get(s.Context, client.ObjectKey{Name: s.Name(), Namespace: s.Namespace}, secret)
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			return append(problems, newMissingProblem(fmt.Sprintf("secret %s not found", s.Name()))), nil
+		}
+		return nil, errors.Wrap(err, 0)
+	}
+
+	var keys []string
+	for key := range s.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		actual, exists := secret.Data[key]
+		if !exists {
+			problems = append(problems, newMissingProblem(fmt.Sprintf("data.%s is missing", key)))
+		} else if string(actual) != s.Data[key] {
+			problems = append(problems, newProblem("data."+key, redactedValue, redactedValue))
+		}
+	}
+
+	return
+}
+
+// Repair re-applies the data of the Secret, the Secret is created when it doesn't exist
+func (s *Secret) Repair() (err error) {
+	secret := &v1.Secret{}
+	err = s.Client.Get(s.Context, client.ObjectKey{Name: s.Name(), Namespace: s.Namespace}, secret)
+	if k8errors.IsNotFound(err) {
+		return s.Create()
+	} else if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	secret.StringData = s.Data
+	err = s.Client.Update(s.Context, secret)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (s *Secret) Exists() (exists bool, err error) {
+	secret := &v1.Secret{}
+	err = s.Client.Get(s.Context, client.ObjectKey{Name: s.Name(), Namespace: s.Namespace}, secret)
+	if k8errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+	return true, nil
+}
+
+func (s *Secret) ListInstalledVersions() (versions []string, err error) {
+	secrets := &v1.SecretList{}
+	labels := client.MatchingLabels{}
+	labels["xjoin.index"] = s.name
+	err = s.Client.List(s.Context, secrets, labels, client.InNamespace(s.Namespace))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	for _, secret := range secrets.Items {
+		versions = append(versions, strings.TrimPrefix(secret.GetName(), s.name+"."))
+	}
+
+	return
+}
+
+func (s *Secret) Reconcile() (err error) {
+	return nil
+}
@@ -0,0 +1,246 @@
+package components
+
+import (
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/avro"
+	"github.com/redhatinsights/xjoin-operator/controllers/database"
+	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
+	"github.com/redhatinsights/xjoin-operator/controllers/kafka"
+)
+
+const (
+	OpenSearchConnectorClass = "io.aiven.kafka.connect.opensearch.OpensearchSinkConnector"
+	PostgreSQLConnectorClass = "io.debezium.connector.jdbc.JdbcSinkConnector"
+
+	//PostgreSQLPrimaryKey is the column the record key is written to
+	PostgreSQLPrimaryKey = "id"
+)
+
+// Sink builds the components that materialize the joined records of an index pipeline in a backend.
+// Every sink consumes the kafka topic written by xjoin-core.
+type Sink interface {
+	// Type is one of v1alpha1.SinkTypeElasticsearch, v1alpha1.SinkTypeOpenSearch, v1alpha1.SinkTypePostgreSQL
+	Type() string
+
+	// SetSchema generates the mapping of the sink (e.g. index properties, table columns) from the index avro schema
+	SetSchema(indexAvroSchema avro.IndexAvroSchema) error
+
+	// AddComponents adds the components of the sink to the manager. The component that stores the records
+	// (e.g. the index or the table) is returned so other components can depend on it.
+	AddComponents(manager *ComponentManager, topic Component) (store Component)
+
+	// AddToCustodian adds the components of the sink to a custodian so stale versions are removed
+	AddToCustodian(custodian *Custodian)
+}
+
+// SearchSink is a Sink whose records can be queried with the Elasticsearch API e.g. by the xjoin-api-subgraph
+type SearchSink interface {
+	Sink
+	Connection() elasticsearch.GenericElasticSearchParameters
+}
+
+// ElasticsearchSink writes the records into an Elasticsearch index, this is the default sink
+type ElasticsearchSink struct {
+	GenericElasticsearch elasticsearch.GenericElasticsearch
+	ConnectionParameters elasticsearch.GenericElasticSearchParameters
+	KafkaClient          kafka.GenericKafka
+	IndexTemplate        string
+	ConnectorTemplate    string
+	TemplateParameters   map[string]interface{}
+	Properties           string //set by SetSchema
+	Analysis             string
+	JSONFields           []string
+	DateFields           []avro.DateField
+}
+
+func (es *ElasticsearchSink) Type() string {
+	return v1alpha1.SinkTypeElasticsearch
+}
+
+func (es *ElasticsearchSink) Connection() elasticsearch.GenericElasticSearchParameters {
+	return es.ConnectionParameters
+}
+
+func (es *ElasticsearchSink) SetSchema(indexAvroSchema avro.IndexAvroSchema) error {
+	es.Properties = indexAvroSchema.ESProperties
+	es.Analysis = indexAvroSchema.ESAnalysis
+	es.JSONFields = indexAvroSchema.JSONFields
+	es.DateFields = indexAvroSchema.DateFields
+	return nil
+}
+
+func (es *ElasticsearchSink) AddToCustodian(custodian *Custodian) {
+	custodian.AddComponent(&ElasticsearchPipeline{GenericElasticsearch: es.GenericElasticsearch})
+	custodian.AddComponent(&ElasticsearchIndex{GenericElasticsearch: es.GenericElasticsearch})
+	custodian.AddComponent(&ElasticsearchConnector{KafkaClient: es.KafkaClient})
+}
+
+// addIndex adds the index and the ingest pipeline that parses the json fields and converts the dates
+func (es *ElasticsearchSink) addIndex(manager *ComponentManager) *ElasticsearchIndex {
+	var indexDependencies []Component
+	if es.withPipeline() {
+		pipeline := &ElasticsearchPipeline{
+			GenericElasticsearch: es.GenericElasticsearch,
+			JsonFields:           es.JSONFields,
+			DateFields:           es.DateFields,
+		}
+		manager.AddComponent(pipeline)
+		indexDependencies = append(indexDependencies, pipeline)
+	}
+
+	index := &ElasticsearchIndex{
+		GenericElasticsearch: es.GenericElasticsearch,
+		Template:             es.IndexTemplate,
+		Properties:           es.Properties,
+		Analysis:             es.Analysis,
+		WithPipeline:         es.withPipeline(),
+	}
+	manager.AddComponent(index, indexDependencies...)
+	return index
+}
+
+func (es *ElasticsearchSink) withPipeline() bool {
+	return es.JSONFields != nil || es.DateFields != nil
+}
+
+func (es *ElasticsearchSink) AddComponents(manager *ComponentManager, topic Component) (store Component) {
+	index := es.addIndex(manager)
+	manager.AddComponent(&ElasticsearchConnector{
+		Template:           es.ConnectorTemplate,
+		KafkaClient:        es.KafkaClient,
+		TemplateParameters: es.TemplateParameters,
+		Topic:              topic.Name(),
+	}, topic, index)
+	return index
+}
+
+// OpenSearchSink writes the records into an OpenSearch index. The index and ingest pipeline are created via the
+// Elasticsearch API which OpenSearch is compatible with, the records are written by the OpenSearch connector.
+type OpenSearchSink struct {
+	ElasticsearchSink
+	Credentials *Secret //the connector reads the password from this Secret
+}
+
+func (ops *OpenSearchSink) Type() string {
+	return v1alpha1.SinkTypeOpenSearch
+}
+
+func (ops *OpenSearchSink) AddComponents(manager *ComponentManager, topic Component) (store Component) {
+	index := ops.addIndex(manager)
+	dependencies := []Component{topic, index}
+
+	templateParameters := copyTemplateParameters(ops.TemplateParameters)
+	templateParameters["OpenSearchURL"] = ops.ConnectionParameters.Url
+	templateParameters["OpenSearchUsername"] = ops.ConnectionParameters.Username
+	if ops.ConnectionParameters.Password != "" {
+		manager.AddComponent(ops.Credentials)
+		dependencies = append(dependencies, ops.Credentials)
+		templateParameters["OpenSearchPassword"] = ops.Credentials.SecretConfigProviderRef(SinkPasswordKey)
+	}
+
+	manager.AddComponent(&SinkConnector{
+		Class:              OpenSearchConnectorClass,
+		Template:           ops.ConnectorTemplate,
+		KafkaClient:        ops.KafkaClient,
+		TemplateParameters: templateParameters,
+		Topic:              topic.Name(),
+	}, dependencies...)
+	return index
+}
+
+// PostgreSQLSink writes the records into a PostgreSQL table via the JDBC sink connector.
+// Nested records are flattened into columns by the connector, see avro.TransformToPostgreSQL.
+type PostgreSQLSink struct {
+	Database           *database.Database
+	Schema             string
+	Columns            []database.Column //set by SetSchema
+	KafkaClient        kafka.GenericKafka
+	ConnectorTemplate  string
+	TemplateParameters map[string]interface{}
+	Credentials        *Secret //the connector reads the password from this Secret
+}
+
+func (pg *PostgreSQLSink) Type() string {
+	return v1alpha1.SinkTypePostgreSQL
+}
+
+func (pg *PostgreSQLSink) SetSchema(indexAvroSchema avro.IndexAvroSchema) (err error) {
+	pg.Columns, err = avro.TransformToPostgreSQL(indexAvroSchema.AvroSchema)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (pg *PostgreSQLSink) AddToCustodian(custodian *Custodian) {
+	custodian.AddComponent(&PostgreSQLTable{Database: pg.Database, Schema: pg.Schema})
+	custodian.AddComponent(&SinkConnector{KafkaClient: pg.KafkaClient})
+}
+
+func (pg *PostgreSQLSink) AddComponents(manager *ComponentManager, topic Component) (store Component) {
+	table := &PostgreSQLTable{
+		Database:   pg.Database,
+		Schema:     pg.Schema,
+		PrimaryKey: PostgreSQLPrimaryKey,
+		Columns:    pg.Columns,
+	}
+	manager.AddComponent(table)
+	manager.AddComponent(pg.Credentials)
+
+	//stringtype=unspecified lets PostgreSQL cast the json and timestamp strings to the column types
+	config := pg.Database.Config
+	url := fmt.Sprintf("jdbc:postgresql://%s:%s/%s?stringtype=unspecified", config.Host, config.Port, config.Name)
+	if config.SSLMode != "" {
+		url = url + "&sslmode=" + config.SSLMode
+	}
+
+	templateParameters := copyTemplateParameters(pg.TemplateParameters)
+	templateParameters["PostgreSQLURL"] = url
+	templateParameters["PostgreSQLUsername"] = config.User
+	templateParameters["PostgreSQLPassword"] = pg.Credentials.SecretConfigProviderRef(SinkPasswordKey)
+	templateParameters["PostgreSQLTable"] = pg.Schema + "." + table.TableName()
+	templateParameters["PostgreSQLPrimaryKey"] = PostgreSQLPrimaryKey
+
+	manager.AddComponent(&SinkConnector{
+		Class:              PostgreSQLConnectorClass,
+		Template:           pg.ConnectorTemplate,
+		KafkaClient:        pg.KafkaClient,
+		TemplateParameters: templateParameters,
+		Topic:              topic.Name(),
+	}, topic, table, pg.Credentials)
+	return table
+}
+
+func copyTemplateParameters(parameters map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	for key, value := range parameters {
+		m[key] = value
+	}
+	return m
+}
+
+// ValidateSinkSpec checks the connection settings required by the sink type are present
+func ValidateSinkSpec(sink *v1alpha1.SinkSpec) error {
+	switch sink.GetType() {
+	case v1alpha1.SinkTypeElasticsearch:
+		return nil
+	case v1alpha1.SinkTypeOpenSearch:
+		if sink.OpenSearch == nil || sink.OpenSearch.URL == nil {
+			return errors.Wrap(errors.New("sink.opensearch.url is required for the opensearch sink"), 0)
+		}
+		return nil
+	case v1alpha1.SinkTypePostgreSQL:
+		pg := sink.PostgreSQL
+		if pg == nil || pg.Hostname == nil || pg.Port == nil || pg.Name == nil || pg.Username == nil ||
+			pg.Password == nil {
+			return errors.Wrap(errors.New(
+				"sink.postgresql.hostname, port, name, username and password are required for the postgresql sink"), 0)
+		}
+		return nil
+	default:
+		return errors.Wrap(fmt.Errorf("invalid sink type: %s, must be one of %s, %s, %s", sink.GetType(),
+			v1alpha1.SinkTypeElasticsearch, v1alpha1.SinkTypeOpenSearch, v1alpha1.SinkTypePostgreSQL), 0)
+	}
+}
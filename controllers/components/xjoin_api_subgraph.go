@@ -2,39 +2,60 @@ package components
 
 import (
 	"context"
+	"fmt"
 	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
 	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
 )
 
 type XJoinAPISubGraph struct {
-	name                  string
-	schemaName            string
-	version               string
-	Client                client.Client
-	Context               context.Context
-	Namespace             string
-	AvroSchema            string
-	Registry              *schemaregistry.ConfluentClient
-	ElasticSearchUsername string
-	ElasticSearchPassword string
-	ElasticSearchURL      string
-	ElasticSearchIndex    string
-	Image                 string
-	Suffix                string
-	GraphQLSchemaName     string
+	name               string
+	schemaName         string
+	version            string
+	Client             client.Client
+	Context            context.Context
+	Namespace          string
+	AvroSchema         string
+	Registry           *schemaregistry.ConfluentClient
+	Credentials        *Secret
+	ElasticSearchURL   string
+	ElasticSearchIndex string
+	Image              string
+	Suffix             string
+	GraphQLSchemaName  string
+	Deployment         v1alpha1.DeploymentSpec
+	Autoscaling        *v1alpha1.AutoscalingSpec
+	ProbePath          string
 }
 
-func (x *XJoinAPISubGraph) SetName(kind string, name string) {
-	x.schemaName = strings.ToLower(kind + "." + name)
-	x.name = strings.ToLower(strings.ReplaceAll(name, ".", "-"))
+// subgraphServicePort is the port of the Service that exposes each subgraph to the federated gateway
+const subgraphServicePort = 4000
+
+// defaultTargetCPUUtilizationPercentage is used when the AutoscalingSpec doesn't define a target
+const defaultTargetCPUUtilizationPercentage = 80
 
-	if x.Suffix != "" {
-		x.name = x.name + "-" + x.Suffix
+// subgraphName is the name of the Deployment and Service of a subgraph without the version
+func subgraphName(name string, suffix string) string {
+	subgraph := strings.ToLower(strings.ReplaceAll(name, ".", "-"))
+	if suffix != "" {
+		subgraph = subgraph + "-" + suffix
 	}
+	return subgraph
+}
+
+// subgraphURL is the url used by the federated gateway to reach the Service of a subgraph
+func subgraphURL(serviceName string, namespace string) string {
+	return fmt.Sprintf("http://%s.%s.svc:%v/graphql", serviceName, namespace, subgraphServicePort)
+}
+
+func (x *XJoinAPISubGraph) SetName(kind string, name string) {
+	x.schemaName = strings.ToLower(kind + "." + name)
+	x.name = subgraphName(name, x.Suffix)
 }
 
 func (x *XJoinAPISubGraph) SetVersion(version string) {
@@ -45,13 +66,57 @@ func (x *XJoinAPISubGraph) Name() string {
 	return x.name + "-" + x.version
 }
 
+// URL is the url of the subgraph's Service
+func (x *XJoinAPISubGraph) URL() string {
+	return subgraphURL(x.Name(), x.Namespace)
+}
+
 func (x *XJoinAPISubGraph) Create() (err error) {
-	deployment := &unstructured.Unstructured{}
+	deployment, err := x.buildDeployment()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	err = x.Client.Create(x.Context, deployment)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
 
-	labels := map[string]interface{}{
+	//create the service
+	service := x.buildService()
+	err = x.Client.Create(x.Context, service)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	//create the autoscaler
+	if x.Autoscaling != nil {
+		err = x.Client.Create(x.Context, x.buildHorizontalPodAutoscaler())
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+
+	return
+}
+
+func (x *XJoinAPISubGraph) labels() map[string]interface{} {
+	return map[string]interface{}{
 		"app":         x.Name(),
 		"xjoin.index": x.name,
 	}
+}
+
+// image is the custom subgraph image when set, otherwise the image of the Deployment settings
+func (x *XJoinAPISubGraph) image() string {
+	if x.Image != "" {
+		return x.Image
+	}
+	return deploymentImage(x.Deployment)
+}
+
+func (x *XJoinAPISubGraph) buildDeployment() (*unstructured.Unstructured, error) {
+	deployment := &unstructured.Unstructured{}
+	labels := x.labels()
 
 	deployment.Object = map[string]interface{}{
 		"metadata": map[string]interface{}{
@@ -106,12 +171,12 @@ func (x *XJoinAPISubGraph) Create() (err error) {
 								"value": x.ElasticSearchURL,
 							},
 							{
-								"name":  "ELASTIC_SEARCH_USERNAME",
-								"value": x.ElasticSearchUsername,
+								"name":      "ELASTIC_SEARCH_USERNAME",
+								"valueFrom": x.Credentials.SecretKeyRef(ElasticSearchUsernameKey),
 							},
 							{
-								"name":  "ELASTIC_SEARCH_PASSWORD",
-								"value": x.ElasticSearchPassword,
+								"name":      "ELASTIC_SEARCH_PASSWORD",
+								"valueFrom": x.Credentials.SecretKeyRef(ElasticSearchPasswordKey),
 							},
 							{
 								"name":  "ELASTIC_SEARCH_INDEX",
@@ -122,19 +187,10 @@ func (x *XJoinAPISubGraph) Create() (err error) {
 								"value": x.GraphQLSchemaName,
 							},
 						},
-						"image":           x.Image,
-						"imagePullPolicy": "Always",
-						"name":            x.Name(),
-						"resources": map[string]interface{}{
-							"limits": map[string]interface{}{
-								"cpu":    "250m",
-								"memory": "512Mi",
-							},
-							"requests": map[string]interface{}{
-								"cpu":    "100m",
-								"memory": "64Mi",
-							},
-						},
+						"image":          x.image(),
+						"name":           x.Name(),
+						"livenessProbe":  x.buildProbe(),
+						"readinessProbe": x.buildProbe(),
 					}},
 				},
 			},
@@ -143,13 +199,78 @@ func (x *XJoinAPISubGraph) Create() (err error) {
 
 	deployment.SetGroupVersionKind(common.DeploymentGVK)
 
-	err = x.Client.Create(x.Context, deployment)
+	err := applyDeploymentSpec(deployment, x.Deployment)
 	if err != nil {
-		return errors.Wrap(err, 0)
+		return nil, errors.Wrap(err, 0)
 	}
 
-	//create the service
+	//the replicas are managed by the autoscaler
+	if x.Autoscaling != nil {
+		unstructured.RemoveNestedField(deployment.Object, "spec", "replicas")
+	}
+
+	return deployment, nil
+}
+
+func (x *XJoinAPISubGraph) buildProbe() map[string]interface{} {
+	return map[string]interface{}{
+		"httpGet": map[string]interface{}{
+			"path": x.ProbePath,
+			"port": "web",
+		},
+		"initialDelaySeconds": 5,
+		"periodSeconds":       10,
+	}
+}
+
+func (x *XJoinAPISubGraph) buildHorizontalPodAutoscaler() *unstructured.Unstructured {
+	autoscaler := &unstructured.Unstructured{}
+
+	minReplicas := int32(1)
+	if x.Autoscaling.MinReplicas != nil {
+		minReplicas = *x.Autoscaling.MinReplicas
+	}
+	targetCPU := int32(defaultTargetCPUUtilizationPercentage)
+	if x.Autoscaling.TargetCPUUtilizationPercentage != nil {
+		targetCPU = *x.Autoscaling.TargetCPUUtilizationPercentage
+	}
+
+	autoscaler.Object = map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":      x.Name(),
+			"namespace": x.Namespace,
+			"labels":    x.labels(),
+		},
+		"spec": map[string]interface{}{
+			"scaleTargetRef": map[string]interface{}{
+				"apiVersion": common.DeploymentGVK.GroupVersion().String(),
+				"kind":       common.DeploymentGVK.Kind,
+				"name":       x.Name(),
+			},
+			"minReplicas": int64(minReplicas),
+			"maxReplicas": int64(x.Autoscaling.MaxReplicas),
+			"metrics": []interface{}{
+				map[string]interface{}{
+					"type": "Resource",
+					"resource": map[string]interface{}{
+						"name": "cpu",
+						"target": map[string]interface{}{
+							"type":               "Utilization",
+							"averageUtilization": int64(targetCPU),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	autoscaler.SetGroupVersionKind(common.HorizontalPodAutoscalerGVK)
+	return autoscaler
+}
+
+func (x *XJoinAPISubGraph) buildService() *unstructured.Unstructured {
 	service := &unstructured.Unstructured{}
+	labels := x.labels()
 
 	service.Object = map[string]interface{}{
 		"metadata": map[string]interface{}{
@@ -158,11 +279,12 @@ func (x *XJoinAPISubGraph) Create() (err error) {
 			"labels":    labels,
 		},
 		"spec": map[string]interface{}{
-			"ports": []map[string]interface{}{
-				{
-					"port":       4000,
+			"ports": []interface{}{
+				map[string]interface{}{
+					"name":       "web",
+					"port":       int64(subgraphServicePort),
 					"protocol":   "TCP",
-					"targetPort": 4000,
+					"targetPort": "web",
 				},
 			},
 			"selector": map[string]interface{}{
@@ -172,15 +294,23 @@ func (x *XJoinAPISubGraph) Create() (err error) {
 	}
 
 	service.SetGroupVersionKind(common.ServiceGVK)
-	err = x.Client.Create(x.Context, service)
-	if err != nil {
-		return errors.Wrap(err, 0)
-	}
-
-	return
+	return service
 }
 
 func (x *XJoinAPISubGraph) Delete() (err error) {
+	//delete the autoscaler
+	autoscaler := &unstructured.Unstructured{}
+	autoscaler.SetGroupVersionKind(common.HorizontalPodAutoscalerGVK)
+	err = x.Client.Get(x.Context, client.ObjectKey{Name: x.Name(), Namespace: x.Namespace}, autoscaler)
+	if err == nil {
+		err = x.Client.Delete(x.Context, autoscaler)
+		if err != nil && !k8errors.IsNotFound(err) {
+			return errors.Wrap(err, 0)
+		}
+	} else if !k8errors.IsNotFound(err) {
+		return errors.Wrap(err, 0)
+	}
+
 	//delete the deployment
 	deployment := &unstructured.Unstructured{}
 	deployment.SetGroupVersionKind(common.DeploymentGVK)
@@ -220,10 +350,165 @@ func (x *XJoinAPISubGraph) Delete() (err error) {
 	return
 }
 
-func (x *XJoinAPISubGraph) CheckDeviation() (problem, err error) {
+func (x *XJoinAPISubGraph) Render() (rendered interface{}, err error) {
+	deployment, err := x.buildDeployment()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	resources := map[string]interface{}{
+		"deployment": deployment.Object,
+		"service":    x.buildService().Object,
+		"url":        x.URL(),
+	}
+	if x.Autoscaling != nil {
+		resources["horizontalPodAutoscaler"] = x.buildHorizontalPodAutoscaler().Object
+	}
+	return resources, nil
+}
+
+func (x *XJoinAPISubGraph) CheckDeviation() (problems []Problem, err error) {
+	deployment := &unstructured.Unstructured{}
+	deployment.SetGroupVersionKind(common.DeploymentGVK)
+	err = x.Client.Get(x.Context, client.ObjectKey{Name: x.Name(), Namespace: x.Namespace}, deployment)
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			return append(problems, newMissingProblem(fmt.Sprintf("deployment %s not found", x.Name()))), nil
+		}
+		return nil, errors.Wrap(err, 0)
+	}
+
+	expected, err := x.buildDeployment()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	problems, err = checkDeploymentDeviation(expected, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	service := &unstructured.Unstructured{}
+	service.SetGroupVersionKind(common.ServiceGVK)
+	err = x.Client.Get(x.Context, client.ObjectKey{Name: x.Name(), Namespace: x.Namespace}, service)
+	if k8errors.IsNotFound(err) {
+		problems = append(problems, newMissingProblem(fmt.Sprintf("service %s not found", x.Name())))
+	} else if err != nil {
+		return nil, errors.Wrap(err, 0)
+	} else {
+		problems = append(problems, compareValues("service.spec",
+			x.buildService().Object["spec"], service.Object["spec"])...)
+	}
+
+	autoscaler := &unstructured.Unstructured{}
+	autoscaler.SetGroupVersionKind(common.HorizontalPodAutoscalerGVK)
+	err = x.Client.Get(x.Context, client.ObjectKey{Name: x.Name(), Namespace: x.Namespace}, autoscaler)
+	if k8errors.IsNotFound(err) {
+		if x.Autoscaling != nil {
+			problems = append(problems, newMissingProblem(
+				fmt.Sprintf("horizontalpodautoscaler %s not found", x.Name())))
+		}
+	} else if err != nil {
+		return nil, errors.Wrap(err, 0)
+	} else if x.Autoscaling == nil {
+		problems = append(problems, newMissingProblem(
+			fmt.Sprintf("horizontalpodautoscaler %s is not expected", x.Name())))
+	} else {
+		problems = append(problems, compareValues("horizontalPodAutoscaler.spec",
+			x.buildHorizontalPodAutoscaler().Object["spec"], autoscaler.Object["spec"])...)
+	}
+
+	return problems, nil
+}
+
+func (x *XJoinAPISubGraph) Repair() (err error) {
+	deployment, err := x.buildDeployment()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = repairDeployment(x.Context, x.Client, deployment)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = x.repairService()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = x.repairHorizontalPodAutoscaler()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
 	return
 }
 
+// repairService creates the service or re-applies its ports and selector
+func (x *XJoinAPISubGraph) repairService() (err error) {
+	expected := x.buildService()
+	service := &unstructured.Unstructured{}
+	service.SetGroupVersionKind(common.ServiceGVK)
+	err = x.Client.Get(x.Context, client.ObjectKey{Name: x.Name(), Namespace: x.Namespace}, service)
+	if k8errors.IsNotFound(err) {
+		err = x.Client.Create(x.Context, expected)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	for _, field := range []string{"ports", "selector"} {
+		err = unstructured.SetNestedField(
+			service.Object, expected.Object["spec"].(map[string]interface{})[field], "spec", field)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	err = x.Client.Update(x.Context, service)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// repairHorizontalPodAutoscaler creates, updates or deletes the autoscaler to match the AutoscalingSpec
+func (x *XJoinAPISubGraph) repairHorizontalPodAutoscaler() (err error) {
+	autoscaler := &unstructured.Unstructured{}
+	autoscaler.SetGroupVersionKind(common.HorizontalPodAutoscalerGVK)
+	err = x.Client.Get(x.Context, client.ObjectKey{Name: x.Name(), Namespace: x.Namespace}, autoscaler)
+	if k8errors.IsNotFound(err) {
+		if x.Autoscaling == nil {
+			return nil
+		}
+		err = x.Client.Create(x.Context, x.buildHorizontalPodAutoscaler())
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	if x.Autoscaling == nil {
+		err = x.Client.Delete(x.Context, autoscaler)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		return nil
+	}
+
+	autoscaler.Object["spec"] = x.buildHorizontalPodAutoscaler().Object["spec"]
+	err = x.Client.Update(x.Context, autoscaler)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
 func (x *XJoinAPISubGraph) Exists() (exists bool, err error) {
 	deployments := &unstructured.UnstructuredList{}
 	deployments.SetGroupVersionKind(common.DeploymentGVK)
@@ -2,8 +2,11 @@ package components
 
 import (
 	"context"
+	"fmt"
 	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
@@ -20,6 +23,7 @@ type XJoinCore struct {
 	SchemaRegistryURL string
 	Namespace         string
 	Schema            string
+	Deployment        v1alpha1.DeploymentSpec
 }
 
 func (xc *XJoinCore) SetName(kind string, name string) {
@@ -35,6 +39,19 @@ func (xc *XJoinCore) Name() string {
 }
 
 func (xc *XJoinCore) Create() (err error) {
+	deployment, err := xc.buildDeployment()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	err = xc.Client.Create(xc.Context, deployment)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	return
+}
+
+func (xc *XJoinCore) buildDeployment() (*unstructured.Unstructured, error) {
 	deployment := &unstructured.Unstructured{}
 
 	labels := map[string]interface{}{
@@ -88,19 +105,8 @@ func (xc *XJoinCore) Create() (err error) {
 								"value": xc.Schema,
 							},
 						},
-						"image":           "quay.io/cloudservices/xjoin-core:latest",
-						"imagePullPolicy": "Always",
-						"name":            xc.Name(),
-						"resources": map[string]interface{}{
-							"limits": map[string]interface{}{
-								"cpu":    "250m",
-								"memory": "512Mi",
-							},
-							"requests": map[string]interface{}{
-								"cpu":    "100m",
-								"memory": "64Mi",
-							},
-						},
+						"image": deploymentImage(xc.Deployment),
+						"name":  xc.Name(),
 					}},
 				},
 			},
@@ -109,12 +115,11 @@ func (xc *XJoinCore) Create() (err error) {
 
 	deployment.SetGroupVersionKind(common.DeploymentGVK)
 
-	err = xc.Client.Create(xc.Context, deployment)
+	err := applyDeploymentSpec(deployment, xc.Deployment)
 	if err != nil {
-		return errors.Wrap(err, 0)
+		return nil, errors.Wrap(err, 0)
 	}
-
-	return
+	return deployment, nil
 }
 
 func (xc *XJoinCore) Delete() (err error) {
@@ -132,7 +137,47 @@ func (xc *XJoinCore) Delete() (err error) {
 	return
 }
 
-func (xc *XJoinCore) CheckDeviation() (problem, err error) {
+func (xc *XJoinCore) Render() (rendered interface{}, err error) {
+	deployment, err := xc.buildDeployment()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return deployment.Object, nil
+}
+
+func (xc *XJoinCore) CheckDeviation() (problems []Problem, err error) {
+	deployment := &unstructured.Unstructured{}
+	deployment.SetGroupVersionKind(common.DeploymentGVK)
+	err = xc.Client.Get(xc.Context, client.ObjectKey{Name: xc.Name(), Namespace: xc.Namespace}, deployment)
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			return append(problems, newMissingProblem(fmt.Sprintf("deployment %s not found", xc.Name()))), nil
+		}
+		return nil, errors.Wrap(err, 0)
+	}
+
+	expected, err := xc.buildDeployment()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	problems, err = checkDeploymentDeviation(expected, deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (xc *XJoinCore) Repair() (err error) {
+	deployment, err := xc.buildDeployment()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = repairDeployment(xc.Context, xc.Client, deployment)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
 	return
 }
 
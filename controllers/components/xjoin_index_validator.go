@@ -2,11 +2,15 @@ package components
 
 import (
 	"context"
+	"fmt"
 	"github.com/go-errors/errors"
 	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
@@ -22,6 +26,7 @@ type XJoinIndexValidator struct {
 	Pause                  bool
 	ParentInstance         client.Object
 	ElasticsearchIndexName string
+	Validation             *v1alpha1.ValidationSpec
 }
 
 func (xv *XJoinIndexValidator) SetName(kind string, name string) {
@@ -36,7 +41,29 @@ func (xv *XJoinIndexValidator) Name() string {
 	return xv.name + "." + xv.version
 }
 
+func (xv *XJoinIndexValidator) spec() (spec map[string]interface{}, err error) {
+	spec = map[string]interface{}{
+		"name":       xv.name,
+		"version":    xv.version,
+		"avroSchema": xv.Schema,
+		"pause":      xv.Pause,
+		"indexName":  xv.ElasticsearchIndexName,
+	}
+	if xv.Validation != nil {
+		spec["validation"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(xv.Validation)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+	}
+	return spec, nil
+}
+
 func (xv *XJoinIndexValidator) Create() (err error) {
+	spec, err := xv.spec()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
 	indexValidator := unstructured.Unstructured{}
 	indexValidator.Object = map[string]interface{}{
 		"metadata": map[string]interface{}{
@@ -47,13 +74,7 @@ func (xv *XJoinIndexValidator) Create() (err error) {
 				"app":                       "xjoin-validator",
 			},
 		},
-		"spec": map[string]interface{}{
-			"name":       xv.name,
-			"version":    xv.version,
-			"avroSchema": xv.Schema,
-			"pause":      xv.Pause,
-			"indexName":  xv.ElasticsearchIndexName,
-		},
+		"spec": spec,
 	}
 	indexValidator.SetGroupVersionKind(common.IndexValidatorGVK)
 
@@ -108,7 +129,65 @@ func (xv *XJoinIndexValidator) Delete() (err error) {
 	return
 }
 
-func (xv *XJoinIndexValidator) CheckDeviation() (problem, err error) {
+func (xv *XJoinIndexValidator) Render() (rendered interface{}, err error) {
+	spec, err := xv.spec()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	return map[string]interface{}{
+		"name": xv.Name(),
+		"spec": spec,
+	}, nil
+}
+
+func (xv *XJoinIndexValidator) CheckDeviation() (problems []Problem, err error) {
+	indexValidator := &unstructured.Unstructured{}
+	indexValidator.SetGroupVersionKind(common.IndexValidatorGVK)
+	err = xv.Client.Get(xv.Context, client.ObjectKey{Name: xv.Name(), Namespace: xv.Namespace}, indexValidator)
+	if err != nil {
+		if k8errors.IsNotFound(err) {
+			return append(problems, newMissingProblem(fmt.Sprintf("validator %s not found", xv.Name()))), nil
+		}
+		return nil, errors.Wrap(err, 0)
+	}
+
+	spec, err := xv.spec()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	expectedSpec, err := toJSONValue(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	actualSpec, err := toJSONValue(indexValidator.Object["spec"])
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	//the validator is also updated by a typed client which omits the zero values of the omitempty fields
+	return compareValuesOmitting("spec", expectedSpec, actualSpec,
+		omitEmptyPaths("spec", reflect.TypeOf(v1alpha1.XJoinIndexValidatorSpec{}))), nil
+}
+
+func (xv *XJoinIndexValidator) Repair() (err error) {
+	indexValidator := &unstructured.Unstructured{}
+	indexValidator.SetGroupVersionKind(common.IndexValidatorGVK)
+	err = xv.Client.Get(xv.Context, client.ObjectKey{Name: xv.Name(), Namespace: xv.Namespace}, indexValidator)
+	if k8errors.IsNotFound(err) {
+		return xv.Create()
+	} else if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	indexValidator.Object["spec"], err = xv.spec()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	err = xv.Client.Update(xv.Context, indexValidator)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
 	return
 }
 
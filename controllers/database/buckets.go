@@ -0,0 +1,67 @@
+package database
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/lib/pq"
+)
+
+// Bucket is the number of records in a hash range and the sum of their checksums
+type Bucket struct {
+	Count    int
+	Checksum uint64
+}
+
+// Add adds the checksum of a record to the bucket
+func (b *Bucket) Add(checksum uint64) {
+	b.Count++
+	b.Checksum += checksum
+}
+
+// IdBucket returns the hash range of an id. The first 32 bits of the md5 of the id select one of buckets equal hash
+// ranges. Must match bucketExpression.
+func IdBucket(id string, buckets int) int {
+	sum := md5.Sum([]byte(id))
+	return int(uint64(binary.BigEndian.Uint32(sum[0:4])) * uint64(buckets) >> 32)
+}
+
+// RecordHash returns the hash range of a record and the checksum of its id and content. The hash range is selected by
+// the id, so a record stays in its range when its content changes.
+func RecordHash(id string, content string, buckets int) (bucket int, checksum uint64) {
+	sum := md5.Sum([]byte(id + "\x00" + content))
+	return IdBucket(id, buckets), binary.BigEndian.Uint64(sum[0:8])
+}
+
+func bucketExpression(primaryKey string, buckets int) string {
+	return fmt.Sprintf("(('x' || substr(md5(%s::text), 1, 8))::bit(32)::bigint * %d / 4294967296)",
+		pq.QuoteIdentifier(primaryKey), buckets)
+}
+
+// RecordIdsInBuckets returns the primary key of each row of a table whose id is in one of the selected hash ranges
+func (db *Database) RecordIdsInBuckets(schema string, table string, primaryKey string, buckets int,
+	selected []int) ([]string, error) {
+
+	if len(selected) == 0 {
+		return nil, nil
+	}
+
+	selectedBuckets := make([]string, len(selected))
+	for idx, bucket := range selected {
+		selectedBuckets[idx] = strconv.Itoa(bucket)
+	}
+
+	ids, err := db.QueryIds(fmt.Sprintf("SELECT %s::text FROM %s WHERE %s IN (%s)",
+		pq.QuoteIdentifier(primaryKey),
+		qualifiedTableName(schema, table),
+		bucketExpression(primaryKey, buckets),
+		strings.Join(selectedBuckets, ", ")))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return ids, nil
+}
@@ -84,7 +84,9 @@ func (db *Database) GetConnection() (connection *sqlx.DB, err error) {
 
 func (db *Database) Close() error {
 	if db.connection != nil {
-		return db.connection.Close()
+		err := db.connection.Close()
+		db.connection = nil
+		return err
 	}
 
 	return nil
@@ -97,11 +99,11 @@ func (db *Database) SetMaxConnections(numConnections int) {
 	}
 }
 
-func (db *Database) RunQuery(query string) (*sqlx.Rows, error) {
+func (db *Database) RunQuery(query string, args ...interface{}) (*sqlx.Rows, error) {
 	if db.connection == nil {
 		return nil, errors.New("cannot run query because there is no database connection")
 	}
-	rows, err := db.connection.Queryx(query)
+	rows, err := db.connection.Queryx(query, args...)
 
 	if err != nil {
 		return nil, fmt.Errorf("error executing query (%s) : %w", query, err)
@@ -110,11 +112,11 @@ func (db *Database) RunQuery(query string) (*sqlx.Rows, error) {
 	return rows, nil
 }
 
-func (db *Database) ExecQuery(query string) (result sql.Result, err error) {
+func (db *Database) ExecQuery(query string, args ...interface{}) (result sql.Result, err error) {
 	if db.connection == nil {
 		return nil, errors.New("cannot run query because there is no database connection")
 	}
-	result, err = db.connection.Exec(query)
+	result, err = db.connection.Exec(query, args...)
 
 	if err != nil {
 		return result, fmt.Errorf("error executing query (%s) : %w", query, err)
@@ -271,8 +273,8 @@ func (db *Database) CountHosts() (int, error) {
 	return response, err
 }
 
-func (db *Database) QueryIds(query string) ([]string, error) {
-	rows, err := db.RunQuery(query)
+func (db *Database) QueryIds(query string, args ...interface{}) ([]string, error) {
+	rows, err := db.RunQuery(query, args...)
 	defer closeRows(rows)
 
 	var ids []string
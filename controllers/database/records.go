@@ -0,0 +1,219 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/lib/pq"
+)
+
+// SplitTableName splits a table name into its schema and table e.g. public.hosts -> public, hosts.
+// The schema defaults to public.
+func SplitTableName(name string) (schema string, table string) {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) == 1 {
+		return "public", parts[0]
+	}
+	return parts[0], parts[1]
+}
+
+// CountRows returns the number of rows of a table
+func (db *Database) CountRows(schema string, table string) (int, error) {
+	rows, err := db.RunQuery(fmt.Sprintf("SELECT count(*) FROM %s", qualifiedTableName(schema, table)))
+	defer closeRows(rows)
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+
+	var count int
+	if rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return 0, errors.Wrap(err, 0)
+		}
+	}
+	return count, nil
+}
+
+// RecordIds returns the primary key of each row of a table as text
+func (db *Database) RecordIds(schema string, table string, primaryKey string) ([]string, error) {
+	ids, err := db.QueryIds(fmt.Sprintf("SELECT %s::text FROM %s",
+		pq.QuoteIdentifier(primaryKey), qualifiedTableName(schema, table)))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return ids, nil
+}
+
+// RecordsByIds returns the columns of the rows with the given primary keys as json objects keyed by the primary key.
+// Numbers are decoded as json.Number so they are compared without losing precision. The ids are bound as an array
+// of the primary key's type so the rows are looked up by the primary key index.
+func (db *Database) RecordsByIds(schema string, table string, primaryKey string, columns []string, ids []string) (
+	map[string]map[string]interface{}, error) {
+
+	records := make(map[string]map[string]interface{})
+	if len(ids) == 0 {
+		return records, nil
+	}
+
+	rows, err := db.RunQuery(fmt.Sprintf(
+		"SELECT %s::text, row_to_json(r)::text FROM (SELECT %s FROM %s WHERE %s = ANY($1)) r",
+		pq.QuoteIdentifier(primaryKey),
+		strings.Join(quotedColumns(primaryKey, columns), ", "),
+		qualifiedTableName(schema, table),
+		pq.QuoteIdentifier(primaryKey)),
+		pq.Array(ids))
+	defer closeRows(rows)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	for rows.Next() {
+		var id, row string
+		err = rows.Scan(&id, &row)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+
+		records[id], err = decodeRecord(row)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+	}
+
+	return records, nil
+}
+
+// ScrollRecords calls fn with the primary key and the columns of each row of a table, the rows are read one at a
+// time so they don't have to fit in memory. Numbers are decoded as json.Number like in RecordsByIds.
+func (db *Database) ScrollRecords(schema string, table string, primaryKey string, columns []string,
+	fn func(id string, record map[string]interface{})) error {
+
+	rows, err := db.RunQuery(fmt.Sprintf(
+		"SELECT %s::text, row_to_json(r)::text FROM (SELECT %s FROM %s) r",
+		pq.QuoteIdentifier(primaryKey),
+		strings.Join(quotedColumns(primaryKey, columns), ", "),
+		qualifiedTableName(schema, table)))
+	defer closeRows(rows)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	for rows.Next() {
+		var id, row string
+		err = rows.Scan(&id, &row)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+
+		record, err := decodeRecord(row)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		fn(id, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// quotedColumns returns the quoted primary key followed by the other quoted columns
+func quotedColumns(primaryKey string, columns []string) []string {
+	quoted := []string{pq.QuoteIdentifier(primaryKey)}
+	for _, column := range columns {
+		if column != primaryKey {
+			quoted = append(quoted, pq.QuoteIdentifier(column))
+		}
+	}
+	return quoted
+}
+
+func decodeRecord(row string) (record map[string]interface{}, err error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(row)))
+	decoder.UseNumber()
+	err = decoder.Decode(&record)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return record, nil
+}
+
+// TouchRows updates the primary key of the rows with the given primary keys to its current value. Each update is
+// written to the WAL, so the rows are emitted again by the Debezium connector that reads the table. The ids are bound
+// as an array of the primary key's type so the rows are looked up by the primary key index. The database user needs
+// the UPDATE privilege on the table.
+func (db *Database) TouchRows(schema string, table string, primaryKey string, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := db.ExecQuery(fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = ANY($1)",
+		qualifiedTableName(schema, table),
+		pq.QuoteIdentifier(primaryKey),
+		pq.QuoteIdentifier(primaryKey),
+		pq.QuoteIdentifier(primaryKey)),
+		pq.Array(ids))
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+
+	touched, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+	return touched, nil
+}
+
+// SampleRecordIds returns the primary keys of a random sample of about size rows of a table with count rows as text.
+// The pages of the table are sampled with TABLESAMPLE SYSTEM, so only the sampled pages are read instead of the whole
+// table. Twice the needed share of the pages is sampled, then size rows are drawn from the sampled rows.
+func (db *Database) SampleRecordIds(schema string, table string, primaryKey string, size int, count int) (
+	[]string, error) {
+
+	percentage := 100.0
+	if count > 0 {
+		percentage = math.Min(percentage, 200*float64(size)/float64(count))
+	}
+
+	ids, err := db.QueryIds(fmt.Sprintf("SELECT %s::text FROM %s TABLESAMPLE SYSTEM (%s) ORDER BY random() LIMIT %d",
+		pq.QuoteIdentifier(primaryKey),
+		qualifiedTableName(schema, table),
+		strconv.FormatFloat(percentage, 'f', -1, 64),
+		size))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return ids, nil
+}
+
+// RecordIdsAfter returns up to limit primary keys as text that follow after in the order of the primary key. The
+// position is bound as the primary key's type so the rows are read from the primary key index, an empty position
+// starts with the first row.
+func (db *Database) RecordIdsAfter(schema string, table string, primaryKey string, after string, limit int) (
+	[]string, error) {
+
+	var condition string
+	var args []interface{}
+	if after != "" {
+		condition = fmt.Sprintf(" WHERE %s > $1", pq.QuoteIdentifier(primaryKey))
+		args = append(args, after)
+	}
+
+	ids, err := db.QueryIds(fmt.Sprintf("SELECT %s::text FROM %s%s ORDER BY %s LIMIT %d",
+		pq.QuoteIdentifier(primaryKey),
+		qualifiedTableName(schema, table),
+		condition,
+		pq.QuoteIdentifier(primaryKey),
+		limit), args...)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return ids, nil
+}
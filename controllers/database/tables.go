@@ -0,0 +1,132 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/lib/pq"
+)
+
+// Column is a column of a table created by the operator.
+// Type is the canonical PostgreSQL type name as returned by format_type e.g. "text[]", "timestamp with time zone"
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TableName converts a resource name into a PostgreSQL table name e.g. xjoinindexpipeline.hosts.1234 ->
+// xjoinindexpipeline_hosts_1234
+func TableName(resourceName string) string {
+	return strings.ToLower(strings.NewReplacer(".", "_", "-", "_").Replace(resourceName))
+}
+
+func qualifiedTableName(schema string, table string) string {
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table)
+}
+
+// CreateTableQuery builds the DDL of a table, primaryKey is added as the first column
+func CreateTableQuery(schema string, table string, primaryKey string, columns []Column) string {
+	definitions := []string{pq.QuoteIdentifier(primaryKey) + " text PRIMARY KEY"}
+	for _, column := range columns {
+		definitions = append(definitions, pq.QuoteIdentifier(column.Name)+" "+column.Type)
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)",
+		qualifiedTableName(schema, table), strings.Join(definitions, ", "))
+}
+
+func (db *Database) CreateTable(schema string, table string, primaryKey string, columns []Column) error {
+	_, err := db.ExecQuery(CreateTableQuery(schema, table, primaryKey, columns))
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// AddColumns adds the columns that don't exist yet to a table
+func (db *Database) AddColumns(schema string, table string, columns []Column) error {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	var definitions []string
+	for _, column := range columns {
+		definitions = append(definitions,
+			"ADD COLUMN IF NOT EXISTS "+pq.QuoteIdentifier(column.Name)+" "+column.Type)
+	}
+
+	_, err := db.ExecQuery(fmt.Sprintf("ALTER TABLE %s %s",
+		qualifiedTableName(schema, table), strings.Join(definitions, ", ")))
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+func (db *Database) DropTable(schema string, table string) error {
+	_, err := db.ExecQuery(fmt.Sprintf("DROP TABLE IF EXISTS %s", qualifiedTableName(schema, table)))
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+func (db *Database) TableExists(schema string, table string) (bool, error) {
+	rows, err := db.RunQuery(fmt.Sprintf(
+		"SELECT 1 FROM information_schema.tables WHERE table_schema = %s AND table_name = %s",
+		pq.QuoteLiteral(schema), pq.QuoteLiteral(table)))
+	defer closeRows(rows)
+	if err != nil {
+		return false, errors.Wrap(err, 0)
+	}
+
+	return rows.Next(), nil
+}
+
+// ListTablesForPrefix lists the names of the tables in schema which start with prefix
+func (db *Database) ListTablesForPrefix(schema string, prefix string) ([]string, error) {
+	rows, err := db.RunQuery(fmt.Sprintf(
+		"SELECT table_name FROM information_schema.tables WHERE table_schema = %s",
+		pq.QuoteLiteral(schema)))
+	defer closeRows(rows)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		if strings.Index(table, prefix) == 0 {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// TableColumns returns the canonical type of each column of a table
+func (db *Database) TableColumns(schema string, table string) (map[string]string, error) {
+	rows, err := db.RunQuery(fmt.Sprintf(
+		`SELECT a.attname, format_type(a.atttypid, a.atttypmod) FROM pg_catalog.pg_attribute a
+			WHERE a.attrelid = %s::regclass AND a.attnum > 0 AND NOT a.attisdropped`,
+		pq.QuoteLiteral(qualifiedTableName(schema, table))))
+	defer closeRows(rows)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, columnType string
+		err = rows.Scan(&name, &columnType)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		columns[name] = columnType
+	}
+	return columns, nil
+}
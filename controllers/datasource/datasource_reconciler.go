@@ -69,7 +69,24 @@ func (d *ReconcileMethods) Refreshing() (err error) {
 }
 
 func (d *ReconcileMethods) RefreshComplete() (err error) {
-	err = d.iteration.DeleteDataSourcePipeline(d.iteration.GetInstance().Name, d.iteration.GetInstance().Status.ActiveVersion)
+	instance := d.iteration.GetInstance()
+	if instance.Status.ActiveVersion == "" {
+		return
+	}
+
+	//the previous version is kept until every IndexPipeline using it has been refreshed
+	usedVersions, err := d.iteration.IndexPipelineVersions()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	if usedVersions[instance.Status.ActiveVersion] {
+		d.iteration.Log.Info("Retaining the previous DataSourcePipeline until the IndexPipelines using it are refreshed",
+			"version", instance.Status.ActiveVersion)
+		instance.Status.RetainedVersions = append(instance.Status.RetainedVersions, instance.Status.ActiveVersion)
+		return
+	}
+
+	err = d.iteration.DeleteDataSourcePipeline(instance.Name, instance.Status.ActiveVersion)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
@@ -84,6 +101,7 @@ func (d *ReconcileMethods) Scrub() (errs []error) {
 	if d.iteration.GetInstance().Status.RefreshingVersion != "" {
 		validVersions = append(validVersions, d.iteration.GetInstance().Status.RefreshingVersion)
 	}
+	validVersions = append(validVersions, d.iteration.GetInstance().Status.RetainedVersions...)
 
 	kafkaClient := kafka.GenericKafka{
 		Context:          d.iteration.Context,
@@ -29,6 +29,11 @@ func (d *DataSourcePipelineChild) Create(version string) (err error) {
 }
 
 func (d *DataSourcePipelineChild) Delete(version string) (err error) {
+	//retained versions are deleted by ReleaseRetainedVersions once no IndexPipeline uses them
+	if d.iteration.IsRetainedVersion(version) {
+		return
+	}
+
 	err = d.iteration.DeleteDataSourcePipeline(d.iteration.GetInstance().GetName(), version)
 	if err != nil {
 		return errors.Wrap(err, 0)
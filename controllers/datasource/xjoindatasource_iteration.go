@@ -7,6 +7,7 @@ import (
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
 	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
@@ -54,6 +55,75 @@ func (i *XJoinDataSourceIteration) DeleteDataSourcePipeline(name string, version
 	return
 }
 
+// UpdateDataSourcePipelineAvroSchema applies the avro schema of the spec to an existing DataSourcePipeline, the
+// DataSourcePipeline then updates its components in place
+func (i *XJoinDataSourceIteration) UpdateDataSourcePipelineAvroSchema(
+	dataSourcePipeline *v1alpha1.XJoinDataSourcePipeline) (err error) {
+
+	dataSourcePipeline.Spec.AvroSchema = i.Parameters.AvroSchema.String()
+	err = i.Client.Update(i.Context, dataSourcePipeline)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+// IndexPipelineVersions returns the versions of the DataSource used by the IndexPipelines in the namespace
+func (i *XJoinDataSourceIteration) IndexPipelineVersions() (versions map[string]bool, err error) {
+	indexPipelines := &v1alpha1.XJoinIndexPipelineList{}
+	err = i.Client.List(i.Context, indexPipelines, client.InNamespace(i.GetInstance().GetNamespace()))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	versions = make(map[string]bool)
+	for _, indexPipeline := range indexPipelines.Items {
+		if dataSource, ok := indexPipeline.Status.DataSources[i.GetInstance().GetName()]; ok {
+			versions[dataSource.Version] = true
+		}
+	}
+	return versions, nil
+}
+
+// IsRetainedVersion is true when the DataSourcePipeline of the version is kept for the IndexPipelines using it
+func (i *XJoinDataSourceIteration) IsRetainedVersion(version string) bool {
+	return utils.ContainsString(i.GetInstance().Status.RetainedVersions, version)
+}
+
+// ReleaseRetainedVersions deletes the DataSourcePipelines of the retained versions that are no longer used by any
+// IndexPipeline
+func (i *XJoinDataSourceIteration) ReleaseRetainedVersions() (err error) {
+	instance := i.GetInstance()
+	if len(instance.Status.RetainedVersions) == 0 {
+		return
+	}
+
+	usedVersions, err := i.IndexPipelineVersions()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	var retainedVersions []string
+	for _, version := range instance.Status.RetainedVersions {
+		if version == instance.Status.ActiveVersion || version == instance.Status.RefreshingVersion {
+			continue
+		} else if usedVersions[version] {
+			retainedVersions = append(retainedVersions, version)
+			continue
+		}
+
+		i.Log.Info("Deleting the retained DataSourcePipeline, it is no longer used by any IndexPipeline",
+			"version", version)
+		err = i.DeleteDataSourcePipeline(instance.GetName(), version)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	instance.Status.RetainedVersions = retainedVersions
+
+	return
+}
+
 func (i *XJoinDataSourceIteration) ReconcilePipelines() (err error) {
 	child := NewDataSourcePipelineChild(i)
 	err = i.ReconcileChild(child)
@@ -23,6 +23,7 @@ type DatasourceTestReconciler struct {
 	Name               string
 	K8sClient          client.Client
 	AvroSchemaFileName string
+	Annotations        map[string]string
 	createdDatasource  v1alpha1.XJoinDataSource
 }
 
@@ -92,6 +93,23 @@ func (d *DatasourceTestReconciler) ReconcileValid() v1alpha1.XJoinDataSource {
 	return *updatedDatasource
 }
 
+// ReconcilePlan creates an XJoinDataSource with the plan annotation and reconciles it
+func (d *DatasourceTestReconciler) ReconcilePlan() v1alpha1.XJoinDataSource {
+	d.Annotations = map[string]string{v1alpha1.PlanAnnotation: "true"}
+	d.registerNewMocks()
+	d.createValidDataSource()
+	result := d.reconcile()
+	Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 30000000000}))
+	return d.GetDataSource()
+}
+
+// RemovePlanAnnotation removes the plan annotation of the XJoinDataSource so the next reconcile applies the changes
+func (d *DatasourceTestReconciler) RemovePlanAnnotation() {
+	dataSource := d.GetDataSource()
+	delete(dataSource.Annotations, v1alpha1.PlanAnnotation)
+	checkError(d.K8sClient.Update(context.Background(), &dataSource))
+}
+
 func (d *DatasourceTestReconciler) ReconcileDelete() {
 	d.registerDeleteMocks()
 	result := d.reconcile()
@@ -138,8 +156,9 @@ func (d *DatasourceTestReconciler) createValidDataSource() {
 
 	datasource := &v1alpha1.XJoinDataSource{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      d.Name,
-			Namespace: d.Namespace,
+			Name:        d.Name,
+			Namespace:   d.Namespace,
+			Annotations: d.Annotations,
 		},
 		Spec: datasourceSpec,
 		TypeMeta: metav1.TypeMeta{
@@ -25,6 +25,8 @@ type DatasourcePipelineTestReconciler struct {
 	Name               string
 	K8sClient          client.Client
 	AvroSchemaFileName string
+	//the schema of the latest version of the registry subject, defaults to the pipeline's avro schema
+	registeredAvroSchema string
 }
 
 func (d *DatasourcePipelineTestReconciler) newXJoinDataSourcePipelineReconciler() *controllers.XJoinDataSourcePipelineReconciler {
@@ -37,18 +39,19 @@ func (d *DatasourcePipelineTestReconciler) newXJoinDataSourcePipelineReconciler(
 		true)
 }
 
+// avroSchema optionally loads the avro schema of the pipeline from a file
+func (d *DatasourcePipelineTestReconciler) avroSchema() string {
+	if d.AvroSchemaFileName == "" {
+		return "{}"
+	}
+	datasourceAvroSchemaBytes, err := os.ReadFile("./test/data/avro/" + d.AvroSchemaFileName + ".json")
+	Expect(err).ToNot(HaveOccurred())
+	return string(datasourceAvroSchemaBytes)
+}
+
 func (d *DatasourcePipelineTestReconciler) CreateValidDataSourcePipeline() {
 	ctx := context.Background()
-
-	//optionally load avro schema from file
-	var datasourceAvroSchema string
-	if d.AvroSchemaFileName != "" {
-		datasourceAvroSchemaBytes, err := os.ReadFile("./test/data/avro/" + d.AvroSchemaFileName + ".json")
-		Expect(err).ToNot(HaveOccurred())
-		datasourceAvroSchema = string(datasourceAvroSchemaBytes)
-	} else {
-		datasourceAvroSchema = "{}"
-	}
+	datasourceAvroSchema := d.avroSchema()
 
 	datasourceSpec := v1alpha1.XJoinDataSourcePipelineSpec{
 		Name:             d.Name,
@@ -230,23 +233,33 @@ func (d *DatasourcePipelineTestReconciler) registerNewMocks() {
 	httpmock.RegisterResponder(
 		"GET",
 		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+d.Name+".1234-value/versions/latest",
-		httpmock.NewStringResponder(200, "{}"))
+		httpmock.NewStringResponder(200, registeredAvroSchemaResponse(
+			"XJoinDataSourcePipeline", d.Name, "1234", d.avroSchema())))
 }
 
 func (d *DatasourcePipelineTestReconciler) registerValidMocks() {
 	httpmock.Reset()
 	httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip) //disable mocks for unregistered http requests
 
+	dataSourcePipeline := &v1alpha1.XJoinDataSourcePipeline{}
+	k8sGet(types.NamespacedName{Name: d.Name, Namespace: d.Namespace}, dataSourcePipeline)
+	subject := "xjoindatasourcepipeline." + dataSourcePipeline.Spec.Name + "." + dataSourcePipeline.Spec.Version + "-value"
+	registeredAvroSchema := dataSourcePipeline.Spec.AvroSchema
+	if d.registeredAvroSchema != "" {
+		registeredAvroSchema = d.registeredAvroSchema
+	}
+
 	//avro schema mocks
 	httpmock.RegisterResponder(
 		"GET",
-		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+d.Name+"-value/versions/1",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/"+subject+"/versions/1",
 		httpmock.NewStringResponder(200, `{}`).Times(1))
 
 	httpmock.RegisterResponder(
 		"GET",
-		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+d.Name+"-value/versions/latest",
-		httpmock.NewStringResponder(200, "{}"))
+		"http://apicurio:1080/apis/ccompat/v6/subjects/"+subject+"/versions/latest",
+		httpmock.NewStringResponder(200, registeredAvroSchemaResponse("XJoinDataSourcePipeline",
+			dataSourcePipeline.Spec.Name, dataSourcePipeline.Spec.Version, registeredAvroSchema)))
 
 	httpmock.RegisterResponder(
 		"GET",
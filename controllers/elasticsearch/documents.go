@@ -0,0 +1,338 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/go-errors/errors"
+)
+
+const scrollPageSize = 5000
+
+// documentsResponse is a page of search results, numbers in the documents are decoded as json.Number
+type documentsResponse struct {
+	ScrollID string `json:"_scroll_id"`
+	Count    int    `json:"count"`
+	Hits     struct {
+		Hits []struct {
+			Source map[string]interface{} `json:"_source"`
+			Sort   []interface{}          `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// CountDocumentsWithField returns the number of documents of an index that contain a value for field
+func (es GenericElasticsearch) CountDocumentsWithField(index string, field string) (int, error) {
+	query, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{"exists": map[string]interface{}{"field": field}},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+
+	req := esapi.CountRequest{
+		Index: []string{index},
+		Body:  bytes.NewReader(query),
+	}
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+
+	response, err := parseDocumentsResponse(res)
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+	return response.Count, nil
+}
+
+// FieldValues returns the value of field of each document of an index that contains the field. The documents are
+// retrieved with a scroll.
+func (es GenericElasticsearch) FieldValues(index string, field string) (values []string, err error) {
+	err = es.ScrollFieldValues(index, field, func(value string) {
+		values = append(values, value)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return values, nil
+}
+
+// ScrollFieldValues calls fn with the value of field of each document of an index that contains the field. The
+// documents are retrieved with a scroll, so the values don't have to fit in memory.
+func (es GenericElasticsearch) ScrollFieldValues(index string, field string, fn func(value string)) error {
+	err := es.scrollDocuments(index, field, field, func(value string, source map[string]interface{}) {
+		fn(value)
+	})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// ScrollFieldSources calls fn with the value of field and the value at path of each document of an index that
+// contains the field, e.g. the id and the record of a data source. The documents are retrieved with a scroll.
+func (es GenericElasticsearch) ScrollFieldSources(index string, field string, path string,
+	fn func(value string, source interface{})) error {
+
+	err := es.scrollDocuments(index, field, path, func(value string, source map[string]interface{}) {
+		sourceValue, _ := SourceValue(source, path)
+		fn(value, sourceValue)
+	})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// scrollDocuments calls fn with the value of field and the source of each document that contains the field. Only the
+// sourcePath of the documents is retrieved.
+func (es GenericElasticsearch) scrollDocuments(index string, field string, sourcePath string,
+	fn func(value string, source map[string]interface{})) error {
+
+	query, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{"exists": map[string]interface{}{"field": field}},
+	})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	size := scrollPageSize
+	req := esapi.SearchRequest{
+		Index:  []string{index},
+		Scroll: time.Minute,
+		Body:   bytes.NewReader(query),
+		Source: []string{sourcePath},
+		Size:   &size,
+		Sort:   []string{"_doc"},
+	}
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	response, err := parseDocumentsResponse(res)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	scrollID := response.ScrollID
+	defer es.clearScroll(scrollID)
+
+	for len(response.Hits.Hits) > 0 {
+		for _, hit := range response.Hits.Hits {
+			if value, ok := SourceValue(hit.Source, field); ok {
+				fn(FormatValue(value), hit.Source)
+			}
+		}
+
+		scrollReq := esapi.ScrollRequest{
+			Scroll:   time.Minute,
+			ScrollID: scrollID,
+		}
+		res, err = scrollReq.Do(es.Context, es.Client)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		response, err = parseDocumentsResponse(res)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if response.ScrollID != "" {
+			scrollID = response.ScrollID
+		}
+	}
+
+	return nil
+}
+
+// DocumentsByFieldValues returns the documents of an index whose field matches one of values, keyed by the value
+func (es GenericElasticsearch) DocumentsByFieldValues(index string, field string, values []string) (
+	map[string]map[string]interface{}, error) {
+
+	documents := make(map[string]map[string]interface{})
+	if len(values) == 0 {
+		return documents, nil
+	}
+
+	query, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{"terms": map[string]interface{}{field: values}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	size := len(values)
+	req := esapi.SearchRequest{
+		Index: []string{index},
+		Body:  bytes.NewReader(query),
+		Size:  &size,
+	}
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	response, err := parseDocumentsResponse(res)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	for _, hit := range response.Hits.Hits {
+		if value, ok := SourceValue(hit.Source, field); ok {
+			documents[FormatValue(value)] = hit.Source
+		}
+	}
+	return documents, nil
+}
+
+// SampleFieldValues returns the value of field of a random sample of size documents that contain the field
+func (es GenericElasticsearch) SampleFieldValues(index string, field string, size int) (values []string, err error) {
+	query, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{
+			"function_score": map[string]interface{}{
+				"query":        map[string]interface{}{"exists": map[string]interface{}{"field": field}},
+				"random_score": map[string]interface{}{},
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	req := esapi.SearchRequest{
+		Index:  []string{index},
+		Body:   bytes.NewReader(query),
+		Source: []string{field},
+		Size:   &size,
+	}
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	response, err := parseDocumentsResponse(res)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	for _, hit := range response.Hits.Hits {
+		if value, ok := SourceValue(hit.Source, field); ok {
+			values = append(values, FormatValue(value))
+		}
+	}
+	return values, nil
+}
+
+// FieldValuesAfter returns the value of field of up to size documents sorted by field, starting after the sort value
+// of a previous call. after is empty for the first page. The sort value of the last document is returned as json.
+func (es GenericElasticsearch) FieldValuesAfter(index string, field string, after string, size int) (
+	values []string, last string, err error) {
+
+	body := map[string]interface{}{
+		"query": map[string]interface{}{"exists": map[string]interface{}{"field": field}},
+		"sort":  []interface{}{map[string]interface{}{field: "asc"}},
+	}
+	if after != "" {
+		body["search_after"] = json.RawMessage(after)
+	}
+	query, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", errors.Wrap(err, 0)
+	}
+
+	req := esapi.SearchRequest{
+		Index:  []string{index},
+		Body:   bytes.NewReader(query),
+		Source: []string{field},
+		Size:   &size,
+	}
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		return nil, "", errors.Wrap(err, 0)
+	}
+	response, err := parseDocumentsResponse(res)
+	if err != nil {
+		return nil, "", errors.Wrap(err, 0)
+	}
+
+	for _, hit := range response.Hits.Hits {
+		if value, ok := SourceValue(hit.Source, field); ok {
+			values = append(values, FormatValue(value))
+		}
+	}
+
+	if len(response.Hits.Hits) > 0 {
+		sort, err := json.Marshal(response.Hits.Hits[len(response.Hits.Hits)-1].Sort)
+		if err != nil {
+			return nil, "", errors.Wrap(err, 0)
+		}
+		last = string(sort)
+	}
+	return values, last, nil
+}
+
+func (es GenericElasticsearch) clearScroll(scrollID string) {
+	if scrollID == "" {
+		return
+	}
+
+	req := esapi.ClearScrollRequest{ScrollID: []string{scrollID}}
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		log.Error(errors.Wrap(err, 0), "Unable to clear scroll")
+		return
+	}
+	_ = res.Body.Close()
+}
+
+func parseDocumentsResponse(res *esapi.Response) (response documentsResponse, err error) {
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return response, errors.Wrap(err, 0)
+	}
+	if res.IsError() {
+		return response, errors.Wrap(fmt.Errorf(
+			"Elasticsearch API error: %d, %s", res.StatusCode, string(body)), 0)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err = decoder.Decode(&response)
+	if err != nil {
+		return response, errors.Wrap(err, 0)
+	}
+	return response, nil
+}
+
+// SourceValue returns the value at the dot separated path of a document
+func SourceValue(source map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = source
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// FormatValue formats a scalar value of a document the same way as PostgreSQL casts it to text
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -159,6 +159,28 @@ func (es GenericElasticsearch) PipelineExists(name string) (exists bool, err err
 	}
 }
 
+// GetPipeline returns the definition of an ingest pipeline, nil if the pipeline doesn't exist
+func (es GenericElasticsearch) GetPipeline(name string) (pipeline map[string]interface{}, err error) {
+	req := esapi.IngestGetPipelineRequest{
+		DocumentID: name,
+	}
+
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	resCode, body, err := parseResponse(res)
+	if resCode == 404 {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	pipeline, _ = body[name].(map[string]interface{})
+	return pipeline, nil
+}
+
 func (es GenericElasticsearch) ListPipelinesForPrefix(prefix string) (esPipelines []string, err error) {
 	req := esapi.IngestGetPipelineRequest{
 		DocumentID: prefix + "*",
@@ -188,13 +210,77 @@ func (es GenericElasticsearch) ListPipelinesForPrefix(prefix string) (esPipeline
 }
 
 func (es GenericElasticsearch) CreateIndex(
-	indexName string, indexTemplate string, properties string, withPipeline bool) error {
+	indexName string, indexTemplate string, properties string, analysis string, withPipeline bool) error {
 
-	tmpl, err := template.New("indexTemplate").Parse(indexTemplate)
+	indexTemplateParsed, err := es.RenderIndexTemplate(indexName, indexTemplate, properties, analysis, withPipeline)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
 
+	req := &esapi.IndicesCreateRequest{
+		Index: indexName,
+		Body:  strings.NewReader(indexTemplateParsed),
+	}
+
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	_, _, err = parseResponse(res)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// PutMapping puts the mappings of the rendered index template on an existing index. Elasticsearch adds the new fields
+// and rejects changes to the existing fields.
+func (es GenericElasticsearch) PutMapping(
+	indexName string, indexTemplate string, properties string, analysis string, withPipeline bool) error {
+
+	indexTemplateParsed, err := es.RenderIndexTemplate(indexName, indexTemplate, properties, analysis, withPipeline)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	var body map[string]interface{}
+	err = json.Unmarshal([]byte(indexTemplateParsed), &body)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	mappings, err := json.Marshal(childMap(body, "mappings"))
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	req := &esapi.IndicesPutMappingRequest{
+		Index: []string{indexName},
+		Body:  bytes.NewReader(mappings),
+	}
+
+	res, err := req.Do(es.Context, es.Client)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	_, _, err = parseResponse(res)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// RenderIndexTemplate returns the body used to create an index. analysis contains the analyzers and normalizers
+// generated from the avro schema, they are added to the settings of the index.
+func (es GenericElasticsearch) RenderIndexTemplate(
+	indexName string, indexTemplate string, properties string, analysis string, withPipeline bool) (string, error) {
+
+	tmpl, err := template.New("indexTemplate").Parse(indexTemplate)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
 	params := es.Parameters
 	params["ElasticSearchIndex"] = indexName
 	params["ElasticSearchProperties"] = properties
@@ -208,25 +294,99 @@ func (es GenericElasticsearch) CreateIndex(
 	var indexTemplateBuffer bytes.Buffer
 	err = tmpl.Execute(&indexTemplateBuffer, params)
 	if err != nil {
-		return errors.Wrap(err, 0)
+		return "", errors.Wrap(err, 0)
 	}
 	indexTemplateParsed := indexTemplateBuffer.String()
 	indexTemplateParsed = strings.ReplaceAll(indexTemplateParsed, "\n", "")
 	indexTemplateParsed = strings.ReplaceAll(indexTemplateParsed, "\t", "")
 
-	req := &esapi.IndicesCreateRequest{
-		Index: indexName,
-		Body:  strings.NewReader(indexTemplateParsed),
+	indexTemplateParsed, err = mergeIndexAnalysis(indexTemplateParsed, analysis)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return indexTemplateParsed, nil
+}
+
+// mergeIndexAnalysis adds the generated analysis settings to a rendered index template. The analyzers, normalizers
+// etc. defined by the template take precedence over the generated ones with the same name.
+func mergeIndexAnalysis(indexBody string, analysis string) (string, error) {
+	if indexBody == "" || analysis == "" {
+		return indexBody, nil
+	}
+
+	var body map[string]interface{}
+	err := json.Unmarshal([]byte(indexBody), &body)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
+	var generated map[string]interface{}
+	err = json.Unmarshal([]byte(analysis), &generated)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
+	settings := childMap(body, "settings")
+	var templateAnalysis map[string]interface{}
+	if index, ok := settings["index"].(map[string]interface{}); ok && index["analysis"] != nil {
+		templateAnalysis = childMap(index, "analysis")
+	} else {
+		templateAnalysis = childMap(settings, "analysis")
+	}
+
+	for section, definitions := range generated {
+		definitionsMap, ok := definitions.(map[string]interface{})
+		if !ok {
+			return "", errors.Wrap(fmt.Errorf("analysis.%s must be an object", section), 0)
+		}
+		templateSection := childMap(templateAnalysis, section)
+		for name, definition := range definitionsMap {
+			if _, exists := templateSection[name]; !exists {
+				templateSection[name] = definition
+			}
+		}
+	}
+
+	merged, err := json.Marshal(body)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return string(merged), nil
+}
+
+// childMap returns parent[key], the child is created when it doesn't exist
+func childMap(parent map[string]interface{}, key string) map[string]interface{} {
+	child, ok := parent[key].(map[string]interface{})
+	if !ok {
+		child = make(map[string]interface{})
+		parent[key] = child
+	}
+	return child
+}
+
+// GetIndex returns the mappings and settings of an index
+func (es GenericElasticsearch) GetIndex(indexName string) (mappings map[string]interface{}, settings map[string]interface{}, err error) {
+	req := &esapi.IndicesGetRequest{
+		Index: []string{indexName},
 	}
 
 	res, err := req.Do(es.Context, es.Client)
 	if err != nil {
-		return errors.Wrap(err, 0)
+		return nil, nil, errors.Wrap(err, 0)
 	}
 
-	_, _, err = parseResponse(res)
+	_, body, err := parseResponse(res)
 	if err != nil {
-		return errors.Wrap(err, 0)
+		return nil, nil, errors.Wrap(err, 0)
 	}
-	return nil
+
+	index, ok := body[indexName].(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.Wrap(errors.New(fmt.Sprintf(
+			"invalid response when getting index %s, index is missing from the body", indexName)), 0)
+	}
+
+	mappings, _ = index["mappings"].(map[string]interface{})
+	settings, _ = index["settings"].(map[string]interface{})
+	return mappings, settings, nil
 }
@@ -55,10 +55,27 @@ type QueryHostIDsRange struct {
 }
 
 type PipelineProcessor struct {
-	Json struct {
-		If    string `json:"if,omitempty"`
-		Field string `json:"field,omitempty"`
-	} `json:"json,omitempty"`
+	Json    *JsonProcessor    `json:"json,omitempty"`
+	Script  *ScriptProcessor  `json:"script,omitempty"`
+	Foreach *ForeachProcessor `json:"foreach,omitempty"`
+}
+
+type JsonProcessor struct {
+	If            string `json:"if,omitempty"`
+	Field         string `json:"field,omitempty"`
+	IgnoreFailure bool   `json:"ignore_failure,omitempty"`
+}
+
+type ForeachProcessor struct {
+	Field         string             `json:"field,omitempty"`
+	IgnoreMissing bool               `json:"ignore_missing,omitempty"`
+	Processor     *PipelineProcessor `json:"processor,omitempty"`
+}
+
+type ScriptProcessor struct {
+	If     string `json:"if,omitempty"`
+	Lang   string `json:"lang,omitempty"`
+	Source string `json:"source,omitempty"`
 }
 
 type Pipeline struct {
@@ -4,8 +4,6 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/components"
-	"github.com/redhatinsights/xjoin-operator/controllers/config"
-	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
 	"github.com/redhatinsights/xjoin-operator/controllers/kafka"
 	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -120,13 +118,8 @@ func (d *ReconcileMethods) Scrub() (errs []error) {
 		ConnectCluster:   d.iteration.Parameters.ConnectCluster.String(),
 	}
 
-	genericElasticsearch, err := elasticsearch.NewGenericElasticsearch(elasticsearch.GenericElasticSearchParameters{
-		Url:        d.iteration.Parameters.ElasticSearchURL.String(),
-		Username:   d.iteration.Parameters.ElasticSearchUsername.String(),
-		Password:   d.iteration.Parameters.ElasticSearchPassword.String(),
-		Parameters: config.ParametersToMap(d.iteration.Parameters),
-		Context:    d.iteration.Context,
-	})
+	sink, err := BuildSink(d.iteration.Context, d.iteration.Client, d.iteration.GetInstance().Namespace,
+		d.iteration.GetInstance().Spec.Sink, &d.iteration.Parameters, kafkaClient)
 	if err != nil {
 		return append(errs, errors.Wrap(err, 0))
 	}
@@ -142,12 +135,7 @@ func (d *ReconcileMethods) Scrub() (errs []error) {
 
 	custodian := components.NewCustodian(
 		d.gvk.Kind, d.iteration.GetInstance().Name, validVersions)
-	custodian.AddComponent(&components.ElasticsearchPipeline{
-		GenericElasticsearch: *genericElasticsearch,
-	})
-	custodian.AddComponent(&components.ElasticsearchIndex{
-		GenericElasticsearch: *genericElasticsearch,
-	})
+	sink.AddToCustodian(custodian)
 
 	kafkaTopics := kafka.StrimziTopics{
 		TopicParameters: kafka.TopicParameters{
@@ -168,7 +156,6 @@ func (d *ReconcileMethods) Scrub() (errs []error) {
 		//ResourceNamePrefix:  this is not needed for generic topics
 	}
 	custodian.AddComponent(&components.KafkaTopic{KafkaTopics: kafkaTopics})
-	custodian.AddComponent(&components.ElasticsearchConnector{KafkaClient: kafkaClient})
 	custodian.AddComponent(components.NewAvroSchema(components.AvroSchemaParameters{
 		Registry: registryConfluentClient}))
 	custodian.AddComponent(components.NewGraphQLSchema(components.GraphQLSchemaParameters{
@@ -0,0 +1,170 @@
+package index
+
+import (
+	"context"
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
+	"github.com/redhatinsights/xjoin-operator/controllers/config"
+	"github.com/redhatinsights/xjoin-operator/controllers/database"
+	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
+	"github.com/redhatinsights/xjoin-operator/controllers/kafka"
+	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
+	k8sUtils "github.com/redhatinsights/xjoin-operator/controllers/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsValidatedSink returns true when the records of the sink type are validated by an XJoinIndexValidator.
+// The elasticsearch and opensearch sinks are validated via the Elasticsearch API.
+func IsValidatedSink(sinkType string) bool {
+	return sinkType == v1alpha1.SinkTypeElasticsearch || sinkType == v1alpha1.SinkTypeOpenSearch
+}
+
+// IsPromotable returns true when an index pipeline with the validation result can become the active version.
+// The pipelines of sinks without a validator are promoted without being validated.
+func IsPromotable(result string) bool {
+	return result == Valid || result == NotValidated
+}
+
+// SearchConnection returns the connection to the Elasticsearch API of a validated sink. The connection of the
+// elasticsearch sink is read from the xjoin-elasticsearch secret, the connection of the opensearch sink from the spec.
+func SearchConnection(ctx context.Context, c client.Client, namespace string, spec *v1alpha1.SinkSpec,
+	p *parameters.IndexParameters) (connection elasticsearch.GenericElasticSearchParameters, err error) {
+
+	connection = elasticsearch.GenericElasticSearchParameters{
+		Parameters: config.ParametersToMap(*p),
+		Context:    ctx,
+	}
+
+	if spec.GetType() != v1alpha1.SinkTypeOpenSearch {
+		connection.Url = p.ElasticSearchURL.String()
+		connection.Username = p.ElasticSearchUsername.String()
+		connection.Password = p.ElasticSearchPassword.String()
+		return connection, nil
+	}
+
+	for _, value := range []struct {
+		param *v1alpha1.StringOrSecretParameter
+		dest  *string
+	}{
+		{param: spec.OpenSearch.URL, dest: &connection.Url},
+		{param: spec.OpenSearch.Username, dest: &connection.Username},
+		{param: spec.OpenSearch.Password, dest: &connection.Password},
+	} {
+		*value.dest, err = k8sUtils.FetchStringOrSecretValue(c, namespace, value.param, ctx)
+		if err != nil {
+			return connection, errors.Wrap(err, 0)
+		}
+	}
+	return connection, nil
+}
+
+// BuildSink builds the sink selected by spec. The connection of the elasticsearch sink is read from the
+// xjoin-elasticsearch secret, the connections of the other sinks are read from the spec.
+func BuildSink(ctx context.Context, c client.Client, namespace string, spec *v1alpha1.SinkSpec,
+	p *parameters.IndexParameters, kafkaClient kafka.GenericKafka) (sink components.Sink, err error) {
+
+	err = components.ValidateSinkSpec(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	parametersMap := config.ParametersToMap(*p)
+
+	switch spec.GetType() {
+	case v1alpha1.SinkTypePostgreSQL:
+		dbParams, err := postgreSQLSinkParams(ctx, c, namespace, spec.PostgreSQL)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+
+		schema := spec.PostgreSQL.Schema
+		if schema == "" {
+			schema = "public"
+		}
+
+		return &components.PostgreSQLSink{
+			Database:           database.NewDatabase(dbParams),
+			Schema:             schema,
+			KafkaClient:        kafkaClient,
+			ConnectorTemplate:  p.PostgreSQLConnectorTemplate.String(),
+			TemplateParameters: parametersMap,
+			Credentials:        sinkCredentials(ctx, c, namespace, dbParams.Password),
+		}, nil
+	default:
+		connection, err := SearchConnection(ctx, c, namespace, spec, p)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+
+		elasticsearchSink, err := buildElasticsearchSink(connection, p, kafkaClient)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		if spec.GetType() != v1alpha1.SinkTypeOpenSearch {
+			return elasticsearchSink, nil
+		}
+
+		elasticsearchSink.ConnectorTemplate = p.OpenSearchConnectorTemplate.String()
+		return &components.OpenSearchSink{
+			ElasticsearchSink: *elasticsearchSink,
+			Credentials:       sinkCredentials(ctx, c, namespace, connection.Password),
+		}, nil
+	}
+}
+
+// sinkCredentials builds the Secret the sink connector reads its password from
+func sinkCredentials(ctx context.Context, c client.Client, namespace string, password string) *components.Secret {
+	return &components.Secret{
+		Client:    c,
+		Context:   ctx,
+		Namespace: namespace,
+		Suffix:    "sink",
+		Data:      map[string]string{components.SinkPasswordKey: password},
+	}
+}
+
+func buildElasticsearchSink(connection elasticsearch.GenericElasticSearchParameters, p *parameters.IndexParameters,
+	kafkaClient kafka.GenericKafka) (*components.ElasticsearchSink, error) {
+
+	genericElasticsearch, err := elasticsearch.NewGenericElasticsearch(connection)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	return &components.ElasticsearchSink{
+		GenericElasticsearch: *genericElasticsearch,
+		ConnectionParameters: connection,
+		KafkaClient:          kafkaClient,
+		IndexTemplate:        p.ElasticSearchIndexTemplate.String(),
+		ConnectorTemplate:    p.ElasticSearchConnectorTemplate.String(),
+		TemplateParameters:   connection.Parameters,
+	}, nil
+}
+
+func postgreSQLSinkParams(ctx context.Context, c client.Client, namespace string,
+	spec *v1alpha1.PostgreSQLSinkSpec) (dbParams database.DBParams, err error) {
+
+	dbParams.SSLMode = spec.SSLMode
+	if dbParams.SSLMode == "" {
+		dbParams.SSLMode = "disable"
+	}
+
+	for _, value := range []struct {
+		param *v1alpha1.StringOrSecretParameter
+		dest  *string
+	}{
+		{param: spec.Hostname, dest: &dbParams.Host},
+		{param: spec.Port, dest: &dbParams.Port},
+		{param: spec.Name, dest: &dbParams.Name},
+		{param: spec.Username, dest: &dbParams.User},
+		{param: spec.Password, dest: &dbParams.Password},
+	} {
+		*value.dest, err = k8sUtils.FetchStringOrSecretValue(c, namespace, value.param, ctx)
+		if err != nil {
+			return dbParams, errors.Wrap(err, 0)
+		}
+	}
+
+	return dbParams, nil
+}
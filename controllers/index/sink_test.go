@@ -0,0 +1,108 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestSinkValidation(t *testing.T) {
+	tests := []struct {
+		sinkType   string
+		validated  bool
+		promotable string
+	}{{
+		sinkType:   v1alpha1.SinkTypeElasticsearch,
+		validated:  true,
+		promotable: Valid,
+	}, {
+		sinkType:   v1alpha1.SinkTypeOpenSearch,
+		validated:  true,
+		promotable: Valid,
+	}, {
+		sinkType:   v1alpha1.SinkTypePostgreSQL,
+		promotable: NotValidated,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.sinkType, func(t *testing.T) {
+			if IsValidatedSink(test.sinkType) != test.validated {
+				t.Errorf("expected the %s sink to be validated: %t", test.sinkType, test.validated)
+			}
+			if !IsPromotable(test.promotable) {
+				t.Errorf("expected the %s result to be promoted", test.promotable)
+			}
+		})
+	}
+
+	if IsPromotable(Invalid) {
+		t.Errorf("expected an invalid version not to be promoted")
+	}
+}
+
+func TestSearchConnectionEnvVars(t *testing.T) {
+	secretName := "xjoinindexpipeline-test-1234-credentials"
+	elasticsearchEnvVar := func(name string, key string) v1.EnvVar {
+		return v1.EnvVar{Name: name, ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: "xjoin-elasticsearch"}, Key: key}}}
+	}
+	credentialEnvVar := func(name string) v1.EnvVar {
+		return v1.EnvVar{Name: name, ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: secretName}, Key: name}}}
+	}
+	passwordRef := &v1alpha1.StringOrSecretParameter{ValueFrom: &v1alpha1.SecretKeyRef{SecretKeyRef: &v1.SecretKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: "opensearch"}, Key: "password"}}}
+
+	tests := []struct {
+		name        string
+		sink        *v1alpha1.SinkSpec
+		envVars     []v1.EnvVar
+		credentials map[string]string
+	}{{
+		name: "elasticsearch",
+		envVars: []v1.EnvVar{
+			elasticsearchEnvVar("ELASTICSEARCH_HOST_URL", "endpoint"),
+			elasticsearchEnvVar("ELASTICSEARCH_USERNAME", "username"),
+			elasticsearchEnvVar("ELASTICSEARCH_PASSWORD", "password"),
+		},
+		credentials: map[string]string{},
+	}, {
+		name: "opensearch",
+		sink: &v1alpha1.SinkSpec{Type: v1alpha1.SinkTypeOpenSearch, OpenSearch: &v1alpha1.OpenSearchSinkSpec{
+			URL:      &v1alpha1.StringOrSecretParameter{Value: "http://opensearch:9200"},
+			Username: &v1alpha1.StringOrSecretParameter{Value: "xjoin"},
+			Password: passwordRef,
+		}},
+		envVars: []v1.EnvVar{
+			{Name: "ELASTICSEARCH_HOST_URL", Value: "http://opensearch:9200"},
+			credentialEnvVar("ELASTICSEARCH_USERNAME"),
+			{Name: "ELASTICSEARCH_PASSWORD", ValueFrom: &v1.EnvVarSource{SecretKeyRef: passwordRef.ValueFrom.SecretKeyRef}},
+		},
+		credentials: map[string]string{"ELASTICSEARCH_USERNAME": "xjoin"},
+	}, {
+		name: "opensearch without credentials",
+		sink: &v1alpha1.SinkSpec{Type: v1alpha1.SinkTypeOpenSearch, OpenSearch: &v1alpha1.OpenSearchSinkSpec{
+			URL: &v1alpha1.StringOrSecretParameter{Value: "http://opensearch:9200"},
+		}},
+		envVars:     []v1.EnvVar{{Name: "ELASTICSEARCH_HOST_URL", Value: "http://opensearch:9200"}},
+		credentials: map[string]string{},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			credentials := make(map[string]string)
+			envVars, err := searchConnectionEnvVars(test.sink, secretName, credentials)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(envVars, test.envVars) {
+				t.Errorf("expected the env vars %v, got %v", test.envVars, envVars)
+			}
+			if !reflect.DeepEqual(credentials, test.credentials) {
+				t.Errorf("expected the credentials %v, got %v", test.credentials, credentials)
+			}
+		})
+	}
+}
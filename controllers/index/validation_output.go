@@ -0,0 +1,60 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+)
+
+// ValidationOutputSchemaVersion is the version of the output schema the xjoin-validation pod must write
+const ValidationOutputSchemaVersion = "v1"
+
+// validationOutputPath is the file the xjoin-validation pod writes its output to. Kubernetes copies the file into
+// the termination message of the container, which is limited to 4096 bytes.
+const validationOutputPath = "/dev/termination-log"
+
+// ValidationOutputMaxBytes is the size of a termination message, longer outputs are truncated by Kubernetes
+const ValidationOutputMaxBytes = 4096
+
+// ValidationOutput is the output of the xjoin-validation pod e.g.
+// {"schemaVersion": "v1", "response": {"result": "valid", "message": "", "details": {}}}
+type ValidationOutput struct {
+	SchemaVersion string                         `json:"schemaVersion"`
+	Response      *validation.ValidationResponse `json:"response"`
+}
+
+// ErrValidationOutputTruncated is returned for an output that was truncated to the size of a termination message.
+// The counts of a truncated output can't be trusted so its result is not applied.
+var ErrValidationOutputTruncated = fmt.Errorf(
+	"the output of the validation pod was truncated to %d bytes, the result is not applied", ValidationOutputMaxBytes)
+
+// ParseValidationOutput parses the termination message of the xjoin-validation pod. An error is returned when the
+// message is empty, truncated, partial, of an unsupported schema version or doesn't contain a result.
+func ParseValidationOutput(message string) (response validation.ValidationResponse, err error) {
+	if strings.TrimSpace(message) == "" {
+		return response, fmt.Errorf("the validation pod did not write its output to %s", validationOutputPath)
+	}
+
+	var output ValidationOutput
+	err = json.Unmarshal([]byte(message), &output)
+	if err != nil && len(message) >= ValidationOutputMaxBytes {
+		return response, ErrValidationOutputTruncated
+	} else if err != nil {
+		return response, fmt.Errorf("unable to parse the output of the validation pod: %w", err)
+	}
+
+	if output.SchemaVersion != ValidationOutputSchemaVersion {
+		return response, fmt.Errorf("unsupported schema version of the validation pod output: %q, expected %q",
+			output.SchemaVersion, ValidationOutputSchemaVersion)
+	}
+	if output.Response == nil {
+		return response, fmt.Errorf("the output of the validation pod is missing the response")
+	}
+	if output.Response.Result != Valid && output.Response.Result != Invalid {
+		return response, fmt.Errorf("invalid result in the output of the validation pod: %q", output.Response.Result)
+	}
+
+	return *output.Response, nil
+}
@@ -0,0 +1,95 @@
+package index
+
+import (
+	"context"
+	"sync"
+
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/validator"
+)
+
+// ValidationRuns tracks the native validations running in the background, one per XJoinIndexValidator.
+// A validation is started by a reconcile and its result is applied by the first reconcile after it completes.
+type ValidationRuns struct {
+	mutex sync.Mutex
+	runs  map[string]*validationRun
+}
+
+// validationRun is a native validation running in the background. The cursor is a copy of the cursor in the
+// status, it's written to the status when the result is applied.
+type validationRun struct {
+	validator validator.Validator
+	cursor    *v1alpha1.ValidationCursorStatus
+	continued bool //a cursor validation continues from the cursor in the status
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	response validation.ValidationResponse
+	report   validator.Report
+	err      error
+}
+
+func NewValidationRuns() *ValidationRuns {
+	return &ValidationRuns{runs: make(map[string]*validationRun)}
+}
+
+// start runs validate in the background, the result is kept until the run is removed
+func (r *ValidationRuns) start(key string, run *validationRun,
+	validate func() (validation.ValidationResponse, validator.Report, error)) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	run.done = make(chan struct{})
+	r.runs[key] = run
+	go func() {
+		defer close(run.done)
+		run.response, run.report, run.err = validate()
+	}()
+}
+
+// get returns the run of key and whether it completed
+func (r *ValidationRuns) get(key string) (run *validationRun, completed bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	run = r.runs[key]
+	if run == nil {
+		return nil, false
+	}
+
+	select {
+	case <-run.done:
+		return run, true
+	default:
+		return run, false
+	}
+}
+
+// remove cancels the run of key and closes its database connections once it completes
+func (r *ValidationRuns) remove(key string) {
+	r.mutex.Lock()
+	run := r.runs[key]
+	delete(r.runs, key)
+	r.mutex.Unlock()
+
+	if run == nil {
+		return
+	}
+	if run.cancel != nil {
+		run.cancel()
+	}
+	go func() {
+		<-run.done
+		run.close()
+	}()
+}
+
+func (run *validationRun) close() {
+	for _, dataSource := range run.validator.DataSources {
+		if err := dataSource.Database.Close(); err != nil {
+			run.validator.Log.Error(err, "Unable to close database connection", "dataSource", dataSource.DataSource)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package index
+
+import (
+	"testing"
+
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+	"github.com/redhatinsights/xjoin-operator/controllers/validator"
+)
+
+func TestValidationRuns(t *testing.T) {
+	runs := NewValidationRuns()
+	key := "test/test-index-validator.1234"
+
+	if run, _ := runs.get(key); run != nil {
+		t.Fatal("expected no run before the validation is started")
+	}
+
+	proceed := make(chan struct{})
+	runs.start(key, &validationRun{}, func() (validation.ValidationResponse, validator.Report, error) {
+		<-proceed
+		return validation.ValidationResponse{Result: Valid}, validator.Report{Result: validator.Valid}, nil
+	})
+
+	run, completed := runs.get(key)
+	if run == nil || completed {
+		t.Fatal("expected the run to be in progress")
+	}
+
+	close(proceed)
+	<-run.done
+	run, completed = runs.get(key)
+	if !completed {
+		t.Fatal("expected the run to be completed")
+	}
+	if run.response.Result != Valid || run.report.Result != validator.Valid || run.err != nil {
+		t.Errorf("expected the result of the validation, got %v %v %v", run.response, run.report, run.err)
+	}
+
+	runs.remove(key)
+	if run, _ := runs.get(key); run != nil {
+		t.Error("expected the run to be removed")
+	}
+}
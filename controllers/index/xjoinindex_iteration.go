@@ -6,8 +6,12 @@ import (
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
 	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sort"
 )
 
 type XJoinIndexIteration struct {
@@ -29,6 +33,44 @@ func (i *XJoinIndexIteration) CreateIndexPipeline(name string, version string) (
 		spec["customSubgraphImages"] = i.Parameters.CustomSubgraphImages.Value()
 	}
 
+	instance := i.GetInstance()
+	if instance.Spec.XJoinCore != nil {
+		spec["xjoinCore"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(instance.Spec.XJoinCore)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	if instance.Spec.XJoinAPISubGraph != nil {
+		spec["xjoinAPISubGraph"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(
+			instance.Spec.XJoinAPISubGraph)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	if instance.Spec.Sink != nil {
+		spec["sink"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(instance.Spec.Sink)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	if instance.Spec.Validation != nil {
+		spec["validation"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(instance.Spec.Validation)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	if len(instance.Spec.DataSources) > 0 {
+		var dataSources []interface{}
+		for idx := range instance.Spec.DataSources {
+			dataSource, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&instance.Spec.DataSources[idx])
+			if err != nil {
+				return errors.Wrap(err, 0)
+			}
+			dataSources = append(dataSources, dataSource)
+		}
+		spec["dataSources"] = dataSources
+	}
+
 	indexPipeline.Object = map[string]interface{}{
 		"metadata": map[string]interface{}{
 			"name":      name + "." + version,
@@ -56,6 +98,17 @@ func (i *XJoinIndexIteration) DeleteIndexPipeline(name string, version string) (
 	return
 }
 
+// UpdateIndexPipelineAvroSchema applies the avro schema of the spec to an existing IndexPipeline, the IndexPipeline
+// then updates its components in place
+func (i *XJoinIndexIteration) UpdateIndexPipelineAvroSchema(indexPipeline *v1alpha1.XJoinIndexPipeline) (err error) {
+	indexPipeline.Spec.AvroSchema = i.Parameters.AvroSchema.String()
+	err = i.Client.Update(i.Context, indexPipeline)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
 func (i XJoinIndexIteration) GetInstance() *v1alpha1.XJoinIndex {
 	return i.Instance.(*v1alpha1.XJoinIndex)
 }
@@ -85,6 +138,37 @@ func (i *XJoinIndexIteration) Finalize() (err error) {
 	return nil
 }
 
+// SupersededDataSources returns the names of the data sources whose version used by the IndexPipeline is no longer
+// the active or refreshing version of the data source, i.e. the data source cut over to a new version. Pinned
+// versions are never superseded.
+func (i *XJoinIndexIteration) SupersededDataSources(indexPipeline *v1alpha1.XJoinIndexPipeline) (
+	superseded []string, err error) {
+
+	for dataSourceName, used := range indexPipeline.Status.DataSources {
+		if used.Policy == v1alpha1.DataSourceVersionPinned {
+			continue
+		}
+
+		dataSource := &v1alpha1.XJoinDataSource{}
+		err = i.Client.Get(i.Context,
+			types.NamespacedName{Name: dataSourceName, Namespace: indexPipeline.GetNamespace()}, dataSource)
+		if k8errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+
+		if dataSource.Status.ActiveVersion != "" &&
+			used.Version != dataSource.Status.ActiveVersion &&
+			used.Version != dataSource.Status.RefreshingVersion {
+			superseded = append(superseded, dataSourceName)
+		}
+	}
+
+	sort.Strings(superseded)
+	return superseded, nil
+}
+
 func (i *XJoinIndexIteration) ReconcilePipeline() (err error) {
 	child := NewIndexPipelineChild(i)
 	err = i.ReconcileChild(child)
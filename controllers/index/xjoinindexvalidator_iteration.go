@@ -1,17 +1,22 @@
 package index
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/go-errors/errors"
 	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
 	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/avro"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
-	"github.com/redhatinsights/xjoin-operator/controllers/k8s"
+	"github.com/redhatinsights/xjoin-operator/controllers/config"
+	"github.com/redhatinsights/xjoin-operator/controllers/database"
+	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
 	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
 	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
 	k8sUtils "github.com/redhatinsights/xjoin-operator/controllers/utils"
+	"github.com/redhatinsights/xjoin-operator/controllers/validator"
 	"github.com/riferrei/srclient"
 	v1 "k8s.io/api/core/v1"
 	k8errors "k8s.io/apimachinery/pkg/api/errors"
@@ -20,28 +25,40 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strconv"
 	"strings"
 )
 
 const XJoinIndexValidatorFinalizer = "finalizer.xjoin.indexvalidator.cloud.redhat.com"
 
-const ValidatorPodRunning = "running"
+const ValidatorPodRunning = "running" //the validation pod or a native validation is running
 const ValidatorPodSuccess = "success"
 const ValidatorPodFailed = "failed"
+const ValidatorPodError = "error"        //the validation pod succeeded but its output is invalid
+const ValidatorRepairing = "repairing"   //mismatched records were emitted again, the index is validated again
+const ValidatorInProgress = "inProgress" //a cursor validation compared a batch of records, the next batch follows
 
 const Valid = "valid"
 const Invalid = "invalid"
+const NotValidated = "notValidated" //the sink of the index pipeline has no validator, it's promoted without validation
+
+// validation modes, native validates in the operator and pod runs the xjoin-validation pod
+const (
+	ValidationModeNative = "native"
+	ValidationModePod    = "pod"
+)
 
 type XJoinIndexValidatorIteration struct {
 	common.Iteration
 	Parameters             parameters.IndexParameters
 	ClientSet              kubernetes.Interface
 	ElasticsearchIndexName string
-	PodLogReader           k8s.LogReader
+	ValidationRuns         *ValidationRuns
 }
 
 func (i *XJoinIndexValidatorIteration) Finalize() (err error) {
 	i.Log.Info("Starting finalizer")
+	i.ValidationRuns.remove(i.validationRunKey())
 	controllerutil.RemoveFinalizer(i.Instance, XJoinIndexValidatorFinalizer)
 
 	ctx, cancel := utils.DefaultContext()
@@ -55,7 +72,9 @@ func (i *XJoinIndexValidatorIteration) Finalize() (err error) {
 	return nil
 }
 
-func (i *XJoinIndexValidatorIteration) ReconcileValidationPod() (phase string, err error) {
+// ReconcileValidation validates the index in the operator or with the xjoin-validation pod depending on the
+// validation.mode parameter
+func (i *XJoinIndexValidatorIteration) ReconcileValidation() (phase string, err error) {
 	//Get index avro schema, references
 	registry := schemaregistry.NewSchemaRegistryConfluentClient(
 		schemaregistry.ConnectionParams{
@@ -65,6 +84,16 @@ func (i *XJoinIndexValidatorIteration) ReconcileValidationPod() (phase string, e
 		})
 	registry.Init()
 
+	//get xjoinindexpipeline, the schema is parsed with the data source versions used by the pipeline
+	indexPipelineNamespacedName := types.NamespacedName{
+		Name:      i.Instance.GetOwnerReferences()[0].Name,
+		Namespace: i.Instance.GetNamespace(),
+	}
+	xjoinIndexPipeline, err := k8sUtils.FetchXJoinIndexPipeline(i.Client, indexPipelineNamespacedName, i.Context)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
 	indexAvroSchemaParser := avro.IndexAvroSchemaParser{
 		AvroSchema:      i.Parameters.AvroSchema.String(),
 		Client:          i.Client,
@@ -73,6 +102,9 @@ func (i *XJoinIndexValidatorIteration) ReconcileValidationPod() (phase string, e
 		Log:             i.Log,
 		SchemaRegistry:  registry,
 		SchemaNamespace: i.Instance.GetName(),
+		DataSources:     xjoinIndexPipeline.Spec.DataSources,
+		UsedDataSources: xjoinIndexPipeline.Status.DataSources,
+		TypeMapping:     i.Parameters.ElasticSearchTypeMapping.String(),
 	}
 	indexAvroSchema, err := indexAvroSchemaParser.Parse()
 
@@ -80,6 +112,361 @@ func (i *XJoinIndexValidatorIteration) ReconcileValidationPod() (phase string, e
 		return "", errors.Wrap(err, 0)
 	}
 
+	switch i.Parameters.ValidationMode.String() {
+	case ValidationModeNative:
+		return i.reconcileNativeValidation(xjoinIndexPipeline, indexAvroSchema)
+	case ValidationModePod:
+		return i.reconcileValidationPod(xjoinIndexPipeline, indexAvroSchema)
+	default:
+		return "", errors.Wrap(errors.New("invalid validation.mode: "+i.Parameters.ValidationMode.String()), 0)
+	}
+}
+
+// reconcileNativeValidation compares the data source tables with the index in the operator. The validation runs in
+// the background so the reconcile isn't blocked, the first reconcile starts it and the reconciles that follow apply
+// its result once it completes.
+func (i *XJoinIndexValidatorIteration) reconcileNativeValidation(
+	xjoinIndexPipeline *v1alpha1.XJoinIndexPipeline, indexAvroSchema avro.IndexAvroSchema) (phase string, err error) {
+
+	key := i.validationRunKey()
+	run, completed := i.ValidationRuns.get(key)
+	if run == nil {
+		err = i.startValidation(key, xjoinIndexPipeline, indexAvroSchema)
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		return ValidatorPodRunning, nil
+	} else if !completed {
+		return ValidatorPodRunning, nil
+	}
+
+	defer i.ValidationRuns.remove(key)
+	return i.applyValidation(xjoinIndexPipeline, run)
+}
+
+func (i *XJoinIndexValidatorIteration) validationRunKey() string {
+	return i.Instance.GetNamespace() + "/" + i.Instance.GetName()
+}
+
+// startValidation connects to the database of each data source and to the sink, then starts the validation in the
+// background. The connections are closed when the run is removed.
+func (i *XJoinIndexValidatorIteration) startValidation(key string,
+	xjoinIndexPipeline *v1alpha1.XJoinIndexPipeline, indexAvroSchema avro.IndexAvroSchema) (err error) {
+
+	dataSourceFields, err := indexAvroSchema.DataSourceFields()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	run := &validationRun{}
+	defer func() {
+		if err != nil {
+			run.close()
+		}
+	}()
+
+	dataSourceVersions := xjoinIndexPipeline.GetDataSources()
+	for _, field := range dataSourceFields {
+		version, ok := dataSourceVersions[field.DataSource]
+		if !ok {
+			return errors.Wrap(fmt.Errorf(
+				"data source %s is not used by the XJoinIndexPipeline %s", field.DataSource, xjoinIndexPipeline.Name), 0)
+		}
+
+		dataSource, err := i.dataSourceDatabase(field.DataSource + "." + version)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		dataSource.DataSourceField = field
+		run.validator.DataSources = append(run.validator.DataSources, dataSource)
+	}
+
+	strategy, err := i.validationStrategy(run)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	//the validation outlives the reconcile, it's canceled when the run is removed
+	ctx, cancel := context.WithCancel(context.Background())
+	run.cancel = cancel
+	connection, err := SearchConnection(ctx, i.Client, i.Instance.GetNamespace(), xjoinIndexPipeline.Spec.Sink, &i.Parameters)
+	if err != nil {
+		cancel()
+		return errors.Wrap(err, 0)
+	}
+	es, err := elasticsearch.NewGenericElasticsearch(connection)
+	if err != nil {
+		cancel()
+		return errors.Wrap(err, 0)
+	}
+
+	run.validator = validator.Validator{
+		Elasticsearch:       es,
+		IndexName:           i.ElasticsearchIndexName,
+		DataSources:         run.validator.DataSources,
+		Strategy:            strategy,
+		ChunkSize:           i.Parameters.ValidationChunkSize.Int(),
+		NumThreads:          i.Parameters.ValidationNumThreads.Int(),
+		PercentageThreshold: i.Parameters.ValidationPercentageThreshold.Int(),
+		MaxReportEntries:    i.Parameters.ValidationReportMaxEntries.Int(),
+		Log:                 i.Log,
+	}
+	i.ValidationRuns.start(key, run, run.validator.Validate)
+	return nil
+}
+
+// applyValidation applies the result of a completed validation run. A failed run is reported in the status and the
+// validation runs again after the validation interval.
+func (i *XJoinIndexValidatorIteration) applyValidation(
+	xjoinIndexPipeline *v1alpha1.XJoinIndexPipeline, run *validationRun) (phase string, err error) {
+
+	if run.err != nil {
+		i.Log.Error(run.err, "Unable to validate the index")
+		i.GetInstance().Status.ValidationError = run.err.Error()
+		return ValidatorPodError, nil
+	}
+
+	response, report := run.response, run.report
+	i.Log.Info(response.Message)
+
+	//a cursor validation is applied once every record was compared, the report contains the mismatches of each batch
+	i.GetInstance().Status.ValidationCursor = run.cursor
+	if cursor := run.cursor; cursor != nil {
+		if run.continued {
+			previous, err := i.readValidationReport()
+			if err != nil {
+				return "", errors.Wrap(err, 0)
+			}
+			if previous.Partial {
+				report.Merge(previous, i.Parameters.ValidationReportMaxEntries.Int())
+			}
+		}
+
+		if !cursor.Done() {
+			report.Partial = true
+			err = i.writeValidationReportConfigMap(report)
+			if err != nil {
+				return "", errors.Wrap(err, 0)
+			}
+			i.GetInstance().Status.ValidationError = ""
+			return ValidatorInProgress, nil
+		}
+		i.GetInstance().Status.ValidationCursor = nil
+	}
+
+	err = i.writeValidationReport(xjoinIndexPipeline, report)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
+	//emit the mismatched records again and validate again before applying the result
+	if i.shouldRepair(report) {
+		repaired, err := run.validator.Repair(report)
+		if err == nil {
+			i.GetInstance().Status.RepairAttempts++
+			i.Log.Info("Repaired mismatched records, validating again",
+				"repaired", repaired, "attempt", i.GetInstance().Status.RepairAttempts)
+			i.GetInstance().Status.ValidationError = ""
+			return ValidatorRepairing, nil
+		}
+		i.Log.Error(err, "Unable to repair the mismatched records, applying the validation result")
+	}
+	i.GetInstance().Status.RepairAttempts = 0
+
+	err = i.updateValidationResponse(xjoinIndexPipeline, response)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
+	i.GetInstance().Status.ValidationError = ""
+	return ValidatorPodSuccess, nil
+}
+
+// shouldRepair returns true when repair is enabled, each mismatched record is listed in the report, the number of
+// mismatched records is within validation.repair.threshold and the repair attempts are not exhausted
+func (i *XJoinIndexValidatorIteration) shouldRepair(report validator.Report) bool {
+	return i.Parameters.ValidationRepairEnabled.Bool() &&
+		report.Mismatched > 0 &&
+		report.Mismatched == len(report.Entries) &&
+		report.Mismatched <= i.Parameters.ValidationRepairThreshold.Int() &&
+		i.GetInstance().Status.RepairAttempts < i.Parameters.ValidationRepairMaxAttempts.Int() &&
+		len(validator.RepairableIds(report)) > 0
+}
+
+// validationStrategy builds the strategy selected by the validation spec. The cursor strategy continues from a copy
+// of the cursor in the status, the run is not continued when a new cursor is started.
+func (i *XJoinIndexValidatorIteration) validationStrategy(run *validationRun) (strategy validator.Strategy, err error) {
+	spec := i.GetInstance().Spec.Validation
+
+	switch spec.GetStrategy() {
+	case v1alpha1.ValidationStrategyFull:
+		return validator.FullStrategy{}, nil
+	case v1alpha1.ValidationStrategySampling:
+		return validator.SamplingStrategy{
+			SampleSize: spec.GetSampleSize(),
+			Confidence: float64(spec.GetConfidence()) / 100,
+		}, nil
+	case v1alpha1.ValidationStrategyHashRange:
+		return validator.HashRangeStrategy{Buckets: spec.GetBuckets()}, nil
+	case v1alpha1.ValidationStrategyCursor:
+		run.cursor = i.GetInstance().Status.ValidationCursor.DeepCopy()
+		run.continued = run.cursor != nil
+		if !run.continued {
+			run.cursor = &v1alpha1.ValidationCursorStatus{}
+		}
+		return validator.NewCursorStrategy(spec.GetBatchSize(), run.cursor, run.validator.DataSources), nil
+	default:
+		return nil, errors.Wrap(errors.New("invalid validation strategy: "+spec.GetStrategy()), 0)
+	}
+}
+
+// dataSourceDatabase connects to the database of an XJoinDataSourcePipeline
+func (i *XJoinIndexValidatorIteration) dataSourceDatabase(
+	dataSourcePipelineName string) (dataSource validator.DataSource, err error) {
+
+	dataSourcePipeline, err := k8sUtils.FetchXJoinDataSourcePipeline(i.Client, types.NamespacedName{
+		Name:      dataSourcePipelineName,
+		Namespace: i.Instance.GetNamespace(),
+	}, i.Context)
+	if err != nil {
+		return dataSource, errors.Wrap(err, 0)
+	}
+
+	p := parameters.BuildDataSourceParameters()
+	configManager, err := config.NewManager(config.ManagerOptions{
+		Client:         i.Client,
+		Parameters:     p,
+		ConfigMapNames: []string{"xjoin-generic"},
+		SecretNames:    nil,
+		Namespace:      i.Instance.GetNamespace(),
+		Spec:           dataSourcePipeline.Spec,
+		Context:        i.Context,
+		Log:            i.Log,
+	})
+	if err != nil {
+		return dataSource, errors.Wrap(err, 0)
+	}
+	err = configManager.Parse()
+	if err != nil {
+		return dataSource, errors.Wrap(err, 0)
+	}
+
+	dataSource.Database = database.NewDatabase(database.DBParams{
+		User:        p.DatabaseUsername.String(),
+		Password:    p.DatabasePassword.String(),
+		Host:        p.DatabaseHostname.String(),
+		Name:        p.DatabaseName.String(),
+		Port:        p.DatabasePort.String(),
+		SSLMode:     p.DatabaseSSLMode.String(),
+		SSLRootCert: p.DatabaseSSLRootCert.String(),
+	})
+	err = dataSource.Database.Connect()
+	if err != nil {
+		return dataSource, errors.Wrap(err, 0)
+	}
+	dataSource.Database.SetMaxConnections(i.Parameters.ValidationNumThreads.Int())
+
+	dataSource.Schema, dataSource.Table = database.SplitTableName(p.DatabaseTable.String())
+	return dataSource, nil
+}
+
+// updateValidationResponse sets the validation response on each XJoinDataSourcePipeline used by the index
+func (i *XJoinIndexValidatorIteration) updateValidationResponse(
+	xjoinIndexPipeline *v1alpha1.XJoinIndexPipeline, response validation.ValidationResponse) error {
+
+	for dataSourceName, dataSourcePipelineVersion := range xjoinIndexPipeline.GetDataSources() {
+		datasourceNamespacedName := types.NamespacedName{
+			Name:      dataSourceName + "." + dataSourcePipelineVersion,
+			Namespace: i.Instance.GetNamespace(),
+		}
+		datasourcePipeline, err := k8sUtils.FetchXJoinDataSourcePipeline(i.Client, datasourceNamespacedName, i.Context)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+
+		datasourcePipeline.Status.ValidationResponse = response
+
+		if err := i.Client.Status().Update(i.Context, datasourcePipeline); err != nil {
+			if k8errors.IsConflict(err) {
+				i.Log.Error(err, "Status conflict")
+				return errors.Wrap(err, 0)
+			}
+			return errors.Wrap(err, 0)
+		}
+	}
+
+	return nil
+}
+
+// ValidationReportConfigMapName returns the name of the ConfigMap that contains the mismatch report of the latest
+// validation run of an XJoinIndexValidator
+func ValidationReportConfigMapName(validatorName string) string {
+	return validatorName + ".report"
+}
+
+// writeValidationReport stores the report of a validation run in a ConfigMap owned by the XJoinIndexValidator and
+// links the ConfigMap from the status of the XJoinIndexPipeline. The report of the previous run is replaced.
+func (i *XJoinIndexValidatorIteration) writeValidationReport(
+	xjoinIndexPipeline *v1alpha1.XJoinIndexPipeline, report validator.Report) error {
+
+	err := i.writeValidationReportConfigMap(report)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	xjoinIndexPipeline.Status.ValidationReport = &v1alpha1.ValidationReportStatus{
+		ConfigMap:  ValidationReportConfigMapName(i.Instance.GetName()),
+		Time:       metav1.Now(),
+		Result:     report.Result,
+		Mismatched: report.Mismatched,
+		Entries:    len(report.Entries),
+	}
+	err = i.Client.Status().Update(i.Context, xjoinIndexPipeline)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	return nil
+}
+
+func (i *XJoinIndexValidatorIteration) writeValidationReportConfigMap(report validator.Report) error {
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = i.WriteOwnedConfigMap(ValidationReportConfigMapName(i.Instance.GetName()), common.IndexValidatorGVK,
+		map[string]string{"report.json": string(reportJSON)})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+// readValidationReport returns the report of the previous validation run, an empty report when there is none
+func (i *XJoinIndexValidatorIteration) readValidationReport() (report validator.Report, err error) {
+	configMap := &v1.ConfigMap{}
+	err = i.Client.Get(i.Context, client.ObjectKey{
+		Name:      ValidationReportConfigMapName(i.Instance.GetName()),
+		Namespace: i.Instance.GetNamespace(),
+	}, configMap)
+	if k8errors.IsNotFound(err) {
+		return report, nil
+	} else if err != nil {
+		return report, errors.Wrap(err, 0)
+	}
+
+	err = json.Unmarshal([]byte(configMap.Data["report.json"]), &report)
+	if err != nil {
+		return report, errors.Wrap(err, 0)
+	}
+	return report, nil
+}
+
+// reconcileValidationPod runs the xjoin-validation pod and parses its output when it completes
+func (i *XJoinIndexValidatorIteration) reconcileValidationPod(
+	xjoinIndexPipeline *v1alpha1.XJoinIndexPipeline, indexAvroSchema avro.IndexAvroSchema) (phase string, err error) {
+
 	//check if pod is already running
 	labels := client.MatchingLabels{}
 	labels["xjoin.index"] = i.Instance.GetName()
@@ -92,11 +479,11 @@ func (i *XJoinIndexValidatorIteration) ReconcileValidationPod() (phase string, e
 
 	//create the pod if not already running
 	if len(podList.Items) == 0 {
-		dbConnectionEnvVars, err := i.buildDBConnectionEnvVars(indexAvroSchema.References)
+		connectionEnvVars, err := i.buildConnectionEnvVars(indexAvroSchema.References, xjoinIndexPipeline.Spec.Sink)
 		if err != nil {
 			return "", errors.Wrap(err, 0)
 		}
-		err = i.createValidationPod(dbConnectionEnvVars, indexAvroSchema.AvroSchemaString)
+		err = i.createValidationPod(connectionEnvVars, indexAvroSchema.AvroSchemaString)
 		if err != nil {
 			return "", errors.Wrap(err, 0)
 		}
@@ -112,44 +499,37 @@ func (i *XJoinIndexValidatorIteration) ReconcileValidationPod() (phase string, e
 	}
 
 	if pod.Status.Phase == v1.PodSucceeded {
-		//check output of xjoin-validation pod
-		response, err := i.ParsePodResponse()
+		//check output of xjoin-validation pod, an invalid output is reported in the status and the pod is
+		//deleted so the validation runs again after the validation interval
+		response, err := i.ParsePodResponse(pod)
 		if err != nil {
-			return "", errors.Wrap(err, 0)
+			i.Log.Error(err, "Invalid output of the xjoin-validation pod")
+			i.GetInstance().Status.ValidationError = err.Error()
+
+			err = i.Client.Delete(i.Context, pod)
+			if err != nil {
+				return "", errors.Wrap(err, 0)
+			}
+			return ValidatorPodError, nil
 		}
+		i.GetInstance().Status.ValidationError = ""
 
 		i.Log.Info(response.Message)
 
-		//get xjoinindexpipeline
-		indexPipelineNamespacedName := types.NamespacedName{
-			Name:      i.Instance.GetOwnerReferences()[0].Name,
-			Namespace: i.Instance.GetNamespace(),
-		}
-		xjoinIndexPipeline, err := k8sUtils.FetchXJoinIndexPipeline(i.Client, indexPipelineNamespacedName, i.Context)
+		//update datasource resource based on xjoin-validation pod's output
+		err = i.updateValidationResponse(xjoinIndexPipeline, response)
 		if err != nil {
 			return "", errors.Wrap(err, 0)
 		}
 
-		//update datasource resource based on xjoin-validation pod's output
-		for dataSourceName, dataSourcePipelineVersion := range xjoinIndexPipeline.Status.DataSources {
-			datasourceNamespacedName := types.NamespacedName{
-				Name:      dataSourceName + "." + dataSourcePipelineVersion,
-				Namespace: i.Instance.GetNamespace(),
-			}
-			datasourcePipeline, err := k8sUtils.FetchXJoinDataSourcePipeline(i.Client, datasourceNamespacedName, i.Context)
-			if err != nil {
-				return "", errors.Wrap(err, 0)
-			}
-
-			datasourcePipeline.Status.ValidationResponse = response
-
-			if err := i.Client.Status().Update(i.Context, datasourcePipeline); err != nil {
-				if k8errors.IsConflict(err) {
-					i.Log.Error(err, "Status conflict")
-					return "", errors.Wrap(err, 0)
-				}
-				return "", errors.Wrap(err, 0)
-			}
+		err = i.writeValidationReport(xjoinIndexPipeline,
+			validator.ReportFromResponse(response, i.Parameters.ValidationReportMaxEntries.Int()))
+		if err != nil {
+			return "", errors.Wrap(err, 0)
+		}
+		//the output of the pod doesn't contain the data source of the mismatched records, they can't be repaired
+		if i.Parameters.ValidationRepairEnabled.Bool() {
+			i.Log.Info("validation.repair.enabled is ignored in pod mode, the mismatched records are not repaired")
 		}
 
 		//cleanup the validation pod
@@ -181,34 +561,62 @@ func (i *XJoinIndexValidatorIteration) ValidationPodName() string {
 	return name
 }
 
-func (i *XJoinIndexValidatorIteration) ParsePodResponse() (validation.ValidationResponse, error) {
-	var response validation.ValidationResponse
-
-	logString, err := i.PodLogReader.GetLogs(i.ValidationPodName(), i.Instance.GetNamespace())
-	if err != nil {
-		return response, errors.Wrap(err, 0)
+// ParsePodResponse parses the output the xjoin-validation pod wrote to the termination message of its container
+func (i *XJoinIndexValidatorIteration) ParsePodResponse(pod *v1.Pod) (validation.ValidationResponse, error) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == i.ValidationPodName() && status.State.Terminated != nil {
+			return ParseValidationOutput(status.State.Terminated.Message)
+		}
 	}
-	strArray := strings.Split(logString, "\n")
+	return validation.ValidationResponse{}, errors.New("the container of the validation pod has not terminated")
+}
 
-	//parse last line, removing trailing new lines
-	var resultString string
-	if len(strArray) > 1 {
-		resultString = strArray[len(strArray)-2]
-	} else {
-		resultString = strArray[len(strArray)-1]
+// CredentialsSecretName is the name of the Secret that contains the database credentials of the validation pod
+func (i *XJoinIndexValidatorIteration) CredentialsSecretName() string {
+	return i.ValidationPodName() + "-credentials"
+}
+
+// credentialEnvVar converts a credential into an env var that reads from a Secret.
+// Secret references are passed through, literal values are added to credentials and read from secretName.
+func credentialEnvVar(param *v1alpha1.StringOrSecretParameter, name string, secretName string,
+	credentials map[string]string) (envVar v1.EnvVar, err error) {
+
+	if param == nil {
+		return envVar, errors.Wrap(errors.New("missing credential for env var "+name), 0)
 	}
 
-	err = json.Unmarshal([]byte(resultString), &response)
-	if err != nil {
-		return response, errors.Wrap(err, 0)
+	if param.IsSecretRef() {
+		envVar, err = param.ConvertToEnvVar(name)
+		if err != nil {
+			return envVar, errors.Wrap(err, 0)
+		}
+		return envVar, nil
 	}
-	return response, nil
+
+	credentials[name] = param.Value
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: secretName,
+				},
+				Key: name,
+			},
+		},
+	}, nil
 }
 
-func (i *XJoinIndexValidatorIteration) buildDBConnectionEnvVars(references []srclient.Reference) (envVars []v1.EnvVar, err error) {
+// buildConnectionEnvVars builds the env vars of the validation pod to connect to the database of each data source
+// and to the Elasticsearch API of the sink
+func (i *XJoinIndexValidatorIteration) buildConnectionEnvVars(
+	references []srclient.Reference, sink *v1alpha1.SinkSpec) (envVars []v1.EnvVar, err error) {
+
 	//gather db connection info for each datasource
 	//the db connection info is passed to the xjoin-validation pod as environment variables
 	//because the xjoin-validation pod does not know how to connect to the Kubernetes API
+	//credentials are read from Secrets so they are not stored in the pod spec
+	credentials := make(map[string]string)
 	for _, ref := range references {
 		//Get datasourcepipeline k8s object to get db connection info
 		dataSourcePipeline := &v1alpha1.XJoinDataSourcePipeline{}
@@ -229,13 +637,15 @@ func (i *XJoinIndexValidatorIteration) buildDBConnectionEnvVars(references []src
 		}
 		envVars = append(envVars, hostnameEnvVar)
 
-		usernameEnvVar, err := dataSourcePipeline.Spec.DatabaseUsername.ConvertToEnvVar(envVarPrefix + "_DB_USERNAME")
+		usernameEnvVar, err := credentialEnvVar(
+			dataSourcePipeline.Spec.DatabaseUsername, envVarPrefix+"_DB_USERNAME", i.CredentialsSecretName(), credentials)
 		if err != nil {
 			return envVars, errors.Wrap(err, 0)
 		}
 		envVars = append(envVars, usernameEnvVar)
 
-		passwordEnvVar, err := dataSourcePipeline.Spec.DatabasePassword.ConvertToEnvVar(envVarPrefix + "_DB_PASSWORD")
+		passwordEnvVar, err := credentialEnvVar(
+			dataSourcePipeline.Spec.DatabasePassword, envVarPrefix+"_DB_PASSWORD", i.CredentialsSecretName(), credentials)
 		if err != nil {
 			return envVars, errors.Wrap(err, 0)
 		}
@@ -260,10 +670,73 @@ func (i *XJoinIndexValidatorIteration) buildDBConnectionEnvVars(references []src
 		envVars = append(envVars, tableEnvVar)
 	}
 
+	searchEnvVars, err := searchConnectionEnvVars(sink, i.CredentialsSecretName(), credentials)
+	if err != nil {
+		return envVars, errors.Wrap(err, 0)
+	}
+	envVars = append(envVars, searchEnvVars...)
+
+	err = i.WriteOwnedSecret(i.CredentialsSecretName(), common.IndexValidatorGVK, credentials)
+	if err != nil {
+		return envVars, errors.Wrap(err, 0)
+	}
+
 	return
 }
 
-func (i *XJoinIndexValidatorIteration) createValidationPod(dbConnectionEnvVars []v1.EnvVar, fullAvroSchema string) error {
+// searchConnectionEnvVars builds the env vars of the validation pod to connect to the Elasticsearch API of the sink.
+// The elasticsearch sink is read from the xjoin-elasticsearch secret, the opensearch sink from the spec.
+func searchConnectionEnvVars(sink *v1alpha1.SinkSpec, secretName string,
+	credentials map[string]string) (envVars []v1.EnvVar, err error) {
+
+	if sink.GetType() != v1alpha1.SinkTypeOpenSearch {
+		for _, value := range []struct{ name, key string }{
+			{name: "ELASTICSEARCH_HOST_URL", key: "endpoint"},
+			{name: "ELASTICSEARCH_USERNAME", key: "username"},
+			{name: "ELASTICSEARCH_PASSWORD", key: "password"},
+		} {
+			envVars = append(envVars, v1.EnvVar{
+				Name: value.name,
+				ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: "xjoin-elasticsearch",
+						},
+						Key: value.key,
+					},
+				},
+			})
+		}
+		return envVars, nil
+	}
+
+	urlEnvVar, err := sink.OpenSearch.URL.ConvertToEnvVar("ELASTICSEARCH_HOST_URL")
+	if err != nil {
+		return envVars, errors.Wrap(err, 0)
+	}
+	envVars = append(envVars, urlEnvVar)
+
+	//the username and password are optional for the opensearch sink
+	for _, value := range []struct {
+		name  string
+		param *v1alpha1.StringOrSecretParameter
+	}{
+		{name: "ELASTICSEARCH_USERNAME", param: sink.OpenSearch.Username},
+		{name: "ELASTICSEARCH_PASSWORD", param: sink.OpenSearch.Password},
+	} {
+		if value.param == nil {
+			continue
+		}
+		envVar, err := credentialEnvVar(value.param, value.name, secretName, credentials)
+		if err != nil {
+			return envVars, errors.Wrap(err, 0)
+		}
+		envVars = append(envVars, envVar)
+	}
+	return envVars, nil
+}
+
+func (i *XJoinIndexValidatorIteration) createValidationPod(connectionEnvVars []v1.EnvVar, fullAvroSchema string) error {
 	//run separate xjoin-validation pod
 	err := i.Client.Create(i.Context, &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -280,44 +753,28 @@ func (i *XJoinIndexValidatorIteration) createValidationPod(dbConnectionEnvVars [
 			Containers: []v1.Container{{
 				Name:  i.ValidationPodName(),
 				Image: "quay.io/cloudservices/xjoin-validation:latest",
-				Env: append(dbConnectionEnvVars, []v1.EnvVar{{
-					Name: "ELASTICSEARCH_HOST_URL",
-					ValueFrom: &v1.EnvVarSource{
-						SecretKeyRef: &v1.SecretKeySelector{
-							LocalObjectReference: v1.LocalObjectReference{
-								Name: "xjoin-elasticsearch",
-							},
-							Key: "endpoint",
-						},
-					},
-				}, {
-					Name: "ELASTICSEARCH_USERNAME",
-					ValueFrom: &v1.EnvVarSource{
-						SecretKeyRef: &v1.SecretKeySelector{
-							LocalObjectReference: v1.LocalObjectReference{
-								Name: "xjoin-elasticsearch",
-							},
-							Key: "username",
-						},
-					},
-				}, {
-					Name: "ELASTICSEARCH_PASSWORD",
-					ValueFrom: &v1.EnvVarSource{
-						SecretKeyRef: &v1.SecretKeySelector{
-							LocalObjectReference: v1.LocalObjectReference{
-								Name: "xjoin-elasticsearch",
-							},
-							Key: "password",
-						},
-					},
-				}, {
+				Env: append(connectionEnvVars, []v1.EnvVar{{
 					Name:  "ELASTICSEARCH_INDEX",
 					Value: i.ElasticsearchIndexName,
 				}, {
 					Name:  "FULL_AVRO_SCHEMA",
 					Value: fullAvroSchema,
+				}, {
+					Name:  "VALIDATION_OUTPUT_PATH",
+					Value: validationOutputPath,
+				}, {
+					Name:  "VALIDATION_OUTPUT_SCHEMA_VERSION",
+					Value: ValidationOutputSchemaVersion,
+				}, {
+					Name:  "VALIDATION_OUTPUT_MAX_BYTES",
+					Value: strconv.Itoa(ValidationOutputMaxBytes),
+				}, {
+					Name:  "VALIDATION_OUTPUT_MAX_IDS",
+					Value: strconv.Itoa(i.Parameters.ValidationReportMaxEntries.Int()),
 				}}...),
-				ImagePullPolicy: "Always",
+				ImagePullPolicy:          "Always",
+				TerminationMessagePath:   validationOutputPath,
+				TerminationMessagePolicy: v1.TerminationMessageReadFile,
 			}},
 		},
 	})
@@ -0,0 +1,90 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
+	"github.com/redhatinsights/xjoin-operator/controllers/validator"
+)
+
+func TestShouldRepair(t *testing.T) {
+	mismatched := func(count int, entryType string) validator.Report {
+		report := validator.Report{Result: validator.Invalid, Mismatched: count}
+		for idx := 0; idx < count; idx++ {
+			report.Entries = append(report.Entries, validator.ReportEntry{
+				DataSource: "hosts", ID: string(rune('a' + idx)), Type: entryType})
+		}
+		return report
+	}
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		threshold      int
+		repairAttempts int
+		report         validator.Report
+		shouldRepair   bool
+	}{{
+		name:         "repairable",
+		enabled:      true,
+		threshold:    3,
+		report:       mismatched(3, validator.MissingInSink),
+		shouldRepair: true,
+	}, {
+		name:      "disabled",
+		threshold: 3,
+		report:    mismatched(3, validator.MissingInSink),
+	}, {
+		name:      "no mismatches",
+		enabled:   true,
+		threshold: 3,
+		report:    validator.Report{Result: validator.Valid},
+	}, {
+		name:      "above the threshold",
+		enabled:   true,
+		threshold: 2,
+		report:    mismatched(3, validator.DifferentFields),
+	}, {
+		name:      "report is truncated",
+		enabled:   true,
+		threshold: 10,
+		report: validator.Report{Result: validator.Invalid, Mismatched: 5, Entries: []validator.ReportEntry{
+			{DataSource: "hosts", ID: "a", Type: validator.MissingInSink}}},
+	}, {
+		name:           "attempts exhausted",
+		enabled:        true,
+		threshold:      3,
+		repairAttempts: 3,
+		report:         mismatched(3, validator.MissingInSink),
+	}, {
+		name:      "only missing in source",
+		enabled:   true,
+		threshold: 3,
+		report:    mismatched(3, validator.MissingInSource),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := parameters.BuildIndexParameters()
+			if err := p.ValidationRepairEnabled.SetValue(test.enabled); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.ValidationRepairThreshold.SetValue(test.threshold); err != nil {
+				t.Fatal(err)
+			}
+
+			instance := &v1alpha1.XJoinIndexValidator{}
+			instance.Status.RepairAttempts = test.repairAttempts
+			i := XJoinIndexValidatorIteration{
+				Iteration:  common.Iteration{Instance: instance},
+				Parameters: *p,
+			}
+
+			if i.shouldRepair(test.report) != test.shouldRepair {
+				t.Errorf("expected shouldRepair to be %t", test.shouldRepair)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ type IndexTestReconciler struct {
 	K8sClient            client.Client
 	AvroSchemaFileName   string
 	CustomSubgraphImages []v1alpha1.CustomSubgraphImage
+	Annotations          map[string]string
 }
 
 func (i *IndexTestReconciler) ReconcileNew() v1alpha1.XJoinIndex {
@@ -53,6 +54,31 @@ func (i *IndexTestReconciler) ReconcileNew() v1alpha1.XJoinIndex {
 	return *createdIndex
 }
 
+// ReconcilePlan creates an XJoinIndex with the plan annotation and reconciles it
+func (i *IndexTestReconciler) ReconcilePlan() v1alpha1.XJoinIndex {
+	i.Annotations = map[string]string{v1alpha1.PlanAnnotation: "true"}
+	i.registerNewMocks()
+	i.createValidIndex()
+	result := i.reconcile()
+	Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 30000000000}))
+	return i.GetIndex()
+}
+
+// RemovePlanAnnotation removes the plan annotation of the XJoinIndex so the next reconcile applies the changes
+func (i *IndexTestReconciler) RemovePlanAnnotation() {
+	index := i.GetIndex()
+	delete(index.Annotations, v1alpha1.PlanAnnotation)
+	checkError(i.K8sClient.Update(context.Background(), &index))
+}
+
+// ReconcileWithError reconciles the XJoinIndex and returns the error of the failed reconcile
+func (i *IndexTestReconciler) ReconcileWithError() error {
+	i.registerNewMocks()
+	indexLookupKey := types.NamespacedName{Name: i.Name, Namespace: i.Namespace}
+	_, err := i.newXJoinIndexReconciler().Reconcile(context.Background(), ctrl.Request{NamespacedName: indexLookupKey})
+	return err
+}
+
 func (i *IndexTestReconciler) ReconcileDelete() {
 	i.registerDeleteMocks()
 	result := i.reconcile()
@@ -108,8 +134,9 @@ func (i *IndexTestReconciler) createValidIndex() {
 
 	index := &v1alpha1.XJoinIndex{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      i.Name,
-			Namespace: i.Namespace,
+			Name:        i.Name,
+			Namespace:   i.Namespace,
+			Annotations: i.Annotations,
 		},
 		Spec: indexSpec,
 		TypeMeta: metav1.TypeMeta{
@@ -18,10 +18,28 @@ import (
 func (kafka *GenericKafka) CreateGenericDebeziumConnector(
 	name string, connectorTemplate string, connectorTemplateParameters map[string]interface{}) error {
 
-	connectorConfig, err := kafka.parseConnectorTemplate(connectorTemplate, connectorTemplateParameters)
+	connectorObj, err := kafka.BuildGenericDebeziumConnector(name, connectorTemplate, connectorTemplateParameters)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	err = kafka.Client.Create(kafka.Context, connectorObj)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
+
+	return nil
+}
+
+// BuildGenericDebeziumConnector returns the KafkaConnector resource for a Debezium connector
+func (kafka *GenericKafka) BuildGenericDebeziumConnector(
+	name string, connectorTemplate string, connectorTemplateParameters map[string]interface{}) (
+	*unstructured.Unstructured, error) {
+
+	connectorConfig, err := kafka.parseConnectorTemplate(connectorTemplate, connectorTemplateParameters)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
 	connectorObj := &unstructured.Unstructured{}
 	connectorObj.Object = map[string]interface{}{
 		"metadata": map[string]interface{}{
@@ -40,6 +58,16 @@ func (kafka *GenericKafka) CreateGenericDebeziumConnector(
 	}
 
 	connectorObj.SetGroupVersionKind(connectorGVK)
+	return connectorObj, nil
+}
+
+func (kafka *GenericKafka) CreateGenericElasticsearchConnector(
+	name string, connectorTemplate string, connectorTemplateParameters map[string]interface{}) error {
+
+	connectorObj, err := kafka.BuildGenericElasticsearchConnector(name, connectorTemplate, connectorTemplateParameters)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
 
 	err = kafka.Client.Create(kafka.Context, connectorObj)
 	if err != nil {
@@ -49,14 +77,41 @@ func (kafka *GenericKafka) CreateGenericDebeziumConnector(
 	return nil
 }
 
-func (kafka *GenericKafka) CreateGenericElasticsearchConnector(
-	name string, connectorTemplate string, connectorTemplateParameters map[string]interface{}) error {
+// BuildGenericElasticsearchConnector returns the KafkaConnector resource for an Elasticsearch sink connector
+func (kafka *GenericKafka) BuildGenericElasticsearchConnector(
+	name string, connectorTemplate string, connectorTemplateParameters map[string]interface{}) (
+	*unstructured.Unstructured, error) {
 
-	connectorConfig, err := kafka.parseConnectorTemplate(connectorTemplate, connectorTemplateParameters)
+	return kafka.BuildGenericSinkConnector(
+		name, "io.confluent.connect.elasticsearch.ElasticsearchSinkConnector", connectorTemplate, connectorTemplateParameters)
+}
+
+func (kafka *GenericKafka) CreateGenericSinkConnector(
+	name string, class string, connectorTemplate string, connectorTemplateParameters map[string]interface{}) error {
+
+	connectorObj, err := kafka.BuildGenericSinkConnector(name, class, connectorTemplate, connectorTemplateParameters)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
 
+	err = kafka.Client.Create(kafka.Context, connectorObj)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	return nil
+}
+
+// BuildGenericSinkConnector returns the KafkaConnector resource for a sink connector of type class
+func (kafka *GenericKafka) BuildGenericSinkConnector(
+	name string, class string, connectorTemplate string, connectorTemplateParameters map[string]interface{}) (
+	*unstructured.Unstructured, error) {
+
+	connectorConfig, err := kafka.parseConnectorTemplate(connectorTemplate, connectorTemplateParameters)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
 	connectorObj := &unstructured.Unstructured{}
 	connectorObj.Object = map[string]interface{}{
 		"metadata": map[string]interface{}{
@@ -67,15 +122,47 @@ func (kafka *GenericKafka) CreateGenericElasticsearchConnector(
 			},
 		},
 		"spec": map[string]interface{}{
-			"class":  "io.confluent.connect.elasticsearch.ElasticsearchSinkConnector",
+			"class":  class,
 			"config": connectorConfig,
 			"pause":  false,
 		},
 	}
 
 	connectorObj.SetGroupVersionKind(connectorGVK)
+	return connectorObj, nil
+}
 
-	err = kafka.Client.Create(kafka.Context, connectorObj)
+// UpdateGenericConnector re-applies the labels and spec of connectorObj to the existing KafkaConnector.
+// The pause state of the existing connector is preserved because it is managed at runtime.
+func (kafka *GenericKafka) UpdateGenericConnector(connectorObj *unstructured.Unstructured) error {
+	connector, err := kafka.GetConnector(connectorObj.GetName())
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	spec, ok := connectorObj.Object["spec"].(map[string]interface{})
+	if !ok {
+		return errors.Wrap(errors.New("spec is missing from connector "+connectorObj.GetName()), 0)
+	}
+	if existingSpec, ok := connector.Object["spec"].(map[string]interface{}); ok {
+		if pause, exists := existingSpec["pause"]; exists {
+			spec["pause"] = pause
+		}
+	}
+	connector.Object["spec"] = spec
+
+	labels := connector.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for key, value := range connectorObj.GetLabels() {
+		labels[key] = value
+	}
+	connector.SetLabels(labels)
+
+	ctx, cancel := utils.DefaultContext()
+	defer cancel()
+	err = kafka.Client.Update(ctx, connector)
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
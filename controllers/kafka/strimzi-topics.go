@@ -235,7 +235,8 @@ func (t *StrimziTopics) CheckDeviation(pipelineVersion string) (problem error, e
 	return nil, nil
 }
 
-func (t *StrimziTopics) CreateGenericTopic(topicName string, topicParameters TopicParameters) error {
+// BuildGenericTopic returns the KafkaTopic resource for topicName
+func (t *StrimziTopics) BuildGenericTopic(topicName string, topicParameters TopicParameters) *unstructured.Unstructured {
 	topic := &unstructured.Unstructured{}
 	topic.Object = map[string]interface{}{
 		"metadata": map[string]interface{}{
@@ -260,6 +261,11 @@ func (t *StrimziTopics) CreateGenericTopic(topicName string, topicParameters Top
 	}
 
 	topic.SetGroupVersionKind(topicGroupVersionKind)
+	return topic
+}
+
+func (t *StrimziTopics) CreateGenericTopic(topicName string, topicParameters TopicParameters) error {
+	topic := t.BuildGenericTopic(topicName, topicParameters)
 
 	err := t.Client.Create(t.Context, topic)
 	if err != nil {
@@ -321,6 +327,35 @@ func (t *StrimziTopics) CreateGenericTopic(topicName string, topicParameters Top
 	return nil
 }
 
+// UpdateGenericTopic re-applies the labels and spec of a topic to the existing KafkaTopic
+func (t *StrimziTopics) UpdateGenericTopic(topicName string, topicParameters TopicParameters) error {
+	expected := t.BuildGenericTopic(topicName, topicParameters)
+
+	topic := &unstructured.Unstructured{}
+	topic.SetGroupVersionKind(topicGroupVersionKind)
+	err := t.Client.Get(t.Context, client.ObjectKey{Name: topicName, Namespace: t.KafkaClusterNamespace}, topic)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	labels := topic.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for key, value := range expected.GetLabels() {
+		labels[key] = value
+	}
+	topic.SetLabels(labels)
+	topic.Object["spec"] = expected.Object["spec"]
+
+	err = t.Client.Update(t.Context, topic)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	return nil
+}
+
 func (t *StrimziTopics) DeleteTopic(topicName string) error {
 	if topicName == "" {
 		return nil
@@ -24,6 +24,8 @@ type CommonParameters struct {
 	SchemaRegistryHost           Parameter
 	SchemaRegistryPort           Parameter
 	AvroSchema                   Parameter
+	DeviationPolicy              Parameter //refresh, repair or report
+	ComponentConcurrency         Parameter
 }
 
 func BuildCommonParameters() CommonParameters {
@@ -145,6 +147,18 @@ func BuildCommonParameters() CommonParameters {
 			SpecKey:      "AvroSchema",
 			DefaultValue: "{}",
 		},
+		DeviationPolicy: Parameter{
+			Type:          reflect.String,
+			ConfigMapName: "xjoin-generic",
+			ConfigMapKey:  "deviation.policy",
+			DefaultValue:  "refresh",
+		},
+		ComponentConcurrency: Parameter{
+			Type:          reflect.Int,
+			ConfigMapName: "xjoin-generic",
+			ConfigMapKey:  "components.concurrency",
+			DefaultValue:  4,
+		},
 	}
 
 	return p
@@ -0,0 +1,181 @@
+package parameters
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	. "github.com/redhatinsights/xjoin-operator/controllers/config"
+	v1 "k8s.io/api/core/v1"
+	"reflect"
+	"strings"
+)
+
+// DeploymentParameters are the xjoin-generic defaults of a Deployment created by the operator.
+// The structured values (resources, nodeSelector, tolerations, affinity, podAnnotations) are json documents.
+type DeploymentParameters struct {
+	Image              Parameter
+	ImageTag           Parameter
+	ImagePullPolicy    Parameter
+	Replicas           Parameter
+	Resources          Parameter
+	NodeSelector       Parameter
+	Tolerations        Parameter
+	Affinity           Parameter
+	PodAnnotations     Parameter
+	ServiceAccountName Parameter
+	ImagePullSecrets   Parameter //comma separated list of secret names
+}
+
+func BuildDeploymentParameters(configMapPrefix string, image string) DeploymentParameters {
+	return DeploymentParameters{
+		Image: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".image",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  image,
+		},
+		ImageTag: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".image.tag",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "latest",
+		},
+		ImagePullPolicy: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".image.pull.policy",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  string(v1.PullAlways),
+		},
+		Replicas: Parameter{
+			Type:          reflect.Int,
+			ConfigMapKey:  configMapPrefix + ".replicas",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  1,
+		},
+		Resources: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".resources",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue: `{
+			  "limits": {"cpu": "250m", "memory": "512Mi"},
+			  "requests": {"cpu": "100m", "memory": "64Mi"}
+			}`,
+		},
+		NodeSelector: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".node.selector",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "",
+		},
+		Tolerations: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".tolerations",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "",
+		},
+		Affinity: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".affinity",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "",
+		},
+		PodAnnotations: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".pod.annotations",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "",
+		},
+		ServiceAccountName: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".service.account",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "",
+		},
+		ImagePullSecrets: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  configMapPrefix + ".image.pull.secrets",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "",
+		},
+	}
+}
+
+// Spec builds the settings of a Deployment from the defaults, fields set in override take priority
+func (d DeploymentParameters) Spec(override *v1alpha1.DeploymentSpec) (spec v1alpha1.DeploymentSpec, err error) {
+	replicas := int32(d.Replicas.Int())
+	spec = v1alpha1.DeploymentSpec{
+		Image:              d.Image.String(),
+		ImageTag:           d.ImageTag.String(),
+		ImagePullPolicy:    v1.PullPolicy(d.ImagePullPolicy.String()),
+		Replicas:           &replicas,
+		ServiceAccountName: d.ServiceAccountName.String(),
+	}
+
+	documents := []struct {
+		parameter Parameter
+		value     interface{}
+	}{
+		{parameter: d.Resources, value: &spec.Resources},
+		{parameter: d.NodeSelector, value: &spec.NodeSelector},
+		{parameter: d.Tolerations, value: &spec.Tolerations},
+		{parameter: d.Affinity, value: &spec.Affinity},
+		{parameter: d.PodAnnotations, value: &spec.PodAnnotations},
+	}
+	for _, document := range documents {
+		if strings.TrimSpace(document.parameter.String()) == "" {
+			continue
+		}
+		err = json.Unmarshal([]byte(document.parameter.String()), document.value)
+		if err != nil {
+			return spec, errors.Wrap(fmt.Errorf(`invalid json for "%s" in configmap %s: %w`,
+				document.parameter.ConfigMapKey, document.parameter.ConfigMapName, err), 0)
+		}
+	}
+
+	for _, name := range strings.Split(d.ImagePullSecrets.String(), ",") {
+		if strings.TrimSpace(name) != "" {
+			spec.ImagePullSecrets = append(spec.ImagePullSecrets, v1.LocalObjectReference{Name: strings.TrimSpace(name)})
+		}
+	}
+
+	if override == nil {
+		return spec, nil
+	}
+
+	if override.Image != "" {
+		spec.Image = override.Image
+	}
+	if override.ImageTag != "" {
+		spec.ImageTag = override.ImageTag
+	}
+	if override.ImagePullPolicy != "" {
+		spec.ImagePullPolicy = override.ImagePullPolicy
+	}
+	if override.Replicas != nil {
+		spec.Replicas = override.Replicas
+	}
+	if override.Resources != nil {
+		spec.Resources = override.Resources
+	}
+	if override.NodeSelector != nil {
+		spec.NodeSelector = override.NodeSelector
+	}
+	if override.Tolerations != nil {
+		spec.Tolerations = override.Tolerations
+	}
+	if override.Affinity != nil {
+		spec.Affinity = override.Affinity
+	}
+	if override.PodAnnotations != nil {
+		spec.PodAnnotations = override.PodAnnotations
+	}
+	if override.ServiceAccountName != "" {
+		spec.ServiceAccountName = override.ServiceAccountName
+	}
+	if override.ImagePullSecrets != nil {
+		spec.ImagePullSecrets = override.ImagePullSecrets
+	}
+
+	return spec, nil
+}
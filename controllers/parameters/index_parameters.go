@@ -25,10 +25,25 @@ type IndexParameters struct {
 	ElasticSearchIndexReplicas       Parameter
 	ElasticSearchIndexShards         Parameter
 	ElasticSearchIndexTemplate       Parameter
+	ElasticSearchTypeMapping         Parameter //legacy maps fields by xjoin.type only, typed maps every avro type
 	KafkaBootstrapURL                Parameter
 	CustomSubgraphImages             Parameter
 	ValidationInterval               Parameter //period between validation checks (seconds)
 	ValidationPodStatusInterval      Parameter //period between checking the status of the validation pod (seconds)
+	ValidationMode                   Parameter //native validates in the operator, pod runs the xjoin-validation pod
+	ValidationPercentageThreshold    Parameter //percentage of mismatched records allowed for the index to be valid
+	ValidationChunkSize              Parameter //number of records compared by each thread of the native validation
+	ValidationNumThreads             Parameter //number of chunks compared in parallel by the native validation
+	ValidationReportMaxEntries       Parameter //maximum number of mismatched records listed in a validation report
+	ValidationRepairEnabled          Parameter //emit the mismatched records again instead of applying the result, native mode only
+	ValidationRepairThreshold        Parameter //maximum number of mismatched records that are repaired
+	ValidationRepairMaxAttempts      Parameter //number of repairs before the validation result is applied
+	ValidationRepairInterval         Parameter //period between a repair and the next validation (seconds)
+	XJoinCoreDeployment              DeploymentParameters
+	XJoinAPISubGraphDeployment       DeploymentParameters
+	XJoinAPISubGraphProbePath        Parameter //http path of the liveness and readiness probes
+	OpenSearchConnectorTemplate      Parameter
+	PostgreSQLConnectorTemplate      Parameter
 }
 
 func BuildIndexParameters() *IndexParameters {
@@ -156,6 +171,12 @@ func BuildIndexParameters() *IndexParameters {
 			ConfigMapName: "xjoin-generic",
 			DefaultValue:  "",
 		},
+		ElasticSearchTypeMapping: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  "elasticsearch.type.mapping",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "typed",
+		},
 		KafkaBootstrapURL: Parameter{
 			Type:          reflect.String,
 			ConfigMapKey:  "kafka.bootstrap.url",
@@ -179,6 +200,135 @@ func BuildIndexParameters() *IndexParameters {
 			ConfigMapName: "xjoin-generic",
 			DefaultValue:  5,
 		},
+		ValidationMode: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  "validation.mode",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "native",
+		},
+		ValidationPercentageThreshold: Parameter{
+			Type:          reflect.Int,
+			ConfigMapKey:  "validation.percentage.threshold",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  5,
+		},
+		ValidationChunkSize: Parameter{
+			Type:          reflect.Int,
+			ConfigMapKey:  "validation.chunk.size",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  2000,
+		},
+		ValidationNumThreads: Parameter{
+			Type:          reflect.Int,
+			ConfigMapKey:  "validation.num.threads",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  20,
+		},
+		ValidationReportMaxEntries: Parameter{
+			Type:          reflect.Int,
+			ConfigMapKey:  "validation.report.max.entries",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  1000,
+		},
+		ValidationRepairEnabled: Parameter{
+			Type:          reflect.Bool,
+			ConfigMapKey:  "validation.repair.enabled",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  false,
+		},
+		ValidationRepairThreshold: Parameter{
+			Type:          reflect.Int,
+			ConfigMapKey:  "validation.repair.threshold",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  100,
+		},
+		ValidationRepairMaxAttempts: Parameter{
+			Type:          reflect.Int,
+			ConfigMapKey:  "validation.repair.max.attempts",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  3,
+		},
+		ValidationRepairInterval: Parameter{
+			Type:          reflect.Int,
+			ConfigMapKey:  "validation.repair.interval",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  30,
+		},
+		XJoinAPISubGraphProbePath: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  "xjoin.api.subgraph.probe.path",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue:  "/healthz",
+		},
+		OpenSearchConnectorTemplate: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  "opensearch.connector.template",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue: `{
+			  "tasks.max": "{{.ElasticSearchTasksMax}}",
+			  "topics": "{{.Topic}}",
+			  "key.ignore": "false",
+			  "connection.url": "{{.OpenSearchURL}}",
+			  {{if .OpenSearchUsername}}"connection.username": "{{.OpenSearchUsername}}",{{end}}
+			  {{if .OpenSearchPassword}}"connection.password": "{{.OpenSearchPassword}}",{{end}}
+			  "transforms.deleteIf.type": "com.redhat.insights.deleteifsmt.DeleteIf$Value",
+			  "transforms.deleteIf.field": "__deleted",
+			  "transforms.deleteIf.value": "true",
+			  "behavior.on.null.values":"delete",
+			  "behavior.on.malformed.documents": "warn",
+			  "schema.ignore": true,
+			  "max.in.flight.requests": {{.ElasticSearchMaxInFlightRequests}},
+			  "errors.log.enable": {{.ElasticSearchErrorsLogEnable}},
+			  "errors.log.include.messages": true,
+			  "max.retries": {{.ElasticSearchMaxRetries}},
+			  "retry.backoff.ms": {{.ElasticSearchRetryBackoffMS}},
+			  "batch.size": {{.ElasticSearchBatchSize}},
+			  "max.buffered.records": {{.ElasticSearchMaxBufferedRecords}},
+			  "linger.ms": {{.ElasticSearchLingerMS}},
+			  "key.converter": "org.apache.kafka.connect.storage.StringConverter",
+			  "value.converter": "io.apicurio.registry.utils.converter.AvroConverter",
+			  "value.converter.apicurio.registry.auto-register": "false",
+			  "value.converter.apicurio.registry.find-latest": "true",
+			  "value.converter.apicurio.registry.url": "{{.SchemaRegistryProtocol}}://{{.SchemaRegistryHost}}:{{.SchemaRegistryPort}}/apis/registry/v2",
+			  "value.converter.enhanced.avro.schema.support": "true"
+			}`,
+		},
+		PostgreSQLConnectorTemplate: Parameter{
+			Type:          reflect.String,
+			ConfigMapKey:  "postgresql.connector.template",
+			ConfigMapName: "xjoin-generic",
+			DefaultValue: `{
+			  "tasks.max": "1",
+			  "topics": "{{.Topic}}",
+			  "connection.url": "{{.PostgreSQLURL}}",
+			  "connection.username": "{{.PostgreSQLUsername}}",
+			  "connection.password": "{{.PostgreSQLPassword}}",
+			  "insert.mode": "upsert",
+			  "delete.enabled": "true",
+			  "primary.key.mode": "record_key",
+			  "primary.key.fields": "{{.PostgreSQLPrimaryKey}}",
+			  "schema.evolution": "none",
+			  "table.name.format": "{{.PostgreSQLTable}}",
+			  "transforms": "deleteIf,flatten",
+			  "transforms.deleteIf.type": "com.redhat.insights.deleteifsmt.DeleteIf$Value",
+			  "transforms.deleteIf.field": "__deleted",
+			  "transforms.deleteIf.value": "true",
+			  "transforms.flatten.type": "org.apache.kafka.connect.transforms.Flatten$Value",
+			  "transforms.flatten.delimiter": "_",
+			  "errors.log.enable": true,
+			  "errors.log.include.messages": true,
+			  "key.converter": "org.apache.kafka.connect.storage.StringConverter",
+			  "value.converter": "io.apicurio.registry.utils.converter.AvroConverter",
+			  "value.converter.apicurio.registry.auto-register": "false",
+			  "value.converter.apicurio.registry.find-latest": "true",
+			  "value.converter.apicurio.registry.url": "{{.SchemaRegistryProtocol}}://{{.SchemaRegistryHost}}:{{.SchemaRegistryPort}}/apis/registry/v2",
+			  "value.converter.enhanced.avro.schema.support": "true"
+			}`,
+		},
+		XJoinCoreDeployment: BuildDeploymentParameters(
+			"xjoin.core", "quay.io/cloudservices/xjoin-core"),
+		XJoinAPISubGraphDeployment: BuildDeploymentParameters(
+			"xjoin.api.subgraph", "quay.io/cloudservices/xjoin-api-subgraph"),
 	}
 
 	p.CommonParameters = BuildCommonParameters()
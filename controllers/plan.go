@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/go-errors/errors"
+	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/config"
+	"github.com/redhatinsights/xjoin-operator/controllers/datasource"
+	"github.com/redhatinsights/xjoin-operator/controllers/index"
+	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
+	k8sUtils "github.com/redhatinsights/xjoin-operator/controllers/utils"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+)
+
+// planVersion is used to name the components of a version that doesn't exist yet
+const planVersion = "plan"
+
+func planModeEnabled(instance client.Object) bool {
+	return instance.GetAnnotations()[xjoin.PlanAnnotation] == "true"
+}
+
+// plannedVersion returns the version whose components are rendered and whether applying the plan creates a new version
+func plannedVersion(state string, instance common.XJoinObject) (version string, refresh bool) {
+	if state == common.START_REFRESH || state == common.NEW {
+		return planVersion, true
+	} else if instance.GetActiveVersion() != "" {
+		return instance.GetActiveVersion(), false
+	} else {
+		return instance.GetRefreshingVersion(), false
+	}
+}
+
+func marshalPlan(value interface{}) (string, error) {
+	planJson, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return string(planJson), nil
+}
+
+// parsePlanJSON parses a json document for a plan, the document is returned as is when it isn't valid json
+func parsePlanJSON(document string) interface{} {
+	var value interface{}
+	err := json.Unmarshal([]byte(document), &value)
+	if err != nil {
+		return document
+	}
+	return value
+}
+
+// writePlan renders the plan into the plan ConfigMap and returns the plan status.
+// Errors while rendering the plan are recorded in the plan instead of failing the reconcile.
+func writePlan(iteration *common.Iteration, ownerGVK schema.GroupVersionKind, instance common.XJoinObject,
+	reconciler *common.Reconciler, render func(version string, refresh bool) (map[string]string, error)) (
+	planStatus *xjoin.PlanStatus, err error) {
+
+	state, err := reconciler.PlannedState()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	version, refresh := plannedVersion(state, instance)
+
+	specHash, err := k8sUtils.SpecHash(instance.GetSpec())
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	planStatus = &xjoin.PlanStatus{
+		SpecHash: specHash,
+		State:    state,
+		Refresh:  refresh,
+	}
+
+	data := map[string]string{
+		"state":    state,
+		"refresh":  strconv.FormatBool(refresh),
+		"version":  version,
+		"specHash": specHash,
+	}
+
+	renderedData, err := render(version, refresh)
+	if err != nil {
+		iteration.Log.Error(err, "Unable to render plan")
+		planStatus.Error = err.Error()
+		data["error"] = err.Error()
+	}
+	for key, value := range renderedData {
+		data[key] = value
+	}
+
+	planStatus.ConfigMapName, err = iteration.WritePlan(ownerGVK, data)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	return planStatus, nil
+}
+
+// renderIndexPlan renders the IndexAvroSchemaParser output and the components of an XJoinIndexPipeline
+func renderIndexPlan(i *index.XJoinIndexIteration, version string, refresh bool, test bool) (
+	data map[string]string, err error) {
+
+	instance := i.GetInstance()
+	pipeline := &xjoin.XJoinIndexPipeline{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.GetName() + "." + version,
+			Namespace: instance.GetNamespace(),
+		},
+		Spec: xjoin.XJoinIndexPipelineSpec{
+			Name:                 instance.GetName(),
+			Version:              version,
+			AvroSchema:           i.Parameters.AvroSchema.String(),
+			Pause:                i.Parameters.Pause.Bool(),
+			CustomSubgraphImages: instance.Spec.CustomSubgraphImages,
+			XJoinCore:            instance.Spec.XJoinCore,
+			XJoinAPISubGraph:     instance.Spec.XJoinAPISubGraph,
+			Sink:                 instance.Spec.Sink,
+			DataSources:          instance.Spec.DataSources,
+			Validation:           instance.Spec.Validation,
+		},
+	}
+	pipeline.Status.Active = !refresh && version == instance.Status.ActiveVersion
+
+	//an existing pipeline keeps the data source versions it already uses
+	if !refresh {
+		existing, err := k8sUtils.FetchXJoinIndexPipeline(
+			i.Client, types.NamespacedName{Name: pipeline.GetName(), Namespace: pipeline.GetNamespace()}, i.Context)
+		if err != nil && !k8errors.IsNotFound(err) {
+			return nil, errors.Wrap(err, 0)
+		} else if err == nil {
+			pipeline.Status.DataSources = existing.Status.DataSources
+		}
+	}
+
+	p := parameters.BuildIndexParameters()
+	configManager, err := config.NewManager(config.ManagerOptions{
+		Client:         i.Client,
+		Parameters:     p,
+		ConfigMapNames: []string{"xjoin-generic"},
+		SecretNames:    []string{"xjoin-elasticsearch"},
+		Namespace:      instance.GetNamespace(),
+		Spec:           pipeline.Spec,
+		Context:        i.Context,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	err = configManager.Parse()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	componentManager, indexAvroSchema, err := buildIndexPipelineComponents(
+		i.Context, i.Client, i.Log, p, pipeline, test)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	defer func() {
+		if closeErr := componentManager.Close(); closeErr != nil {
+			i.Log.Error(closeErr, "Unable to close the connections of the components")
+		}
+	}()
+
+	componentPlans, err := componentManager.Plan()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	data = make(map[string]string)
+	data["components.json"], err = marshalPlan(componentPlans)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	data["avroSchema.json"], err = marshalPlan(map[string]interface{}{
+		"avroSchema":   parsePlanJSON(indexAvroSchema.AvroSchemaString),
+		"references":   indexAvroSchema.References,
+		"sourceTopics": indexAvroSchema.SourceTopics,
+		"dataSources":  indexAvroSchema.DataSources,
+		"esProperties": parsePlanJSON(indexAvroSchema.ESProperties),
+		"esAnalysis":   parsePlanJSON(indexAvroSchema.ESAnalysis),
+		"jsonFields":   indexAvroSchema.JSONFields,
+		"dateFields":   indexAvroSchema.DateFields,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	return data, nil
+}
+
+// renderDataSourcePlan renders the components of an XJoinDataSourcePipeline
+func renderDataSourcePlan(i *datasource.XJoinDataSourceIteration, version string, test bool) (
+	data map[string]string, err error) {
+
+	instance := i.GetInstance()
+	pipeline := &xjoin.XJoinDataSourcePipeline{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.GetName() + "." + version,
+			Namespace: instance.GetNamespace(),
+		},
+		Spec: xjoin.XJoinDataSourcePipelineSpec{
+			Name:             instance.GetName(),
+			Version:          version,
+			AvroSchema:       i.Parameters.AvroSchema.String(),
+			DatabaseHostname: instance.Spec.DatabaseHostname,
+			DatabasePort:     instance.Spec.DatabasePort,
+			DatabaseName:     instance.Spec.DatabaseName,
+			DatabaseUsername: instance.Spec.DatabaseUsername,
+			DatabasePassword: instance.Spec.DatabasePassword,
+			DatabaseTable:    instance.Spec.DatabaseTable,
+			Pause:            i.Parameters.Pause.Bool(),
+		},
+	}
+
+	p := parameters.BuildDataSourceParameters()
+	configManager, err := config.NewManager(config.ManagerOptions{
+		Client:         i.Client,
+		Parameters:     p,
+		ConfigMapNames: []string{"xjoin-generic"},
+		SecretNames:    nil,
+		Namespace:      instance.GetNamespace(),
+		Spec:           pipeline.Spec,
+		Context:        i.Context,
+		Log:            i.Log,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	err = configManager.Parse()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	componentManager := buildDataSourcePipelineComponents(i.Context, i.Client, p, pipeline, test)
+	componentPlans, err := componentManager.Plan()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	data = make(map[string]string)
+	data["components.json"], err = marshalPlan(componentPlans)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	return data, nil
+}
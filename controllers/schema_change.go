@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/go-errors/errors"
+	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/avro"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	k8sUtils "github.com/redhatinsights/xjoin-operator/controllers/utils"
+)
+
+// checkSchemaChange compares the avro schema of the active version with the avro schema of the spec and decides how
+// the change is applied. activeSpec is the spec with the avro schema of the active version, the change can only be
+// applied in place when activeSpec matches the spec the active version was created from.
+func checkSchemaChange(changeSpec *xjoin.SchemaChangeSpec, instance common.XJoinObject, activeSpec interface{},
+	specHash string, activeSchema string, newSchema string) (status *xjoin.SchemaCompatibilityStatus, err error) {
+
+	status = &xjoin.SchemaCompatibilityStatus{
+		ActiveVersion: instance.GetActiveVersion(),
+		SpecHash:      specHash,
+		Action:        xjoin.SchemaChangeActionRefresh,
+	}
+
+	comparison, err := avro.CompareSchemas(activeSchema, newSchema)
+	if err != nil {
+		//an unparsable schema fails when the new version is created, only block it when a compatibility is required
+		status.Message = err.Error()
+		if changeSpec.GetCompatibility() != xjoin.SchemaCompatibilityNone {
+			status.Action = xjoin.SchemaChangeActionBlocked
+		}
+		return status, nil
+	}
+
+	status.Backward = comparison.Backward
+	status.Forward = comparison.Forward
+	status.Full = comparison.Full()
+	status.Additive = comparison.Additive()
+	status.Changes = comparison.Changes
+
+	activeSpecHash, err := k8sUtils.SpecHash(activeSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	if !comparison.Satisfies(changeSpec.GetCompatibility()) {
+		status.Action = xjoin.SchemaChangeActionBlocked
+		status.Message = fmt.Sprintf(
+			"the new avro schema is not %s compatible with the avro schema of version %s",
+			changeSpec.GetCompatibility(), instance.GetActiveVersion())
+	} else if changeSpec.GetPolicy() == xjoin.SchemaChangePolicyInPlaceWhenAdditive && status.Additive &&
+		instance.GetRefreshingVersion() == "" && activeSpecHash == instance.GetSpecHash() {
+		status.Action = xjoin.SchemaChangeActionInPlace
+	}
+
+	return status, nil
+}
+
+// schemaChangePending is true when the spec changed since the last reconcile and the change includes the avro schema
+func schemaChangePending(instance common.XJoinObject, specHash string, activeSchema string, newSchema string) bool {
+	return instance.GetActiveVersion() != "" && instance.GetDeletionTimestamp() == nil &&
+		instance.GetSpecHash() != "" && instance.GetSpecHash() != specHash && activeSchema != newSchema
+}
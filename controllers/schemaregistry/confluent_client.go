@@ -84,6 +84,19 @@ func (sr *ConfluentClient) GetSchemaReferences(subject string) (references []src
 	return schema.References(), nil
 }
 
+// GetSchemaVersion returns a version of the subject's schema, version 0 is the latest version
+func (sr *ConfluentClient) GetSchemaVersion(subject string, version int) (schema *srclient.Schema, err error) {
+	if version == 0 {
+		schema, err = sr.Client.GetLatestSchema(subject)
+	} else {
+		schema, err = sr.Client.GetSchemaByVersion(subject, version)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return schema, nil
+}
+
 func (sr *ConfluentClient) GetSchema(subject string) (schema string, err error) {
 	schemaObj, err := sr.Client.GetLatestSchema(subject)
 	if err != nil {
@@ -65,13 +65,37 @@ func (c *RestClient) MakeRequest(requestParams Request) (resCode int, body map[s
 	return res.StatusCode, body, nil
 }
 
-func (c *RestClient) RegisterGraphQLSchema(name string) (id string, err error) {
-	headers := make(map[string]string)
-	headers["Content-Type"] = "application/graphql"
+const DefaultGraphQLSchema = "type Query {internalServerError: string}"
+
+// GraphQLSchemaLabels returns the labels that are added to a graphql schema artifact.
+// subgraphURL is the url used by the federated gateway to reach the subgraph that serves the schema.
+func GraphQLSchemaLabels(subgraphURL string) []string {
+	return []string{"xjoin-subgraph-url=" + subgraphURL, "graphql"}
+}
+
+// RegisterGraphQLSchema registers the content of a graphql schema, DefaultGraphQLSchema is registered when the
+// content is empty
+func (c *RestClient) RegisterGraphQLSchema(name string, subgraphURL string, content string) (id string, err error) {
+	return c.registerGraphQLSchema(name, subgraphURL, content, "/groups/default/artifacts")
+}
+
+// UpdateGraphQLSchema registers a graphql schema, creating a new version of the artifact if it already exists.
+// The labels are re-applied and the new version is disabled.
+func (c *RestClient) UpdateGraphQLSchema(name string, subgraphURL string, content string) (id string, err error) {
+	return c.registerGraphQLSchema(name, subgraphURL, content, "/groups/default/artifacts?ifExists=UPDATE")
+}
+
+func (c *RestClient) registerGraphQLSchema(
+	name string, subgraphURL string, content string, path string) (id string, err error) {
+
+	if content == "" {
+		content = DefaultGraphQLSchema
+	}
+
 	resCode, resBody, err := c.MakeRequest(Request{
 		Method: http.MethodPost,
-		Path:   "/groups/default/artifacts",
-		Body:   "type Query {internalServerError: string}",
+		Path:   path,
+		Body:   content,
 		Headers: map[string]string{
 			"Content-Type":            "application/graphql",
 			"X-Registry-ArtifactId":   name,
@@ -96,9 +120,8 @@ func (c *RestClient) RegisterGraphQLSchema(name string) (id string, err error) {
 	}
 
 	//add labels
-	url := "http://" + strings.ReplaceAll(name, ".", "-") + ".test.svc:4000/graphql" //TODO url is static
 	labelsBody := make(map[string]interface{})
-	labelsBody["labels"] = []string{"xjoin-subgraph-url=" + url, "graphql"}
+	labelsBody["labels"] = GraphQLSchemaLabels(subgraphURL)
 	labelsBodyJson, err := json.Marshal(labelsBody)
 	if err != nil {
 		return "", errors.Wrap(err, 0)
@@ -124,6 +147,72 @@ func (c *RestClient) RegisterGraphQLSchema(name string) (id string, err error) {
 	return name, nil
 }
 
+// GetGraphQLSchema returns the content of the latest version of a graphql schema artifact
+func (c *RestClient) GetGraphQLSchema(name string) (schema string, exists bool, err error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseUrl+"/groups/default/artifacts/"+name, nil)
+	if err != nil {
+		return "", false, errors.Wrap(err, 0)
+	}
+
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return "", false, errors.Wrap(err, 0)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", false, errors.Wrap(err, 0)
+	}
+
+	if res.StatusCode == 404 {
+		return "", false, nil
+	} else if res.StatusCode >= 300 {
+		return "", false, errors.Wrap(errors.New(fmt.Sprintf(
+			"unable to get graphql schema, statusCode: %v, body: %s", res.StatusCode, string(resBody))), 0)
+	}
+
+	return string(resBody), true, nil
+}
+
+// GetGraphQLSchemaVersionLabels returns the labels of the version of a graphql schema artifact that has the content.
+// registered is false when the artifact doesn't exist or none of its versions has the content.
+func (c *RestClient) GetGraphQLSchemaVersionLabels(name string, content string) (
+	labels []string, registered bool, err error) {
+
+	if content == "" {
+		content = DefaultGraphQLSchema
+	}
+
+	resCode, resBody, err := c.MakeRequest(Request{
+		Method: http.MethodPost,
+		Path:   "/groups/default/artifacts/" + name + "/meta",
+		Body:   content,
+		Headers: map[string]string{
+			"Content-Type": "application/graphql",
+			"Accept":       "application/json",
+		},
+	})
+	if err != nil {
+		return nil, false, errors.Wrap(err, 0)
+	}
+
+	if resCode == 404 {
+		return nil, false, nil
+	} else if resCode >= 300 {
+		return nil, false, errors.Wrap(errors.New(fmt.Sprintf(
+			"unable to get graphql schema version, statusCode: %v, message: %s", resCode, resBody["message"])), 0)
+	}
+
+	labelsInterface, _ := resBody["labels"].([]interface{})
+	for _, label := range labelsInterface {
+		if labelString, ok := label.(string); ok {
+			labels = append(labels, labelString)
+		}
+	}
+	return labels, true, nil
+}
+
 func (c *RestClient) GetSchemaState(name string) (state string, err error) {
 	resCode, resBody, err := c.MakeRequest(Request{
 		Method: http.MethodGet,
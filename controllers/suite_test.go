@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"github.com/go-errors/errors"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,6 +27,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	xjoinApi "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -110,6 +113,68 @@ func NewNamespace() (string, error) {
 	return name, nil
 }
 
+// expectCondition validates the status and reason of a condition, then returns the condition
+func expectCondition(conditions []metav1.Condition, conditionType string, status metav1.ConditionStatus,
+	reason string) metav1.Condition {
+
+	condition := meta.FindStatusCondition(conditions, conditionType)
+	Expect(condition).ToNot(BeNil())
+	Expect(condition.Status).To(Equal(status), condition.Message)
+	Expect(condition.Reason).To(Equal(reason), condition.Message)
+	return *condition
+}
+
+// expectPlanConfigMap validates the plan ConfigMap of an XJoinIndex/XJoinDataSource is owned by it and contains the
+// planned state, then returns the ConfigMap
+func expectPlanConfigMap(owner client.Object, kind string, plan *xjoinApi.PlanStatus) v1.ConfigMap {
+	Expect(plan).ToNot(BeNil())
+	Expect(plan.ConfigMapName).To(Equal(strings.ToLower(kind) + "." + owner.GetName() + ".plan"))
+	Expect(plan.Error).To(Equal(""))
+
+	configMap := v1.ConfigMap{}
+	k8sGet(client.ObjectKey{Name: plan.ConfigMapName, Namespace: owner.GetNamespace()}, &configMap)
+
+	controller := true
+	Expect(configMap.OwnerReferences).To(Equal([]metav1.OwnerReference{{
+		APIVersion:         "xjoin.cloud.redhat.com/v1alpha1",
+		Kind:               kind,
+		Name:               owner.GetName(),
+		UID:                owner.GetUID(),
+		Controller:         &controller,
+		BlockOwnerDeletion: &controller,
+	}}))
+	Expect(configMap.Data).To(HaveKeyWithValue("state", plan.State))
+	Expect(configMap.Data).To(HaveKeyWithValue("refresh", strconv.FormatBool(plan.Refresh)))
+	Expect(configMap.Data).To(HaveKeyWithValue("specHash", plan.SpecHash))
+	Expect(configMap.Data).ToNot(HaveKey("error"))
+	return configMap
+}
+
+// expectedAvroSchema returns the subject and the schema an AvroSchema component registers
+func expectedAvroSchema(kind string, name string, version string, avroSchema string) (subject string, schema string) {
+	avroSchemaComponent := components.NewAvroSchema(components.AvroSchemaParameters{Schema: avroSchema})
+	avroSchemaComponent.SetName(kind, name)
+	avroSchemaComponent.SetVersion(version)
+	schema, err := avroSchemaComponent.SetSchemaNameNamespace()
+	checkError(err)
+	return avroSchemaComponent.Name(), schema
+}
+
+// registeredAvroSchemaResponse is the registry's response for the version of the subject that was registered by an
+// AvroSchema component, so the mocked registry doesn't deviate from the expected schema
+func registeredAvroSchemaResponse(kind string, name string, version string, avroSchema string) string {
+	subject, schema := expectedAvroSchema(kind, name, version, avroSchema)
+	response, err := json.Marshal(map[string]interface{}{
+		"subject":    subject,
+		"version":    1,
+		"id":         1,
+		"schema":     schema,
+		"references": []interface{}{},
+	})
+	checkError(err)
+	return string(response)
+}
+
 func LoadExpectedKafkaResourceConfig(filename string) *bytes.Buffer {
 	file, err := os.ReadFile(filename)
 	checkError(err)
@@ -167,11 +232,9 @@ func CreateValidIndexPipeline(namespace string, customSubgraphImages []xjoinApi.
 			ApiCurioResponseFilename: "datasource-latest-version",
 		}},
 	}
-	indexPipeline := indexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
-		GraphQLSchemaExistingState: "DISABLED",
-		GraphQLSchemaNewState:      "DISABLED",
-	})
+	indexPipeline := indexPipelineReconciler.ReconcileCreate()
 	Expect(indexPipeline.Status.Active).To(Equal(false))
+	Expect(indexPipeline.Status.Deviations).To(BeEmpty())
 
 	//reconcile the index to flip the refreshing pipeline to be active
 	indexReconciler.ReconcileUpdated()
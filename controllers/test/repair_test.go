@@ -0,0 +1,69 @@
+package test
+
+import (
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redhatinsights/xjoin-operator/controllers/avro"
+	logger "github.com/redhatinsights/xjoin-operator/controllers/log"
+	"github.com/redhatinsights/xjoin-operator/controllers/validator"
+)
+
+var _ = Describe("Repair", func() {
+	var i *Iteration
+
+	BeforeEach(func() {
+		iteration, err := Before()
+		Expect(err).ToNot(HaveOccurred())
+		i = iteration
+	})
+
+	AfterEach(func() {
+		err := After(i)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	newValidator := func() *validator.Validator {
+		return &validator.Validator{
+			DataSources: []validator.DataSource{{
+				DataSourceField: avro.DataSourceField{DataSource: "hosts", PrimaryKey: "id"},
+				Database:        i.DbClient,
+				Schema:          "public",
+				Table:           "hosts",
+			}},
+			ChunkSize: 1,
+			Log:       logger.NewLogger("test"),
+		}
+	}
+
+	It("Touches the rows of the repairable records", func() {
+		missingHost, err := i.InsertSimpleHost()
+		Expect(err).ToNot(HaveOccurred())
+		differentHost, err := i.InsertSimpleHost()
+		Expect(err).ToNot(HaveOccurred())
+		validHost, err := i.InsertSimpleHost()
+		Expect(err).ToNot(HaveOccurred())
+
+		repaired, err := newValidator().Repair(validator.Report{Mismatched: 3, Entries: []validator.ReportEntry{
+			{DataSource: "hosts", ID: missingHost, Type: validator.MissingInSink},
+			{DataSource: "hosts", ID: differentHost, Type: validator.DifferentFields, Fields: []string{"display_name"}},
+			{DataSource: "hosts", ID: validHost, Type: validator.MissingInSource},
+		}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(repaired).To(Equal(2))
+	})
+
+	It("Skips the records deleted since the validation", func() {
+		host, err := i.InsertSimpleHost()
+		Expect(err).ToNot(HaveOccurred())
+		deletedHost, err := uuid.NewUUID()
+		Expect(err).ToNot(HaveOccurred())
+
+		repaired, err := newValidator().Repair(validator.Report{Mismatched: 2, Entries: []validator.ReportEntry{
+			{DataSource: "hosts", ID: host, Type: validator.MissingInSink},
+			{DataSource: "hosts", ID: deletedHost.String(), Type: validator.MissingInSink},
+		}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(repaired).To(Equal(1))
+	})
+})
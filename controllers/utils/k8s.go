@@ -60,6 +60,14 @@ func FetchXJoinIndexPipelines(c client.Client, ctx context.Context) (*xjoin.XJoi
 	return list, err
 }
 
+func FetchXJoinIndexPipelinesInNamespace(
+	c client.Client, namespace string, ctx context.Context) (*xjoin.XJoinIndexPipelineList, error) {
+
+	list := &xjoin.XJoinIndexPipelineList{}
+	err := c.List(ctx, list, client.InNamespace(namespace))
+	return list, err
+}
+
 func FetchXJoinIndexValidator(c client.Client, namespacedName types.NamespacedName, ctx context.Context) (*xjoin.XJoinIndexValidator, error) {
 	instance := &xjoin.XJoinIndexValidator{}
 	err := c.Get(ctx, namespacedName, instance)
@@ -137,3 +145,29 @@ func FetchSecret(c client.Client, namespace string, name string, ctx context.Con
 
 	return secret, err
 }
+
+// FetchStringOrSecretValue returns the value of param, the value is read from the Secret when param references one
+func FetchStringOrSecretValue(
+	c client.Client, namespace string, param *xjoin.StringOrSecretParameter, ctx context.Context) (string, error) {
+
+	if param == nil {
+		return "", nil
+	} else if !param.IsSecretRef() {
+		return param.Value, nil
+	}
+
+	secretKeyRef := param.ValueFrom.SecretKeyRef
+	secret, err := FetchSecret(c, namespace, secretKeyRef.Name, ctx)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	if secret == nil {
+		return "", errors.Wrap(fmt.Errorf("secret not found: %s", secretKeyRef.Name), 0)
+	}
+
+	value, exists := secret.Data[secretKeyRef.Key]
+	if !exists {
+		return "", errors.Wrap(fmt.Errorf("key %s not found in secret %s", secretKeyRef.Key, secretKeyRef.Name), 0)
+	}
+	return string(value), nil
+}
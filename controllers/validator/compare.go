@@ -0,0 +1,201 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
+)
+
+// dateLayouts are the formats of dates in PostgreSQL's json output and in the documents of the index
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// equalValues compares a value of a database record with the value of the same field in the index. Only the keys of
+// the database record are compared, the documents can contain fields derived from the record. Numbers are compared
+// by value, dates with millisecond precision and json columns stored as strings are compared with their parsed value.
+func equalValues(dbValue interface{}, esValue interface{}) bool {
+	if dbValue == nil || esValue == nil {
+		return dbValue == nil && esValue == nil
+	}
+
+	switch db := dbValue.(type) {
+	case map[string]interface{}:
+		es, ok := parseJSONValue(esValue).(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range db {
+			if !equalValues(value, es[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		es, ok := parseJSONValue(esValue).([]interface{})
+		if !ok || len(db) != len(es) {
+			return false
+		}
+		for idx := range db {
+			if !equalValues(db[idx], es[idx]) {
+				return false
+			}
+		}
+		return true
+	case string:
+		switch esValue.(type) {
+		case map[string]interface{}, []interface{}:
+			return equalValues(parseJSONValue(db), esValue)
+		}
+	}
+
+	if reflect.DeepEqual(dbValue, esValue) ||
+		elasticsearch.FormatValue(dbValue) == elasticsearch.FormatValue(esValue) {
+		return true
+	}
+
+	if dbNumber, ok := parseNumber(dbValue); ok {
+		if esNumber, ok := parseNumber(esValue); ok {
+			return dbNumber == esNumber
+		}
+	}
+
+	if dbTime, ok := parseTime(dbValue); ok {
+		if esTime, ok := parseTime(esValue); ok {
+			return dbTime.UnixMilli() == esTime.UnixMilli()
+		}
+	}
+
+	return false
+}
+
+// differentFields returns the dot separated paths of the fields of a database record whose values differ from the
+// document. Nested objects are compared field by field, any other value is compared as a whole.
+func differentFields(dbValue interface{}, esValue interface{}, path string) (fields []string) {
+	db, dbIsObject := parseJSONValue(dbValue).(map[string]interface{})
+	es, esIsObject := parseJSONValue(esValue).(map[string]interface{})
+	if !dbIsObject || !esIsObject {
+		if equalValues(dbValue, esValue) {
+			return nil
+		}
+		return []string{path}
+	}
+
+	keys := make([]string, 0, len(db))
+	for key := range db {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		fields = append(fields, differentFields(db[key], es[key], fieldPath)...)
+	}
+	return fields
+}
+
+// canonicalValue formats a value of a database record or a document so that values compared equal by equalValues are
+// usually formatted the same way: json strings are parsed, numbers are formatted by value, dates as milliseconds since
+// the epoch, object keys are sorted and null fields are omitted. Values formatted differently are compared again by
+// equalValues, e.g. a date indexed as a number.
+func canonicalValue(value interface{}) string {
+	switch v := parseJSONValue(value).(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key, fieldValue := range v {
+			if fieldValue != nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var builder strings.Builder
+		builder.WriteString("{")
+		for idx, key := range keys {
+			if idx > 0 {
+				builder.WriteString(",")
+			}
+			builder.WriteString(strconv.Quote(key) + ":" + canonicalValue(v[key]))
+		}
+		builder.WriteString("}")
+		return builder.String()
+	case []interface{}:
+		values := make([]string, len(v))
+		for idx, item := range v {
+			values[idx] = canonicalValue(item)
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	}
+
+	if number, ok := parseNumber(value); ok {
+		return strconv.FormatFloat(number, 'g', -1, 64)
+	}
+	if _, ok := value.(string); ok {
+		if parsed, ok := parseTime(value); ok {
+			return strconv.FormatInt(parsed.UnixMilli(), 10)
+		}
+	}
+	return strconv.Quote(elasticsearch.FormatValue(value))
+}
+
+// parseJSONValue parses a string that contains a json object or array, any other value is returned as is
+func parseJSONValue(value interface{}) interface{} {
+	str, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	var parsed interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(str)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return value
+	}
+	return parsed
+}
+
+func parseNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// parseTime parses a date string or a number of milliseconds since the epoch
+func parseTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		millis, err := v.Int64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.UnixMilli(millis), true
+	case string:
+		for _, layout := range dateLayouts {
+			if parsed, err := time.Parse(layout, v); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
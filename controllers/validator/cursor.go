@@ -0,0 +1,113 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+)
+
+// CursorStrategy compares the next BatchSize records of each data source in the order of their ids, then the next
+// BatchSize documents of the index in the order of their ids. The Cursor keeps the position between reconciles and
+// the mismatches found so far, the result is complete when the Cursor is done.
+type CursorStrategy struct {
+	BatchSize int
+	Cursor    *v1alpha1.ValidationCursorStatus
+}
+
+// NewCursorStrategy builds a CursorStrategy that continues from cursor. The positions of the data sources that are no
+// longer validated are removed.
+func NewCursorStrategy(batchSize int, cursor *v1alpha1.ValidationCursorStatus,
+	dataSources []DataSource) CursorStrategy {
+
+	var positions []v1alpha1.ValidationCursorPosition
+	for _, dataSource := range dataSources {
+		position := v1alpha1.ValidationCursorPosition{Path: dataSource.Path}
+		for _, existing := range cursor.DataSources {
+			if existing.Path == dataSource.Path {
+				position = existing
+			}
+		}
+		positions = append(positions, position)
+	}
+	cursor.DataSources = positions
+
+	return CursorStrategy{BatchSize: batchSize, Cursor: cursor}
+}
+
+func (s CursorStrategy) position(dataSource DataSource) (*v1alpha1.ValidationCursorPosition, error) {
+	for idx := range s.Cursor.DataSources {
+		if s.Cursor.DataSources[idx].Path == dataSource.Path {
+			return &s.Cursor.DataSources[idx], nil
+		}
+	}
+	return nil, errors.Wrap(fmt.Errorf("no cursor position for data source %s", dataSource.Path), 0)
+}
+
+func (s CursorStrategy) validateDataSource(v *Validator, dataSource DataSource) (result dataSourceResult, err error) {
+	position, err := s.position(dataSource)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	result.previousMismatches = position.Mismatched
+
+	//records of the database that are missing from the index or have different content
+	if !position.DatabaseDone {
+		ids, err := dataSource.Database.RecordIdsAfter(
+			dataSource.Schema, dataSource.Table, dataSource.PrimaryKey, position.DatabaseID, s.BatchSize)
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+
+		if len(ids) > 0 {
+			dbIds, esIds, err := v.lookupIds(dataSource, ids)
+			if err != nil {
+				return result, errors.Wrap(err, 0)
+			}
+			err = v.compareIds(dataSource, dbIds, esIds, &result)
+			if err != nil {
+				return result, errors.Wrap(err, 0)
+			}
+			err = v.compareContent(dataSource, utils.Difference(dbIds, result.missingFromES), &result)
+			if err != nil {
+				return result, errors.Wrap(err, 0)
+			}
+
+			position.DatabaseID = ids[len(ids)-1]
+			position.Records += len(ids)
+		}
+		position.DatabaseDone = len(ids) < s.BatchSize
+	}
+
+	//documents of the index whose record is missing from the database
+	if !position.ElasticsearchDone {
+		ids, last, err := v.Elasticsearch.FieldValuesAfter(
+			v.IndexName, idField(dataSource), position.ElasticsearchSort, s.BatchSize)
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+
+		if len(ids) > 0 {
+			dbIds, esIds, err := v.lookupIds(dataSource, ids)
+			if err != nil {
+				return result, errors.Wrap(err, 0)
+			}
+			err = v.compareIds(dataSource, nil, utils.Difference(esIds, dbIds), &result)
+			if err != nil {
+				return result, errors.Wrap(err, 0)
+			}
+		}
+		if last != "" {
+			position.ElasticsearchSort = last
+		}
+		position.ElasticsearchDone = len(ids) < s.BatchSize
+	}
+
+	position.Mismatched = result.mismatchCount()
+	result.records = position.Records
+	result.message = fmt.Sprintf("%s: %v records validated, %v do not match.",
+		dataSource.DataSource, position.Records, position.Mismatched)
+
+	return result, nil
+}
@@ -0,0 +1,136 @@
+package validator
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
+)
+
+// mismatch is a record whose content differs between the database and the index
+type mismatch struct {
+	validation.MismatchContentDetails
+	fields []string //paths of the fields with different values
+}
+
+// fullValidation compares the content of the records with the given ids. The ids are split into chunks which are
+// validated in batches of NumThreads in parallel, the mismatched records are validated again to account for lag.
+func (v *Validator) fullValidation(dataSource DataSource, columns []string, ids []string) (
+	mismatches []mismatch, err error) {
+
+	chunked := chunks(ids, v.ChunkSize)
+	allMismatches := make(chan mismatch, len(ids))
+	errorsChan := make(chan error, len(chunked))
+	numThreads := 0
+	wg := new(sync.WaitGroup)
+
+	v.Log.Info("Full Validation Start: "+time.Now().String(), "dataSource", dataSource.DataSource)
+
+	for idx, chunk := range chunked {
+		wg.Add(1)
+		numThreads += 1
+		go v.validateChunkAsync(dataSource, columns, chunk, allMismatches, errorsChan, wg)
+
+		if numThreads == v.NumThreads || idx == len(chunked)-1 {
+			wg.Wait()
+			numThreads = 0
+		}
+	}
+
+	v.Log.Info("Full Validation End: "+time.Now().String(), "dataSource", dataSource.DataSource)
+
+	close(allMismatches)
+	close(errorsChan)
+
+	if len(errorsChan) > 0 {
+		for e := range errorsChan {
+			v.Log.Error(e, "Error during full validation")
+		}
+		return nil, errors.New("Error during full validation")
+	}
+
+	//double check mismatched records to account for lag
+	var mismatchedIds []string
+	for m := range allMismatches {
+		mismatchedIds = append(mismatchedIds, m.ID)
+	}
+
+	for _, chunk := range chunks(mismatchedIds, v.ChunkSize) {
+		chunkMismatches, err := v.validateChunk(dataSource, columns, chunk)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		mismatches = append(mismatches, chunkMismatches...)
+	}
+
+	return mismatches, nil
+}
+
+func (v *Validator) validateChunkAsync(dataSource DataSource, columns []string, chunk []string,
+	allMismatches chan mismatch, errorsChan chan error, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+
+	mismatches, err := v.validateChunk(dataSource, columns, chunk)
+	if err != nil {
+		errorsChan <- err
+		return
+	}
+
+	for _, m := range mismatches {
+		allMismatches <- m
+	}
+}
+
+// validateChunk compares the columns of each record with the field of the document that contains the record. Records
+// missing from either side are skipped, they are reported by the id validation.
+func (v *Validator) validateChunk(dataSource DataSource, columns []string, chunk []string) (
+	mismatches []mismatch, err error) {
+
+	records, err := dataSource.Database.RecordsByIds(
+		dataSource.Schema, dataSource.Table, dataSource.PrimaryKey, columns, chunk)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	documents, err := v.Elasticsearch.DocumentsByFieldValues(
+		v.IndexName, dataSource.Path+"."+dataSource.PrimaryKey, chunk)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	for _, id := range chunk {
+		record, inDatabase := records[id]
+		document, inElasticsearch := documents[id]
+		if !inDatabase || !inElasticsearch {
+			continue
+		}
+
+		esRecord, _ := elasticsearch.SourceValue(document, dataSource.Path)
+		if equalValues(record, esRecord) {
+			continue
+		}
+
+		databaseContent, err := json.Marshal(record)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		elasticsearchContent, err := json.Marshal(esRecord)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		mismatches = append(mismatches, mismatch{
+			MismatchContentDetails: validation.MismatchContentDetails{
+				ID:                   id,
+				DatabaseContent:      string(databaseContent),
+				ElasticsearchContent: string(elasticsearchContent),
+			},
+			fields: differentFields(record, esRecord, ""),
+		})
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,120 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+	"github.com/redhatinsights/xjoin-operator/controllers/database"
+)
+
+// HashRangeStrategy compares the count, then splits the ids into Buckets ranges of their hash. The number of records
+// and the sum of their checksums are compared for each range, only the ids and content of the records of the
+// mismatched ranges are compared. The checksum of a record covers its id and content, so a record whose content
+// differs mismatches its range.
+type HashRangeStrategy struct {
+	Buckets int
+}
+
+func (s HashRangeStrategy) validateDataSource(v *Validator, dataSource DataSource) (
+	result dataSourceResult, err error) {
+
+	result, ok, err := v.validateCount(dataSource)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	} else if !ok {
+		return result, nil
+	}
+
+	columns, err := v.columns(dataSource)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	dbBuckets := make(map[int]database.Bucket)
+	err = dataSource.Database.ScrollRecords(dataSource.Schema, dataSource.Table, dataSource.PrimaryKey, columns,
+		func(id string, record map[string]interface{}) {
+			s.addRecord(dbBuckets, id, record)
+		})
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	esBuckets := make(map[int]database.Bucket)
+	err = v.Elasticsearch.ScrollFieldSources(v.IndexName, idField(dataSource), dataSource.Path,
+		func(id string, source interface{}) {
+			s.addRecord(esBuckets, id, documentRecord(source, dataSource.PrimaryKey, columns))
+		})
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+
+	var mismatchedBuckets []int
+	for bucket := 0; bucket < s.Buckets; bucket++ {
+		if dbBuckets[bucket] != esBuckets[bucket] {
+			mismatchedBuckets = append(mismatchedBuckets, bucket)
+		}
+	}
+	v.Log.Info("Hash range validation results", "dataSource", dataSource.DataSource,
+		"buckets", s.Buckets, "mismatchedBuckets", len(mismatchedBuckets))
+
+	//drill down into the mismatched ranges
+	if len(mismatchedBuckets) > 0 {
+		dbIds, err := dataSource.Database.RecordIdsInBuckets(
+			dataSource.Schema, dataSource.Table, dataSource.PrimaryKey, s.Buckets, mismatchedBuckets)
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+
+		selected := make(map[int]bool, len(mismatchedBuckets))
+		for _, bucket := range mismatchedBuckets {
+			selected[bucket] = true
+		}
+		var esIds []string
+		err = v.Elasticsearch.ScrollFieldValues(v.IndexName, idField(dataSource), func(id string) {
+			if selected[database.IdBucket(id, s.Buckets)] {
+				esIds = append(esIds, id)
+			}
+		})
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+
+		err = v.compareIds(dataSource, dbIds, esIds, &result)
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+		err = v.compareContent(dataSource, utils.Difference(dbIds, result.missingFromES), &result)
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+	}
+
+	result.message = fmt.Sprintf(
+		"%s: %v of %v hash ranges do not match, %v missing from elasticsearch, %v only in elasticsearch, "+
+			"%v with mismatched content.",
+		dataSource.DataSource, len(mismatchedBuckets), s.Buckets, len(result.missingFromES), len(result.onlyInES),
+		len(result.mismatches))
+
+	return result, nil
+}
+
+// addRecord adds the checksum of the id and content of a record to its hash range
+func (s HashRangeStrategy) addRecord(buckets map[int]database.Bucket, id string, record map[string]interface{}) {
+	bucket, checksum := database.RecordHash(id, canonicalValue(record), s.Buckets)
+	b := buckets[bucket]
+	b.Add(checksum)
+	buckets[bucket] = b
+}
+
+// documentRecord returns the primary key and columns of the record contained in a document, the document can contain
+// fields derived from the record that are not compared
+func documentRecord(source interface{}, primaryKey string, columns []string) map[string]interface{} {
+	record := make(map[string]interface{})
+	object, ok := parseJSONValue(source).(map[string]interface{})
+	if !ok {
+		return record
+	}
+	for _, column := range append([]string{primaryKey}, columns...) {
+		record[column] = object[column]
+	}
+	return record
+}
@@ -0,0 +1,70 @@
+package validator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/redhatinsights/xjoin-operator/controllers/database"
+)
+
+func TestHashRangeRecordChecksum(t *testing.T) {
+	columns := []string{"display_name", "count", "facts", "modified_on", "tags"}
+	record := map[string]interface{}{
+		"id":           "1",
+		"display_name": "host",
+		"count":        json.Number("2"),
+		"facts":        `{"os": "rhel", "cores": 4}`,
+		"modified_on":  "2023-01-02T03:04:05.123456",
+		"tags":         nil,
+	}
+
+	tests := []struct {
+		name     string
+		document interface{}
+		matches  bool
+	}{{
+		name: "equal content",
+		document: map[string]interface{}{
+			"id":           "1",
+			"display_name": "host",
+			"count":        json.Number("2.0"),
+			"facts":        map[string]interface{}{"cores": json.Number("4"), "os": "rhel"},
+			"modified_on":  "2023-01-02T03:04:05.123Z",
+			"derived":      "not a column",
+		},
+		matches: true,
+	}, {
+		name: "different content",
+		document: map[string]interface{}{
+			"id":           "1",
+			"display_name": "renamed",
+			"count":        json.Number("2"),
+			"facts":        map[string]interface{}{"cores": json.Number("4"), "os": "rhel"},
+			"modified_on":  "2023-01-02T03:04:05.123Z",
+		},
+	}, {
+		name:     "missing record",
+		document: nil,
+	}}
+
+	strategy := HashRangeStrategy{Buckets: 16}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbBuckets := make(map[int]database.Bucket)
+			strategy.addRecord(dbBuckets, "1", record)
+			esBuckets := make(map[int]database.Bucket)
+			strategy.addRecord(esBuckets, "1", documentRecord(test.document, "id", columns))
+
+			bucket := database.IdBucket("1", strategy.Buckets)
+			if esBuckets[bucket].Count != 1 {
+				t.Fatalf("expected the document in the hash range %d of its id", bucket)
+			}
+			if (dbBuckets[bucket] == esBuckets[bucket]) != test.matches {
+				t.Errorf("expected the hash range of the record to match: %t", test.matches)
+			}
+			if test.matches && !equalValues(record, test.document) {
+				t.Error("expected the record and the document to be compared equal")
+			}
+		})
+	}
+}
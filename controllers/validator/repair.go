@@ -0,0 +1,45 @@
+package validator
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+)
+
+// RepairableIds returns the ids of the mismatched records that can be emitted again by their data source, grouped by
+// data source. Documents missing in the source can't be repaired because there is no record to emit.
+func RepairableIds(report Report) map[string][]string {
+	ids := make(map[string][]string)
+	for _, entry := range report.Entries {
+		if entry.DataSource != "" && (entry.Type == MissingInSink || entry.Type == DifferentFields) {
+			ids[entry.DataSource] = append(ids[entry.DataSource], entry.ID)
+		}
+	}
+	return ids
+}
+
+// Repair touches the rows of the repairable records of a report so they flow through the pipelines again. The
+// number of touched rows is returned, records deleted since the validation are skipped.
+func (v *Validator) Repair(report Report) (repaired int, err error) {
+	ids := RepairableIds(report)
+
+	for _, dataSource := range v.DataSources {
+		dataSourceIds := ids[dataSource.DataSource]
+		if len(dataSourceIds) == 0 {
+			continue
+		}
+
+		for _, chunk := range chunks(dataSourceIds, v.ChunkSize) {
+			touched, err := dataSource.Database.TouchRows(
+				dataSource.Schema, dataSource.Table, dataSource.PrimaryKey, chunk)
+			if err != nil {
+				return repaired, errors.Wrap(err, 0)
+			}
+			repaired += int(touched)
+		}
+
+		v.Log.Info("Repaired mismatched records", "dataSource", dataSource.DataSource,
+			"ids", dataSourceIds[:utils.Min(idDiffMaxLength, len(dataSourceIds))])
+	}
+
+	return repaired, nil
+}
@@ -0,0 +1,61 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+
+	logger "github.com/redhatinsights/xjoin-operator/controllers/log"
+)
+
+func TestRepairableIds(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []ReportEntry
+		ids     map[string][]string
+	}{{
+		name: "grouped by data source",
+		entries: []ReportEntry{
+			{DataSource: "hosts", ID: "1", Type: MissingInSink},
+			{DataSource: "groups", ID: "2", Type: DifferentFields, Fields: []string{"name"}},
+			{DataSource: "hosts", ID: "3", Type: DifferentFields, Fields: []string{"display_name"}},
+		},
+		ids: map[string][]string{"hosts": {"1", "3"}, "groups": {"2"}},
+	}, {
+		name:    "missing in source",
+		entries: []ReportEntry{{DataSource: "hosts", ID: "1", Type: MissingInSource}},
+		ids:     map[string][]string{},
+	}, {
+		name:    "unknown data source",
+		entries: []ReportEntry{{ID: "1", Type: MissingInSink}},
+		ids:     map[string][]string{},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids := RepairableIds(Report{Mismatched: len(test.entries), Entries: test.entries})
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Errorf("expected ids %v, got %v", test.ids, ids)
+			}
+		})
+	}
+}
+
+func TestRepairWithoutRepairableIds(t *testing.T) {
+	//the database of a data source without repairable records is never used
+	v := Validator{
+		DataSources: []DataSource{{Schema: "public", Table: "hosts"}},
+		ChunkSize:   10,
+		Log:         logger.NewLogger("test"),
+	}
+	v.DataSources[0].DataSource = "hosts"
+
+	repaired, err := v.Repair(Report{Mismatched: 1, Entries: []ReportEntry{
+		{DataSource: "hosts", ID: "1", Type: MissingInSource},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repaired != 0 {
+		t.Errorf("expected 0 repaired records, got %d", repaired)
+	}
+}
@@ -0,0 +1,80 @@
+package validator
+
+import validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+
+// types of the entries of a mismatch report
+const (
+	MissingInSink   = "missingInSink"   //the record is in the data source but not in the index
+	MissingInSource = "missingInSource" //the document is in the index but the record is not in the data source
+	DifferentFields = "differentFields" //the record and the document have different values
+)
+
+// Report lists the mismatched records found by a validation run. The number of entries is bounded, Mismatched is
+// the number of mismatched records including the ones that aren't listed.
+type Report struct {
+	Result     string        `json:"result"`
+	Message    string        `json:"message,omitempty"`
+	Mismatched int           `json:"mismatched"`
+	Entries    []ReportEntry `json:"entries"`
+	Partial    bool          `json:"partial,omitempty"` //a cursor validation is in progress
+}
+
+// ReportEntry is a mismatched record
+type ReportEntry struct {
+	DataSource string   `json:"dataSource,omitempty"`
+	ID         string   `json:"id"`
+	Type       string   `json:"type"`
+	Fields     []string `json:"fields,omitempty"` //paths of the fields with different values
+}
+
+// add appends an entry unless the report already contains maxEntries entries
+func (r *Report) add(maxEntries int, entry ReportEntry) {
+	if len(r.Entries) < maxEntries {
+		r.Entries = append(r.Entries, entry)
+	}
+}
+
+// Merge appends the entries of a previous report of the same cursor validation, the entries remain bounded
+func (r *Report) Merge(previous Report, maxEntries int) {
+	entries := r.Entries
+	r.Entries = []ReportEntry{}
+	for _, entry := range append(previous.Entries, entries...) {
+		r.add(maxEntries, entry)
+	}
+}
+
+// ReportFromResponse builds the report of a validation response e.g. the output of the xjoin-validation pod. The
+// fields of the mismatched records are compared using the database and elasticsearch content of the response.
+func ReportFromResponse(response validation.ValidationResponse, maxEntries int) Report {
+	report := Report{
+		Result:     response.Result,
+		Message:    response.Message,
+		Mismatched: response.Details.TotalMismatch,
+		Entries:    []ReportEntry{},
+	}
+
+	for _, id := range response.Details.IdsMissingFromElasticsearch {
+		report.add(maxEntries, ReportEntry{ID: id, Type: MissingInSink})
+	}
+	for _, id := range response.Details.IdsOnlyInElasticsearch {
+		report.add(maxEntries, ReportEntry{ID: id, Type: MissingInSource})
+	}
+
+	withContent := make(map[string]bool)
+	for _, details := range response.Details.MismatchContentDetails {
+		withContent[details.ID] = true
+		fields := differentFields(
+			parseJSONValue(details.DatabaseContent), parseJSONValue(details.ElasticsearchContent), "")
+		report.add(maxEntries, ReportEntry{ID: details.ID, Type: DifferentFields, Fields: fields})
+	}
+	for _, id := range response.Details.IdsWithMismatchContent {
+		if !withContent[id] {
+			report.add(maxEntries, ReportEntry{ID: id, Type: DifferentFields})
+		}
+	}
+
+	if report.Mismatched < len(report.Entries) {
+		report.Mismatched = len(report.Entries)
+	}
+	return report
+}
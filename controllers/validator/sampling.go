@@ -0,0 +1,73 @@
+package validator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+)
+
+// SamplingStrategy compares the count, then a random sample of ids from the database and from the index. The content
+// of the sampled records is compared. The mismatch ratio of the data source is the upper bound of the mismatch ratio
+// of the sample at the Confidence level, so a data source is only valid when the sample is large enough.
+type SamplingStrategy struct {
+	SampleSize int
+	Confidence float64 //e.g. 0.95
+}
+
+func (s SamplingStrategy) validateDataSource(v *Validator, dataSource DataSource) (result dataSourceResult, err error) {
+	result, ok, err := v.validateCount(dataSource)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	} else if !ok {
+		return result, nil
+	}
+
+	dbSample, err := dataSource.Database.SampleRecordIds(
+		dataSource.Schema, dataSource.Table, dataSource.PrimaryKey, s.SampleSize, result.records)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	esSample, err := v.Elasticsearch.SampleFieldValues(v.IndexName, idField(dataSource), s.SampleSize)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	sample := append(dbSample, utils.Difference(esSample, dbSample)...)
+
+	dbIds, esIds, err := v.lookupIds(dataSource, sample)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	err = v.compareIds(dataSource, dbIds, esIds, &result)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	err = v.compareContent(dataSource, utils.Difference(dbIds, result.missingFromES), &result)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+
+	result.sampled = len(sample)
+	if result.sampled > 0 {
+		result.upperBound = upperBound(result.mismatchCount(), result.sampled, s.Confidence)
+	}
+	result.message = fmt.Sprintf(
+		"%s: %v of %v sampled records do not match, at most %.2f%% of %v records do not match with %.0f%% confidence.",
+		dataSource.DataSource, result.mismatchCount(), result.sampled, result.upperBound*100, result.records,
+		s.Confidence*100)
+
+	return result, nil
+}
+
+// upperBound returns the upper bound of the one-sided Wilson score interval of the ratio of mismatched records at the
+// confidence level
+func upperBound(mismatched int, sampled int, confidence float64) float64 {
+	z := math.Sqrt2 * math.Erfinv(2*confidence-1)
+	n := float64(sampled)
+	p := float64(mismatched) / n
+
+	center := p + z*z/(2*n)
+	margin := z * math.Sqrt(p*(1-p)/n+z*z/(4*n*n))
+	return math.Min((center+margin)/(1+z*z/n), 1)
+}
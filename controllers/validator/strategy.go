@@ -0,0 +1,46 @@
+package validator
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+)
+
+// Strategy selects the records of a data source that are compared with the index
+type Strategy interface {
+	validateDataSource(v *Validator, dataSource DataSource) (dataSourceResult, error)
+}
+
+// FullStrategy compares the count, every id and the content of every record. Every id of the data source is loaded
+// into memory.
+type FullStrategy struct{}
+
+func (s FullStrategy) validateDataSource(v *Validator, dataSource DataSource) (result dataSourceResult, err error) {
+	result, ok, err := v.validateCount(dataSource)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	} else if !ok {
+		return result, nil
+	}
+
+	//id validation
+	dbIds, err := dataSource.Database.RecordIds(dataSource.Schema, dataSource.Table, dataSource.PrimaryKey)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	esIds, err := v.Elasticsearch.FieldValues(v.IndexName, idField(dataSource))
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	err = v.compareIds(dataSource, dbIds, esIds, &result)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+
+	//full validation of the records that exist in both
+	err = v.compareContent(dataSource, utils.Difference(dbIds, result.missingFromES), &result)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+
+	return result, nil
+}
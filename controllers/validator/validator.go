@@ -0,0 +1,299 @@
+package validator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+	"github.com/redhatinsights/xjoin-operator/controllers/avro"
+	"github.com/redhatinsights/xjoin-operator/controllers/database"
+	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
+	logger "github.com/redhatinsights/xjoin-operator/controllers/log"
+)
+
+// results of a validation, the same values are returned by the xjoin-validation pod
+const (
+	Valid   = "valid"
+	Invalid = "invalid"
+)
+
+// idDiffMaxLength limits the number of ids and mismatched records included in a validation response
+const idDiffMaxLength = 50
+
+// Validator compares the records of the data source tables with the documents of an elasticsearch index. The Strategy
+// selects the records of each data source that are compared, by default the number of records is compared first, then
+// the ids, then the content of each record in chunks validated in parallel. Mismatched records are checked a second
+// time to account for replication lag.
+type Validator struct {
+	Elasticsearch       *elasticsearch.GenericElasticsearch
+	IndexName           string
+	DataSources         []DataSource
+	Strategy            Strategy //defaults to FullStrategy
+	ChunkSize           int
+	NumThreads          int
+	PercentageThreshold int //percentage of mismatched records allowed for the index to be valid
+	MaxReportEntries    int //maximum number of mismatched records listed in the report
+	Log                 logger.Log
+}
+
+// DataSource is the table of a data source and the field of the index documents that contains its records
+type DataSource struct {
+	avro.DataSourceField
+	Database *database.Database
+	Schema   string
+	Table    string
+}
+
+// dataSourceResult is the outcome of validating a single data source
+type dataSourceResult struct {
+	records            int
+	countMismatch      int //only set when the count validation fails, the ids and content are not validated
+	missingFromES      []string
+	onlyInES           []string
+	mismatches         []mismatch
+	previousMismatches int     //mismatches found by the previous reconciles of a cursor validation
+	sampled            int     //number of records compared by a sampling validation
+	upperBound         float64 //upper bound of the ratio of mismatched records estimated from the sample
+	message            string  //replaces the default message of the data source
+}
+
+func (r dataSourceResult) mismatchCount() int {
+	if r.countMismatch > 0 {
+		return r.countMismatch
+	}
+	return r.previousMismatches + len(r.missingFromES) + len(r.onlyInES) + len(r.mismatches)
+}
+
+// estimatedMismatches is the number of mismatched records the result is computed with. A sampling validation
+// estimates it from the upper bound of the mismatch ratio of the sample.
+func (r dataSourceResult) estimatedMismatches() float64 {
+	if r.sampled > 0 && r.countMismatch == 0 {
+		return r.upperBound * float64(r.records)
+	}
+	return float64(r.mismatchCount())
+}
+
+// Validate validates each data source and combines the results. The index is valid when the percentage of mismatched
+// records across all data sources is within PercentageThreshold. The report lists the mismatched records.
+func (v *Validator) Validate() (response validation.ValidationResponse, report Report, err error) {
+	var messages []string
+	report.Entries = []ReportEntry{}
+	totalRecords := 0
+	estimatedMismatches := 0.0
+
+	strategy := v.Strategy
+	if strategy == nil {
+		strategy = FullStrategy{}
+	}
+
+	for _, dataSource := range v.DataSources {
+		result, err := strategy.validateDataSource(v, dataSource)
+		if err != nil {
+			return response, report, errors.Wrap(err, 0)
+		}
+
+		totalRecords += result.records
+		estimatedMismatches += result.estimatedMismatches()
+		details := &response.Details
+		details.TotalMismatch += result.mismatchCount()
+		details.IdsMissingFromElasticsearchCount += len(result.missingFromES)
+		details.IdsMissingFromElasticsearch = appendBounded(details.IdsMissingFromElasticsearch, result.missingFromES)
+		details.IdsOnlyInElasticsearchCount += len(result.onlyInES)
+		details.IdsOnlyInElasticsearch = appendBounded(details.IdsOnlyInElasticsearch, result.onlyInES)
+		for _, m := range result.mismatches {
+			details.IdsWithMismatchContent = appendBounded(details.IdsWithMismatchContent, []string{m.ID})
+			if len(details.MismatchContentDetails) < idDiffMaxLength {
+				details.MismatchContentDetails = append(details.MismatchContentDetails, m.MismatchContentDetails)
+			}
+		}
+
+		for _, id := range result.missingFromES {
+			report.add(v.MaxReportEntries, ReportEntry{DataSource: dataSource.DataSource, ID: id, Type: MissingInSink})
+		}
+		for _, id := range result.onlyInES {
+			report.add(v.MaxReportEntries, ReportEntry{DataSource: dataSource.DataSource, ID: id, Type: MissingInSource})
+		}
+		for _, m := range result.mismatches {
+			report.add(v.MaxReportEntries, ReportEntry{
+				DataSource: dataSource.DataSource, ID: m.ID, Type: DifferentFields, Fields: m.fields})
+		}
+
+		if result.message != "" {
+			messages = append(messages, result.message)
+		} else if result.countMismatch > 0 {
+			messages = append(messages, fmt.Sprintf("%s: count validation failed - %v of %v records do not match.",
+				dataSource.DataSource, result.countMismatch, result.records))
+		} else {
+			messages = append(messages, fmt.Sprintf(
+				"%s: %v records validated, %v missing from elasticsearch, %v only in elasticsearch, %v with mismatched content.",
+				dataSource.DataSource, result.records, len(result.missingFromES), len(result.onlyInES),
+				len(result.mismatches)))
+		}
+	}
+
+	mismatchRatio := estimatedMismatches / math.Max(float64(totalRecords), 1)
+	if mismatchRatio*100 <= float64(v.PercentageThreshold) {
+		response.Result = Valid
+	} else {
+		response.Result = Invalid
+		response.Reason = fmt.Sprintf("%v records (%.2f%%) do not match", math.Round(estimatedMismatches),
+			mismatchRatio*100)
+	}
+	response.Message = strings.Join(messages, " ")
+
+	report.Result = response.Result
+	report.Message = response.Message
+	report.Mismatched = response.Details.TotalMismatch
+
+	v.Log.Info("Validation results",
+		"index", v.IndexName,
+		"result", response.Result,
+		"validationThresholdPercent", v.PercentageThreshold,
+		"mismatchRatio", mismatchRatio,
+		"mismatchCount", response.Details.TotalMismatch,
+		"totalRecords", totalRecords)
+
+	return response, report, nil
+}
+
+// validateCount compares the number of records of a data source with the number of documents that contain the record.
+// ok is false when the counts are too far apart for the ids and content to be compared.
+func (v *Validator) validateCount(dataSource DataSource) (result dataSourceResult, ok bool, err error) {
+	result.records, err = dataSource.Database.CountRows(dataSource.Schema, dataSource.Table)
+	if err != nil {
+		return result, false, errors.Wrap(err, 0)
+	}
+	esCount, err := v.Elasticsearch.CountDocumentsWithField(v.IndexName, idField(dataSource))
+	if err != nil {
+		return result, false, errors.Wrap(err, 0)
+	}
+
+	countMismatch := int(math.Abs(float64(result.records - esCount)))
+	v.Log.Info("Fetched record counts", "dataSource", dataSource.DataSource,
+		"database", result.records, "elasticsearch", esCount)
+	if float64(countMismatch)/math.Max(float64(result.records), 1)*100 > float64(v.PercentageThreshold) {
+		result.countMismatch = countMismatch
+		return result, false, nil
+	}
+	return result, true, nil
+}
+
+// compareIds adds the ids read from the database that are not in the ids read from the index and vice versa to the
+// result. The mismatched ids are retrieved a second time to check if they were invalid due to lag.
+func (v *Validator) compareIds(dataSource DataSource, dbIds []string, esIds []string, result *dataSourceResult) error {
+	missingFromES := utils.Difference(dbIds, esIds)
+	onlyInES := utils.Difference(esIds, dbIds)
+
+	if len(missingFromES)+len(onlyInES) > 0 {
+		var err error
+		missingFromES, onlyInES, err = v.recheckIds(dataSource, append(missingFromES, onlyInES...))
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+
+	v.Log.Info("ID validation results",
+		"dataSource", dataSource.DataSource,
+		"missingFromElasticsearch", missingFromES[:utils.Min(idDiffMaxLength, len(missingFromES))],
+		"onlyInElasticsearch", onlyInES[:utils.Min(idDiffMaxLength, len(onlyInES))])
+
+	result.missingFromES = append(result.missingFromES, missingFromES...)
+	result.onlyInES = append(result.onlyInES, onlyInES...)
+	return nil
+}
+
+// compareContent adds the records whose content differs from the index to the result
+func (v *Validator) compareContent(dataSource DataSource, ids []string, result *dataSourceResult) error {
+	columns, err := v.columns(dataSource)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	mismatches, err := v.fullValidation(dataSource, columns, ids)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+
+	result.mismatches = append(result.mismatches, mismatches...)
+	return nil
+}
+
+// lookupIds returns the ids that exist in the database and the ids that exist in the index
+func (v *Validator) lookupIds(dataSource DataSource, ids []string) (dbIds []string, esIds []string, err error) {
+	for _, chunk := range chunks(ids, v.ChunkSize) {
+		records, err := dataSource.Database.RecordsByIds(
+			dataSource.Schema, dataSource.Table, dataSource.PrimaryKey, nil, chunk)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, 0)
+		}
+		documents, err := v.Elasticsearch.DocumentsByFieldValues(v.IndexName, idField(dataSource), chunk)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, 0)
+		}
+
+		for _, id := range chunk {
+			if _, ok := records[id]; ok {
+				dbIds = append(dbIds, id)
+			}
+			if _, ok := documents[id]; ok {
+				esIds = append(esIds, id)
+			}
+		}
+	}
+
+	return dbIds, esIds, nil
+}
+
+// recheckIds retrieves the mismatched ids from the database and elasticsearch a second time
+func (v *Validator) recheckIds(dataSource DataSource, ids []string) (missingFromES []string, onlyInES []string,
+	err error) {
+
+	dbIds, esIds, err := v.lookupIds(dataSource, ids)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, 0)
+	}
+
+	return utils.Difference(dbIds, esIds), utils.Difference(esIds, dbIds), nil
+}
+
+// columns returns the fields of the data source record that are columns of the table
+func (v *Validator) columns(dataSource DataSource) (columns []string, err error) {
+	tableColumns, err := dataSource.Database.TableColumns(dataSource.Schema, dataSource.Table)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	for _, field := range dataSource.Fields {
+		if _, ok := tableColumns[field]; ok {
+			columns = append(columns, field)
+		}
+	}
+	return columns, nil
+}
+
+// idField is the field of the index documents that contains the primary key of the data source record
+func idField(dataSource DataSource) string {
+	return dataSource.Path + "." + dataSource.PrimaryKey
+}
+
+func appendBounded(ids []string, more []string) []string {
+	for _, id := range more {
+		if len(ids) >= idDiffMaxLength {
+			break
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func chunks(ids []string, chunkSize int) (chunked [][]string) {
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	for start := 0; start < len(ids); start += chunkSize {
+		chunked = append(chunked, ids[start:utils.Min(start+chunkSize, len(ids))])
+	}
+	return chunked
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/go-logr/logr"
 	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
 	"github.com/redhatinsights/xjoin-operator/controllers/config"
 	. "github.com/redhatinsights/xjoin-operator/controllers/datasource"
 	"github.com/redhatinsights/xjoin-operator/controllers/index"
@@ -20,7 +21,9 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"time"
 )
 
@@ -64,11 +67,33 @@ func (r *XJoinDataSourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			LogConstructor: logConstructor,
 			RateLimiter:    workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 1*time.Minute),
 		}).
+
+		// trigger Reconcile when an IndexPipeline using the DataSource changes to release retained versions
+		Watches(
+			&source.Kind{Type: &xjoin.XJoinIndexPipeline{}},
+			handler.EnqueueRequestsFromMapFunc(func(indexPipeline client.Object) (requests []reconcile.Request) {
+				pipeline, ok := indexPipeline.(*xjoin.XJoinIndexPipeline)
+				if !ok {
+					return requests
+				}
+
+				for dataSourceName := range pipeline.Status.DataSources {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{
+							Namespace: pipeline.GetNamespace(),
+							Name:      dataSourceName,
+						},
+					})
+				}
+				return requests
+			}),
+		).
 		Complete(r)
 }
 
 // +kubebuilder:rbac:groups=xjoin.cloud.redhat.com,resources=xjoindatasources;xjoindatasources/status;xjoindatasources/finalizers,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups="",resources=configmaps;pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
 
 func (r *XJoinDataSourceReconciler) Reconcile(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := xjoinlogger.NewLogger("controller_xjoindatasource", "DataSource", request.Name, "Namespace", request.Namespace)
@@ -86,6 +111,15 @@ func (r *XJoinDataSourceReconciler) Reconcile(ctx context.Context, request ctrl.
 		return result, errors.Wrap(err, 0)
 	}
 
+	//the conditions of a failed reconcile are computed from the instance as it was fetched
+	originalInstance := instance.DeepCopy()
+	pipelineStatuses := make(common.ChildPipelineStatuses)
+	defer func() {
+		if err != nil {
+			common.UpdateConditionsAfterError(r.Client, originalInstance, pipelineStatuses, err, reqLogger)
+		}
+	}()
+
 	p := parameters.BuildDataSourceParameters()
 
 	if p.Pause.Bool() {
@@ -111,7 +145,6 @@ func (r *XJoinDataSourceReconciler) Reconcile(ctx context.Context, request ctrl.
 		return result, errors.Wrap(err, 0)
 	}
 
-	originalInstance := instance.DeepCopy()
 	i := XJoinDataSourceIteration{
 		Parameters: *p,
 		Iteration: common.Iteration{
@@ -129,18 +162,27 @@ func (r *XJoinDataSourceReconciler) Reconcile(ctx context.Context, request ctrl.
 	}
 
 	//check status of active and refreshing IndexPipelines, update instance.Status accordingly
+	var activeDataSourcePipeline *xjoin.XJoinDataSourcePipeline
 	if instance.Status.ActiveVersion != "" {
 		datasourcePipelineNamespacedName := types.NamespacedName{
 			Name:      i.Instance.GetName() + "." + instance.Status.ActiveVersion,
 			Namespace: i.Instance.GetNamespace(),
 		}
 
-		activeDataSourcePipeline, err := k8sUtils.FetchXJoinDataSourcePipeline(i.Client, datasourcePipelineNamespacedName, i.Context)
+		activeDataSourcePipeline, err = k8sUtils.FetchXJoinDataSourcePipeline(i.Client, datasourcePipelineNamespacedName, i.Context)
 		if err != nil {
 			return reconcile.Result{}, errors.Wrap(err, 0)
 		}
 
-		instance.Status.ActiveVersionIsValid = activeDataSourcePipeline.Status.ValidationResponse.Result == index.Valid
+		instance.Status.ActiveVersionIsValid = activeDataSourcePipeline.Status.ValidationResponse.Result == index.Valid &&
+			!components.DeviationsInvalidateVersion(p.DeviationPolicy.String(), activeDataSourcePipeline.Status.Deviations)
+		pipelineStatuses[instance.Status.ActiveVersion] = common.ChildPipelineStatus{
+			Version:            instance.Status.ActiveVersion,
+			Exists:             true,
+			Deleting:           activeDataSourcePipeline.GetDeletionTimestamp() != nil,
+			ValidationResponse: activeDataSourcePipeline.Status.ValidationResponse,
+			Deviations:         activeDataSourcePipeline.Status.Deviations,
+		}
 	}
 
 	if instance.Status.RefreshingVersion != "" {
@@ -154,11 +196,83 @@ func (r *XJoinDataSourceReconciler) Reconcile(ctx context.Context, request ctrl.
 			return reconcile.Result{}, errors.Wrap(err, 0)
 		}
 
-		instance.Status.RefreshingVersionIsValid = refreshingDataSourcePipeline.Status.ValidationResponse.Result == index.Valid
+		instance.Status.RefreshingVersionIsValid = refreshingDataSourcePipeline.Status.ValidationResponse.Result == index.Valid &&
+			!components.DeviationsInvalidateVersion(p.DeviationPolicy.String(), refreshingDataSourcePipeline.Status.Deviations)
+		pipelineStatuses[instance.Status.RefreshingVersion] = common.ChildPipelineStatus{
+			Version:            instance.Status.RefreshingVersion,
+			Exists:             true,
+			Deleting:           refreshingDataSourcePipeline.GetDeletionTimestamp() != nil,
+			ValidationResponse: refreshingDataSourcePipeline.Status.ValidationResponse,
+			Deviations:         refreshingDataSourcePipeline.Status.Deviations,
+		}
 	}
 
 	dataSourceReconciler := NewReconcileMethods(i, common.DataSourceGVK)
 	reconciler := common.NewReconciler(dataSourceReconciler, instance, reqLogger)
+	//in plan mode the components are rendered into a ConfigMap for review instead of applying the changes
+	if planModeEnabled(instance) && instance.GetDeletionTimestamp() == nil {
+		reqLogger.Info("Plan mode is enabled, rendering the plan without applying changes")
+		instance.Status.Plan, err = writePlan(&i.Iteration, common.DataSourceGVK, instance, reconciler,
+			func(version string, refresh bool) (map[string]string, error) {
+				return renderDataSourcePlan(&i, version, r.Test)
+			})
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+
+		common.SetStatusConditions(instance, pipelineStatuses)
+		return i.UpdateStatusAndRequeue(time.Second * 30)
+	} else if instance.Status.Plan != nil {
+		err = i.DeletePlan(common.DataSourceGVK)
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+		instance.Status.Plan = nil
+	}
+
+	if instance.GetDeletionTimestamp() == nil {
+		err = i.ReleaseRetainedVersions()
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+	}
+
+	//compare the avro schema of the active version with a changed avro schema before the change is applied
+	specHash, err := k8sUtils.SpecHash(instance.Spec)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	holdSpecChange := false
+	if activeDataSourcePipeline != nil && schemaChangePending(
+		instance, specHash, activeDataSourcePipeline.Spec.AvroSchema, i.Parameters.AvroSchema.String()) {
+
+		activeSpec := instance.Spec.DeepCopy()
+		activeSpec.AvroSchema = activeDataSourcePipeline.Spec.AvroSchema
+		instance.Status.SchemaCompatibility, err = checkSchemaChange(instance.Spec.SchemaChange, instance, *activeSpec,
+			specHash, activeDataSourcePipeline.Spec.AvroSchema, i.Parameters.AvroSchema.String())
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+
+		switch instance.Status.SchemaCompatibility.Action {
+		case xjoin.SchemaChangeActionBlocked:
+			reqLogger.Warn("Avro schema change is not applied",
+				"reason", instance.Status.SchemaCompatibility.Message)
+			reconciler.HoldSpecChange()
+			holdSpecChange = true
+		case xjoin.SchemaChangeActionInPlace:
+			reqLogger.Info("Applying the additive avro schema change to the active version",
+				"version", instance.Status.ActiveVersion)
+			err = i.UpdateDataSourcePipelineAvroSchema(activeDataSourcePipeline)
+			if err != nil {
+				return result, errors.Wrap(err, 0)
+			}
+			instance.Status.SpecHash = specHash
+		}
+	} else if instance.Status.SchemaCompatibility != nil && instance.Status.SchemaCompatibility.SpecHash != specHash {
+		instance.Status.SchemaCompatibility = nil
+	}
+
 	err = reconciler.Reconcile(false)
 	if err != nil {
 		return result, errors.Wrap(err, 0)
@@ -169,10 +283,12 @@ func (r *XJoinDataSourceReconciler) Reconcile(ctx context.Context, request ctrl.
 		return reconcile.Result{}, nil
 	}
 
-	instance.Status.SpecHash, err = k8sUtils.SpecHash(instance.Spec)
-	if err != nil {
-		return result, errors.Wrap(err, 0)
+	if !holdSpecChange {
+		instance.Status.SpecHash = specHash
 	}
 
+	pipelineStatuses.AddCreatedVersions(instance)
+	common.SetStatusConditions(instance, pipelineStatuses)
+
 	return i.UpdateStatusAndRequeue(time.Second * 30)
 }
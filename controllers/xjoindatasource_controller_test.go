@@ -6,8 +6,12 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	corev1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"os"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	//+kubebuilder:scaffold:imports
 )
@@ -226,6 +230,80 @@ var _ = Describe("XJoinDataSource", func() {
 			Expect(updatedDatasource.Status.ActiveVersionIsValid).To(Equal(true))
 		})
 
+		It("Should keep the previous DataSourcePipeline until the IndexPipelines using it are removed", func() {
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      "test-data-source",
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			validDatasource := datasourceReconciler.ReconcileValid()
+			previousVersion := validDatasource.Status.ActiveVersion
+
+			//an IndexPipeline uses the active version
+			indexPipeline := &v1alpha1.XJoinIndexPipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-index.1234",
+					Namespace: namespace,
+				},
+				Spec: v1alpha1.XJoinIndexPipelineSpec{
+					Name:       "test-index",
+					Version:    "1234",
+					AvroSchema: "{}",
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), indexPipeline)).Should(Succeed())
+			indexPipeline.Status.DataSources = map[string]v1alpha1.DataSourceVersion{
+				validDatasource.GetName(): {Version: previousVersion},
+			}
+			Expect(k8sClient.Status().Update(context.Background(), indexPipeline)).Should(Succeed())
+
+			//refresh the datasource
+			activePipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      validDatasource.GetName() + "." + previousVersion,
+				K8sClient: k8sClient,
+			}
+			activePipelineReconciler.ReconcileInvalid()
+			datasourceReconciler.reconcile()
+			updatedDatasource := datasourceReconciler.GetDataSource()
+			refreshingVersion := updatedDatasource.Status.RefreshingVersion
+			Expect(refreshingVersion).ToNot(Equal(""))
+
+			refreshingPipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      validDatasource.GetName() + "." + refreshingVersion,
+				K8sClient: k8sClient,
+			}
+			refreshingPipelineReconciler.ReconcileValid()
+
+			//validate the previous version is retained after the cut over
+			datasourceReconciler.reconcile()
+			updatedDatasource = datasourceReconciler.GetDataSource()
+			Expect(updatedDatasource.Status.ActiveVersion).To(Equal(refreshingVersion))
+			Expect(updatedDatasource.Status.RetainedVersions).To(Equal([]string{previousVersion}))
+
+			datasourceReconciler.reconcile()
+			previousPipeline := &v1alpha1.XJoinDataSourcePipeline{}
+			previousPipelineLookupKey := types.NamespacedName{
+				Name:      validDatasource.GetName() + "." + previousVersion,
+				Namespace: namespace,
+			}
+			Expect(k8sClient.Get(context.Background(), previousPipelineLookupKey, previousPipeline)).Should(Succeed())
+			Expect(previousPipeline.GetDeletionTimestamp()).To(BeNil())
+
+			//validate the previous version is deleted once no IndexPipeline uses it
+			Expect(k8sClient.Delete(context.Background(), indexPipeline)).Should(Succeed())
+			datasourceReconciler.reconcile()
+			updatedDatasource = datasourceReconciler.GetDataSource()
+			Expect(updatedDatasource.Status.RetainedVersions).To(BeEmpty())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), previousPipelineLookupKey, previousPipeline)
+				return err != nil || previousPipeline.GetDeletionTimestamp() != nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+		})
+
 		It("Should create a refreshing pipeline when the active DataSourcePipeline becomes invalid", func() {
 			//setup initial state with an invalid refreshing pipeline
 			datasourceReconciler := DatasourceTestReconciler{
@@ -282,5 +360,139 @@ var _ = Describe("XJoinDataSource", func() {
 			Expect(refreshingPipeline.Name).To(Equal(
 				updatedDatasource.GetName() + "." + updatedDatasource.Status.RefreshingVersion))
 		})
+
+		It("Should start a refresh when the avro schema of the active DataSourcePipeline deviates", func() {
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      "test-data-source",
+				K8sClient: k8sClient,
+			}
+			createdDatasource := datasourceReconciler.ReconcileNew()
+
+			pipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      createdDatasource.GetName() + "." + createdDatasource.Status.RefreshingVersion,
+				K8sClient: k8sClient,
+			}
+			pipelineReconciler.ReconcileValid()
+			datasourceReconciler.reconcile()
+			updatedDatasource := datasourceReconciler.GetDataSource()
+			Expect(updatedDatasource.Status.ActiveVersion).To(Equal(createdDatasource.Status.RefreshingVersion))
+			Expect(updatedDatasource.Status.ActiveVersionIsValid).To(Equal(true))
+
+			//register a different schema as the latest version of the subject outside of the operator
+			pipelineReconciler.registeredAvroSchema = `{"type":"record","name":"Value","fields":[{"name":"edited","type":"string"}]}`
+			activePipeline := pipelineReconciler.ReconcileValid()
+			Expect(activePipeline.Status.Deviations).ToNot(BeEmpty())
+			for _, deviation := range activePipeline.Status.Deviations {
+				Expect(deviation.ComponentType).To(Equal("AvroSchema"))
+			}
+
+			//validate the DataSource starts a refresh and keeps the active version until the refresh completes
+			datasourceReconciler.reconcile()
+			updatedDatasource = datasourceReconciler.GetDataSource()
+			Expect(updatedDatasource.Status.Phase).To(Equal(common.START_REFRESH))
+			Expect(updatedDatasource.Status.ActiveVersion).To(Equal(createdDatasource.Status.RefreshingVersion))
+			Expect(updatedDatasource.Status.ActiveVersionIsValid).To(Equal(false))
+			Expect(updatedDatasource.Status.RefreshingVersion).ToNot(Equal(""))
+			Expect(updatedDatasource.Status.RefreshingVersion).ToNot(Equal(createdDatasource.Status.RefreshingVersion))
+
+			err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(&activePipeline), &activePipeline)
+			checkError(err)
+			Expect(activePipeline.GetDeletionTimestamp()).To(BeNil())
+		})
+	})
+
+	Context("Schema changes", func() {
+		It("Should not refresh when the new avro schema doesn't meet the required compatibility", func() {
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      "test-data-source",
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			validDatasource := datasourceReconciler.ReconcileValid()
+
+			//change the avro schema and require backward compatibility
+			avroSchema, err := os.ReadFile("./test/data/avro/xjoindatasource-single-field.json")
+			checkError(err)
+			validDatasource.Spec.AvroSchema = string(avroSchema)
+			validDatasource.Spec.SchemaChange = &v1alpha1.SchemaChangeSpec{
+				Compatibility: v1alpha1.SchemaCompatibilityBackward,
+			}
+			Expect(k8sClient.Update(context.Background(), &validDatasource)).Should(Succeed())
+
+			//validate the change is blocked
+			datasourceReconciler.reconcile()
+			updatedDatasource := datasourceReconciler.GetDataSource()
+			Expect(updatedDatasource.Status.ActiveVersion).To(Equal(validDatasource.Status.ActiveVersion))
+			Expect(updatedDatasource.Status.RefreshingVersion).To(Equal(""))
+			Expect(updatedDatasource.Status.SpecHash).To(Equal(validDatasource.Status.SpecHash))
+			Expect(updatedDatasource.Status.SchemaCompatibility).ToNot(BeNil())
+			Expect(updatedDatasource.Status.SchemaCompatibility.Backward).To(Equal(false))
+			Expect(updatedDatasource.Status.SchemaCompatibility.Action).To(Equal(v1alpha1.SchemaChangeActionBlocked))
+
+			//validate the change is applied with a refresh once the compatibility is no longer required
+			updatedDatasource.Spec.SchemaChange = nil
+			Expect(k8sClient.Update(context.Background(), &updatedDatasource)).Should(Succeed())
+			datasourceReconciler.reconcile()
+			updatedDatasource = datasourceReconciler.GetDataSource()
+			Expect(updatedDatasource.Status.ActiveVersion).To(Equal(validDatasource.Status.ActiveVersion))
+			Expect(updatedDatasource.Status.RefreshingVersion).ToNot(Equal(""))
+			Expect(updatedDatasource.Status.SchemaCompatibility.Action).To(Equal(v1alpha1.SchemaChangeActionRefresh))
+		})
+	})
+
+	Context("Plan mode", func() {
+		It("Should render the plan into a ConfigMap without creating a XJoinDataSourcePipeline", func() {
+			reconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      "test-data-source",
+				K8sClient: k8sClient,
+			}
+			plannedDataSource := reconciler.ReconcilePlan()
+			Expect(plannedDataSource.Status.ActiveVersion).To(Equal(""))
+			Expect(plannedDataSource.Status.RefreshingVersion).To(Equal(""))
+			Expect(plannedDataSource.Status.Plan.State).To(Equal(common.NEW))
+			Expect(plannedDataSource.Status.Plan.Refresh).To(Equal(true))
+
+			dataSourcePipelineList := &v1alpha1.XJoinDataSourcePipelineList{}
+			err := k8sClient.List(context.Background(), dataSourcePipelineList, client.InNamespace(namespace))
+			checkError(err)
+			Expect(dataSourcePipelineList.Items).To(BeEmpty())
+
+			configMap := expectPlanConfigMap(&plannedDataSource, "XJoinDataSource", plannedDataSource.Status.Plan)
+			Expect(configMap.Data).To(HaveKeyWithValue("version", "plan"))
+			Expect(configMap.Data).ToNot(HaveKey("avroSchema.json"))
+			Expect(configMap.Data["components.json"]).To(ContainSubstring("xjoindatasourcepipeline.test-data-source.plan"))
+			Expect(configMap.Data["components.json"]).ToNot(ContainSubstring("dbPassword"))
+		})
+
+		It("Should apply the changes and delete the plan when the plan annotation is removed", func() {
+			reconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      "test-data-source",
+				K8sClient: k8sClient,
+			}
+			plannedDataSource := reconciler.ReconcilePlan()
+			configMapName := plannedDataSource.Status.Plan.ConfigMapName
+
+			reconciler.RemovePlanAnnotation()
+			reconciler.registerNewMocks()
+			reconciler.reconcile()
+			updatedDataSource := reconciler.GetDataSource()
+			Expect(updatedDataSource.Status.Plan).To(BeNil())
+			Expect(updatedDataSource.Status.RefreshingVersion).ToNot(Equal(""))
+
+			dataSourcePipeline := &v1alpha1.XJoinDataSourcePipeline{}
+			k8sGet(types.NamespacedName{
+				Name:      updatedDataSource.Name + "." + updatedDataSource.Status.RefreshingVersion,
+				Namespace: namespace,
+			}, dataSourcePipeline)
+
+			err := k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: configMapName, Namespace: namespace}, &corev1.ConfigMap{})
+			Expect(k8errors.IsNotFound(err)).To(BeTrue())
+		})
 	})
 })
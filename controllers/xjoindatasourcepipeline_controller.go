@@ -134,14 +134,82 @@ func (r *XJoinDataSourcePipelineReconciler) Reconcile(ctx context.Context, reque
 		return reconcile.Result{}, errors.Wrap(err, 0)
 	}
 
+	componentManager := buildDataSourcePipelineComponents(ctx, r.Client, p, instance, r.Test)
+
+	if instance.GetDeletionTimestamp() != nil {
+		reqLogger.Info("Starting finalizer")
+		err = componentManager.DeleteAll()
+		if err != nil {
+			reqLogger.Error(err, "error deleting components during finalizer")
+			return
+		}
+
+		controllerutil.RemoveFinalizer(instance, xjoindatasourcepipelineFinalizer)
+		ctx, cancel := utils.DefaultContext()
+		defer cancel()
+		err = r.Client.Update(ctx, instance)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, 0)
+		}
+
+		reqLogger.Info("Successfully finalized")
+		return reconcile.Result{}, nil
+	}
+
+	err = componentManager.CreateAll()
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, 0)
+	}
+
+	err = componentManager.Reconcile()
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, 0)
+	}
+
+	//an additive change of the avro schema is applied in place by migrating the existing components
+	avroSchemaHash, err := k8sUtils.SpecHash(p.AvroSchema.String())
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, 0)
+	}
+	migrate := instance.Status.AvroSchemaHash != "" && instance.Status.AvroSchemaHash != avroSchemaHash
+	if migrate {
+		reqLogger.Info("Avro schema changed, migrating the components in place")
+	}
+
+	deviations, remediations, err := componentManager.HandleDeviations(p.DeviationPolicy.String(), migrate)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, 0)
+	}
+	instance.Status.AvroSchemaHash = avroSchemaHash
+
+	if len(deviations) > 0 {
+		reqLogger.Warn("Component deviations found",
+			"policy", p.DeviationPolicy.String(), "deviations", deviations)
+	}
+	instance.Status.Deviations = deviations
+
+	//keep the last remediations so a successful repair remains visible after the deviations are resolved
+	if len(remediations) > 0 {
+		reqLogger.Info("Component deviations remediated", "remediations", remediations)
+		instance.Status.Remediations = remediations
+	}
+
+	return i.UpdateStatusAndRequeue(time.Second * 30)
+}
+
+// buildDataSourcePipelineComponents builds the components of an XJoinDataSourcePipeline.
+// Building the components doesn't modify any external resources.
+func buildDataSourcePipelineComponents(ctx context.Context, c client.Client, p *parameters.DataSourceParameters,
+	instance *xjoin.XJoinDataSourcePipeline, test bool) (componentManager components.ComponentManager) {
+
 	kafkaClient := kafka.GenericKafka{
 		Context:          ctx,
 		ConnectNamespace: p.ConnectClusterNamespace.String(),
 		ConnectCluster:   p.ConnectCluster.String(),
 		KafkaNamespace:   p.KafkaClusterNamespace.String(),
 		KafkaCluster:     p.KafkaCluster.String(),
-		Client:           i.Client,
-		Test:             r.Test,
+		Client:           c,
+		Test:             test,
 	}
 
 	registry := schemaregistry.NewSchemaRegistryConfluentClient(
@@ -153,11 +221,13 @@ func (r *XJoinDataSourcePipelineReconciler) Reconcile(ctx context.Context, reque
 
 	registry.Init()
 
-	componentManager := components.NewComponentManager(common.DataSourcePipelineGVK.Kind, instance.Spec.Name, p.Version.String())
-	componentManager.AddComponent(components.NewAvroSchema(components.AvroSchemaParameters{
+	componentManager = components.NewComponentManager(common.DataSourcePipelineGVK.Kind, instance.Spec.Name, p.Version.String())
+	componentManager.SetConcurrency(p.ComponentConcurrency.Int())
+	avroSchemaComponent := components.NewAvroSchema(components.AvroSchemaParameters{
 		Schema:   p.AvroSchema.String(),
 		Registry: registry,
-	}))
+	})
+	componentManager.AddComponent(avroSchemaComponent)
 
 	kafkaTopics := kafka.StrimziTopics{
 		TopicParameters: kafka.TopicParameters{
@@ -172,12 +242,12 @@ func (r *XJoinDataSourcePipelineReconciler) Reconcile(ctx context.Context, reque
 		},
 		KafkaClusterNamespace: p.KafkaClusterNamespace.String(),
 		KafkaCluster:          p.KafkaCluster.String(),
-		Client:                i.Client,
-		Test:                  r.Test,
+		Client:                c,
+		Test:                  test,
 		Context:               ctx,
 		//ResourceNamePrefix:  this is not needed for generic topics
 	}
-	componentManager.AddComponent(&components.KafkaTopic{
+	kafkaTopicComponent := &components.KafkaTopic{
 		TopicParameters: kafka.TopicParameters{
 			Replicas:           p.KafkaTopicReplicas.Int(),
 			Partitions:         p.KafkaTopicPartitions.Int(),
@@ -189,53 +259,14 @@ func (r *XJoinDataSourcePipelineReconciler) Reconcile(ctx context.Context, reque
 			CreationTimeout:    p.KafkaTopicCreationTimeout.Int(),
 		},
 		KafkaTopics: kafkaTopics,
-	})
+	}
+	componentManager.AddComponent(kafkaTopicComponent)
 
 	componentManager.AddComponent(&components.DebeziumConnector{
 		TemplateParameters: config.ParametersToMap(*p),
 		KafkaClient:        kafkaClient,
 		Template:           p.DebeziumConnectorTemplate.String(),
-	})
-
-	if instance.GetDeletionTimestamp() != nil {
-		reqLogger.Info("Starting finalizer")
-		err = componentManager.DeleteAll()
-		if err != nil {
-			reqLogger.Error(err, "error deleting components during finalizer")
-			return
-		}
-
-		controllerutil.RemoveFinalizer(instance, xjoindatasourcepipelineFinalizer)
-		ctx, cancel := utils.DefaultContext()
-		defer cancel()
-		err = r.Client.Update(ctx, instance)
-		if err != nil {
-			return reconcile.Result{}, errors.Wrap(err, 0)
-		}
-
-		reqLogger.Info("Successfully finalized")
-		return reconcile.Result{}, nil
-	}
-
-	err = componentManager.CreateAll()
-	if err != nil {
-		return reconcile.Result{}, errors.Wrap(err, 0)
-	}
-
-	err = componentManager.Reconcile()
-	if err != nil {
-		return reconcile.Result{}, errors.Wrap(err, 0)
-	}
+	}, kafkaTopicComponent, avroSchemaComponent)
 
-	problems, err := componentManager.CheckForDeviations()
-	if err != nil {
-		return reconcile.Result{}, errors.Wrap(err, 0)
-	}
-
-	if len(problems) > 0 {
-		//TODO: set instance status to invalid, add problems to status
-		reqLogger.Info("TODO: Set Instance status to invalid, add", "problems", len(problems))
-	}
-
-	return i.UpdateStatusAndRequeue(time.Second * 30)
+	return componentManager
 }
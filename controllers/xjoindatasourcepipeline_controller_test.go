@@ -8,6 +8,8 @@ import (
 	"github.com/jarcoal/httpmock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
 	"k8s.io/apimachinery/pkg/types"
 	"os"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -141,6 +143,51 @@ var _ = Describe("XJoinDataSourcePipeline", func() {
 		})
 	})
 
+	Context("Schema changes", func() {
+		It("Migrates the Avro Schema in place when the avro schema changes", func() {
+			reconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      "test-data-source-pipeline",
+				K8sClient: k8sClient,
+			}
+			createdDataSourcePipeline := reconciler.ReconcileNew()
+			Expect(createdDataSourcePipeline.Status.AvroSchemaHash).ToNot(Equal(""))
+
+			httpmock.Reset()
+			httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip)
+			subject, previousSchema := expectedAvroSchema(
+				"XJoinDataSourcePipeline", createdDataSourcePipeline.Spec.Name, "1234", "{}")
+			registry := newFakeSchemaRegistry(subject)
+			registry.register(previousSchema)
+
+			//change the avro schema
+			avroSchema, err := os.ReadFile("./test/data/avro/xjoindatasource-single-field.json")
+			checkError(err)
+			createdDataSourcePipeline.Spec.AvroSchema = string(avroSchema)
+			Expect(k8sClient.Update(context.Background(), &createdDataSourcePipeline)).Should(Succeed())
+			reconciler.reconcile()
+
+			//validates the new schema was registered on the existing subject
+			_, newSchema := expectedAvroSchema(
+				"XJoinDataSourcePipeline", createdDataSourcePipeline.Spec.Name, "1234", string(avroSchema))
+			Expect(registry.versions).To(Equal(map[int]string{1: previousSchema, 2: newSchema}))
+
+			updatedDataSourcePipeline := &v1alpha1.XJoinDataSourcePipeline{}
+			lookupKey := types.NamespacedName{Name: createdDataSourcePipeline.Name, Namespace: namespace}
+			Expect(k8sClient.Get(context.Background(), lookupKey, updatedDataSourcePipeline)).Should(Succeed())
+			Expect(updatedDataSourcePipeline.Status.AvroSchemaHash).ToNot(Equal(""))
+			Expect(updatedDataSourcePipeline.Status.AvroSchemaHash).ToNot(
+				Equal(createdDataSourcePipeline.Status.AvroSchemaHash))
+			Expect(updatedDataSourcePipeline.Status.Deviations).To(BeEmpty())
+			Expect(updatedDataSourcePipeline.Status.Remediations).To(ContainElement(v1alpha1.ComponentRemediation{
+				ComponentType: "AvroSchema",
+				ComponentName: subject,
+				Action:        components.RemediationMigrate,
+				Message:       "migrated in place",
+			}))
+		})
+	})
+
 	Context("Reconcile Deletion", func() {
 		It("Deletes the Debezium Kafka Connector", func() {
 			name := "test-data-source-pipeline"
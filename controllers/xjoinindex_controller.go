@@ -4,8 +4,10 @@ import (
 	"context"
 	"github.com/go-errors/errors"
 	"github.com/go-logr/logr"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/utils"
 	xjoin "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
 	"github.com/redhatinsights/xjoin-operator/controllers/config"
 	. "github.com/redhatinsights/xjoin-operator/controllers/index"
 	xjoinlogger "github.com/redhatinsights/xjoin-operator/controllers/log"
@@ -19,7 +21,9 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"time"
 )
 
@@ -63,11 +67,43 @@ func (r *XJoinIndexReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			LogConstructor: logConstructor,
 			RateLimiter:    workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 1*time.Minute),
 		}).
+
+		// trigger Reconcile when the status of a DataSource used by one of the Index's pipelines changes
+		Watches(
+			&source.Kind{Type: &xjoin.XJoinDataSource{}},
+			handler.EnqueueRequestsFromMapFunc(func(dataSource client.Object) (requests []reconcile.Request) {
+				ctx, cancel := utils.DefaultContext()
+				defer cancel()
+
+				indexPipelines, err := k8sUtils.FetchXJoinIndexPipelinesInNamespace(
+					r.Client, dataSource.GetNamespace(), ctx)
+				if err != nil {
+					r.Log.Error(err, "Failed to fetch IndexPipelines in Watch")
+					return requests
+				}
+
+				indexes := make(map[types.NamespacedName]bool)
+				for _, indexPipeline := range indexPipelines.Items {
+					if _, ok := indexPipeline.Status.DataSources[dataSource.GetName()]; ok {
+						indexes[types.NamespacedName{
+							Namespace: indexPipeline.GetNamespace(),
+							Name:      indexPipeline.Spec.Name,
+						}] = true
+					}
+				}
+
+				for indexName := range indexes {
+					requests = append(requests, reconcile.Request{NamespacedName: indexName})
+				}
+				return requests
+			}),
+		).
 		Complete(r)
 }
 
 // +kubebuilder:rbac:groups=xjoin.cloud.redhat.com,resources=xjoinindices;xjoinindices/status;xjoinindices/finalizers,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups="",resources=configmaps;pods;deployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods;deployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
 
 func (r *XJoinIndexReconciler) Reconcile(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := xjoinlogger.NewLogger("controller_xjoinindex", "Index", request.Name, "Namespace", request.Namespace)
@@ -85,6 +121,15 @@ func (r *XJoinIndexReconciler) Reconcile(ctx context.Context, request ctrl.Reque
 		return
 	}
 
+	//the conditions of a failed reconcile are computed from the instance as it was fetched
+	originalInstance := instance.DeepCopy()
+	pipelineStatuses := make(common.ChildPipelineStatuses)
+	defer func() {
+		if err != nil {
+			common.UpdateConditionsAfterError(r.Client, originalInstance, pipelineStatuses, err, reqLogger)
+		}
+	}()
+
 	p := parameters.BuildIndexParameters()
 
 	if p.Pause.Bool() {
@@ -108,7 +153,6 @@ func (r *XJoinIndexReconciler) Reconcile(ctx context.Context, request ctrl.Reque
 		return result, errors.Wrap(err, 0)
 	}
 
-	originalInstance := instance.DeepCopy()
 	i := XJoinIndexIteration{
 		Parameters: *p,
 		Iteration: common.Iteration{
@@ -126,18 +170,27 @@ func (r *XJoinIndexReconciler) Reconcile(ctx context.Context, request ctrl.Reque
 	}
 
 	//check status of active and refreshing IndexPipelines, update instance.Status accordingly
+	var activeIndexPipeline *xjoin.XJoinIndexPipeline
 	if instance.Status.ActiveVersion != "" {
 		indexPipelineNamespacedName := types.NamespacedName{
 			Name:      i.Instance.GetName() + "." + instance.Status.ActiveVersion,
 			Namespace: i.Instance.GetNamespace(),
 		}
 
-		activeIndexPipeline, err := k8sUtils.FetchXJoinIndexPipeline(i.Client, indexPipelineNamespacedName, i.Context)
+		activeIndexPipeline, err = k8sUtils.FetchXJoinIndexPipeline(i.Client, indexPipelineNamespacedName, i.Context)
 		if err != nil {
 			return reconcile.Result{}, errors.Wrap(err, 0)
 		}
 
-		instance.Status.ActiveVersionIsValid = activeIndexPipeline.Status.ValidationResponse.Result == Valid
+		instance.Status.ActiveVersionIsValid = IsPromotable(activeIndexPipeline.Status.ValidationResponse.Result) &&
+			!components.DeviationsInvalidateVersion(p.DeviationPolicy.String(), activeIndexPipeline.Status.Deviations)
+		pipelineStatuses[instance.Status.ActiveVersion] = common.ChildPipelineStatus{
+			Version:            instance.Status.ActiveVersion,
+			Exists:             true,
+			Deleting:           activeIndexPipeline.GetDeletionTimestamp() != nil,
+			ValidationResponse: activeIndexPipeline.Status.ValidationResponse,
+			Deviations:         activeIndexPipeline.Status.Deviations,
+		}
 	}
 
 	if instance.Status.RefreshingVersion != "" {
@@ -151,12 +204,92 @@ func (r *XJoinIndexReconciler) Reconcile(ctx context.Context, request ctrl.Reque
 			return reconcile.Result{}, errors.Wrap(err, 0)
 		}
 
-		instance.Status.RefreshingVersionIsValid = refreshingIndexPipeline.Status.ValidationResponse.Result == Valid
+		instance.Status.RefreshingVersionIsValid = IsPromotable(refreshingIndexPipeline.Status.ValidationResponse.Result) &&
+			!components.DeviationsInvalidateVersion(p.DeviationPolicy.String(), refreshingIndexPipeline.Status.Deviations)
+		pipelineStatuses[instance.Status.RefreshingVersion] = common.ChildPipelineStatus{
+			Version:            instance.Status.RefreshingVersion,
+			Exists:             true,
+			Deleting:           refreshingIndexPipeline.GetDeletionTimestamp() != nil,
+			ValidationResponse: refreshingIndexPipeline.Status.ValidationResponse,
+			Deviations:         refreshingIndexPipeline.Status.Deviations,
+		}
 	}
 
 	indexReconcileMethods := NewReconcileMethods(i, common.IndexGVK)
 	reconciler := common.NewReconciler(indexReconcileMethods, instance, reqLogger)
-	err = reconciler.Reconcile(false)
+	//in plan mode the components are rendered into a ConfigMap for review instead of applying the changes
+	if planModeEnabled(instance) && instance.GetDeletionTimestamp() == nil {
+		reqLogger.Info("Plan mode is enabled, rendering the plan without applying changes")
+		instance.Status.Plan, err = writePlan(&i.Iteration, common.IndexGVK, instance, reconciler,
+			func(version string, refresh bool) (map[string]string, error) {
+				return renderIndexPlan(&i, version, refresh, r.Test)
+			})
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+
+		common.SetStatusConditions(instance, pipelineStatuses)
+		return i.UpdateStatusAndRequeue(time.Second * 30)
+	} else if instance.Status.Plan != nil {
+		err = i.DeletePlan(common.IndexGVK)
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+		instance.Status.Plan = nil
+	}
+
+	//compare the avro schema of the active version with a changed avro schema before the change is applied
+	specHash, err := k8sUtils.SpecHash(instance.Spec)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	holdSpecChange := false
+	if activeIndexPipeline != nil &&
+		schemaChangePending(instance, specHash, activeIndexPipeline.Spec.AvroSchema, i.Parameters.AvroSchema.String()) {
+
+		activeSpec := instance.Spec.DeepCopy()
+		activeSpec.AvroSchema = activeIndexPipeline.Spec.AvroSchema
+		instance.Status.SchemaCompatibility, err = checkSchemaChange(instance.Spec.SchemaChange, instance, *activeSpec,
+			specHash, activeIndexPipeline.Spec.AvroSchema, i.Parameters.AvroSchema.String())
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+
+		switch instance.Status.SchemaCompatibility.Action {
+		case xjoin.SchemaChangeActionBlocked:
+			reqLogger.Warn("Avro schema change is not applied",
+				"reason", instance.Status.SchemaCompatibility.Message)
+			reconciler.HoldSpecChange()
+			holdSpecChange = true
+		case xjoin.SchemaChangeActionInPlace:
+			reqLogger.Info("Applying the additive avro schema change to the active version",
+				"version", instance.Status.ActiveVersion)
+			err = i.UpdateIndexPipelineAvroSchema(activeIndexPipeline)
+			if err != nil {
+				return result, errors.Wrap(err, 0)
+			}
+			instance.Status.SpecHash = specHash
+		}
+	} else if instance.Status.SchemaCompatibility != nil && instance.Status.SchemaCompatibility.SpecHash != specHash {
+		instance.Status.SchemaCompatibility = nil
+	}
+
+	//refresh the index when a referenced data source cut over to a new version. The data source keeps the previous
+	//version until the refreshed IndexPipeline replaces the active one.
+	forceRefresh := false
+	if activeIndexPipeline != nil && instance.Status.RefreshingVersion == "" && instance.GetDeletionTimestamp() == nil {
+		superseded, err := i.SupersededDataSources(activeIndexPipeline)
+		if err != nil {
+			return result, errors.Wrap(err, 0)
+		}
+		if len(superseded) > 0 {
+			reqLogger.Info("Referenced data sources cut over to a new version, refreshing the index",
+				"dataSources", superseded)
+			forceRefresh = true
+		}
+	}
+
+	err = reconciler.Reconcile(forceRefresh)
 	if err != nil {
 		return result, errors.Wrap(err, 0)
 	}
@@ -166,10 +299,12 @@ func (r *XJoinIndexReconciler) Reconcile(ctx context.Context, request ctrl.Reque
 		return reconcile.Result{}, nil
 	}
 
-	instance.Status.SpecHash, err = k8sUtils.SpecHash(instance.Spec)
-	if err != nil {
-		return result, errors.Wrap(err, 0)
+	if !holdSpecChange {
+		instance.Status.SpecHash = specHash
 	}
 
+	pipelineStatuses.AddCreatedVersions(instance)
+	common.SetStatusConditions(instance, pipelineStatuses)
+
 	return i.UpdateStatusAndRequeue(time.Second * 30)
 }
@@ -2,10 +2,15 @@ package controllers_test
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/jarcoal/httpmock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
+	corev1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -64,6 +69,55 @@ var _ = Describe("XJoinIndex", func() {
 		})
 	})
 
+	Context("Conditions", func() {
+		It("Should set the conditions after creating the refreshing XJoinIndexPipeline", func() {
+			reconciler := IndexTestReconciler{
+				Namespace: namespace,
+				Name:      "test-index",
+				K8sClient: k8sClient,
+			}
+			createdIndex := reconciler.ReconcileNew()
+
+			expectCondition(createdIndex.Status.Conditions, v1alpha1.ConditionReady,
+				metav1.ConditionFalse, common.ReasonNoActiveVersion)
+			expectCondition(createdIndex.Status.Conditions, v1alpha1.ConditionRefreshing,
+				metav1.ConditionTrue, common.ReasonRefreshInProgress)
+			expectCondition(createdIndex.Status.Conditions, v1alpha1.ConditionValidated,
+				metav1.ConditionUnknown, common.ReasonValidationPending)
+			expectCondition(createdIndex.Status.Conditions, v1alpha1.ConditionComponentsHealthy,
+				metav1.ConditionTrue, common.ReasonPipelinesPresent)
+			expectCondition(createdIndex.Status.Conditions, v1alpha1.ConditionDegraded,
+				metav1.ConditionFalse, common.ReasonAsExpected)
+		})
+
+		It("Should set the Degraded condition when the reconcile fails", func() {
+			reconciler := IndexTestReconciler{
+				Namespace: namespace,
+				Name:      "test-index",
+				K8sClient: k8sClient,
+			}
+			createdIndex := reconciler.ReconcileNew()
+
+			configMap := &corev1.ConfigMap{}
+			k8sGet(types.NamespacedName{Name: "xjoin-generic", Namespace: namespace}, configMap)
+			configMap.Data["validation.repair.threshold"] = "invalid"
+			checkError(k8sClient.Update(context.Background(), configMap))
+
+			err := reconciler.ReconcileWithError()
+			Expect(err).To(HaveOccurred())
+
+			//only the conditions of the failed reconcile are written
+			failedIndex := reconciler.GetIndex()
+			Expect(failedIndex.Status.RefreshingVersion).To(Equal(createdIndex.Status.RefreshingVersion))
+			Expect(failedIndex.Status.Phase).To(Equal(createdIndex.Status.Phase))
+			expectCondition(failedIndex.Status.Conditions, v1alpha1.ConditionRefreshing,
+				metav1.ConditionTrue, common.ReasonRefreshInProgress)
+			degraded := expectCondition(failedIndex.Status.Conditions, v1alpha1.ConditionDegraded,
+				metav1.ConditionTrue, common.ReasonReconcileError)
+			Expect(degraded.Message).To(ContainSubstring("validation.repair.threshold"))
+		})
+	})
+
 	Context("Reconcile Delete", func() {
 		It("Should delete a XJoinIndexPipeline", func() {
 			reconciler := IndexTestReconciler{
@@ -128,10 +182,7 @@ var _ = Describe("XJoinIndex", func() {
 					ApiCurioResponseFilename: "datasource-latest-version",
 				}},
 			}
-			indexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
-				GraphQLSchemaExistingState: "ENABLED",
-				GraphQLSchemaNewState:      "DISABLED",
-			})
+			indexPipelineReconciler.ReconcileCreate()
 
 			//validate the index's status is updated
 			updatedIndex := indexReconciler.ReconcileUpdated()
@@ -179,10 +230,7 @@ var _ = Describe("XJoinIndex", func() {
 					ApiCurioResponseFilename: "datasource-latest-version",
 				}},
 			}
-			indexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
-				GraphQLSchemaExistingState: "ENABLED",
-				GraphQLSchemaNewState:      "DISABLED",
-			})
+			indexPipelineReconciler.ReconcileCreate()
 
 			//reconcile the index to the valid state
 			updatedIndex := indexReconciler.ReconcileUpdated()
@@ -226,5 +274,201 @@ var _ = Describe("XJoinIndex", func() {
 			Expect(refreshingIndexPipeline.Name).To(Equal(
 				updatedIndex.GetName() + "." + updatedIndex.Status.RefreshingVersion))
 		})
+
+		It("Should start a refresh when a component of the active IndexPipeline deviates", func() {
+			resources := CreateValidIndexPipeline(namespace, nil)
+			activeVersion := resources.IndexPipeline.Spec.Version
+
+			//edit the elasticsearch pipeline outside of the operator
+			resources.IndexPipelineReconciler.pipelineRequestBody = `{"description":"edited","processors":[]}`
+			indexPipeline := resources.IndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "ENABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			Expect(indexPipeline.Status.Deviations).ToNot(BeEmpty())
+			for _, deviation := range indexPipeline.Status.Deviations {
+				Expect(deviation.ComponentType).To(Equal("ElasticsearchPipeline"))
+				Expect(deviation.ComponentName).To(Equal("xjoinindexpipeline." + indexPipeline.Name))
+			}
+
+			//validate the index starts a refresh and keeps the active version until the refresh completes
+			updatedIndex := resources.IndexReconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.Phase).To(Equal(common.START_REFRESH))
+			Expect(updatedIndex.Status.ActiveVersion).To(Equal(activeVersion))
+			Expect(updatedIndex.Status.ActiveVersionIsValid).To(Equal(false))
+			Expect(updatedIndex.Status.RefreshingVersion).ToNot(Equal(""))
+			Expect(updatedIndex.Status.RefreshingVersion).ToNot(Equal(activeVersion))
+
+			activeIndexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(&indexPipeline), activeIndexPipeline)
+			checkError(err)
+			Expect(activeIndexPipeline.GetDeletionTimestamp()).To(BeNil())
+		})
+
+		It("Should not refresh when the subgraphs push their graphql schemas to the registry", func() {
+			resources := CreateValidIndexPipeline(namespace, []v1alpha1.CustomSubgraphImage{{
+				Name:  "test-custom-image",
+				Image: "quay.io/cloudservices/host-inventory-subgraph:latest",
+			}})
+
+			//the subgraphs push the schema they serve as the latest version of the artifacts registered by the operator
+			resources.IndexPipelineReconciler.graphQLSchemaPushed = "type Query {hosts: [Host]}"
+			indexPipeline := resources.IndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "ENABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			Expect(indexPipeline.Status.Deviations).To(BeEmpty())
+
+			updatedIndex := resources.IndexReconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.Phase).To(Equal(common.VALID))
+			Expect(updatedIndex.Status.ActiveVersion).To(Equal(resources.IndexPipeline.Spec.Version))
+			Expect(updatedIndex.Status.ActiveVersionIsValid).To(Equal(true))
+			Expect(updatedIndex.Status.RefreshingVersion).To(Equal(""))
+		})
+
+		It("Should only report a deviation of the active IndexPipeline with the report policy", func() {
+			resources := CreateValidIndexPipeline(namespace, nil)
+			setDeviationPolicy(namespace, components.DeviationPolicyReport)
+
+			//edit the elasticsearch pipeline outside of the operator
+			resources.IndexPipelineReconciler.pipelineRequestBody = `{"description":"edited","processors":[]}`
+			indexPipeline := resources.IndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "ENABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			Expect(indexPipeline.Status.Deviations).ToNot(BeEmpty())
+			for _, remediation := range indexPipeline.Status.Remediations {
+				Expect(remediation.Action).To(Equal(components.DeviationPolicyReport))
+			}
+
+			updatedIndex := resources.IndexReconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.Phase).To(Equal(common.VALID))
+			Expect(updatedIndex.Status.ActiveVersion).To(Equal(resources.IndexPipeline.Spec.Version))
+			Expect(updatedIndex.Status.ActiveVersionIsValid).To(Equal(true))
+			Expect(updatedIndex.Status.RefreshingVersion).To(Equal(""))
+		})
+
+		It("Should refresh the index when a referenced data source cuts over to a new version", func() {
+			indexReconciler := IndexTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index",
+				AvroSchemaFileName: "xjoinindex-with-referenced-field",
+				K8sClient:          k8sClient,
+			}
+			createdIndex := indexReconciler.ReconcileNew()
+
+			//create a valid datasource
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			//reconcile the index to the valid state
+			indexPipelineReconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           createdIndex.Name,
+				Version:        createdIndex.Status.RefreshingVersion,
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			indexPipelineReconciler.ReconcileCreate()
+
+			updatedIndex := indexReconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.ActiveVersion).To(Equal(createdIndex.Status.RefreshingVersion))
+			Expect(updatedIndex.Status.ActiveVersionIsValid).To(Equal(true))
+			Expect(updatedIndex.Status.RefreshingVersion).To(Equal(""))
+
+			//refresh the datasource and cut over to the new version
+			activeDataSourcePipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName + "." + createdDataSource.Status.ActiveVersion,
+				K8sClient: k8sClient,
+			}
+			activeDataSourcePipelineReconciler.ReconcileInvalid()
+			datasourceReconciler.reconcile()
+			refreshingDataSource := datasourceReconciler.GetDataSource()
+
+			refreshingDataSourcePipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName + "." + refreshingDataSource.Status.RefreshingVersion,
+				K8sClient: k8sClient,
+			}
+			refreshingDataSourcePipelineReconciler.ReconcileValid()
+			datasourceReconciler.reconcile()
+			updatedDataSource := datasourceReconciler.GetDataSource()
+			Expect(updatedDataSource.Status.ActiveVersion).To(Equal(refreshingDataSource.Status.RefreshingVersion))
+			Expect(updatedDataSource.Status.RetainedVersions).To(
+				Equal([]string{createdDataSource.Status.ActiveVersion}))
+
+			//validate the index starts a refresh and keeps the active version until it completes
+			updatedIndex = indexReconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.ActiveVersion).To(Equal(createdIndex.Status.RefreshingVersion))
+			Expect(updatedIndex.Status.RefreshingVersion).ToNot(Equal(""))
+			Expect(updatedIndex.Status.RefreshingVersion).ToNot(Equal(createdIndex.Status.RefreshingVersion))
+		})
+	})
+
+	Context("Plan mode", func() {
+		It("Should render the plan into a ConfigMap without creating a XJoinIndexPipeline", func() {
+			reconciler := IndexTestReconciler{
+				Namespace: namespace,
+				Name:      "test-index",
+				K8sClient: k8sClient,
+			}
+			plannedIndex := reconciler.ReconcilePlan()
+			Expect(plannedIndex.Status.ActiveVersion).To(Equal(""))
+			Expect(plannedIndex.Status.RefreshingVersion).To(Equal(""))
+			Expect(plannedIndex.Status.Plan.State).To(Equal(common.NEW))
+			Expect(plannedIndex.Status.Plan.Refresh).To(Equal(true))
+
+			indexPipelineList := &v1alpha1.XJoinIndexPipelineList{}
+			err := k8sClient.List(context.Background(), indexPipelineList, client.InNamespace(namespace))
+			checkError(err)
+			Expect(indexPipelineList.Items).To(BeEmpty())
+
+			configMap := expectPlanConfigMap(&plannedIndex, "XJoinIndex", plannedIndex.Status.Plan)
+			Expect(configMap.Data).To(HaveKeyWithValue("version", "plan"))
+			Expect(configMap.Data).To(HaveKey("avroSchema.json"))
+
+			var componentPlans []components.ComponentPlan
+			err = json.Unmarshal([]byte(configMap.Data["components.json"]), &componentPlans)
+			checkError(err)
+			Expect(componentPlans).To(ContainElement(components.ComponentPlan{
+				Type: "ElasticsearchIndex", Name: "xjoinindexpipeline.test-index.plan"}))
+		})
+
+		It("Should apply the changes and delete the plan when the plan annotation is removed", func() {
+			reconciler := IndexTestReconciler{
+				Namespace: namespace,
+				Name:      "test-index",
+				K8sClient: k8sClient,
+			}
+			plannedIndex := reconciler.ReconcilePlan()
+			configMapName := plannedIndex.Status.Plan.ConfigMapName
+
+			reconciler.RemovePlanAnnotation()
+			updatedIndex := reconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.Plan).To(BeNil())
+			Expect(updatedIndex.Status.RefreshingVersion).ToNot(Equal(""))
+
+			indexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			k8sGet(types.NamespacedName{
+				Name:      updatedIndex.Name + "." + updatedIndex.Status.RefreshingVersion,
+				Namespace: namespace,
+			}, indexPipeline)
+
+			err := k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: configMapName, Namespace: namespace}, &corev1.ConfigMap{})
+			Expect(k8errors.IsNotFound(err)).To(BeTrue())
+		})
 	})
 })
@@ -10,7 +10,6 @@ import (
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
 	"github.com/redhatinsights/xjoin-operator/controllers/components"
 	"github.com/redhatinsights/xjoin-operator/controllers/config"
-	"github.com/redhatinsights/xjoin-operator/controllers/elasticsearch"
 	. "github.com/redhatinsights/xjoin-operator/controllers/index"
 	"github.com/redhatinsights/xjoin-operator/controllers/kafka"
 	xjoinlogger "github.com/redhatinsights/xjoin-operator/controllers/log"
@@ -31,7 +30,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
-	"strings"
 	"time"
 )
 
@@ -114,7 +112,9 @@ func (r *XJoinIndexPipelineReconciler) SetupWithManager(mgr ctrl.Manager) error
 // +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkaconnects;kafkas,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=configmaps;pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=services;events,verbs=get;list;watch;create;delete;update
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;delete;update
 // +kubebuilder:rbac:groups="apps",resources=deployments,verbs=get;list;watch;create;delete;update
+// +kubebuilder:rbac:groups="autoscaling",resources=horizontalpodautoscalers,verbs=get;list;watch;create;delete;update
 
 func (r *XJoinIndexPipelineReconciler) Reconcile(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := xjoinlogger.NewLogger("controller_xjoinindexpipeline", "IndexPipeline", request.Name, "Namespace", request.Namespace)
@@ -178,7 +178,126 @@ func (r *XJoinIndexPipelineReconciler) Reconcile(ctx context.Context, request ct
 		return reconcile.Result{}, errors.Wrap(err, 0)
 	}
 
-	parametersMap := config.ParametersToMap(*p)
+	componentManager, indexAvroSchema, err := buildIndexPipelineComponents(ctx, r.Client, reqLogger, p, instance, r.Test)
+	if err != nil {
+		return result, errors.Wrap(err, 0)
+	}
+	//the components reuse their connections during the reconcile
+	defer func() {
+		if closeErr := componentManager.Close(); closeErr != nil {
+			reqLogger.Error(closeErr, "Unable to close the connections of the components")
+		}
+	}()
+
+	if instance.GetDeletionTimestamp() != nil {
+		reqLogger.Info("Starting finalizer")
+		err = componentManager.DeleteAll()
+		if err != nil {
+			reqLogger.Error(err, "error deleting components during finalizer")
+			return
+		}
+
+		controllerutil.RemoveFinalizer(instance, xjoinindexpipelineFinalizer)
+		ctx, cancel := utils.DefaultContext()
+		defer cancel()
+		err = r.Client.Update(ctx, instance)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, 0)
+		}
+
+		reqLogger.Info("Successfully finalized")
+		return reconcile.Result{}, nil
+	}
+
+	err = componentManager.CreateAll()
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, 0)
+	}
+
+	err = componentManager.Reconcile()
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, 0)
+	}
+
+	//an additive change of the avro schema is applied in place by migrating the existing components
+	avroSchemaHash, err := k8sUtils.SpecHash(p.AvroSchema.String())
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, 0)
+	}
+	migrate := instance.Status.AvroSchemaHash != "" && instance.Status.AvroSchemaHash != avroSchemaHash
+	if migrate {
+		reqLogger.Info("Avro schema changed, migrating the components in place")
+	}
+
+	deviations, remediations, err := componentManager.HandleDeviations(p.DeviationPolicy.String(), migrate)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, 0)
+	}
+	instance.Status.AvroSchemaHash = avroSchemaHash
+
+	if len(deviations) > 0 {
+		reqLogger.Warn("Component deviations found",
+			"policy", p.DeviationPolicy.String(), "deviations", deviations)
+	}
+	instance.Status.Deviations = deviations
+
+	//keep the last remediations so a successful repair remains visible after the deviations are resolved
+	if len(remediations) > 0 {
+		reqLogger.Info("Component deviations remediated", "remediations", remediations)
+		instance.Status.Remediations = remediations
+	}
+
+	//check the validity of each datasource version used by the pipeline
+	allDataSourcesValid := true
+	for datasourceName, dataSourceVersion := range indexAvroSchema.DataSources {
+		//GET each datasourcePipeline
+		dataSourcePipelineName := types.NamespacedName{
+			Namespace: instance.GetNamespace(),
+			Name:      datasourceName + "." + dataSourceVersion.Version,
+		}
+		dataSourcePipeline, err := k8sUtils.FetchXJoinDataSourcePipeline(r.Client, dataSourcePipelineName, ctx)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, 0)
+		}
+
+		if dataSourcePipeline.Status.ValidationResponse.Result == Invalid {
+			allDataSourcesValid = false
+		}
+	}
+
+	//a sink without a validator is reported as not validated, its version is promoted without being validated
+	if !IsValidatedSink(instance.Spec.Sink.GetType()) {
+		instance.Status.ValidationResponse.Result = NotValidated
+		instance.Status.ValidationResponse.Reason = "the " + instance.Spec.Sink.GetType() +
+			" sink has no validator, the version is promoted without validation"
+	} else if allDataSourcesValid {
+		instance.Status.ValidationResponse.Result = Valid
+	} else {
+		instance.Status.ValidationResponse.Result = Invalid
+	}
+
+	if !reflect.DeepEqual(instance.Status.DataSources, indexAvroSchema.DataSources) {
+		instance.Status.DataSources = indexAvroSchema.DataSources
+	}
+
+	//the hash of the generated graphql schema makes a change of the schema visible before the subgraph is updated
+	instance.Status.GraphQLSchemaHash = ""
+	if indexAvroSchema.GraphQLSchema != "" {
+		instance.Status.GraphQLSchemaHash, err = k8sUtils.SpecHash(indexAvroSchema.GraphQLSchema)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, 0)
+		}
+	}
+
+	i.Instance = instance
+	return i.UpdateStatusAndRequeue(time.Second * 30)
+}
+
+// buildIndexPipelineComponents parses the avro schema of an XJoinIndexPipeline and builds the components of the
+// pipeline. Building the components doesn't modify any external resources.
+func buildIndexPipelineComponents(ctx context.Context, c client.Client, log xjoinlogger.Log,
+	p *parameters.IndexParameters, instance *xjoin.XJoinIndexPipeline, test bool) (
+	componentManager components.ComponentManager, indexAvroSchema avro.IndexAvroSchema, err error) {
 
 	kafkaClient := kafka.GenericKafka{
 		Context:          ctx,
@@ -186,8 +305,8 @@ func (r *XJoinIndexPipelineReconciler) Reconcile(ctx context.Context, request ct
 		ConnectCluster:   p.ConnectCluster.String(),
 		KafkaNamespace:   p.KafkaClusterNamespace.String(),
 		KafkaCluster:     p.KafkaCluster.String(),
-		Client:           i.Client,
-		Test:             r.Test,
+		Client:           c,
+		Test:             test,
 	}
 
 	kafkaTopics := kafka.StrimziTopics{
@@ -203,8 +322,8 @@ func (r *XJoinIndexPipelineReconciler) Reconcile(ctx context.Context, request ct
 		},
 		KafkaClusterNamespace: p.KafkaClusterNamespace.String(),
 		KafkaCluster:          p.KafkaCluster.String(),
-		Client:                r.Client,
-		Test:                  r.Test,
+		Client:                c,
+		Test:                  test,
 		Context:               ctx,
 	}
 
@@ -222,16 +341,9 @@ func (r *XJoinIndexPipelineReconciler) Reconcile(ctx context.Context, request ct
 		KafkaTopics: kafkaTopics,
 	}
 
-	elasticSearchConnection := elasticsearch.GenericElasticSearchParameters{
-		Url:        p.ElasticSearchURL.String(),
-		Username:   p.ElasticSearchUsername.String(),
-		Password:   p.ElasticSearchPassword.String(),
-		Parameters: parametersMap,
-		Context:    i.Context,
-	}
-	genericElasticsearch, err := elasticsearch.NewGenericElasticsearch(elasticSearchConnection)
+	sink, err := BuildSink(ctx, c, instance.GetNamespace(), instance.Spec.Sink, p, kafkaClient)
 	if err != nil {
-		return result, errors.Wrap(err, 0)
+		return componentManager, indexAvroSchema, errors.Wrap(err, 0)
 	}
 
 	schemaRegistryConnectionParams := schemaregistry.ConnectionParams{
@@ -245,185 +357,142 @@ func (r *XJoinIndexPipelineReconciler) Reconcile(ctx context.Context, request ct
 
 	indexAvroSchemaParser := avro.IndexAvroSchemaParser{
 		AvroSchema:      p.AvroSchema.String(),
-		Client:          i.Client,
-		Context:         i.Context,
-		Namespace:       i.Instance.GetNamespace(),
-		Log:             i.Log,
+		Client:          c,
+		Context:         ctx,
+		Namespace:       instance.GetNamespace(),
+		Log:             log,
 		SchemaRegistry:  confluentClient,
-		SchemaNamespace: i.Instance.GetName(),
-		Active:          i.GetInstance().Status.Active,
+		SchemaNamespace: instance.GetName(),
+		Active:          instance.Status.Active,
+		DataSources:     instance.Spec.DataSources,
+		UsedDataSources: instance.Status.DataSources,
+		GraphQLName:     instance.Spec.Name,
+		TypeMapping:     p.ElasticSearchTypeMapping.String(),
 	}
-	indexAvroSchema, err := indexAvroSchemaParser.Parse()
+	indexAvroSchema, err = indexAvroSchemaParser.Parse()
 	if err != nil {
-		return result, errors.Wrap(err, 0)
+		return componentManager, indexAvroSchema, errors.Wrap(err, 0)
 	}
-
-	componentManager := components.NewComponentManager(common.IndexPipelineGVK.Kind, instance.Spec.Name, p.Version.String())
-
-	if indexAvroSchema.JSONFields != nil {
-		componentManager.AddComponent(&components.ElasticsearchPipeline{
-			GenericElasticsearch: *genericElasticsearch,
-			JsonFields:           indexAvroSchema.JSONFields,
-		})
+	err = sink.SetSchema(indexAvroSchema)
+	if err != nil {
+		return componentManager, indexAvroSchema, errors.Wrap(err, 0)
 	}
 
-	elasticSearchIndexComponent := &components.ElasticsearchIndex{
-		GenericElasticsearch: *genericElasticsearch,
-		Template:             p.ElasticSearchIndexTemplate.String(),
-		Properties:           indexAvroSchema.ESProperties,
-		WithPipeline:         indexAvroSchema.JSONFields != nil,
+	xjoinCoreDeployment, err := p.XJoinCoreDeployment.Spec(instance.Spec.XJoinCore)
+	if err != nil {
+		return componentManager, indexAvroSchema, errors.Wrap(err, 0)
+	}
+	var xjoinAPISubGraphOverride *xjoin.DeploymentSpec
+	var xjoinAPISubGraphAutoscaling *xjoin.AutoscalingSpec
+	if instance.Spec.XJoinAPISubGraph != nil {
+		xjoinAPISubGraphOverride = &instance.Spec.XJoinAPISubGraph.DeploymentSpec
+		xjoinAPISubGraphAutoscaling = instance.Spec.XJoinAPISubGraph.Autoscaling
 	}
-	componentManager.AddComponent(elasticSearchIndexComponent)
+	xjoinAPISubGraphDeployment, err := p.XJoinAPISubGraphDeployment.Spec(xjoinAPISubGraphOverride)
+	if err != nil {
+		return componentManager, indexAvroSchema, errors.Wrap(err, 0)
+	}
+
+	componentManager = components.NewComponentManager(common.IndexPipelineGVK.Kind, instance.Spec.Name, p.Version.String())
+	componentManager.SetConcurrency(p.ComponentConcurrency.Int())
+
 	componentManager.AddComponent(kafkaTopic)
-	componentManager.AddComponent(&components.ElasticsearchConnector{
-		Template:           p.ElasticSearchConnectorTemplate.String(),
-		KafkaClient:        kafkaClient,
-		TemplateParameters: parametersMap,
-		Topic:              kafkaTopic.Name(),
-	})
-	componentManager.AddComponent(components.NewAvroSchema(components.AvroSchemaParameters{
+	store := sink.AddComponents(&componentManager, kafkaTopic)
+	avroSchemaComponent := components.NewAvroSchema(components.AvroSchemaParameters{
 		Schema:   indexAvroSchema.AvroSchemaString,
 		Registry: confluentClient,
-	}))
-	graphqlSchemaComponent := components.NewGraphQLSchema(components.GraphQLSchemaParameters{
-		Registry: registryRestClient,
-		Active:   i.GetInstance().Status.Active,
 	})
-	componentManager.AddComponent(graphqlSchemaComponent)
+	componentManager.AddComponent(avroSchemaComponent)
 	componentManager.AddComponent(&components.XJoinCore{
-		Client:            i.Client,
-		Context:           i.Context,
+		Client:            c,
+		Context:           ctx,
 		SourceTopics:      indexAvroSchema.SourceTopics,
 		SinkTopic:         indexAvroSchemaParser.AvroSubjectToKafkaTopic(kafkaTopic.Name()),
 		KafkaBootstrap:    p.KafkaBootstrapURL.String(),
 		SchemaRegistryURL: p.SchemaRegistryProtocol.String() + "://" + p.SchemaRegistryHost.String() + ":" + p.SchemaRegistryPort.String(),
-		Namespace:         i.Instance.GetNamespace(),
+		Namespace:         instance.GetNamespace(),
 		Schema:            indexAvroSchema.AvroSchemaString,
+		Deployment:        xjoinCoreDeployment,
+	}, kafkaTopic, avroSchemaComponent)
+
+	//the xjoin-api-subgraph queries the records via the Elasticsearch API, it's not created for other sinks
+	searchSink, isSearchSink := sink.(components.SearchSink)
+	if !isSearchSink {
+		return componentManager, indexAvroSchema, nil
+	}
+	connection := searchSink.Connection()
+
+	graphqlSchemaComponent := components.NewGraphQLSchema(components.GraphQLSchemaParameters{
+		Schema:    indexAvroSchema.GraphQLSchema,
+		Registry:  registryRestClient,
+		Namespace: instance.GetNamespace(),
+		Active:    instance.Status.Active,
 	})
+	componentManager.AddComponent(graphqlSchemaComponent)
+	credentialsSecret := &components.Secret{
+		Client:    c,
+		Context:   ctx,
+		Namespace: instance.GetNamespace(),
+		Data: map[string]string{
+			components.ElasticSearchUsernameKey: connection.Username,
+			components.ElasticSearchPasswordKey: connection.Password,
+		},
+	}
+	componentManager.AddComponent(credentialsSecret)
 	componentManager.AddComponent(&components.XJoinAPISubGraph{
-		Client:                i.Client,
-		Context:               i.Context,
-		Namespace:             i.Instance.GetNamespace(),
-		AvroSchema:            indexAvroSchema.AvroSchemaString,
-		Registry:              confluentClient,
-		ElasticSearchURL:      p.ElasticSearchURL.String(),
-		ElasticSearchUsername: p.ElasticSearchUsername.String(),
-		ElasticSearchPassword: p.ElasticSearchPassword.String(),
-		ElasticSearchIndex:    elasticSearchIndexComponent.Name(),
-		Image:                 "quay.io/cloudservices/xjoin-api-subgraph:latest", //TODO
-		GraphQLSchemaName:     graphqlSchemaComponent.Name(),
-	})
-	componentManager.AddComponent(&components.XJoinIndexValidator{
-		Client:                 i.Client,
-		Context:                i.Context,
-		Namespace:              i.Instance.GetNamespace(),
-		Schema:                 p.AvroSchema.String(),
-		Pause:                  i.Parameters.Pause.Bool(),
-		ParentInstance:         i.Instance,
-		ElasticsearchIndexName: elasticSearchIndexComponent.Name(),
-	})
+		Client:             c,
+		Context:            ctx,
+		Namespace:          instance.GetNamespace(),
+		AvroSchema:         indexAvroSchema.AvroSchemaString,
+		Registry:           confluentClient,
+		ElasticSearchURL:   connection.Url,
+		Credentials:        credentialsSecret,
+		ElasticSearchIndex: store.Name(),
+		GraphQLSchemaName:  graphqlSchemaComponent.Name(),
+		Deployment:         xjoinAPISubGraphDeployment,
+		Autoscaling:        xjoinAPISubGraphAutoscaling,
+		ProbePath:          p.XJoinAPISubGraphProbePath.String(),
+	}, graphqlSchemaComponent, store, avroSchemaComponent, credentialsSecret)
+
+	//the validator compares the data sources with the index in the xjoin-elasticsearch cluster
+	if IsValidatedSink(sink.Type()) {
+		componentManager.AddComponent(&components.XJoinIndexValidator{
+			Client:                 c,
+			Context:                ctx,
+			Namespace:              instance.GetNamespace(),
+			Schema:                 p.AvroSchema.String(),
+			Pause:                  p.Pause.Bool(),
+			ParentInstance:         instance,
+			ElasticsearchIndexName: store.Name(),
+			Validation:             instance.Spec.Validation,
+		}, store)
+	}
 
 	for _, customSubgraphImage := range instance.Spec.CustomSubgraphImages {
 		customSubgraphGraphQLSchemaComponent := components.NewGraphQLSchema(components.GraphQLSchemaParameters{
-			Registry: registryRestClient,
-			Suffix:   customSubgraphImage.Name,
-			Active:   i.GetInstance().Status.Active,
+			Registry:  registryRestClient,
+			Namespace: instance.GetNamespace(),
+			Suffix:    customSubgraphImage.Name,
+			Active:    instance.Status.Active,
 		})
 		componentManager.AddComponent(customSubgraphGraphQLSchemaComponent)
 		componentManager.AddComponent(&components.XJoinAPISubGraph{
-			Client:                i.Client,
-			Context:               i.Context,
-			Namespace:             i.Instance.GetNamespace(),
-			AvroSchema:            indexAvroSchema.AvroSchemaString,
-			Registry:              confluentClient,
-			ElasticSearchURL:      p.ElasticSearchURL.String(),
-			ElasticSearchUsername: p.ElasticSearchUsername.String(),
-			ElasticSearchPassword: p.ElasticSearchPassword.String(),
-			ElasticSearchIndex:    elasticSearchIndexComponent.Name(),
-			Image:                 customSubgraphImage.Image,
-			Suffix:                customSubgraphImage.Name,
-			GraphQLSchemaName:     customSubgraphGraphQLSchemaComponent.Name(),
-		})
+			Client:             c,
+			Context:            ctx,
+			Namespace:          instance.GetNamespace(),
+			AvroSchema:         indexAvroSchema.AvroSchemaString,
+			Registry:           confluentClient,
+			ElasticSearchURL:   connection.Url,
+			Credentials:        credentialsSecret,
+			ElasticSearchIndex: store.Name(),
+			Image:              customSubgraphImage.Image,
+			Suffix:             customSubgraphImage.Name,
+			GraphQLSchemaName:  customSubgraphGraphQLSchemaComponent.Name(),
+			Deployment:         xjoinAPISubGraphDeployment,
+			Autoscaling:        xjoinAPISubGraphAutoscaling,
+			ProbePath:          p.XJoinAPISubGraphProbePath.String(),
+		}, customSubgraphGraphQLSchemaComponent, store, avroSchemaComponent, credentialsSecret)
 	}
 
-	if instance.GetDeletionTimestamp() != nil {
-		reqLogger.Info("Starting finalizer")
-		err = componentManager.DeleteAll()
-		if err != nil {
-			reqLogger.Error(err, "error deleting components during finalizer")
-			return
-		}
-
-		controllerutil.RemoveFinalizer(instance, xjoinindexpipelineFinalizer)
-		ctx, cancel := utils.DefaultContext()
-		defer cancel()
-		err = r.Client.Update(ctx, instance)
-		if err != nil {
-			return reconcile.Result{}, errors.Wrap(err, 0)
-		}
-
-		reqLogger.Info("Successfully finalized")
-		return reconcile.Result{}, nil
-	}
-
-	err = componentManager.CreateAll()
-	if err != nil {
-		return reconcile.Result{}, errors.Wrap(err, 0)
-	}
-
-	err = componentManager.Reconcile()
-	if err != nil {
-		return reconcile.Result{}, errors.Wrap(err, 0)
-	}
-
-	problems, err := componentManager.CheckForDeviations()
-	if err != nil {
-		return reconcile.Result{}, errors.Wrap(err, 0)
-	}
-
-	if len(problems) > 0 {
-		//TODO: set instance status to invalid, add problems to status
-		reqLogger.Info("TODO: Set Instance status to invalid, add", "problems", len(problems))
-	}
-
-	//build list of datasources
-	dataSources := make(map[string]string)
-	allDataSourcesValid := true
-	for _, ref := range indexAvroSchema.References {
-		//get each datasource name and resource version
-		datasourceName := strings.Split(ref.Name, ".Value")[0]
-
-		datasourcePipelineVersion := strings.Split(ref.Subject, "xjoindatasourcepipeline.")[1]
-		datasourcePipelineVersion = strings.Split(datasourcePipelineVersion, ".")[1]
-		datasourcePipelineVersion = strings.Split(datasourcePipelineVersion, "-value")[0]
-
-		dataSources[datasourceName] = datasourcePipelineVersion
-
-		//GET each datasourcePipeline
-		dataSourcePipelineName := types.NamespacedName{
-			Namespace: instance.GetNamespace(),
-			Name:      datasourceName + "." + datasourcePipelineVersion,
-		}
-		dataSourcePipeline, err := k8sUtils.FetchXJoinDataSourcePipeline(r.Client, dataSourcePipelineName, ctx)
-		if err != nil {
-			return reconcile.Result{}, errors.Wrap(err, 0)
-		}
-
-		if dataSourcePipeline.Status.ValidationResponse.Result == Invalid {
-			allDataSourcesValid = false
-		}
-	}
-
-	if allDataSourcesValid {
-		instance.Status.ValidationResponse.Result = Valid
-	} else {
-		instance.Status.ValidationResponse.Result = Invalid
-	}
-
-	if !reflect.DeepEqual(instance.Status.DataSources, dataSources) {
-		instance.Status.DataSources = dataSources
-	}
-
-	i.Instance = instance
-	return i.UpdateStatusAndRequeue(time.Second * 30)
+	return componentManager, indexAvroSchema, nil
 }
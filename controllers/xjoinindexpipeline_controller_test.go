@@ -5,16 +5,22 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"strings"
 
 	"github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
 	"github.com/jarcoal/httpmock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/avro"
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	xjoinAvro "github.com/redhatinsights/xjoin-operator/controllers/avro"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
 	"github.com/redhatinsights/xjoin-operator/controllers/index"
+	k8sUtils "github.com/redhatinsights/xjoin-operator/controllers/utils"
 	v1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
@@ -104,6 +110,74 @@ var _ = Describe("XJoinIndexPipeline", func() {
 			Expect(actualElasticsearchConfig).To(Equal(expectedElasticsearchConfig))
 		})
 
+		It("Should create an OpenSearch Connector when the sink is opensearch", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				Sink: &v1alpha1.SinkSpec{
+					Type: v1alpha1.SinkTypeOpenSearch,
+					OpenSearch: &v1alpha1.OpenSearchSinkSpec{
+						URL:      &v1alpha1.StringOrSecretParameter{Value: "http://localhost:9200"},
+						Username: &v1alpha1.StringOrSecretParameter{Value: "opensearch-user"},
+						Password: &v1alpha1.StringOrSecretParameter{Value: "opensearch-password"},
+					},
+				},
+			}
+			indexPipeline := reconciler.ReconcileNew()
+
+			info := httpmock.GetCallCountInfo()
+			Expect(info["PUT http://localhost:9200/xjoinindexpipeline."+reconciler.GetName()]).To(Equal(1))
+
+			connectorName := "xjoinindexpipeline." + reconciler.GetName()
+			connectorLookupKey := types.NamespacedName{Name: connectorName, Namespace: namespace}
+			connector := &v1beta2.KafkaConnector{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), connectorLookupKey, connector)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			openSearchClass := "io.aiven.kafka.connect.opensearch.OpensearchSinkConnector"
+			Expect(connector.Spec.Class).To(Equal(&openSearchClass))
+
+			var config map[string]interface{}
+			err := json.Unmarshal(connector.Spec.Config.Raw, &config)
+			checkError(err)
+			Expect(config["connection.url"]).To(Equal("http://localhost:9200"))
+			Expect(config["connection.username"]).To(Equal("opensearch-user"))
+			Expect(config["topics"]).To(Equal("xjoinindexpipeline." + reconciler.GetName()))
+
+			//the connector reads the password via the strimzi config provider from the sink's secret
+			sinkSecretName := "xjoinindexpipeline." + reconciler.Name + "-sink." + reconciler.Version
+			Expect(config["connection.password"]).To(Equal(
+				"${secrets:" + namespace + "/" + sinkSecretName + ":sink.password}"))
+			Expect(string(connector.Spec.Config.Raw)).ToNot(ContainSubstring("opensearch-password"))
+			sinkSecret := &corev1.Secret{}
+			err = k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: sinkSecretName, Namespace: namespace}, sinkSecret)
+			checkError(err)
+			Expect(string(sinkSecret.Data["sink.password"])).To(Equal("opensearch-password"))
+
+			//the subgraph reads the opensearch credentials from the pipeline's secret
+			secret := &corev1.Secret{}
+			secretLookupKey := types.NamespacedName{Name: "xjoinindexpipeline." + reconciler.GetName(), Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), secretLookupKey, secret)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			Expect(string(secret.Data["elasticsearch.username"])).To(Equal("opensearch-user"))
+
+			//the opensearch index is validated via the Elasticsearch API like the elasticsearch sink
+			validatorLookupKey := types.NamespacedName{Name: "xjoinindexpipeline." + reconciler.GetName(), Namespace: namespace}
+			validator := &v1alpha1.XJoinIndexValidator{}
+			err = k8sClient.Get(context.Background(), validatorLookupKey, validator)
+			checkError(err)
+			Expect(validator.Spec.IndexName).To(Equal("xjoinindexpipeline." + reconciler.GetName()))
+			Expect(indexPipeline.Status.ValidationResponse.Result).ToNot(Equal(index.NotValidated))
+		})
+
 		It("Should create a Kafka Topic", func() {
 			reconciler := XJoinIndexPipelineTestReconciler{
 				Namespace:      namespace,
@@ -333,14 +407,28 @@ var _ = Describe("XJoinIndexPipeline", func() {
 					ValueFrom: nil,
 				},
 				{
-					Name:      "ELASTIC_SEARCH_USERNAME",
-					Value:     "xjoin",
-					ValueFrom: nil,
+					Name:  "ELASTIC_SEARCH_USERNAME",
+					Value: "",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "xjoinindexpipeline.test-index-pipeline.1234",
+							},
+							Key: "elasticsearch.username",
+						},
+					},
 				},
 				{
-					Name:      "ELASTIC_SEARCH_PASSWORD",
-					Value:     "xjoin1337",
-					ValueFrom: nil,
+					Name:  "ELASTIC_SEARCH_PASSWORD",
+					Value: "",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "xjoinindexpipeline.test-index-pipeline.1234",
+							},
+							Key: "elasticsearch.password",
+						},
+					},
 				},
 				{
 					Name:      "ELASTIC_SEARCH_INDEX",
@@ -375,6 +463,150 @@ var _ = Describe("XJoinIndexPipeline", func() {
 			Expect(deployment.Spec.ProgressDeadlineSeconds).To(Equal(&progressDeadlineSeconds))
 		})
 
+		It("Should create a secret with the Elasticsearch credentials", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			secret := &corev1.Secret{}
+			secretLookupKey := types.NamespacedName{Name: "xjoinindexpipeline.test-index-pipeline.1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), secretLookupKey, secret)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(secret.Data).To(Equal(map[string][]byte{
+				"elasticsearch.username": []byte("xjoin"),
+				"elasticsearch.password": []byte("xjoin1337"),
+			}))
+		})
+
+		It("Should apply the deployment settings from the spec", func() {
+			replicas := int32(3)
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				XJoinCore: &v1alpha1.DeploymentSpec{
+					ImageTag:        "abc123",
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Replicas:        &replicas,
+					Resources: &corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("1"),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+					},
+					NodeSelector:       map[string]string{"node-role": "xjoin"},
+					Tolerations:        []corev1.Toleration{{Key: "xjoin", Operator: corev1.TolerationOpExists}},
+					PodAnnotations:     map[string]string{"prometheus.io/scrape": "true"},
+					ServiceAccountName: "xjoin-core",
+					ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "quay-pull"}},
+				},
+				XJoinAPISubGraph: &v1alpha1.XJoinAPISubGraphSpec{
+					DeploymentSpec: v1alpha1.DeploymentSpec{
+						Image: "quay.io/example/xjoin-api-subgraph",
+					},
+				},
+			}
+			reconciler.ReconcileNew()
+
+			deployment := &v1.Deployment{}
+			deploymentLookupKey := types.NamespacedName{
+				Name: "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(deployment.Spec.Replicas).To(Equal(&replicas))
+			Expect(deployment.Spec.Template.Annotations).To(Equal(map[string]string{"prometheus.io/scrape": "true"}))
+			Expect(deployment.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"node-role": "xjoin"}))
+			Expect(deployment.Spec.Template.Spec.Tolerations).To(Equal(
+				[]corev1.Toleration{{Key: "xjoin", Operator: corev1.TolerationOpExists}}))
+			Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal("xjoin-core"))
+			Expect(deployment.Spec.Template.Spec.ImagePullSecrets).To(Equal(
+				[]corev1.LocalObjectReference{{Name: "quay-pull"}}))
+			Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("quay.io/cloudservices/xjoin-core:abc123"))
+			Expect(container.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+			Expect(container.Resources.Limits.Cpu().String()).To(Equal("1"))
+			Expect(container.Resources.Limits.Memory().String()).To(Equal("1Gi"))
+			Expect(container.Resources.Requests.Cpu().String()).To(Equal("1"))
+
+			deploymentLookupKey = types.NamespacedName{Name: "test-index-pipeline-1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(
+				Equal("quay.io/example/xjoin-api-subgraph:latest"))
+			Expect(deployment.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullAlways))
+		})
+
+		It("Should create a service, probes and an autoscaler for the xjoin-api-subgraph", func() {
+			minReplicas := int32(2)
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				XJoinAPISubGraph: &v1alpha1.XJoinAPISubGraphSpec{
+					Autoscaling: &v1alpha1.AutoscalingSpec{
+						MinReplicas: &minReplicas,
+						MaxReplicas: 5,
+					},
+				},
+			}
+			reconciler.ReconcileNew()
+
+			lookupKey := types.NamespacedName{Name: "test-index-pipeline-1234", Namespace: namespace}
+
+			service := &corev1.Service{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), lookupKey, service)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].Port).To(Equal(int32(4000)))
+			Expect(service.Spec.Ports[0].TargetPort).To(Equal(intstr.FromString("web")))
+			Expect(service.Spec.Selector).To(Equal(map[string]string{"app": "test-index-pipeline-1234"}))
+
+			deployment := &v1.Deployment{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), lookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.ReadinessProbe).ToNot(BeNil())
+			Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal("/healthz"))
+			Expect(container.ReadinessProbe.HTTPGet.Port).To(Equal(intstr.FromString("web")))
+			Expect(container.LivenessProbe).ToNot(BeNil())
+			Expect(container.LivenessProbe.HTTPGet.Path).To(Equal("/healthz"))
+
+			autoscaler := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), lookupKey, autoscaler)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			Expect(autoscaler.Spec.ScaleTargetRef.Kind).To(Equal("Deployment"))
+			Expect(autoscaler.Spec.ScaleTargetRef.Name).To(Equal("test-index-pipeline-1234"))
+			Expect(autoscaler.Spec.MinReplicas).To(Equal(&minReplicas))
+			Expect(autoscaler.Spec.MaxReplicas).To(Equal(int32(5)))
+			Expect(autoscaler.Spec.Metrics).To(HaveLen(1))
+			Expect(*autoscaler.Spec.Metrics[0].Resource.Target.AverageUtilization).To(Equal(int32(80)))
+		})
+
 		It("Should create custom subgraph graphql schema", func() {
 			reconciler := XJoinIndexPipelineTestReconciler{
 				Namespace:      namespace,
@@ -472,14 +704,28 @@ var _ = Describe("XJoinIndexPipeline", func() {
 					ValueFrom: nil,
 				},
 				{
-					Name:      "ELASTIC_SEARCH_USERNAME",
-					Value:     "xjoin",
-					ValueFrom: nil,
+					Name:  "ELASTIC_SEARCH_USERNAME",
+					Value: "",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "xjoinindexpipeline.test-index-pipeline.1234",
+							},
+							Key: "elasticsearch.username",
+						},
+					},
 				},
 				{
-					Name:      "ELASTIC_SEARCH_PASSWORD",
-					Value:     "xjoin1337",
-					ValueFrom: nil,
+					Name:  "ELASTIC_SEARCH_PASSWORD",
+					Value: "",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "xjoinindexpipeline.test-index-pipeline.1234",
+							},
+							Key: "elasticsearch.password",
+						},
+					},
 				},
 				{
 					Name:      "ELASTIC_SEARCH_INDEX",
@@ -543,12 +789,524 @@ var _ = Describe("XJoinIndexPipeline", func() {
 			Expect(count).To(Equal(1))
 
 			count = info["GET http://localhost:9200/_ingest/pipeline/xjoinindexpipeline.test-index-pipeline.1234"]
-			Expect(count).To(Equal(1))
+			Expect(count).To(Equal(2)) //the deviation check reads the pipeline again
 
 			count = info["PUT http://localhost:9200/_ingest/pipeline/xjoinindexpipeline.test-index-pipeline.1234"]
 			Expect(count).To(Equal(1))
 		})
 
+		It("Should map the avro types to elasticsearch types", func() {
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-typed-fields",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":          map[string]interface{}{"type": "keyword"},
+				"count":       map[string]interface{}{"type": "integer"},
+				"size":        map[string]interface{}{"type": "long"},
+				"ratio":       map[string]interface{}{"type": "float"},
+				"score":       map[string]interface{}{"type": "double"},
+				"created_on":  map[string]interface{}{"type": "date"},
+				"modified_on": map[string]interface{}{"type": "date"},
+				"price":       map[string]interface{}{"type": "scaled_float", "scaling_factor": float64(100)},
+				"state":       map[string]interface{}{"type": "keyword"},
+				"external_id": map[string]interface{}{"type": "keyword"},
+				"description": map[string]interface{}{"type": "text"},
+			}))
+
+			//micro timestamps are converted to epoch millis by the ingest pipeline
+			var pipeline map[string]interface{}
+			err = json.Unmarshal([]byte(reconciler.pipelineRequestBody), &pipeline)
+			checkError(err)
+			Expect(pipeline["processors"]).To(Equal([]interface{}{
+				map[string]interface{}{
+					"script": map[string]interface{}{
+						"if":     "ctx.testdatasource?.created_on != null",
+						"lang":   "painless",
+						"source": "ctx.testdatasource.created_on = ctx.testdatasource.created_on / 1000L",
+					},
+				},
+			}))
+		})
+
+		It("Should keep the legacy elasticsearch mapping when it is selected", func() {
+			setTypeMapping(namespace, xjoinAvro.TypeMappingLegacy)
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-typed-fields",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			//only xjoin.type and xjoin.es.type are used, everything else is a keyword
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":          map[string]interface{}{"type": "keyword"},
+				"count":       map[string]interface{}{"type": "keyword"},
+				"size":        map[string]interface{}{"type": "keyword"},
+				"ratio":       map[string]interface{}{"type": "keyword"},
+				"score":       map[string]interface{}{"type": "keyword"},
+				"created_on":  map[string]interface{}{"type": "keyword"},
+				"modified_on": map[string]interface{}{"type": "keyword"},
+				"price":       map[string]interface{}{"type": "keyword"},
+				"state":       map[string]interface{}{"type": "keyword"},
+				"external_id": map[string]interface{}{"type": "keyword"},
+				"description": map[string]interface{}{"type": "text"},
+			}))
+			Expect(reconciler.pipelineRequestBody).ToNot(ContainSubstring("created_on"))
+		})
+
+		It("Should register the graphql schema generated from the avro schema", func() {
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-typed-fields",
+				}},
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			Expect(reconciler.graphQLSchemaBody).To(ContainSubstring(
+				"  testIndexPipeline(filter: TestIndexPipelineFilter, order_by: TestIndexPipelineOrderBy, " +
+					"order_how: OrderDirection, limit: Int = 10, offset: Int = 0): TestIndexPipelineCollection!\n"))
+			Expect(reconciler.graphQLSchemaBody).To(ContainSubstring(
+				"type TestIndexPipelineTestdatasource @key(fields: \"id\") {\n" +
+					"  id: String\n" +
+					"  count: Int\n" +
+					"  size: Long\n" +
+					"  ratio: Float\n" +
+					"  score: Float\n" +
+					"  created_on: Date\n" +
+					"  modified_on: Date\n" +
+					"  price: Float\n" +
+					"  state: String\n" +
+					"  external_id: String\n" +
+					"  description: String\n" +
+					"}"))
+			Expect(reconciler.graphQLSchemaBody).To(ContainSubstring(
+				"input TestIndexPipelineFilter {\n" +
+					"  testdatasource: TestIndexPipelineTestdatasourceFilter\n" +
+					"  AND: [TestIndexPipelineFilter!]\n" +
+					"  OR: [TestIndexPipelineFilter!]\n" +
+					"  NOT: TestIndexPipelineFilter\n" +
+					"}"))
+
+			//text fields can't be sorted
+			Expect(reconciler.graphQLSchemaBody).To(ContainSubstring("  testdatasource_external_id\n}"))
+			Expect(reconciler.graphQLSchemaBody).ToNot(ContainSubstring("testdatasource_description"))
+
+			graphQLSchemaHash, err := k8sUtils.SpecHash(reconciler.graphQLSchemaBody)
+			checkError(err)
+			Expect(createdIndexPipeline.Status.GraphQLSchemaHash).To(Equal(graphQLSchemaHash))
+		})
+
+		It("Should apply the xjoin.es mapping hints and generate the analysis settings", func() {
+			setIndexTemplate(namespace,
+				`{"settings":{"index":{"number_of_shards":"1"}},"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-mapping-hints",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			Expect(properties["search_all"]).To(Equal(map[string]interface{}{"type": "text"}))
+
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id": map[string]interface{}{"type": "keyword"},
+				"display_name": map[string]interface{}{
+					"type":         "keyword",
+					"ignore_above": float64(256),
+					"copy_to":      []interface{}{"search_all"},
+					"fields": map[string]interface{}{
+						"lowercase": map[string]interface{}{"type": "keyword", "normalizer": "case_insensitive"},
+					},
+				},
+				"description": map[string]interface{}{
+					"type":     "text",
+					"analyzer": "folding",
+					"copy_to":  []interface{}{"search_all"},
+				},
+				"checksum": map[string]interface{}{"type": "keyword", "index": false, "doc_values": false},
+				"systems": map[string]interface{}{
+					"type": "nested",
+					"properties": map[string]interface{}{
+						"name":    map[string]interface{}{"type": "keyword"},
+						"version": map[string]interface{}{"type": "keyword"},
+					},
+				},
+			}))
+
+			settings := index["settings"].(map[string]interface{})
+			Expect(settings["analysis"]).To(Equal(map[string]interface{}{
+				"analyzer": map[string]interface{}{
+					"folding": map[string]interface{}{
+						"tokenizer": "standard",
+						"filter":    []interface{}{"lowercase", "asciifolding"},
+					},
+				},
+				"normalizer": map[string]interface{}{
+					"case_insensitive": map[string]interface{}{"filter": "lowercase"},
+				},
+			}))
+		})
+
+		It("Should apply the transformations to the avro schema and the elasticsearch mapping", func() {
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-transformations",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-transformed-fields",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			caseInsensitiveKeyword := map[string]interface{}{
+				"type": "keyword",
+				"fields": map[string]interface{}{
+					"lowercase": map[string]interface{}{"type": "keyword", "normalizer": "case_insensitive"},
+				},
+			}
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":           map[string]interface{}{"type": "keyword"},
+				"display_name": caseInsensitiveKeyword,
+				"name":         caseInsensitiveKeyword,
+				"state":        map[string]interface{}{"type": "keyword"},
+				"facts":        map[string]interface{}{"type": "object"},
+				"labels":       map[string]interface{}{"type": "object"},
+				"label":        map[string]interface{}{"type": "keyword"},
+				"arch":         map[string]interface{}{"type": "keyword"},
+				"title":        map[string]interface{}{"type": "keyword"},
+				"labels_structured": map[string]interface{}{
+					"type": "nested",
+					"properties": map[string]interface{}{
+						"key":   map[string]interface{}{"type": "keyword"},
+						"value": map[string]interface{}{"type": "keyword"},
+					},
+				},
+			}))
+
+			//xjoin-core receives the transformed schema
+			deployment := &v1.Deployment{}
+			deploymentLookupKey := types.NamespacedName{
+				Name: "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			var sinkSchema avro.Schema
+			for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "SINK_SCHEMA" {
+					err = json.Unmarshal([]byte(env.Value), &sinkSchema)
+					checkError(err)
+				}
+			}
+			Expect(sinkSchema.Transformations).To(HaveLen(7))
+			Expect(sinkSchema.Fields).To(HaveLen(1))
+
+			var fieldNames []string
+			for _, field := range sinkSchema.Fields[0].Type[0].Fields {
+				fieldNames = append(fieldNames, field.Name)
+			}
+			Expect(fieldNames).To(Equal([]string{
+				"id", "display_name", "facts", "labels", "state", "name", "label", "arch", "labels_structured", "title"}))
+		})
+
+		It("Should expand nested references and the subjects referenced by data sources", func() {
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-nested-references",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-shared-references",
+				}},
+				RegistrySubjects: []RegistrySubject{{
+					Subject:                  "com.example.Address-value",
+					Version:                  "1",
+					ApiCurioResponseFilename: "shared-address",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			//the shared subject is fetched once per reconcile
+			info := httpmock.GetCallCountInfo()
+			Expect(info["GET http://apicurio:1080/apis/ccompat/v6/subjects/com.example.Address-value/versions/1"]).
+				To(Equal(1))
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties["inventory"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			address := map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"street": map[string]interface{}{"type": "keyword"},
+					"geo": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"lat": map[string]interface{}{"type": "double"},
+							"lon": map[string]interface{}{"type": "double"},
+						},
+					},
+				},
+			}
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":             map[string]interface{}{"type": "keyword"},
+				"home_address":   address,
+				"work_addresses": address,
+			}))
+
+			//the shared record type is defined once in the schema passed to xjoin-core
+			deployment := &v1.Deployment{}
+			deploymentLookupKey := types.NamespacedName{
+				Name: "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			var sinkSchema string
+			for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "SINK_SCHEMA" {
+					sinkSchema = env.Value
+				}
+			}
+			Expect(strings.Count(sinkSchema, `"name":"com.example.Address"`)).To(Equal(1))
+			Expect(sinkSchema).To(ContainSubstring(`{"items":"com.example.Address","type":"array"}`))
+		})
+
+		It("Should record the data source version chosen by the version policy", func() {
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+				DataSourceSpecs: []v1alpha1.DataSourceVersionSpec{{
+					Name:   dataSourceName,
+					Policy: v1alpha1.DataSourceVersionPolicyActiveOnly,
+				}},
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			Expect(createdIndexPipeline.Status.DataSources).To(Equal(map[string]v1alpha1.DataSourceVersion{
+				dataSourceName: {
+					Version:       createdDataSource.Status.ActiveVersion,
+					SchemaVersion: 1,
+					Policy:        v1alpha1.DataSourceVersionPolicyActiveOnly,
+				},
+			}))
+			Expect(createdIndexPipeline.GetDataSources()).To(Equal(map[string]string{
+				dataSourceName: createdDataSource.Status.ActiveVersion,
+			}))
+		})
+
+		It("Should map unions and arrays of records to elasticsearch types", func() {
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-unions-and-arrays",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":        map[string]interface{}{"type": "keyword"},
+				"reference": map[string]interface{}{"type": "keyword"},
+				"tags":      map[string]interface{}{"type": "keyword"},
+				"systems": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name":    map[string]interface{}{"type": "keyword"},
+						"profile": map[string]interface{}{"type": "object"},
+					},
+				},
+			}))
+
+			//json fields inside arrays are parsed for each item
+			var pipeline map[string]interface{}
+			err = json.Unmarshal([]byte(reconciler.pipelineRequestBody), &pipeline)
+			checkError(err)
+			Expect(pipeline["processors"]).To(Equal([]interface{}{
+				map[string]interface{}{
+					"foreach": map[string]interface{}{
+						"field":          "testdatasource.systems",
+						"ignore_missing": true,
+						"processor": map[string]interface{}{
+							"json": map[string]interface{}{
+								"field":          "_ingest._value.profile",
+								"ignore_failure": true,
+							},
+						},
+					},
+				},
+			}))
+		})
+
 		It("Should create an XJoinIndexValidation resource", func() {
 			configFileName := "xjoinindex"
 			reconciler := XJoinIndexPipelineTestReconciler{
@@ -598,6 +1356,33 @@ var _ = Describe("XJoinIndexPipeline", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(validator.Spec.AvroSchema).To(Equal(string(indexAvroSchema)))
 		})
+
+		It("Should pass the validation spec to the XJoinIndexValidation resource", func() {
+			validation := &v1alpha1.ValidationSpec{
+				Strategy:   v1alpha1.ValidationStrategySampling,
+				SampleSize: 500,
+				Confidence: 99,
+			}
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				Validation:     validation,
+			}
+			reconciler.ReconcileNew()
+
+			validatorLookupKey := types.NamespacedName{
+				Name: "xjoinindexpipeline.test-index-pipeline.1234", Namespace: namespace}
+			validator := &v1alpha1.XJoinIndexValidator{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), validatorLookupKey, validator)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(validator.Spec.Validation).To(Equal(validation))
+		})
 	})
 
 	Context("Reconcile Deletion", func() {
@@ -2,6 +2,9 @@ package controllers_test
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
 	"os"
 
 	"github.com/jarcoal/httpmock"
@@ -9,6 +12,8 @@ import (
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -24,9 +29,22 @@ type XJoinIndexPipelineTestReconciler struct {
 	Version              string
 	ConfigFileName       string
 	CustomSubgraphImages []v1alpha1.CustomSubgraphImage
+	XJoinCore            *v1alpha1.DeploymentSpec
+	XJoinAPISubGraph     *v1alpha1.XJoinAPISubGraphSpec
+	Sink                 *v1alpha1.SinkSpec
+	Validation           *v1alpha1.ValidationSpec
 	K8sClient            client.Client
 	DataSources          []DataSource
+	DataSourceSpecs      []v1alpha1.DataSourceVersionSpec
+	RegistrySubjects     []RegistrySubject
 	createdIndexPipeline v1alpha1.XJoinIndexPipeline
+	indexRequestBody     string
+	indexCreated         bool
+	pipelineRequestBody  string
+	graphQLSchemaBody    string
+	avroSchemaBody       string
+	graphQLSchemaLabels  map[string][]string
+	graphQLSchemaPushed  string //the latest version of each graphql schema pushed by the subgraph pods
 }
 
 type DataSource struct {
@@ -35,13 +53,26 @@ type DataSource struct {
 	ApiCurioResponseFilename string
 }
 
+// RegistrySubject is a schema registry subject referenced by a data source schema
+type RegistrySubject struct {
+	Subject                  string
+	Version                  string
+	ApiCurioResponseFilename string
+}
+
 func (x *XJoinIndexPipelineTestReconciler) GetName() string {
 	return x.Name + "." + x.Version
 }
 
 func (x *XJoinIndexPipelineTestReconciler) ReconcileNew() v1alpha1.XJoinIndexPipeline {
-	x.registerNewMocks()
 	x.createValidIndexPipeline()
+	return x.ReconcileCreate()
+}
+
+// ReconcileCreate reconciles an XJoinIndexPipeline whose components don't exist yet e.g. a pipeline created by its
+// XJoinIndex. The mocks record the created resources so later reconciles read them back.
+func (x *XJoinIndexPipelineTestReconciler) ReconcileCreate() v1alpha1.XJoinIndexPipeline {
+	x.registerNewMocks()
 	result := x.reconcile()
 	Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 30000000000}))
 	indexPipelineLookupKey := types.NamespacedName{Name: x.GetName(), Namespace: x.Namespace}
@@ -159,13 +190,18 @@ func (x *XJoinIndexPipelineTestReconciler) registerDeleteMocks() {
 			httpmock.NewStringResponder(200, `{}`).Once())
 	}
 
+	x.registerSubjectMocks()
+
 	for _, dataSource := range x.DataSources {
 		response, err := os.ReadFile("./test/data/apicurio/" + dataSource.ApiCurioResponseFilename + ".json")
 		checkError(err)
-		httpmock.RegisterResponder(
-			"GET",
-			"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/latest",
-			httpmock.NewStringResponder(200, string(response)))
+		//later reconciles fetch the schema version recorded in the status
+		for _, version := range []string{"latest", "1"} {
+			httpmock.RegisterResponder(
+				"GET",
+				"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/"+version,
+				httpmock.NewStringResponder(200, string(response)))
+		}
 
 		httpmock.RegisterResponder(
 			"GET",
@@ -187,12 +223,20 @@ func (x *XJoinIndexPipelineTestReconciler) registerNewMocks() {
 	httpmock.RegisterResponder(
 		"HEAD",
 		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
-		httpmock.NewStringResponder(404, `{}`))
+		x.elasticsearchIndexExistsResponder())
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		x.elasticsearchIndexResponder())
 
 	httpmock.RegisterResponder(
 		"PUT",
 		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
-		httpmock.NewStringResponder(201, `{}`))
+		recordRequestBody(&x.indexRequestBody, func(req *http.Request) (*http.Response, error) {
+			x.indexCreated = true
+			return httpmock.NewStringResponse(201, `{}`), nil
+		}))
 
 	//avro schema mocks
 	httpmock.RegisterResponder(
@@ -203,7 +247,7 @@ func (x *XJoinIndexPipelineTestReconciler) registerNewMocks() {
 	httpmock.RegisterResponder(
 		"POST",
 		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions",
-		httpmock.NewStringResponder(200, `{"createdBy":"","createdOn":"2022-07-27T17:28:11+0000","modifiedBy":"","modifiedOn":"2022-07-27T17:28:11+0000","id":1,"version":1,"type":"AVRO","globalId":1,"state":"ENABLED","groupId":"null","contentId":1,"references":[]}`))
+		recordRequestBody(&x.avroSchemaBody, httpmock.NewStringResponder(200, `{"createdBy":"","createdOn":"2022-07-27T17:28:11+0000","modifiedBy":"","modifiedOn":"2022-07-27T17:28:11+0000","id":1,"version":1,"type":"AVRO","globalId":1,"state":"ENABLED","groupId":"null","contentId":1,"references":[]}`)))
 
 	httpmock.RegisterResponder(
 		"GET",
@@ -211,12 +255,10 @@ func (x *XJoinIndexPipelineTestReconciler) registerNewMocks() {
 		httpmock.NewStringResponder(200, `{"schema":"{\"name\":\"Value\",\"namespace\":\"xjoindatasourcepipeline.`+x.GetName()+`\"}","schemaType":"AVRO","references":[]}`))
 
 	//graphql schema state update mocks
-	getMetaResponder, err := httpmock.NewJsonResponder(200, map[string]string{"state": "ENABLED"})
-	checkError(err)
 	httpmock.RegisterResponder(
 		"GET",
 		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
-		getMetaResponder)
+		x.graphQLSchemaMetaResponder("xjoinindexpipeline."+x.GetName(), "ENABLED"))
 
 	httpmock.RegisterMatcherResponder(
 		"PUT",
@@ -230,71 +272,95 @@ func (x *XJoinIndexPipelineTestReconciler) registerNewMocks() {
 		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/versions",
 		httpmock.NewStringResponder(404, `{}`))
 
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName(),
+		x.graphQLSchemaResponder())
+
 	httpmock.RegisterResponder(
 		"POST",
 		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts",
-		httpmock.NewStringResponder(201, `{}`))
+		x.recordGraphQLSchema(httpmock.NewStringResponder(201, `{}`)))
 
 	httpmock.RegisterResponder(
 		"PUT",
 		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
-		httpmock.NewStringResponder(200, `{}`))
+		x.recordGraphQLSchemaLabels("xjoinindexpipeline."+x.GetName(), httpmock.NewStringResponder(200, `{}`)))
+
+	httpmock.RegisterResponder(
+		"POST",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
+		x.graphQLSchemaVersionResponder("xjoinindexpipeline."+x.GetName()))
 
 	for _, customImage := range x.CustomSubgraphImages {
 		//custom subgraph graphql schema mocks
 		httpmock.RegisterResponder(
 			"GET",
-			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-"+customImage.Name+"."+x.Version+"/versions",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/versions",
 			httpmock.NewStringResponder(404, `{}`))
 
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version,
+			x.customGraphQLSchemaResponder())
+
 		httpmock.RegisterResponder(
 			"POST",
 			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts",
-			httpmock.NewStringResponder(201, `{}`))
+			x.recordGraphQLSchema(httpmock.NewStringResponder(201, `{}`)))
 
+		customSchemaName := "xjoinindexpipeline." + x.Name + "-" + customImage.Name + "." + x.Version
 		httpmock.RegisterResponder(
 			"PUT",
-			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-"+customImage.Name+"."+x.Version+"/meta",
-			httpmock.NewStringResponder(200, `{}`))
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/"+customSchemaName+"/meta",
+			x.recordGraphQLSchemaLabels(customSchemaName, httpmock.NewStringResponder(200, `{}`)))
+
+		httpmock.RegisterResponder(
+			"POST",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/"+customSchemaName+"/meta",
+			x.graphQLSchemaVersionResponder(customSchemaName))
 
 		//graphql schema state update mocks
-		getMetaResponder, err = httpmock.NewJsonResponder(200, map[string]string{"state": "ENABLED"})
-		checkError(err)
 		httpmock.RegisterResponder(
 			"GET",
-			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-"+customImage.Name+"."+x.Version+"/meta",
-			getMetaResponder)
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/"+customSchemaName+"/meta",
+			x.graphQLSchemaMetaResponder(customSchemaName, "ENABLED"))
 
 		httpmock.RegisterMatcherResponder(
 			"PUT",
-			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-"+customImage.Name+"."+x.Version+"/state",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/state",
 			httpmock.BodyContainsString(`"state":"DISABLED"`).WithName("DisabledState"),
 			httpmock.NewStringResponder(200, `{}`))
 	}
 
+	x.registerSubjectMocks()
+
 	for _, dataSource := range x.DataSources {
 		response, err := os.ReadFile("./test/data/apicurio/" + dataSource.ApiCurioResponseFilename + ".json")
 		checkError(err)
-		httpmock.RegisterResponder(
-			"GET",
-			"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/latest",
-			httpmock.NewStringResponder(200, string(response)))
+		//later reconciles fetch the schema version recorded in the status
+		for _, version := range []string{"latest", "1"} {
+			httpmock.RegisterResponder(
+				"GET",
+				"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/"+version,
+				httpmock.NewStringResponder(200, string(response)))
+		}
 
 		httpmock.RegisterResponder(
 			"GET",
 			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
-			httpmock.NewStringResponder(404, "{}").Once())
+			x.elasticsearchPipelineResponder())
 
 		httpmock.RegisterResponder(
 			"PUT",
 			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
-			httpmock.NewStringResponder(200, "{}"))
+			recordRequestBody(&x.pipelineRequestBody, httpmock.NewStringResponder(200, "{}")))
 	}
 
 	httpmock.RegisterResponder(
 		"GET",
 		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/latest",
-		httpmock.NewStringResponder(200, "{}"))
+		x.avroSchemaResponder())
 }
 
 func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMocksParams) {
@@ -307,6 +373,11 @@ func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMo
 		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
 		httpmock.NewStringResponder(200, `{}`))
 
+	httpmock.RegisterResponder(
+		"GET",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		x.elasticsearchIndexResponder())
+
 	httpmock.RegisterResponder(
 		"GET",
 		"http://localhost:9200/_cat/indices/"+x.Name+".%2A",
@@ -338,7 +409,12 @@ func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMo
 	httpmock.RegisterResponder(
 		"GET",
 		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
-		getMetaResponder)
+		x.graphQLSchemaMetaResponder("xjoinindexpipeline."+x.GetName(), params.GraphQLSchemaExistingState))
+
+	httpmock.RegisterResponder(
+		"POST",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
+		x.graphQLSchemaVersionResponder("xjoinindexpipeline."+x.GetName()))
 
 	httpmock.RegisterMatcherResponder(
 		"PUT",
@@ -352,16 +428,6 @@ func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMo
 		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/1",
 		httpmock.NewStringResponder(200, `{}`))
 
-	httpmock.RegisterResponder(
-		"GET",
-		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/latest",
-		httpmock.NewStringResponder(200, `{}`))
-
-	httpmock.RegisterResponder(
-		"GET",
-		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/latest",
-		httpmock.NewStringResponder(200, `{}`))
-
 	httpmock.RegisterResponder(
 		"GET",
 		"http://apicurio:1080/apis/ccompat/v6/subjects",
@@ -372,6 +438,11 @@ func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMo
 		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/versions",
 		httpmock.NewStringResponder(200, `{}`))
 
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName(),
+		x.graphQLSchemaResponder())
+
 	responder, err := httpmock.NewJsonResponder(200, httpmock.File("./test/data/apicurio/empty-response.json"))
 	checkError(err)
 	httpmock.RegisterResponder(
@@ -386,10 +457,15 @@ func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMo
 			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/versions",
 			getMetaResponder)
 
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version,
+			x.customGraphQLSchemaResponder())
+
 		httpmock.RegisterResponder(
 			"POST",
 			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts",
-			httpmock.NewStringResponder(201, `{}`))
+			x.recordGraphQLSchema(httpmock.NewStringResponder(201, `{}`)))
 
 		httpmock.RegisterResponder(
 			"PUT",
@@ -399,7 +475,13 @@ func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMo
 		httpmock.RegisterResponder(
 			"GET",
 			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/meta",
-			getMetaResponder)
+			x.graphQLSchemaMetaResponder(
+				"xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version, params.GraphQLSchemaExistingState))
+
+		httpmock.RegisterResponder(
+			"POST",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/meta",
+			x.graphQLSchemaVersionResponder("xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version))
 
 		httpmock.RegisterMatcherResponder(
 			"PUT",
@@ -408,29 +490,34 @@ func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMo
 			httpmock.NewStringResponder(200, `{}`))
 	}
 
+	x.registerSubjectMocks()
+
 	for _, dataSource := range x.DataSources {
 		response, err := os.ReadFile("./test/data/apicurio/" + dataSource.ApiCurioResponseFilename + ".json")
 		checkError(err)
-		httpmock.RegisterResponder(
-			"GET",
-			"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/latest",
-			httpmock.NewStringResponder(200, string(response)))
+		//later reconciles fetch the schema version recorded in the status
+		for _, version := range []string{"latest", "1"} {
+			httpmock.RegisterResponder(
+				"GET",
+				"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/"+version,
+				httpmock.NewStringResponder(200, string(response)))
+		}
 
 		httpmock.RegisterResponder(
 			"GET",
 			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
-			httpmock.NewStringResponder(404, "{}").Once())
+			x.elasticsearchPipelineResponder())
 
 		httpmock.RegisterResponder(
 			"PUT",
 			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
-			httpmock.NewStringResponder(200, "{}"))
+			recordRequestBody(&x.pipelineRequestBody, httpmock.NewStringResponder(200, "{}")))
 	}
 
 	httpmock.RegisterResponder(
 		"GET",
 		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/latest",
-		httpmock.NewStringResponder(200, "{}"))
+		x.avroSchemaResponder())
 }
 
 func (x *XJoinIndexPipelineTestReconciler) newXJoinIndexPipelineReconciler() *controllers.XJoinIndexPipelineReconciler {
@@ -454,6 +541,11 @@ func (x *XJoinIndexPipelineTestReconciler) createValidIndexPipeline() {
 		AvroSchema:           string(indexAvroSchema),
 		Pause:                false,
 		CustomSubgraphImages: x.CustomSubgraphImages,
+		XJoinCore:            x.XJoinCore,
+		XJoinAPISubGraph:     x.XJoinAPISubGraph,
+		Sink:                 x.Sink,
+		DataSources:          x.DataSourceSpecs,
+		Validation:           x.Validation,
 	}
 
 	index := &v1alpha1.XJoinIndex{
@@ -477,6 +569,11 @@ func (x *XJoinIndexPipelineTestReconciler) createValidIndexPipeline() {
 		AvroSchema:           string(indexAvroSchema),
 		Pause:                false,
 		CustomSubgraphImages: x.CustomSubgraphImages,
+		XJoinCore:            x.XJoinCore,
+		XJoinAPISubGraph:     x.XJoinAPISubGraph,
+		Sink:                 x.Sink,
+		DataSources:          x.DataSourceSpecs,
+		Validation:           x.Validation,
 	}
 
 	blockOwnerDeletion := true
@@ -520,3 +617,201 @@ func (x *XJoinIndexPipelineTestReconciler) createValidIndexPipeline() {
 	Expect(createdIndexPipeline.Spec.AvroSchema).Should(Equal(string(indexAvroSchema)))
 	Expect(createdIndexPipeline.Spec.CustomSubgraphImages).Should(Equal(x.CustomSubgraphImages))
 }
+
+func (x *XJoinIndexPipelineTestReconciler) registerSubjectMocks() {
+	for _, subject := range x.RegistrySubjects {
+		response, err := os.ReadFile("./test/data/apicurio/" + subject.ApiCurioResponseFilename + ".json")
+		checkError(err)
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/ccompat/v6/subjects/"+subject.Subject+"/versions/"+subject.Version,
+			httpmock.NewStringResponder(200, string(response)))
+	}
+}
+
+// setIndexTemplate sets the elasticsearch.index.template of the namespace's xjoin-generic ConfigMap
+func setIndexTemplate(namespace string, template string) {
+	configMap := &corev1.ConfigMap{}
+	err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "xjoin-generic", Namespace: namespace}, configMap)
+	checkError(err)
+	configMap.Data["elasticsearch.index.template"] = template
+	err = k8sClient.Update(context.Background(), configMap)
+	checkError(err)
+}
+
+// setTypeMapping sets the elasticsearch.type.mapping of the namespace's xjoin-generic ConfigMap
+func setTypeMapping(namespace string, typeMapping string) {
+	configMap := &corev1.ConfigMap{}
+	err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "xjoin-generic", Namespace: namespace}, configMap)
+	checkError(err)
+	configMap.Data["elasticsearch.type.mapping"] = typeMapping
+	err = k8sClient.Update(context.Background(), configMap)
+	checkError(err)
+}
+
+// recordGraphQLSchema records the content of the graphql schema registered for the pipeline, the schemas of the custom
+// subgraphs are registered with the same request
+func (x *XJoinIndexPipelineTestReconciler) recordGraphQLSchema(responder httpmock.Responder) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Registry-ArtifactId") == "xjoinindexpipeline."+x.GetName() {
+			requestBody, err := io.ReadAll(req.Body)
+			checkError(err)
+			x.graphQLSchemaBody = string(requestBody)
+		}
+		return responder(req)
+	}
+}
+
+// graphQLSchemaResponder returns the content of the latest version of the graphql schema
+func (x *XJoinIndexPipelineTestReconciler) graphQLSchemaResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if x.graphQLSchemaPushed != "" {
+			return httpmock.NewStringResponse(200, x.graphQLSchemaPushed), nil
+		}
+		if x.graphQLSchemaBody == "" {
+			return httpmock.NewStringResponse(200, schemaregistry.DefaultGraphQLSchema), nil
+		}
+		return httpmock.NewStringResponse(200, x.graphQLSchemaBody), nil
+	}
+}
+
+// customGraphQLSchemaResponder returns the content of the latest version of the graphql schema of a custom subgraph,
+// the operator registers the placeholder
+func (x *XJoinIndexPipelineTestReconciler) customGraphQLSchemaResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if x.graphQLSchemaPushed != "" {
+			return httpmock.NewStringResponse(200, x.graphQLSchemaPushed), nil
+		}
+		return httpmock.NewStringResponse(200, schemaregistry.DefaultGraphQLSchema), nil
+	}
+}
+
+// graphQLSchemaVersionResponder finds the version of a graphql schema that has the content of the request, the
+// operator registers the generated schema for the pipeline and the placeholder for the custom subgraphs
+func (x *XJoinIndexPipelineTestReconciler) graphQLSchemaVersionResponder(name string) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		content, err := io.ReadAll(req.Body)
+		checkError(err)
+
+		registered := schemaregistry.DefaultGraphQLSchema
+		if name == "xjoinindexpipeline."+x.GetName() && x.graphQLSchemaBody != "" {
+			registered = x.graphQLSchemaBody
+		}
+		if string(content) != registered {
+			return httpmock.NewStringResponse(404, `{"error_code":404,"message":"No version found"}`), nil
+		}
+		return httpmock.NewJsonResponse(200, map[string]interface{}{
+			"version": "1",
+			"labels":  x.graphQLSchemaLabels[name],
+		})
+	}
+}
+
+// recordGraphQLSchemaLabels records the labels put on the metadata of a graphql schema
+func (x *XJoinIndexPipelineTestReconciler) recordGraphQLSchemaLabels(
+	name string, responder httpmock.Responder) httpmock.Responder {
+
+	return func(req *http.Request) (*http.Response, error) {
+		var meta struct {
+			Labels []string `json:"labels"`
+		}
+		err := json.NewDecoder(req.Body).Decode(&meta)
+		checkError(err)
+		if x.graphQLSchemaLabels == nil {
+			x.graphQLSchemaLabels = make(map[string][]string)
+		}
+		x.graphQLSchemaLabels[name] = meta.Labels
+		return responder(req)
+	}
+}
+
+// graphQLSchemaMetaResponder returns the metadata of a graphql schema with the recorded labels
+func (x *XJoinIndexPipelineTestReconciler) graphQLSchemaMetaResponder(name string, state string) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewJsonResponse(200, map[string]interface{}{
+			"state":  state,
+			"labels": x.graphQLSchemaLabels[name],
+		})
+	}
+}
+
+// avroSchemaResponder returns the latest version of the registered avro schema
+func (x *XJoinIndexPipelineTestReconciler) avroSchemaResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if x.avroSchemaBody == "" {
+			return httpmock.NewStringResponse(200, `{}`), nil
+		}
+
+		var registered map[string]interface{}
+		err := json.Unmarshal([]byte(x.avroSchemaBody), &registered)
+		checkError(err)
+		registered["subject"] = "xjoinindexpipeline." + x.GetName() + "-value"
+		registered["version"] = 1
+		registered["id"] = 1
+		return httpmock.NewJsonResponse(200, registered)
+	}
+}
+
+// elasticsearchIndexExistsResponder responds to the existence check of the index once it is created
+func (x *XJoinIndexPipelineTestReconciler) elasticsearchIndexExistsResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if !x.indexCreated {
+			return httpmock.NewStringResponse(404, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{}`), nil
+	}
+}
+
+// elasticsearchIndexResponder returns the mappings and settings of the created index, the settings are nested under
+// index like Elasticsearch does
+func (x *XJoinIndexPipelineTestReconciler) elasticsearchIndexResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		var created struct {
+			Mappings map[string]interface{} `json:"mappings"`
+			Settings map[string]interface{} `json:"settings"`
+		}
+		if x.indexRequestBody != "" {
+			err := json.Unmarshal([]byte(x.indexRequestBody), &created)
+			checkError(err)
+		}
+
+		indexSettings := make(map[string]interface{})
+		for key, value := range created.Settings {
+			if nested, ok := value.(map[string]interface{}); ok && key == "index" {
+				for nestedKey, nestedValue := range nested {
+					indexSettings[nestedKey] = nestedValue
+				}
+			} else {
+				indexSettings[key] = value
+			}
+		}
+
+		return httpmock.NewJsonResponse(200, map[string]interface{}{
+			"xjoinindexpipeline." + x.GetName(): map[string]interface{}{
+				"mappings": created.Mappings,
+				"settings": map[string]interface{}{"index": indexSettings},
+			},
+		})
+	}
+}
+
+// elasticsearchPipelineResponder returns the created elasticsearch pipeline
+func (x *XJoinIndexPipelineTestReconciler) elasticsearchPipelineResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if x.pipelineRequestBody == "" {
+			return httpmock.NewStringResponse(404, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200,
+			`{"xjoinindexpipeline.`+x.GetName()+`":`+x.pipelineRequestBody+`}`), nil
+	}
+}
+
+// recordRequestBody stores the body of the request in body before responding
+func recordRequestBody(body *string, responder httpmock.Responder) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		requestBody, err := io.ReadAll(req.Body)
+		checkError(err)
+		*body = string(requestBody)
+		return responder(req)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
 	"github.com/redhatinsights/xjoin-operator/controllers/config"
 	. "github.com/redhatinsights/xjoin-operator/controllers/index"
-	"github.com/redhatinsights/xjoin-operator/controllers/k8s"
 	xjoinlogger "github.com/redhatinsights/xjoin-operator/controllers/log"
 	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
 	k8sUtils "github.com/redhatinsights/xjoin-operator/controllers/utils"
@@ -28,14 +27,14 @@ import (
 const xjoinindexValidatorFinalizer = "finalizer.xjoin.indexvalidator.cloud.redhat.com"
 
 type XJoinIndexValidatorReconciler struct {
-	Client       client.Client
-	Log          logr.Logger
-	Scheme       *runtime.Scheme
-	Recorder     record.EventRecorder
-	Namespace    string
-	Test         bool
-	ClientSet    kubernetes.Interface
-	PodLogReader k8s.LogReader
+	Client    client.Client
+	Log       logr.Logger
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	Namespace string
+	Test      bool
+	ClientSet kubernetes.Interface
+	Runs      *ValidationRuns //native validations running in the background
 }
 
 func NewXJoinIndexValidatorReconciler(
@@ -45,18 +44,17 @@ func NewXJoinIndexValidatorReconciler(
 	log logr.Logger,
 	recorder record.EventRecorder,
 	namespace string,
-	isTest bool,
-	podLogReader k8s.LogReader) *XJoinIndexValidatorReconciler {
+	isTest bool) *XJoinIndexValidatorReconciler {
 
 	return &XJoinIndexValidatorReconciler{
-		Client:       client,
-		Log:          log,
-		Scheme:       scheme,
-		Recorder:     recorder,
-		Namespace:    namespace,
-		Test:         isTest,
-		ClientSet:    clientset,
-		PodLogReader: podLogReader,
+		Client:    client,
+		Log:       log,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		Namespace: namespace,
+		Test:      isTest,
+		ClientSet: clientset,
+		Runs:      NewValidationRuns(),
 	}
 }
 
@@ -78,7 +76,9 @@ func (r *XJoinIndexValidatorReconciler) SetupWithManager(mgr ctrl.Manager) error
 }
 
 // +kubebuilder:rbac:groups=xjoin.cloud.redhat.com,resources=xjoinindexvalidators;xjoinindexvalidators/status;xjoinindexvalidators/finalizers,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups="",resources=configmaps;pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
 
 func (r *XJoinIndexValidatorReconciler) Reconcile(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := xjoinlogger.NewLogger("controller_xjoinindexvalidator", "IndexValidator", request.Name, "Namespace", request.Namespace)
@@ -134,7 +134,7 @@ func (r *XJoinIndexValidatorReconciler) Reconcile(ctx context.Context, request c
 		},
 		ClientSet:              r.ClientSet,
 		ElasticsearchIndexName: instance.Spec.IndexName,
-		PodLogReader:           r.PodLogReader,
+		ValidationRuns:         r.Runs,
 	}
 
 	if err = i.AddFinalizer(xjoinindexValidatorFinalizer); err != nil {
@@ -146,15 +146,18 @@ func (r *XJoinIndexValidatorReconciler) Reconcile(ctx context.Context, request c
 		if err != nil {
 			return result, errors.Wrap(err, 0)
 		}
+		return result, nil
 	}
 
-	phase, err := i.ReconcileValidationPod()
+	phase, err := i.ReconcileValidation()
 	if err != nil {
 		return result, errors.Wrap(err, 0)
 	}
 	instance.Status.ValidationPodPhase = phase
-	if phase == ValidatorPodSuccess {
+	if phase == ValidatorPodSuccess || phase == ValidatorPodError {
 		return i.UpdateStatusAndRequeue(time.Second * time.Duration(p.ValidationInterval.Int()))
+	} else if phase == ValidatorRepairing {
+		return i.UpdateStatusAndRequeue(time.Second * time.Duration(p.ValidationRepairInterval.Int()))
 	} else if phase == ValidatorPodFailed {
 		return i.UpdateStatusAndRequeue(time.Second * time.Duration(0))
 	} else {
@@ -2,13 +2,17 @@ package controllers_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/jarcoal/httpmock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
 	"github.com/redhatinsights/xjoin-operator/controllers/index"
-	"github.com/redhatinsights/xjoin-operator/controllers/k8s/mocks"
+	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
+	"github.com/redhatinsights/xjoin-operator/controllers/validator"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"os"
@@ -29,6 +33,7 @@ var _ = Describe("XJoinIndexValidator", func() {
 		var err error
 		namespace, err = NewNamespace()
 		checkError(err)
+		setValidationMode(namespace, index.ValidationModePod)
 	})
 
 	AfterEach(func() {
@@ -43,7 +48,6 @@ var _ = Describe("XJoinIndexValidator", func() {
 				Version:        "1234",
 				ConfigFileName: "xjoinindex",
 				K8sClient:      k8sClient,
-				PodLogReader:   &mocks.LogReader{},
 			}
 			reconciler.CreateDatasource()
 			createdIndexValidator, _ := reconciler.ReconcileCreate()
@@ -61,7 +65,6 @@ var _ = Describe("XJoinIndexValidator", func() {
 				Version:        "1234",
 				ConfigFileName: configFileName,
 				K8sClient:      k8sClient,
-				PodLogReader:   &mocks.LogReader{},
 			}
 			reconciler.CreateDatasource()
 			reconciler.ReconcileCreate()
@@ -78,6 +81,7 @@ var _ = Describe("XJoinIndexValidator", func() {
 			}))
 
 			Expect(pod.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+			credentialsSecretName := strings.ReplaceAll(reconciler.GetName(), ".", "-") + "-credentials"
 
 			Expect(pod.Spec.Containers).To(HaveLen(1))
 			Expect(pod.Spec.Containers[0].Name).To(Equal(strings.ReplaceAll(reconciler.GetName(), ".", "-")))
@@ -124,6 +128,14 @@ var _ = Describe("XJoinIndexValidator", func() {
 					},
 				},
 			}))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "VALIDATION_OUTPUT_MAX_BYTES",
+				Value: "4096",
+			}))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "VALIDATION_OUTPUT_MAX_IDS",
+				Value: "1000",
+			}))
 			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
 				Name:      "ELASTICSEARCH_INDEX",
 				Value:     "xjoinindexpipeline." + reconciler.GetName(),
@@ -157,15 +169,29 @@ var _ = Describe("XJoinIndexValidator", func() {
 			}))
 
 			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
-				Name:      "testdatasource_DB_PASSWORD",
-				Value:     "dbPassword",
-				ValueFrom: nil,
+				Name:  "testdatasource_DB_PASSWORD",
+				Value: "",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: credentialsSecretName,
+						},
+						Key: "testdatasource_DB_PASSWORD",
+					},
+				},
 			}))
 
 			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
-				Name:      "testdatasource_DB_USERNAME",
-				Value:     "dbUsername",
-				ValueFrom: nil,
+				Name:  "testdatasource_DB_USERNAME",
+				Value: "",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: credentialsSecretName,
+						},
+						Key: "testdatasource_DB_USERNAME",
+					},
+				},
 			}))
 
 			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
@@ -173,6 +199,18 @@ var _ = Describe("XJoinIndexValidator", func() {
 				Value:     "dbHost",
 				ValueFrom: nil,
 			}))
+
+			//database credentials are read from a secret owned by the validator
+			credentialsSecret := &corev1.Secret{}
+			err := k8sClient.Get(context.Background(),
+				client.ObjectKey{Name: credentialsSecretName, Namespace: namespace}, credentialsSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(credentialsSecret.Data).To(Equal(map[string][]byte{
+				"testdatasource_DB_USERNAME": []byte("dbUsername"),
+				"testdatasource_DB_PASSWORD": []byte("dbPassword"),
+			}))
+			Expect(credentialsSecret.OwnerReferences).To(HaveLen(1))
+			Expect(credentialsSecret.OwnerReferences[0].Kind).To(Equal("XJoinIndexValidator"))
 		})
 
 		It("Should requeue after ValidationPodStatusInterval", func() {
@@ -182,7 +220,6 @@ var _ = Describe("XJoinIndexValidator", func() {
 				Version:        "1234",
 				ConfigFileName: "xjoinindex",
 				K8sClient:      k8sClient,
-				PodLogReader:   &mocks.LogReader{},
 			}
 			reconciler.CreateDatasource()
 			_, result := reconciler.ReconcileCreate()
@@ -198,7 +235,6 @@ var _ = Describe("XJoinIndexValidator", func() {
 				Version:        "1234",
 				ConfigFileName: "xjoinindex",
 				K8sClient:      k8sClient,
-				PodLogReader:   &mocks.LogReader{},
 			}
 			reconciler.CreateDatasource()
 			reconciler.ReconcileCreate()
@@ -214,7 +250,6 @@ var _ = Describe("XJoinIndexValidator", func() {
 				Version:        "1234",
 				ConfigFileName: "xjoinindex",
 				K8sClient:      k8sClient,
-				PodLogReader:   &mocks.LogReader{},
 			}
 			reconciler.CreateDatasource()
 			reconciler.ReconcileCreate()
@@ -226,25 +261,67 @@ var _ = Describe("XJoinIndexValidator", func() {
 		})
 	})
 
+	Context("Reconcile Native Validation", func() {
+		BeforeEach(func() {
+			setValidationMode(namespace, index.ValidationModeNative)
+		})
+
+		It("Should be the default validation mode", func() {
+			Expect(parameters.BuildIndexParameters().ValidationMode.DefaultValue).To(Equal(index.ValidationModeNative))
+		})
+
+		It("Should validate in the background without a pod and requeue after ValidationPodStatusInterval", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-validator",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			validator, result := reconciler.ReconcileCreate()
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 5 * time.Second}))
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodRunning))
+
+			pods := reconciler.ListValidatorPods()
+			Expect(pods.Items).To(BeEmpty())
+		})
+
+		It("Should apply the result once the validation completes and requeue after ValidationInterval", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-validator",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			validator, result := reconciler.ReconcileNativeResult()
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 60 * time.Second}))
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodSuccess))
+			Expect(validator.Status.ValidationError).To(BeEmpty())
+
+			pods := reconciler.ListValidatorPods()
+			Expect(pods.Items).To(BeEmpty())
+		})
+	})
+
 	Context("Reconcile Pod Success", func() {
 		It("Should requeue after ValidationInterval", func() {
 			name := "test-index-validator"
 			version := "1234"
 
-			logBytes, err := os.ReadFile("./test/data/validator/success.log.txt")
+			terminationMessage, err := os.ReadFile("./test/data/validator/success.json")
 			checkError(err)
 
-			podLogReader := mocks.LogReader{}
-			podLogReader.
-				On("GetLogs", name+"-"+version, namespace).Return(string(logBytes), err)
-
 			reconciler := XJoinIndexValidatorTestReconciler{
-				Namespace:      namespace,
-				Name:           name,
-				Version:        version,
-				ConfigFileName: "xjoinindex",
-				K8sClient:      k8sClient,
-				PodLogReader:   &podLogReader,
+				Namespace:          namespace,
+				Name:               name,
+				Version:            version,
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
 			}
 			reconciler.CreateDatasource()
 			reconciler.ReconcileCreate()
@@ -258,20 +335,16 @@ var _ = Describe("XJoinIndexValidator", func() {
 			name := "test-index-validator"
 			version := "1234"
 
-			logBytes, err := os.ReadFile("./test/data/validator/success.log.txt")
+			terminationMessage, err := os.ReadFile("./test/data/validator/success.json")
 			checkError(err)
 
-			podLogReader := mocks.LogReader{}
-			podLogReader.
-				On("GetLogs", name+"-"+version, namespace).Return(string(logBytes), err)
-
 			indexValidatorReconciler := XJoinIndexValidatorTestReconciler{
-				Namespace:      namespace,
-				Name:           name,
-				Version:        version,
-				ConfigFileName: "xjoinindex",
-				K8sClient:      k8sClient,
-				PodLogReader:   &podLogReader,
+				Namespace:          namespace,
+				Name:               name,
+				Version:            version,
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
 			}
 			indexValidatorReconciler.CreateDatasource()
 			indexValidatorReconciler.ReconcileCreate()
@@ -291,6 +364,49 @@ var _ = Describe("XJoinIndexValidator", func() {
 			Expect(len(pods.Items)).To(Equal(0))
 		})
 
+		It("Should write the mismatch report and link it from the XJoinIndexPipeline status", func() {
+			terminationMessage, err := os.ReadFile("./test/data/validator/invalid.json")
+			checkError(err)
+
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index-validator",
+				Version:            "1234",
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			reconciler.ReconcileSuccess()
+
+			indexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			err = k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: "test-index-pipeline", Namespace: namespace}, indexPipeline)
+			checkError(err)
+			reportStatus := indexPipeline.Status.ValidationReport
+			Expect(reportStatus).ToNot(BeNil())
+			Expect(reportStatus.ConfigMap).To(Equal(index.ValidationReportConfigMapName(reconciler.GetName())))
+			Expect(reportStatus.Result).To(Equal(index.Invalid))
+			Expect(reportStatus.Mismatched).To(Equal(3))
+			Expect(reportStatus.Entries).To(Equal(3))
+
+			configMap := &corev1.ConfigMap{}
+			err = k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: reportStatus.ConfigMap, Namespace: namespace}, configMap)
+			checkError(err)
+
+			var report validator.Report
+			err = json.Unmarshal([]byte(configMap.Data["report.json"]), &report)
+			checkError(err)
+			Expect(report.Entries).To(Equal([]validator.ReportEntry{
+				{ID: "1", Type: validator.MissingInSink},
+				{ID: "2", Type: validator.MissingInSource},
+				{ID: "3", Type: validator.DifferentFields, Fields: []string{"name"}},
+			}))
+		})
+
 		It("Should update each DataSourcePipeline's ValidationResponse status", func() {
 			//create datasource
 			datasourceReconciler := DatasourceTestReconciler{
@@ -338,26 +454,20 @@ var _ = Describe("XJoinIndexValidator", func() {
 			Expect(datasourcePipeline.Status.ValidationResponse.Result).To(Equal(""))
 
 			//reconcile indexpipelinevalidator to successful validation
-			logBytes, err := os.ReadFile("./test/data/validator/success.log.txt")
+			terminationMessage, err := os.ReadFile("./test/data/validator/success.json")
 			checkError(err)
 
-			podLogReader := mocks.LogReader{}
-			podLogReader.
-				On("GetLogs",
-					"xjoinindexpipeline-"+indexReconciler.Name+"-"+createdIndex.Status.RefreshingVersion,
-					namespace).Return(string(logBytes), err)
-
 			validator := &v1alpha1.XJoinIndexValidatorList{}
 			err = k8sClient.List(context.Background(), validator, client.InNamespace(namespace))
 			checkError(err)
 
 			indexValidatorReconciler := XJoinIndexValidatorTestReconciler{
-				Namespace:      namespace,
-				Name:           "xjoinindexpipeline." + indexReconciler.Name,
-				Version:        createdIndex.Status.RefreshingVersion,
-				ConfigFileName: "xjoinindex",
-				K8sClient:      k8sClient,
-				PodLogReader:   &podLogReader,
+				Namespace:          namespace,
+				Name:               "xjoinindexpipeline." + indexReconciler.Name,
+				Version:            createdIndex.Status.RefreshingVersion,
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
 			}
 			indexValidatorReconciler.ReconcileRunning()
 			indexValidatorReconciler.ReconcileSuccess()
@@ -369,25 +479,98 @@ var _ = Describe("XJoinIndexValidator", func() {
 		})
 	})
 
+	Context("Reconcile Invalid Pod Output", func() {
+		It("Should set the error phase and delete the pod when the output is partial", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index-validator",
+				Version:            "1234",
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: `{"schemaVersion":"v1","response":{"result":"val`,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			validator, result := reconciler.ReconcileSuccess()
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodError))
+			Expect(validator.Status.ValidationError).To(ContainSubstring("unable to parse the output"))
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 60 * time.Second}))
+			Expect(reconciler.ListValidatorPods().Items).To(BeEmpty())
+		})
+
+		It("Should set the error phase and not apply the result when the output was truncated", func() {
+			details := validation.ResponseDetails{TotalMismatch: 1000, IdsMissingFromElasticsearchCount: 1000}
+			for id := 0; id < 1000; id++ {
+				details.IdsMissingFromElasticsearch = append(details.IdsMissingFromElasticsearch, fmt.Sprint(id))
+			}
+			output, err := json.Marshal(index.ValidationOutput{
+				SchemaVersion: index.ValidationOutputSchemaVersion,
+				Response: &validation.ValidationResponse{
+					Result:  index.Invalid,
+					Reason:  "1000 records (100.00%) do not match",
+					Details: details,
+				},
+			})
+			checkError(err)
+			Expect(len(output)).To(BeNumerically(">", index.ValidationOutputMaxBytes))
+
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index-validator",
+				Version:            "1234",
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(output[:index.ValidationOutputMaxBytes]), //truncated like the kubelet does
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			indexValidator, _ := reconciler.ReconcileSuccess()
+			Expect(indexValidator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodError))
+			Expect(indexValidator.Status.ValidationError).To(Equal(index.ErrValidationOutputTruncated.Error()))
+			Expect(reconciler.ListValidatorPods().Items).To(BeEmpty())
+
+			indexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			err = k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: "test-index-pipeline", Namespace: namespace}, indexPipeline)
+			checkError(err)
+			Expect(indexPipeline.Status.ValidationReport).To(BeNil())
+		})
+
+		It("Should set the error phase when the output has an unsupported schema version", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index-validator",
+				Version:            "1234",
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: `{"schemaVersion":"v0","response":{"result":"valid"}}`,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			validator, _ := reconciler.ReconcileSuccess()
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodError))
+			Expect(validator.Status.ValidationError).To(ContainSubstring("unsupported schema version"))
+		})
+	})
+
 	Context("Reconcile Pod Failure", func() {
 		It("Should requeue immediately", func() {
 			name := "test-index-validator"
 			version := "1234"
 
-			logBytes, err := os.ReadFile("./test/data/validator/success.log.txt")
+			terminationMessage, err := os.ReadFile("./test/data/validator/success.json")
 			checkError(err)
 
-			podLogReader := mocks.LogReader{}
-			podLogReader.
-				On("GetLogs", name+"-"+version, namespace).Return(string(logBytes), err)
-
 			reconciler := XJoinIndexValidatorTestReconciler{
-				Namespace:      namespace,
-				Name:           name,
-				Version:        version,
-				ConfigFileName: "xjoinindex",
-				K8sClient:      k8sClient,
-				PodLogReader:   &podLogReader,
+				Namespace:          namespace,
+				Name:               name,
+				Version:            version,
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
 			}
 			reconciler.ReconcileCreate()
 			_, result := reconciler.ReconcileFailure()
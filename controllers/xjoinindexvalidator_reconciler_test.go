@@ -8,7 +8,7 @@ import (
 	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
 	"github.com/redhatinsights/xjoin-operator/controllers"
 	"github.com/redhatinsights/xjoin-operator/controllers/common"
-	"github.com/redhatinsights/xjoin-operator/controllers/k8s"
+	"github.com/redhatinsights/xjoin-operator/controllers/index"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -28,7 +28,8 @@ type XJoinIndexValidatorTestReconciler struct {
 	K8sClient             client.Client
 	ConfigFileName        string
 	createdIndexValidator v1alpha1.XJoinIndexValidator
-	PodLogReader          k8s.LogReader
+	TerminationMessage    string //output of the validation pod when it succeeds
+	validatorReconciler   *controllers.XJoinIndexValidatorReconciler
 }
 
 func (x *XJoinIndexValidatorTestReconciler) GetName() string {
@@ -52,10 +53,35 @@ func (x *XJoinIndexValidatorTestReconciler) ReconcileRunning() (v1alpha1.XJoinIn
 	return x.createdIndexValidator, result
 }
 
+// ReconcileNativeResult reconciles until the native validation started by ReconcileCreate completes
+func (x *XJoinIndexValidatorTestReconciler) ReconcileNativeResult() (validator v1alpha1.XJoinIndexValidator, result reconcile.Result) {
+	validatorLookupKey := types.NamespacedName{Name: x.GetName(), Namespace: x.Namespace}
+	Eventually(func() string {
+		x.registerRunningMocks()
+		result = x.reconcile()
+		err := x.K8sClient.Get(context.Background(), validatorLookupKey, &validator)
+		checkError(err)
+		return validator.Status.ValidationPodPhase
+	}, K8sGetTimeout, K8sGetInterval).ShouldNot(Equal(index.ValidatorPodRunning))
+
+	return
+}
+
 func (x *XJoinIndexValidatorTestReconciler) ReconcileSuccess() (validator v1alpha1.XJoinIndexValidator, result reconcile.Result) {
 	x.registerSuccessMocks()
 	validatorPods := x.ListValidatorPods()
 	validatorPods.Items[0].Status.Phase = corev1.PodSucceeded
+	validatorPods.Items[0].Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  validatorPods.Items[0].Name,
+		Image: validatorPods.Items[0].Spec.Containers[0].Image,
+		State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				ExitCode: 0,
+				Reason:   "Completed",
+				Message:  x.TerminationMessage,
+			},
+		},
+	}}
 	err := x.K8sClient.Status().Update(context.Background(), &validatorPods.Items[0])
 	newPods := x.ListValidatorPods()
 	newPods.Items[0].GetNamespace()
@@ -199,16 +225,21 @@ func (x *XJoinIndexValidatorTestReconciler) createIndexValidator() {
 	Expect(createdIndexValidator.Spec.IndexName).Should(Equal(validatorIndexName))
 }
 
+// newXJoinIndexValidatorReconciler returns the same reconciler on each call so the native validations it runs in the
+// background are applied by the following reconciles
 func (x *XJoinIndexValidatorTestReconciler) newXJoinIndexValidatorReconciler() *controllers.XJoinIndexValidatorReconciler {
-	return controllers.NewXJoinIndexValidatorReconciler(
+	if x.validatorReconciler != nil {
+		return x.validatorReconciler
+	}
+	x.validatorReconciler = controllers.NewXJoinIndexValidatorReconciler(
 		x.K8sClient,
 		scheme.Scheme,
 		fake.NewSimpleClientset(),
 		testLogger,
 		record.NewFakeRecorder(10),
 		x.Namespace,
-		true,
-		x.PodLogReader)
+		true)
+	return x.validatorReconciler
 }
 
 func (x *XJoinIndexValidatorTestReconciler) reconcile() reconcile.Result {
@@ -271,3 +302,23 @@ func (x *XJoinIndexValidatorTestReconciler) registerFailureMocks() {
 		httpmock.NewStringResponder(
 			200, fmt.Sprintf(`{"id": 1, "subject": "xjoindatasourcepipeline.hosts.1674571335703357092-value", "version": 1, "schema": "%s", "references": "[]"}`, schema)))
 }
+
+// setDeviationPolicy sets deviation.policy in the xjoin-generic ConfigMap of the namespace
+func setDeviationPolicy(namespace string, policy string) {
+	configMap := &corev1.ConfigMap{}
+	err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "xjoin-generic", Namespace: namespace}, configMap)
+	checkError(err)
+	configMap.Data["deviation.policy"] = policy
+	err = k8sClient.Update(context.Background(), configMap)
+	checkError(err)
+}
+
+// setValidationMode sets validation.mode in the xjoin-generic ConfigMap of the namespace
+func setValidationMode(namespace string, mode string) {
+	configMap := &corev1.ConfigMap{}
+	err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "xjoin-generic", Namespace: namespace}, configMap)
+	checkError(err)
+	configMap.Data["validation.mode"] = mode
+	err = k8sClient.Update(context.Background(), configMap)
+	checkError(err)
+}
@@ -0,0 +1,183 @@
+package zzfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
+	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
+)
+
+// fakeSchemaRegistry is an in memory subject of the confluent compatible API. Like the registry, registering a
+// schema that is already a version of the subject returns the existing version.
+type fakeSchemaRegistry struct {
+	versions map[int]string //version -> schema
+	ids      map[string]int //schema -> id
+	latest   int
+	deleted  []int
+}
+
+func newFakeSchemaRegistry(subject string) *fakeSchemaRegistry {
+	registry := &fakeSchemaRegistry{versions: map[int]string{}, ids: map[string]int{}}
+	base := "http://apicurio:1080/apis/ccompat/v6"
+	versionURL := regexp.QuoteMeta(base + "/subjects/" + subject + "/versions/")
+
+	httpmock.RegisterResponder("POST", base+"/subjects/"+subject+"/versions",
+		func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Schema string `json:"schema"`
+			}
+			err := json.NewDecoder(req.Body).Decode(&body)
+			if err != nil {
+				return nil, err
+			}
+			return httpmock.NewJsonResponse(200, map[string]int{"id": registry.register(body.Schema)})
+		})
+	httpmock.RegisterResponder("GET", base+"/subjects/"+subject+"/versions",
+		func(req *http.Request) (*http.Response, error) {
+			var versions []int
+			for version := 1; version <= registry.latest; version++ {
+				if _, exists := registry.versions[version]; exists {
+					versions = append(versions, version)
+				}
+			}
+			if len(versions) == 0 {
+				return httpmock.NewStringResponse(404, `{"error_code":40401,"message":"Subject not found"}`), nil
+			}
+			return httpmock.NewJsonResponse(200, versions)
+		})
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(versionURL+`(\w+)$`),
+		func(req *http.Request) (*http.Response, error) {
+			version := registry.latestVersion()
+			param := httpmock.MustGetSubmatch(req, 1)
+			if param != "latest" {
+				version, _ = strconv.Atoi(param)
+			}
+			schema, exists := registry.versions[version]
+			if !exists {
+				return httpmock.NewStringResponse(404, `{"error_code":40402,"message":"Version not found"}`), nil
+			}
+			return httpmock.NewJsonResponse(200, map[string]interface{}{
+				"subject": subject, "version": version, "id": registry.ids[schema], "schema": schema})
+		})
+	httpmock.RegisterRegexpResponder("DELETE", regexp.MustCompile(versionURL+`(\d+)$`),
+		func(req *http.Request) (*http.Response, error) {
+			version, _ := strconv.Atoi(httpmock.MustGetSubmatch(req, 1))
+			delete(registry.versions, version)
+			registry.deleted = append(registry.deleted, version)
+			return httpmock.NewJsonResponse(200, version)
+		})
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(regexp.QuoteMeta(base+"/schemas/ids/")+`(\d+)$`),
+		func(req *http.Request) (*http.Response, error) {
+			id, _ := strconv.Atoi(httpmock.MustGetSubmatch(req, 1))
+			for version, schema := range registry.versions {
+				if registry.ids[schema] == id {
+					return httpmock.NewJsonResponse(200, map[string]interface{}{"schema": schema, "version": version})
+				}
+			}
+			return httpmock.NewStringResponse(404, `{"error_code":40403,"message":"Schema not found"}`), nil
+		})
+
+	return registry
+}
+
+func (r *fakeSchemaRegistry) register(schema string) int {
+	for _, existing := range r.versions {
+		if existing == schema {
+			return r.ids[schema]
+		}
+	}
+	if _, exists := r.ids[schema]; !exists {
+		r.ids[schema] = len(r.ids) + 1
+	}
+	r.latest++
+	r.versions[r.latest] = schema
+	return r.ids[schema]
+}
+
+func (r *fakeSchemaRegistry) latestVersion() (latest int) {
+	for version := range r.versions {
+		if version > latest {
+			latest = version
+		}
+	}
+	return
+}
+
+var _ = Describe("AvroSchema", func() {
+	var avroSchema *components.AvroSchema
+	var registry *fakeSchemaRegistry
+
+	editedSchema := func(fieldName string) string {
+		return fmt.Sprintf(`{"type":"record","name":"Value","namespace":"xjoindatasourcepipeline.test",`+
+			`"fields":[{"name":"%s","type":"string"}]}`, fieldName)
+	}
+
+	BeforeEach(func() {
+		httpmock.Activate()
+		httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip) //disable mocks for unregistered http requests
+
+		confluentClient := schemaregistry.NewSchemaRegistryConfluentClient(schemaregistry.ConnectionParams{
+			Protocol: "http",
+			Hostname: "apicurio",
+			Port:     "1080",
+		})
+		confluentClient.Init()
+
+		avroSchema = components.NewAvroSchema(components.AvroSchemaParameters{
+			Schema:   `{"type":"record","name":"Value","fields":[{"name":"id","type":"string"}]}`,
+			Registry: confluentClient,
+		})
+		avroSchema.SetName("XJoinDataSourcePipeline", "test")
+		avroSchema.SetVersion("1")
+		registry = newFakeSchemaRegistry(avroSchema.Name())
+	})
+
+	AfterEach(func() {
+		httpmock.DeactivateAndReset()
+	})
+
+	It("Should make the expected schema the latest version again when the latest version was edited", func() {
+		checkError(avroSchema.Create())
+		registry.register(editedSchema("edited"))
+		registry.register(editedSchema("edited-again"))
+
+		problems, err := avroSchema.CheckDeviation()
+		checkError(err)
+		Expect(problems).To(HaveLen(1))
+
+		checkError(avroSchema.Repair())
+
+		problems, err = avroSchema.CheckDeviation()
+		checkError(err)
+		Expect(problems).To(BeEmpty())
+		Expect(registry.deleted).To(Equal([]int{3, 2}))
+		Expect(registry.versions).To(HaveLen(1))
+	})
+
+	It("Should register the expected schema when it's not a version of the subject", func() {
+		registry.register(editedSchema("edited"))
+
+		checkError(avroSchema.Repair())
+
+		problems, err := avroSchema.CheckDeviation()
+		checkError(err)
+		Expect(problems).To(BeEmpty())
+		Expect(registry.deleted).To(BeEmpty())
+		Expect(registry.latestVersion()).To(Equal(2))
+	})
+
+	It("Should register the expected schema when the subject doesn't exist", func() {
+		checkError(avroSchema.Repair())
+
+		expected, err := avroSchema.SetSchemaNameNamespace()
+		checkError(err)
+		Expect(registry.versions).To(Equal(map[int]string{1: expected}))
+	})
+})
@@ -0,0 +1,376 @@
+package zzfake
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeComponent is an in memory component, it deviates until it is repaired
+type fakeComponent struct {
+	name     string
+	exists   bool
+	deviates bool
+}
+
+func (f *fakeComponent) Name() string {
+	return f.name
+}
+
+func (f *fakeComponent) Create() error {
+	f.exists = true
+	return nil
+}
+
+func (f *fakeComponent) Delete() error {
+	f.exists = false
+	return nil
+}
+
+func (f *fakeComponent) CheckDeviation() ([]components.Problem, error) {
+	if !f.deviates {
+		return nil, nil
+	}
+	return []components.Problem{{Message: "hand edited", Path: "spec", Expected: "a", Actual: "b"}}, nil
+}
+
+func (f *fakeComponent) Exists() (bool, error) {
+	return f.exists, nil
+}
+
+func (f *fakeComponent) SetName(string, string) {}
+
+func (f *fakeComponent) SetVersion(string) {}
+
+func (f *fakeComponent) ListInstalledVersions() ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeComponent) Reconcile() error {
+	return nil
+}
+
+type fakeRepairableComponent struct {
+	fakeComponent
+	repairErr error
+	repaired  int
+}
+
+func (f *fakeRepairableComponent) Repair() error {
+	f.repaired++
+	if f.repairErr != nil {
+		return f.repairErr
+	}
+	f.deviates = false
+	return nil
+}
+
+type fakeMigratableComponent struct {
+	fakeComponent
+	migrateErr error
+}
+
+func (f *fakeMigratableComponent) Migrate() error {
+	if f.migrateErr != nil {
+		return f.migrateErr
+	}
+	f.deviates = false
+	return nil
+}
+
+// createRecorder records the order in which components are created and the number created in parallel.
+// When waitFor is set each creation waits, up to a second, for waitFor components to be created at once.
+type createRecorder struct {
+	mutex      sync.Mutex
+	created    []string
+	deleted    []string
+	running    int
+	maxRunning int
+	waitFor    int
+}
+
+func (r *createRecorder) start() {
+	r.mutex.Lock()
+	r.running++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+	r.mutex.Unlock()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		r.mutex.Lock()
+		parallel := r.maxRunning >= r.waitFor
+		r.mutex.Unlock()
+		if parallel {
+			return
+		}
+	}
+}
+
+func (r *createRecorder) finish(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.running--
+	r.created = append(r.created, name)
+}
+
+type fakeCreatableComponent struct {
+	fakeComponent
+	recorder  *createRecorder
+	createErr error
+}
+
+func (f *fakeCreatableComponent) Create() error {
+	f.recorder.start()
+	time.Sleep(10 * time.Millisecond)
+	f.recorder.finish(f.name)
+
+	if k8errors.IsAlreadyExists(f.createErr) {
+		f.exists = true
+	}
+	if f.createErr != nil {
+		return f.createErr
+	}
+	f.exists = true
+	return nil
+}
+
+func (f *fakeCreatableComponent) Delete() error {
+	f.recorder.deleted = append(f.recorder.deleted, f.name)
+	f.exists = false
+	return nil
+}
+
+func remediationMessages(remediations []v1alpha1.ComponentRemediation) map[string]string {
+	messages := make(map[string]string)
+	for _, remediation := range remediations {
+		messages[remediation.ComponentType+"/"+remediation.ComponentName] = remediation.Message
+	}
+	return messages
+}
+
+func remediationActions(remediations []v1alpha1.ComponentRemediation) map[string]string {
+	actions := make(map[string]string)
+	for _, remediation := range remediations {
+		actions[remediation.ComponentType+"/"+remediation.ComponentName] = remediation.Action
+	}
+	return actions
+}
+
+var _ = Describe("ComponentManager", func() {
+	Context("Repair policy", func() {
+		It("Should repair a deviating component in place", func() {
+			repairable := &fakeRepairableComponent{fakeComponent: fakeComponent{name: "index", deviates: true}}
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(&fakeComponent{name: "topic"})
+			manager.AddComponent(repairable)
+
+			deviations, remediations, err := manager.HandleDeviations(components.DeviationPolicyRepair, false)
+			checkError(err)
+			Expect(deviations).To(BeEmpty())
+			Expect(repairable.repaired).To(Equal(1))
+			Expect(remediations).To(Equal([]v1alpha1.ComponentRemediation{{
+				ComponentType: "fakeRepairableComponent",
+				ComponentName: "index",
+				Action:        components.DeviationPolicyRepair,
+				Message:       "repaired in place",
+			}}))
+		})
+
+		It("Should escalate to a refresh when the repair fails", func() {
+			repairable := &fakeRepairableComponent{
+				fakeComponent: fakeComponent{name: "index", deviates: true},
+				repairErr:     errors.New("connection refused"),
+			}
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(repairable)
+
+			deviations, remediations, err := manager.HandleDeviations(components.DeviationPolicyRepair, false)
+			checkError(err)
+			Expect(deviations).To(HaveLen(1))
+			Expect(components.DeviationsInvalidateVersion(components.DeviationPolicyRepair, deviations)).To(BeTrue())
+			Expect(remediations).To(Equal([]v1alpha1.ComponentRemediation{{
+				ComponentType: "fakeRepairableComponent",
+				ComponentName: "index",
+				Action:        components.DeviationPolicyRefresh,
+				Message:       "repair failed: connection refused",
+			}}))
+		})
+
+		It("Should escalate to a refresh when the component can't be repaired", func() {
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(&fakeComponent{name: "topic", deviates: true})
+
+			deviations, remediations, err := manager.HandleDeviations(components.DeviationPolicyRepair, false)
+			checkError(err)
+			Expect(deviations).To(HaveLen(1))
+			Expect(remediations).To(HaveLen(1))
+			Expect(remediations[0].Action).To(Equal(components.DeviationPolicyRefresh))
+			Expect(remediations[0].Message).To(Equal("component does not support repair"))
+		})
+
+		It("Should not mix up the repairs of components of different types with the same name", func() {
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(&fakeComponent{name: "xjoinindexpipeline.test.1", deviates: true})
+			manager.AddComponent(&fakeRepairableComponent{
+				fakeComponent: fakeComponent{name: "xjoinindexpipeline.test.1", deviates: true}})
+
+			_, remediations, err := manager.HandleDeviations(components.DeviationPolicyRepair, false)
+			checkError(err)
+			Expect(remediationActions(remediations)).To(Equal(map[string]string{
+				"fakeComponent/xjoinindexpipeline.test.1":           components.DeviationPolicyRefresh,
+				"fakeRepairableComponent/xjoinindexpipeline.test.1": components.DeviationPolicyRepair,
+			}))
+		})
+	})
+
+	Context("Avro schema migration", func() {
+		It("Should not mix up the migrations of components of different types with the same name", func() {
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(&fakeMigratableComponent{
+				fakeComponent: fakeComponent{name: "xjoinindexpipeline.test.1", deviates: true},
+				migrateErr:    errors.New("mapping conflict")})
+			manager.AddComponent(&fakeComponent{name: "xjoinindexpipeline.test.1", deviates: true})
+			manager.AddComponent(&fakeRepairableComponent{
+				fakeComponent: fakeComponent{name: "xjoinindexpipeline.test.1", deviates: true}})
+
+			_, remediations, err := manager.HandleDeviations(components.DeviationPolicyRefresh, true)
+			checkError(err)
+			Expect(remediationActions(remediations)).To(Equal(map[string]string{
+				"fakeMigratableComponent/xjoinindexpipeline.test.1": components.DeviationPolicyRefresh,
+				"fakeComponent/xjoinindexpipeline.test.1":           components.DeviationPolicyRefresh,
+				"fakeRepairableComponent/xjoinindexpipeline.test.1": components.RemediationMigrate,
+			}))
+			Expect(remediationMessages(remediations)).To(Equal(map[string]string{
+				"fakeMigratableComponent/xjoinindexpipeline.test.1": "migration failed: mapping conflict",
+				"fakeComponent/xjoinindexpipeline.test.1":           "component does not support migration",
+				"fakeRepairableComponent/xjoinindexpipeline.test.1": "migrated in place",
+			}))
+		})
+	})
+
+	Context("CreateAll", func() {
+		var recorder *createRecorder
+
+		BeforeEach(func() {
+			recorder = &createRecorder{}
+		})
+
+		newComponent := func(name string) *fakeCreatableComponent {
+			return &fakeCreatableComponent{fakeComponent: fakeComponent{name: name}, recorder: recorder}
+		}
+
+		It("Should create each component after its dependencies", func() {
+			topic := newComponent("topic")
+			index := newComponent("index")
+			connector := newComponent("connector")
+			deployment := newComponent("deployment")
+
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.SetConcurrency(4)
+			manager.AddComponent(topic)
+			manager.AddComponent(index)
+			manager.AddComponent(connector, topic, index)
+			manager.AddComponent(deployment, connector)
+
+			checkError(manager.CreateAll())
+			Expect(recorder.created).To(HaveLen(4))
+			Expect(recorder.created[:2]).To(ConsistOf("topic", "index"))
+			Expect(recorder.created[2:]).To(Equal([]string{"connector", "deployment"}))
+		})
+
+		It("Should create independent components in parallel by default", func() {
+			recorder.waitFor = 3
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			for _, name := range []string{"a", "b", "c"} {
+				manager.AddComponent(newComponent(name))
+			}
+
+			checkError(manager.CreateAll())
+			Expect(recorder.created).To(ConsistOf("a", "b", "c"))
+			Expect(recorder.maxRunning).To(Equal(3))
+		})
+
+		It("Should create the components one at a time when the concurrency is 1", func() {
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.SetConcurrency(1)
+			for _, name := range []string{"a", "b", "c"} {
+				manager.AddComponent(newComponent(name))
+			}
+
+			checkError(manager.CreateAll())
+			Expect(recorder.created).To(Equal([]string{"a", "b", "c"}))
+			Expect(recorder.maxRunning).To(Equal(1))
+		})
+
+		It("Should create at most the concurrency limit of components in parallel", func() {
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.SetConcurrency(2)
+			for _, name := range []string{"a", "b", "c", "d", "e"} {
+				manager.AddComponent(newComponent(name))
+			}
+
+			checkError(manager.CreateAll())
+			Expect(recorder.created).To(HaveLen(5))
+			Expect(recorder.maxRunning).To(Equal(2))
+		})
+
+		It("Should not create components that already exist", func() {
+			existing := newComponent("existing")
+			existing.exists = true
+
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(existing)
+			manager.AddComponent(newComponent("new"), existing)
+
+			checkError(manager.CreateAll())
+			Expect(recorder.created).To(Equal([]string{"new"}))
+		})
+
+		It("Should delete the components it created when a component fails in the middle of the graph", func() {
+			existing := newComponent("existing")
+			existing.exists = true
+			topic := newComponent("topic")
+			index := newComponent("index")
+			connector := newComponent("connector")
+			connector.createErr = errors.New("connect is unavailable")
+			deployment := newComponent("deployment")
+
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(existing)
+			manager.AddComponent(topic, existing)
+			manager.AddComponent(index, topic)
+			manager.AddComponent(connector, topic)
+			manager.AddComponent(deployment, index, connector)
+
+			err := manager.CreateAll()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(
+				"unable to create fakeCreatableComponent connector: connect is unavailable"))
+			Expect(recorder.created).ToNot(ContainElement("deployment"))
+			Expect(recorder.deleted).To(Equal([]string{"index", "topic"}))
+			Expect(existing.exists).To(BeTrue())
+		})
+
+		It("Should not delete a component that was created by someone else during the creation", func() {
+			topic := newComponent("topic")
+			index := newComponent("index")
+			index.createErr = k8errors.NewAlreadyExists(schema.GroupResource{Resource: "indexes"}, "index")
+
+			manager := components.NewComponentManager("XJoinIndexPipeline", "test", "1")
+			manager.AddComponent(topic)
+			manager.AddComponent(index, topic)
+
+			Expect(manager.CreateAll()).To(HaveOccurred())
+			Expect(recorder.deleted).To(Equal([]string{"topic"}))
+			Expect(index.exists).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,353 @@
+package zzfake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	strimziApi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	xjoinApi "github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/components"
+	//+kubebuilder:scaffold:imports
+)
+
+// These tests use Ginkgo (BDD-style Go testing framework). Refer to
+// http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+
+var cfg *rest.Config
+var k8sClient client.Client
+var testEnv *envtest.Environment
+
+var K8sGetTimeout = 3 * time.Second
+var K8sGetInterval = 100 * time.Millisecond
+
+var testLogger = logf.Log.WithName("test")
+
+// this is used to output stack traces when an error occurs
+func checkError(err error) {
+	if err != nil {
+		testLogger.Error(errors.Wrap(err, 0), "test failure"); GinkgoWriter.Println("STACK", errors.Wrap(err, 0).ErrorStack())
+	}
+	Expect(err).ToNot(HaveOccurred())
+}
+
+func k8sGet(key client.ObjectKey, obj client.Object) {
+	ctx := context.Background()
+	Eventually(func() bool {
+		err := k8sClient.Get(ctx, key, obj)
+		return err == nil
+	}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+}
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t,
+		"Controller Suite")
+}
+
+func NewNamespace() (string, error) {
+	name := "test" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	namespace := v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	err := k8sClient.Create(context.Background(), &namespace)
+	if err != nil {
+		return "", err
+	}
+
+	configMap := v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "xjoin-generic",
+			Namespace: name,
+		},
+		Data: map[string]string{
+			"kafka.cluster.namespace":   name,
+			"connect.cluster.namespace": name,
+			"schemaregistry.port":       "1080",
+			"schemaregistry.host":       "apicurio",
+		},
+	}
+	err = k8sClient.Create(context.Background(), &configMap)
+	if err != nil {
+		return "", err
+	}
+
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "xjoin-elasticsearch",
+			Namespace: name,
+		},
+		Type: "opaque",
+		StringData: map[string]string{
+			"endpoint": "http://localhost:9200",
+			"password": "xjoin1337",
+			"username": "xjoin",
+		},
+	}
+	err = k8sClient.Create(context.Background(), &secret)
+	checkError(err)
+
+	return name, nil
+}
+
+// expectCondition validates the status and reason of a condition, then returns the condition
+func expectCondition(conditions []metav1.Condition, conditionType string, status metav1.ConditionStatus,
+	reason string) metav1.Condition {
+
+	condition := meta.FindStatusCondition(conditions, conditionType)
+	Expect(condition).ToNot(BeNil())
+	Expect(condition.Status).To(Equal(status), condition.Message)
+	Expect(condition.Reason).To(Equal(reason), condition.Message)
+	return *condition
+}
+
+// expectPlanConfigMap validates the plan ConfigMap of an XJoinIndex/XJoinDataSource is owned by it and contains the
+// planned state, then returns the ConfigMap
+func expectPlanConfigMap(owner client.Object, kind string, plan *xjoinApi.PlanStatus) v1.ConfigMap {
+	Expect(plan).ToNot(BeNil())
+	Expect(plan.ConfigMapName).To(Equal(strings.ToLower(kind) + "." + owner.GetName() + ".plan"))
+	Expect(plan.Error).To(Equal(""))
+
+	configMap := v1.ConfigMap{}
+	k8sGet(client.ObjectKey{Name: plan.ConfigMapName, Namespace: owner.GetNamespace()}, &configMap)
+
+	controller := true
+	Expect(configMap.OwnerReferences).To(Equal([]metav1.OwnerReference{{
+		APIVersion:         "xjoin.cloud.redhat.com/v1alpha1",
+		Kind:               kind,
+		Name:               owner.GetName(),
+		UID:                owner.GetUID(),
+		Controller:         &controller,
+		BlockOwnerDeletion: &controller,
+	}}))
+	Expect(configMap.Data).To(HaveKeyWithValue("state", plan.State))
+	Expect(configMap.Data).To(HaveKeyWithValue("refresh", strconv.FormatBool(plan.Refresh)))
+	Expect(configMap.Data).To(HaveKeyWithValue("specHash", plan.SpecHash))
+	Expect(configMap.Data).ToNot(HaveKey("error"))
+	return configMap
+}
+
+// expectedAvroSchema returns the subject and the schema an AvroSchema component registers
+func expectedAvroSchema(kind string, name string, version string, avroSchema string) (subject string, schema string) {
+	avroSchemaComponent := components.NewAvroSchema(components.AvroSchemaParameters{Schema: avroSchema})
+	avroSchemaComponent.SetName(kind, name)
+	avroSchemaComponent.SetVersion(version)
+	schema, err := avroSchemaComponent.SetSchemaNameNamespace()
+	checkError(err)
+	return avroSchemaComponent.Name(), schema
+}
+
+// registeredAvroSchemaResponse is the registry's response for the version of the subject that was registered by an
+// AvroSchema component, so the mocked registry doesn't deviate from the expected schema
+func registeredAvroSchemaResponse(kind string, name string, version string, avroSchema string) string {
+	subject, schema := expectedAvroSchema(kind, name, version, avroSchema)
+	response, err := json.Marshal(map[string]interface{}{
+		"subject":    subject,
+		"version":    1,
+		"id":         1,
+		"schema":     schema,
+		"references": []interface{}{},
+	})
+	checkError(err)
+	return string(response)
+}
+
+func LoadExpectedKafkaResourceConfig(filename string) *bytes.Buffer {
+	file, err := os.ReadFile(filename)
+	checkError(err)
+	buffer := bytes.NewBuffer([]byte{})
+	err = json.Compact(buffer, file)
+	checkError(err)
+	return buffer
+}
+
+type IndexPipelineTestResources struct {
+	IndexReconciler         IndexTestReconciler
+	IndexPipelineReconciler XJoinIndexPipelineTestReconciler
+	DatasourceReconciler    DatasourceTestReconciler
+	IndexPipeline           xjoinApi.XJoinIndexPipeline
+	Index                   xjoinApi.XJoinIndex
+	DataSource              xjoinApi.XJoinDataSource
+}
+
+type UpdatedMocksParams struct {
+	GraphQLSchemaExistingState string
+	GraphQLSchemaNewState      string
+}
+
+func CreateValidIndexPipeline(namespace string, customSubgraphImages []xjoinApi.CustomSubgraphImage) IndexPipelineTestResources {
+	indexReconciler := IndexTestReconciler{
+		Namespace:            namespace,
+		Name:                 "test-index",
+		K8sClient:            k8sClient,
+		AvroSchemaFileName:   "xjoinindex-with-referenced-field",
+		CustomSubgraphImages: customSubgraphImages,
+	}
+	createdIndex := indexReconciler.ReconcileNew()
+
+	//create a valid datasource
+	dataSourceName := "testdatasource"
+	datasourceReconciler := DatasourceTestReconciler{
+		Namespace: namespace,
+		Name:      dataSourceName,
+		K8sClient: k8sClient,
+	}
+	datasourceReconciler.ReconcileNew()
+	createdDataSource := datasourceReconciler.ReconcileValid()
+
+	//reconcile the refreshing indexpipeline to be valid
+	indexPipelineReconciler := XJoinIndexPipelineTestReconciler{
+		Namespace:            namespace,
+		Name:                 createdIndex.Name,
+		Version:              createdIndex.Status.RefreshingVersion,
+		ConfigFileName:       "xjoinindex-with-referenced-field",
+		K8sClient:            k8sClient,
+		CustomSubgraphImages: customSubgraphImages,
+		DataSources: []DataSource{{
+			Name:                     dataSourceName,
+			Version:                  createdDataSource.Status.ActiveVersion,
+			ApiCurioResponseFilename: "datasource-latest-version",
+		}},
+	}
+	indexPipeline := indexPipelineReconciler.ReconcileCreate()
+	Expect(indexPipeline.Status.Active).To(Equal(false))
+	Expect(indexPipeline.Status.Deviations).To(BeEmpty())
+
+	//reconcile the index to flip the refreshing pipeline to be active
+	indexReconciler.ReconcileUpdated()
+	indexPipeline = indexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+		GraphQLSchemaExistingState: "DISABLED",
+		GraphQLSchemaNewState:      "ENABLED",
+	})
+
+	return IndexPipelineTestResources{
+		IndexReconciler:         indexReconciler,
+		IndexPipelineReconciler: indexPipelineReconciler,
+		DatasourceReconciler:    datasourceReconciler,
+		IndexPipeline:           indexPipeline,
+		Index:                   createdIndex,
+		DataSource:              createdDataSource,
+	}
+}
+
+var _ = BeforeSuite(func() {
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+	myscheme, err := xjoinApi.SchemeBuilder.Build()
+	Expect(err).NotTo(HaveOccurred())
+	err = scheme.AddToScheme(myscheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = strimziApi.AddToScheme(myscheme)
+	Expect(err).NotTo(HaveOccurred())
+	k8sClient = fieldClient{fake.NewClientBuilder().WithScheme(myscheme).Build()}
+	_ = cfg
+	_ = testEnv
+	_ = filepath.Join
+	_ = envtest.Environment{}
+	_ = schema.GroupVersionKind{}
+})
+
+type fieldClient struct {
+	client.Client
+}
+
+func (f fieldClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if secret, ok := obj.(*v1.Secret); ok && secret.StringData != nil {
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		for key, value := range secret.StringData {
+			secret.Data[key] = []byte(value)
+		}
+		secret.StringData = nil
+	}
+	return f.Client.Create(ctx, obj, opts...)
+}
+
+//Update ignores the status like the api server does for resources with a status subresource
+func (f fieldClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	gvk, err := apiutil.GVKForObject(obj, f.Client.Scheme())
+	if err != nil || gvk.Group != "xjoin.cloud.redhat.com" {
+		return f.Client.Update(ctx, obj, opts...)
+	}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(gvk)
+	err = f.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if err != nil {
+		return f.Client.Update(ctx, obj, opts...)
+	}
+	updated, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	if status, ok := existing.Object["status"]; ok {
+		updated["status"] = status
+	} else {
+		delete(updated, "status")
+	}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(updated, obj)
+	if err != nil {
+		return err
+	}
+	return f.Client.Update(ctx, obj, opts...)
+}
+
+func (f fieldClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	var name string
+	var filtered []client.ListOption
+	for _, opt := range opts {
+		if fields, ok := opt.(client.MatchingFields); ok {
+			for key, value := range fields {
+				if key == "metadata.name" {
+					name = value
+				} else if key == "metadata.namespace" {
+					filtered = append(filtered, client.InNamespace(value))
+				}
+			}
+			continue
+		}
+		filtered = append(filtered, opt)
+	}
+	err := f.Client.List(ctx, list, filtered...)
+	if err != nil || name == "" {
+		return err
+	}
+	items, err := apimeta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	var kept []runtime.Object
+	for _, item := range items {
+		if item.(client.Object).GetName() == name {
+			kept = append(kept, item)
+		}
+	}
+	return apimeta.SetList(list, kept)
+}
@@ -0,0 +1,2128 @@
+package zzfake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redhatinsights/xjoin-go-lib/pkg/avro"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	xjoinAvro "github.com/redhatinsights/xjoin-operator/controllers/avro"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/index"
+	k8sUtils "github.com/redhatinsights/xjoin-operator/controllers/utils"
+	v1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	//+kubebuilder:scaffold:imports
+)
+
+var _ = Describe("XJoinIndexPipeline", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		httpmock.Activate()
+		httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip) //disable mocks for unregistered http requests
+
+		var err error
+		namespace, err = NewNamespace()
+		checkError(err)
+	})
+
+	AfterEach(func() {
+		httpmock.DeactivateAndReset()
+	})
+
+	Context("Reconcile Creation", func() {
+		It("Should add a finalizer to the indexPipeline", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+			Expect(createdIndexPipeline.Finalizers).To(HaveLen(1))
+			Expect(createdIndexPipeline.Finalizers).To(ContainElement("finalizer.xjoin.indexpipeline.cloud.redhat.com"))
+		})
+
+		It("Should create an Elasticsearch Index", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			info := httpmock.GetCallCountInfo()
+			count := info["PUT http://localhost:9200/xjoinindexpipeline."+reconciler.GetName()]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should create an Elasticsearch Connector", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			connectorName := "xjoinindexpipeline." + reconciler.GetName()
+			connectorLookupKey := types.NamespacedName{Name: connectorName, Namespace: namespace}
+			elasticsearchConnector := &v1beta2.KafkaConnector{}
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), connectorLookupKey, elasticsearchConnector)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			elasticsearchClass := "io.confluent.connect.elasticsearch.ElasticsearchSinkConnector"
+			connectorPause := false
+
+			Expect(elasticsearchConnector.Name).To(Equal(connectorName))
+			Expect(elasticsearchConnector.Namespace).To(Equal(namespace))
+			Expect(elasticsearchConnector.Spec.Class).To(Equal(&elasticsearchClass))
+			Expect(elasticsearchConnector.Spec.Pause).To(Equal(&connectorPause))
+			Expect(elasticsearchConnector.GetLabels()).To(Equal(map[string]string{"strimzi.io/cluster": "connect"}))
+
+			//config comparison
+			expectedElasticsearchConfig := LoadExpectedKafkaResourceConfig("./test/data/kafka/elasticsearch_config.json")
+			actualElasticsearchConfig := bytes.NewBuffer([]byte{})
+			err := json.Compact(actualElasticsearchConfig, elasticsearchConnector.Spec.Config.Raw)
+			checkError(err)
+			Expect(actualElasticsearchConfig).To(Equal(expectedElasticsearchConfig))
+		})
+
+		It("Should create an OpenSearch Connector when the sink is opensearch", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				Sink: &v1alpha1.SinkSpec{
+					Type: v1alpha1.SinkTypeOpenSearch,
+					OpenSearch: &v1alpha1.OpenSearchSinkSpec{
+						URL:      &v1alpha1.StringOrSecretParameter{Value: "http://localhost:9200"},
+						Username: &v1alpha1.StringOrSecretParameter{Value: "opensearch-user"},
+						Password: &v1alpha1.StringOrSecretParameter{Value: "opensearch-password"},
+					},
+				},
+			}
+			indexPipeline := reconciler.ReconcileNew()
+
+			info := httpmock.GetCallCountInfo()
+			Expect(info["PUT http://localhost:9200/xjoinindexpipeline."+reconciler.GetName()]).To(Equal(1))
+
+			connectorName := "xjoinindexpipeline." + reconciler.GetName()
+			connectorLookupKey := types.NamespacedName{Name: connectorName, Namespace: namespace}
+			connector := &v1beta2.KafkaConnector{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), connectorLookupKey, connector)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			openSearchClass := "io.aiven.kafka.connect.opensearch.OpensearchSinkConnector"
+			Expect(connector.Spec.Class).To(Equal(&openSearchClass))
+
+			var config map[string]interface{}
+			err := json.Unmarshal(connector.Spec.Config.Raw, &config)
+			checkError(err)
+			Expect(config["connection.url"]).To(Equal("http://localhost:9200"))
+			Expect(config["connection.username"]).To(Equal("opensearch-user"))
+			Expect(config["topics"]).To(Equal("xjoinindexpipeline." + reconciler.GetName()))
+
+			//the connector reads the password via the strimzi config provider from the sink's secret
+			sinkSecretName := "xjoinindexpipeline." + reconciler.Name + "-sink." + reconciler.Version
+			Expect(config["connection.password"]).To(Equal(
+				"${secrets:" + namespace + "/" + sinkSecretName + ":sink.password}"))
+			Expect(string(connector.Spec.Config.Raw)).ToNot(ContainSubstring("opensearch-password"))
+			sinkSecret := &corev1.Secret{}
+			err = k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: sinkSecretName, Namespace: namespace}, sinkSecret)
+			checkError(err)
+			Expect(string(sinkSecret.Data["sink.password"])).To(Equal("opensearch-password"))
+
+			//the subgraph reads the opensearch credentials from the pipeline's secret
+			secret := &corev1.Secret{}
+			secretLookupKey := types.NamespacedName{Name: "xjoinindexpipeline." + reconciler.GetName(), Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), secretLookupKey, secret)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			Expect(string(secret.Data["elasticsearch.username"])).To(Equal("opensearch-user"))
+
+			//the opensearch index is validated via the Elasticsearch API like the elasticsearch sink
+			validatorLookupKey := types.NamespacedName{Name: "xjoinindexpipeline." + reconciler.GetName(), Namespace: namespace}
+			validator := &v1alpha1.XJoinIndexValidator{}
+			err = k8sClient.Get(context.Background(), validatorLookupKey, validator)
+			checkError(err)
+			Expect(validator.Spec.IndexName).To(Equal("xjoinindexpipeline." + reconciler.GetName()))
+			Expect(indexPipeline.Status.ValidationResponse.Result).ToNot(Equal(index.NotValidated))
+		})
+
+		It("Should create a Kafka Topic", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			topicName := "xjoinindexpipeline." + reconciler.GetName()
+			topicLookupKey := types.NamespacedName{Name: topicName, Namespace: namespace}
+			topic := &v1beta2.KafkaTopic{}
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), topicLookupKey, topic)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			topicReplicas := int32(1)
+			topicPartitions := int32(1)
+
+			Expect(topic.Name).To(Equal(topicName))
+			Expect(topic.Namespace).To(Equal(namespace))
+			Expect(topic.Spec.Replicas).To(Equal(&topicReplicas))
+			Expect(topic.Spec.Partitions).To(Equal(&topicPartitions))
+
+			//config comparison
+			expectedTopicConfig := LoadExpectedKafkaResourceConfig("./test/data/kafka/topic_config.json")
+			actualTopicConfig := bytes.NewBuffer([]byte{})
+			err := json.Compact(actualTopicConfig, topic.Spec.Config.Raw)
+			checkError(err)
+			Expect(actualTopicConfig).To(Equal(expectedTopicConfig))
+		})
+
+		It("Should create an Avro Schema", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			//TODO validate the body of the request is correct
+			//validates the correct API calls were made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline.test-index-pipeline.1234-value/versions/1"]
+			Expect(count).To(Equal(1))
+
+			count = info["POST http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline.test-index-pipeline.1234-value/versions"]
+			Expect(count).To(Equal(1))
+
+			count = info["GET http://apicurio:1080/apis/ccompat/v6/schemas/ids/1"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should create a GraphQL Schema", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:            namespace,
+				Name:                 "test-index-pipeline",
+				Version:              "1234",
+				ConfigFileName:       "xjoinindex",
+				CustomSubgraphImages: nil,
+				K8sClient:            k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			//TODO validate the body of the request is correct
+			//validates the correct API calls were made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline.1234/versions"]
+			Expect(count).To(Equal(1))
+
+			count = info["POST http://apicurio:1080/apis/registry/v2/groups/default/artifacts"]
+			Expect(count).To(Equal(1))
+
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline.1234/meta"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should create an xjoin-core deployment", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			deploymentName := "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234"
+			deploymentLookupKey := types.NamespacedName{Name: deploymentName, Namespace: namespace}
+			deployment := &v1.Deployment{}
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			replicas := int32(1)
+			revisionHistoryLimit := int32(10)
+			progressDeadlineSeconds := int32(600)
+
+			Expect(deployment.Name).To(Equal(deploymentName))
+			Expect(deployment.Namespace).To(Equal(namespace))
+			Expect(deployment.Spec.Replicas).To(Equal(&replicas))
+			Expect(deployment.Spec.Selector.MatchLabels).To(Equal(map[string]string{
+				"app":         "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234",
+				"xjoin.index": "xjoin-core-xjoinindexpipeline-test-index-pipeline",
+			}))
+			Expect(deployment.GetLabels()).To(Equal(map[string]string{
+				"app":         "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234",
+				"xjoin.index": "xjoin-core-xjoinindexpipeline-test-index-pipeline",
+			}))
+			Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Name).To(Equal("xjoin-core-xjoinindexpipeline-test-index-pipeline-1234"))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("quay.io/cloudservices/xjoin-core:latest"))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(HaveLen(5))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElements([]corev1.EnvVar{
+				{
+					Name:      "SOURCE_TOPICS",
+					Value:     "", //TODO
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SINK_TOPIC",
+					Value:     "xjoinindexpipeline.test",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SCHEMA_REGISTRY_URL",
+					Value:     "http://apicurio:1080/apis/registry/v2",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "KAFKA_BOOTSTRAP",
+					Value:     "localhost:9092",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SINK_SCHEMA",
+					Value:     `{"type":"record","name":"Value","namespace":"test-index-pipeline.1234"}`,
+					ValueFrom: nil,
+				},
+			}))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Command).To(BeNil())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Args).To(BeNil())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Ports).To(BeNil())
+			Expect(deployment.Spec.Strategy.Type).To(Equal(v1.DeploymentStrategyType("RollingUpdate")))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.Type).To(Equal(intstr.Type(1)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.IntVal).To(Equal(int32(0)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.StrVal).To(Equal("25%"))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.Type).To(Equal(intstr.Type(1)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.IntVal).To(Equal(int32(0)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.StrVal).To(Equal("25%"))
+			Expect(deployment.Spec.MinReadySeconds).To(Equal(int32(0)))
+			Expect(deployment.Spec.RevisionHistoryLimit).To(Equal(&revisionHistoryLimit))
+			Expect(deployment.Spec.Paused).To(Equal(false))
+			Expect(deployment.Spec.ProgressDeadlineSeconds).To(Equal(&progressDeadlineSeconds))
+		})
+
+		It("Should create an xjoin-api-subgraph deployment", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			deploymentName := "test-index-pipeline-1234"
+			deploymentLookupKey := types.NamespacedName{Name: deploymentName, Namespace: namespace}
+			deployment := &v1.Deployment{}
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			replicas := int32(1)
+			revisionHistoryLimit := int32(10)
+			progressDeadlineSeconds := int32(600)
+
+			Expect(deployment.Name).To(Equal(deploymentName))
+			Expect(deployment.Namespace).To(Equal(namespace))
+			Expect(deployment.Spec.Replicas).To(Equal(&replicas))
+			Expect(deployment.GetLabels()).To(Equal(map[string]string{
+				"app":         "test-index-pipeline-1234",
+				"xjoin.index": "test-index-pipeline",
+			}))
+			Expect(deployment.Spec.Selector.MatchLabels).To(Equal(map[string]string{
+				"app":         "test-index-pipeline-1234",
+				"xjoin.index": "test-index-pipeline",
+			}))
+
+			Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Name).To(Equal("test-index-pipeline-1234"))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("quay.io/cloudservices/xjoin-api-subgraph:latest"))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(HaveLen(9))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElements([]corev1.EnvVar{
+				{
+					Name:      "AVRO_SCHEMA",
+					Value:     `{"type":"record","name":"Value","namespace":"test-index-pipeline.1234"}`,
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SCHEMA_REGISTRY_PROTOCOL",
+					Value:     "http",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SCHEMA_REGISTRY_HOSTNAME",
+					Value:     "apicurio",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SCHEMA_REGISTRY_PORT",
+					Value:     "1080",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "ELASTIC_SEARCH_URL",
+					Value:     "http://localhost:9200",
+					ValueFrom: nil,
+				},
+				{
+					Name:  "ELASTIC_SEARCH_USERNAME",
+					Value: "",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "xjoinindexpipeline.test-index-pipeline.1234",
+							},
+							Key: "elasticsearch.username",
+						},
+					},
+				},
+				{
+					Name:  "ELASTIC_SEARCH_PASSWORD",
+					Value: "",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "xjoinindexpipeline.test-index-pipeline.1234",
+							},
+							Key: "elasticsearch.password",
+						},
+					},
+				},
+				{
+					Name:      "ELASTIC_SEARCH_INDEX",
+					Value:     "xjoinindexpipeline.test-index-pipeline.1234",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "GRAPHQL_SCHEMA_NAME",
+					Value:     "xjoinindexpipeline.test-index-pipeline.1234",
+					ValueFrom: nil,
+				},
+			}))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Command).To(BeNil())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Args).To(BeNil())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Ports).To(Equal([]corev1.ContainerPort{{
+				Name:          "web",
+				HostPort:      int32(0),
+				ContainerPort: int32(8000),
+				Protocol:      "TCP",
+				HostIP:        "",
+			}}))
+			Expect(deployment.Spec.Strategy.Type).To(Equal(v1.DeploymentStrategyType("RollingUpdate")))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.Type).To(Equal(intstr.Type(1)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.IntVal).To(Equal(int32(0)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.StrVal).To(Equal("25%"))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.Type).To(Equal(intstr.Type(1)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.IntVal).To(Equal(int32(0)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.StrVal).To(Equal("25%"))
+			Expect(deployment.Spec.MinReadySeconds).To(Equal(int32(0)))
+			Expect(deployment.Spec.RevisionHistoryLimit).To(Equal(&revisionHistoryLimit))
+			Expect(deployment.Spec.Paused).To(Equal(false))
+			Expect(deployment.Spec.ProgressDeadlineSeconds).To(Equal(&progressDeadlineSeconds))
+		})
+
+		It("Should create a secret with the Elasticsearch credentials", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			secret := &corev1.Secret{}
+			secretLookupKey := types.NamespacedName{Name: "xjoinindexpipeline.test-index-pipeline.1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), secretLookupKey, secret)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(secret.Data).To(Equal(map[string][]byte{
+				"elasticsearch.username": []byte("xjoin"),
+				"elasticsearch.password": []byte("xjoin1337"),
+			}))
+		})
+
+		It("Should apply the deployment settings from the spec", func() {
+			replicas := int32(3)
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				XJoinCore: &v1alpha1.DeploymentSpec{
+					ImageTag:        "abc123",
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Replicas:        &replicas,
+					Resources: &corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("1"),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+					},
+					NodeSelector:       map[string]string{"node-role": "xjoin"},
+					Tolerations:        []corev1.Toleration{{Key: "xjoin", Operator: corev1.TolerationOpExists}},
+					PodAnnotations:     map[string]string{"prometheus.io/scrape": "true"},
+					ServiceAccountName: "xjoin-core",
+					ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "quay-pull"}},
+				},
+				XJoinAPISubGraph: &v1alpha1.XJoinAPISubGraphSpec{
+					DeploymentSpec: v1alpha1.DeploymentSpec{
+						Image: "quay.io/example/xjoin-api-subgraph",
+					},
+				},
+			}
+			reconciler.ReconcileNew()
+
+			deployment := &v1.Deployment{}
+			deploymentLookupKey := types.NamespacedName{
+				Name: "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(deployment.Spec.Replicas).To(Equal(&replicas))
+			Expect(deployment.Spec.Template.Annotations).To(Equal(map[string]string{"prometheus.io/scrape": "true"}))
+			Expect(deployment.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"node-role": "xjoin"}))
+			Expect(deployment.Spec.Template.Spec.Tolerations).To(Equal(
+				[]corev1.Toleration{{Key: "xjoin", Operator: corev1.TolerationOpExists}}))
+			Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal("xjoin-core"))
+			Expect(deployment.Spec.Template.Spec.ImagePullSecrets).To(Equal(
+				[]corev1.LocalObjectReference{{Name: "quay-pull"}}))
+			Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("quay.io/cloudservices/xjoin-core:abc123"))
+			Expect(container.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+			Expect(container.Resources.Limits.Cpu().String()).To(Equal("1"))
+			Expect(container.Resources.Limits.Memory().String()).To(Equal("1Gi"))
+			Expect(container.Resources.Requests.Cpu().String()).To(Equal("1"))
+
+			deploymentLookupKey = types.NamespacedName{Name: "test-index-pipeline-1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(
+				Equal("quay.io/example/xjoin-api-subgraph:latest"))
+			Expect(deployment.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullAlways))
+		})
+
+		It("Should create a service, probes and an autoscaler for the xjoin-api-subgraph", func() {
+			minReplicas := int32(2)
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				XJoinAPISubGraph: &v1alpha1.XJoinAPISubGraphSpec{
+					Autoscaling: &v1alpha1.AutoscalingSpec{
+						MinReplicas: &minReplicas,
+						MaxReplicas: 5,
+					},
+				},
+			}
+			reconciler.ReconcileNew()
+
+			lookupKey := types.NamespacedName{Name: "test-index-pipeline-1234", Namespace: namespace}
+
+			service := &corev1.Service{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), lookupKey, service)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].Port).To(Equal(int32(4000)))
+			Expect(service.Spec.Ports[0].TargetPort).To(Equal(intstr.FromString("web")))
+			Expect(service.Spec.Selector).To(Equal(map[string]string{"app": "test-index-pipeline-1234"}))
+
+			deployment := &v1.Deployment{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), lookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.ReadinessProbe).ToNot(BeNil())
+			Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal("/healthz"))
+			Expect(container.ReadinessProbe.HTTPGet.Port).To(Equal(intstr.FromString("web")))
+			Expect(container.LivenessProbe).ToNot(BeNil())
+			Expect(container.LivenessProbe.HTTPGet.Path).To(Equal("/healthz"))
+
+			autoscaler := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), lookupKey, autoscaler)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			Expect(autoscaler.Spec.ScaleTargetRef.Kind).To(Equal("Deployment"))
+			Expect(autoscaler.Spec.ScaleTargetRef.Name).To(Equal("test-index-pipeline-1234"))
+			Expect(autoscaler.Spec.MinReplicas).To(Equal(&minReplicas))
+			Expect(autoscaler.Spec.MaxReplicas).To(Equal(int32(5)))
+			Expect(autoscaler.Spec.Metrics).To(HaveLen(1))
+			Expect(*autoscaler.Spec.Metrics[0].Resource.Target.AverageUtilization).To(Equal(int32(80)))
+		})
+
+		It("Should create custom subgraph graphql schema", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				CustomSubgraphImages: []v1alpha1.CustomSubgraphImage{{
+					Name:  "test-custom-image",
+					Image: "quay.io/cloudservices/host-inventory-subgraph:latest",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			//TODO validate the body of the request is correct
+			//validates the correct API calls were made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-test-custom-image.1234/versions"]
+			Expect(count).To(Equal(1))
+
+			count = info["POST http://apicurio:1080/apis/registry/v2/groups/default/artifacts"]
+			Expect(count).To(Equal(2)) //called once for generic gql schema, then a second time for custom subgraph schema
+
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-test-custom-image.1234/meta"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should create custom subgraph deployments", func() {
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				CustomSubgraphImages: []v1alpha1.CustomSubgraphImage{{
+					Name:  "test-custom-image",
+					Image: "quay.io/cloudservices/host-inventory-subgraph:latest",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			deploymentName := "test-index-pipeline-test-custom-image-1234"
+			deploymentLookupKey := types.NamespacedName{Name: deploymentName, Namespace: namespace}
+			deployment := &v1.Deployment{}
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			replicas := int32(1)
+			revisionHistoryLimit := int32(10)
+			progressDeadlineSeconds := int32(600)
+
+			Expect(deployment.Name).To(Equal(deploymentName))
+			Expect(deployment.Namespace).To(Equal(namespace))
+			Expect(deployment.Spec.Replicas).To(Equal(&replicas))
+			Expect(deployment.GetLabels()).To(Equal(map[string]string{
+				"app":         "test-index-pipeline-test-custom-image-1234",
+				"xjoin.index": "test-index-pipeline-test-custom-image",
+			}))
+			Expect(deployment.Spec.Selector.MatchLabels).To(Equal(map[string]string{
+				"app":         "test-index-pipeline-test-custom-image-1234",
+				"xjoin.index": "test-index-pipeline-test-custom-image",
+			}))
+
+			Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Name).To(Equal(deploymentName))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("quay.io/cloudservices/host-inventory-subgraph:latest"))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(HaveLen(9))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElements([]corev1.EnvVar{
+				{
+					Name:      "AVRO_SCHEMA",
+					Value:     `{"type":"record","name":"Value","namespace":"test-index-pipeline.1234"}`,
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SCHEMA_REGISTRY_PROTOCOL",
+					Value:     "http",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SCHEMA_REGISTRY_HOSTNAME",
+					Value:     "apicurio",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "SCHEMA_REGISTRY_PORT",
+					Value:     "1080",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "ELASTIC_SEARCH_URL",
+					Value:     "http://localhost:9200",
+					ValueFrom: nil,
+				},
+				{
+					Name:  "ELASTIC_SEARCH_USERNAME",
+					Value: "",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "xjoinindexpipeline.test-index-pipeline.1234",
+							},
+							Key: "elasticsearch.username",
+						},
+					},
+				},
+				{
+					Name:  "ELASTIC_SEARCH_PASSWORD",
+					Value: "",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "xjoinindexpipeline.test-index-pipeline.1234",
+							},
+							Key: "elasticsearch.password",
+						},
+					},
+				},
+				{
+					Name:      "ELASTIC_SEARCH_INDEX",
+					Value:     "xjoinindexpipeline.test-index-pipeline.1234",
+					ValueFrom: nil,
+				},
+				{
+					Name:      "GRAPHQL_SCHEMA_NAME",
+					Value:     "xjoinindexpipeline.test-index-pipeline-test-custom-image.1234",
+					ValueFrom: nil,
+				},
+			}))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Command).To(BeNil())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Args).To(BeNil())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Ports).To(Equal([]corev1.ContainerPort{{
+				Name:          "web",
+				HostPort:      int32(0),
+				ContainerPort: int32(8000),
+				Protocol:      "TCP",
+				HostIP:        "",
+			}}))
+			Expect(deployment.Spec.Strategy.Type).To(Equal(v1.DeploymentStrategyType("RollingUpdate")))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.Type).To(Equal(intstr.Type(1)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.IntVal).To(Equal(int32(0)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.StrVal).To(Equal("25%"))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.Type).To(Equal(intstr.Type(1)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.IntVal).To(Equal(int32(0)))
+			Expect(deployment.Spec.Strategy.RollingUpdate.MaxSurge.StrVal).To(Equal("25%"))
+			Expect(deployment.Spec.MinReadySeconds).To(Equal(int32(0)))
+			Expect(deployment.Spec.RevisionHistoryLimit).To(Equal(&revisionHistoryLimit))
+			Expect(deployment.Spec.Paused).To(Equal(false))
+			Expect(deployment.Spec.ProgressDeadlineSeconds).To(Equal(&progressDeadlineSeconds))
+		})
+
+		It("Should create an Elasticsearch Pipeline when the AvroSchema contains at least one JSON field", func() {
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-json-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline.testdatasource."+createdDataSource.Status.ActiveVersion+"-value/versions/latest"]
+			Expect(count).To(Equal(1))
+
+			count = info["GET http://localhost:9200/_ingest/pipeline/xjoinindexpipeline.test-index-pipeline.1234"]
+			Expect(count).To(Equal(2)) //the deviation check reads the pipeline again
+
+			count = info["PUT http://localhost:9200/_ingest/pipeline/xjoinindexpipeline.test-index-pipeline.1234"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should map the avro types to elasticsearch types", func() {
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-typed-fields",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":          map[string]interface{}{"type": "keyword"},
+				"count":       map[string]interface{}{"type": "integer"},
+				"size":        map[string]interface{}{"type": "long"},
+				"ratio":       map[string]interface{}{"type": "float"},
+				"score":       map[string]interface{}{"type": "double"},
+				"created_on":  map[string]interface{}{"type": "date"},
+				"modified_on": map[string]interface{}{"type": "date"},
+				"price":       map[string]interface{}{"type": "scaled_float", "scaling_factor": float64(100)},
+				"state":       map[string]interface{}{"type": "keyword"},
+				"external_id": map[string]interface{}{"type": "keyword"},
+				"description": map[string]interface{}{"type": "text"},
+			}))
+
+			//micro timestamps are converted to epoch millis by the ingest pipeline
+			var pipeline map[string]interface{}
+			err = json.Unmarshal([]byte(reconciler.pipelineRequestBody), &pipeline)
+			checkError(err)
+			Expect(pipeline["processors"]).To(Equal([]interface{}{
+				map[string]interface{}{
+					"script": map[string]interface{}{
+						"if":     "ctx.testdatasource?.created_on != null",
+						"lang":   "painless",
+						"source": "ctx.testdatasource.created_on = ctx.testdatasource.created_on / 1000L",
+					},
+				},
+			}))
+		})
+
+		It("Should keep the legacy elasticsearch mapping when it is selected", func() {
+			setTypeMapping(namespace, xjoinAvro.TypeMappingLegacy)
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-typed-fields",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			//only xjoin.type and xjoin.es.type are used, everything else is a keyword
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":          map[string]interface{}{"type": "keyword"},
+				"count":       map[string]interface{}{"type": "keyword"},
+				"size":        map[string]interface{}{"type": "keyword"},
+				"ratio":       map[string]interface{}{"type": "keyword"},
+				"score":       map[string]interface{}{"type": "keyword"},
+				"created_on":  map[string]interface{}{"type": "keyword"},
+				"modified_on": map[string]interface{}{"type": "keyword"},
+				"price":       map[string]interface{}{"type": "keyword"},
+				"state":       map[string]interface{}{"type": "keyword"},
+				"external_id": map[string]interface{}{"type": "keyword"},
+				"description": map[string]interface{}{"type": "text"},
+			}))
+			Expect(reconciler.pipelineRequestBody).ToNot(ContainSubstring("created_on"))
+		})
+
+		It("Should register the graphql schema generated from the avro schema", func() {
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-typed-fields",
+				}},
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			Expect(reconciler.graphQLSchemaBody).To(ContainSubstring(
+				"  testIndexPipeline(filter: TestIndexPipelineFilter, order_by: TestIndexPipelineOrderBy, " +
+					"order_how: OrderDirection, limit: Int = 10, offset: Int = 0): TestIndexPipelineCollection!\n"))
+			Expect(reconciler.graphQLSchemaBody).To(ContainSubstring(
+				"type TestIndexPipelineTestdatasource @key(fields: \"id\") {\n" +
+					"  id: String\n" +
+					"  count: Int\n" +
+					"  size: Long\n" +
+					"  ratio: Float\n" +
+					"  score: Float\n" +
+					"  created_on: Date\n" +
+					"  modified_on: Date\n" +
+					"  price: Float\n" +
+					"  state: String\n" +
+					"  external_id: String\n" +
+					"  description: String\n" +
+					"}"))
+			Expect(reconciler.graphQLSchemaBody).To(ContainSubstring(
+				"input TestIndexPipelineFilter {\n" +
+					"  testdatasource: TestIndexPipelineTestdatasourceFilter\n" +
+					"  AND: [TestIndexPipelineFilter!]\n" +
+					"  OR: [TestIndexPipelineFilter!]\n" +
+					"  NOT: TestIndexPipelineFilter\n" +
+					"}"))
+
+			//text fields can't be sorted
+			Expect(reconciler.graphQLSchemaBody).To(ContainSubstring("  testdatasource_external_id\n}"))
+			Expect(reconciler.graphQLSchemaBody).ToNot(ContainSubstring("testdatasource_description"))
+
+			graphQLSchemaHash, err := k8sUtils.SpecHash(reconciler.graphQLSchemaBody)
+			checkError(err)
+			Expect(createdIndexPipeline.Status.GraphQLSchemaHash).To(Equal(graphQLSchemaHash))
+		})
+
+		It("Should apply the xjoin.es mapping hints and generate the analysis settings", func() {
+			setIndexTemplate(namespace,
+				`{"settings":{"index":{"number_of_shards":"1"}},"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-mapping-hints",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			Expect(properties["search_all"]).To(Equal(map[string]interface{}{"type": "text"}))
+
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id": map[string]interface{}{"type": "keyword"},
+				"display_name": map[string]interface{}{
+					"type":         "keyword",
+					"ignore_above": float64(256),
+					"copy_to":      []interface{}{"search_all"},
+					"fields": map[string]interface{}{
+						"lowercase": map[string]interface{}{"type": "keyword", "normalizer": "case_insensitive"},
+					},
+				},
+				"description": map[string]interface{}{
+					"type":     "text",
+					"analyzer": "folding",
+					"copy_to":  []interface{}{"search_all"},
+				},
+				"checksum": map[string]interface{}{"type": "keyword", "index": false, "doc_values": false},
+				"systems": map[string]interface{}{
+					"type": "nested",
+					"properties": map[string]interface{}{
+						"name":    map[string]interface{}{"type": "keyword"},
+						"version": map[string]interface{}{"type": "keyword"},
+					},
+				},
+			}))
+
+			settings := index["settings"].(map[string]interface{})
+			Expect(settings["analysis"]).To(Equal(map[string]interface{}{
+				"analyzer": map[string]interface{}{
+					"folding": map[string]interface{}{
+						"tokenizer": "standard",
+						"filter":    []interface{}{"lowercase", "asciifolding"},
+					},
+				},
+				"normalizer": map[string]interface{}{
+					"case_insensitive": map[string]interface{}{"filter": "lowercase"},
+				},
+			}))
+		})
+
+		It("Should apply the transformations to the avro schema and the elasticsearch mapping", func() {
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-transformations",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-transformed-fields",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			caseInsensitiveKeyword := map[string]interface{}{
+				"type": "keyword",
+				"fields": map[string]interface{}{
+					"lowercase": map[string]interface{}{"type": "keyword", "normalizer": "case_insensitive"},
+				},
+			}
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":           map[string]interface{}{"type": "keyword"},
+				"display_name": caseInsensitiveKeyword,
+				"name":         caseInsensitiveKeyword,
+				"state":        map[string]interface{}{"type": "keyword"},
+				"facts":        map[string]interface{}{"type": "object"},
+				"labels":       map[string]interface{}{"type": "object"},
+				"label":        map[string]interface{}{"type": "keyword"},
+				"arch":         map[string]interface{}{"type": "keyword"},
+				"title":        map[string]interface{}{"type": "keyword"},
+				"labels_structured": map[string]interface{}{
+					"type": "nested",
+					"properties": map[string]interface{}{
+						"key":   map[string]interface{}{"type": "keyword"},
+						"value": map[string]interface{}{"type": "keyword"},
+					},
+				},
+			}))
+
+			//xjoin-core receives the transformed schema
+			deployment := &v1.Deployment{}
+			deploymentLookupKey := types.NamespacedName{
+				Name: "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			var sinkSchema avro.Schema
+			for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "SINK_SCHEMA" {
+					err = json.Unmarshal([]byte(env.Value), &sinkSchema)
+					checkError(err)
+				}
+			}
+			Expect(sinkSchema.Transformations).To(HaveLen(7))
+			Expect(sinkSchema.Fields).To(HaveLen(1))
+
+			var fieldNames []string
+			for _, field := range sinkSchema.Fields[0].Type[0].Fields {
+				fieldNames = append(fieldNames, field.Name)
+			}
+			Expect(fieldNames).To(Equal([]string{
+				"id", "display_name", "facts", "labels", "state", "name", "label", "arch", "labels_structured", "title"}))
+		})
+
+		It("Should expand nested references and the subjects referenced by data sources", func() {
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-nested-references",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-shared-references",
+				}},
+				RegistrySubjects: []RegistrySubject{{
+					Subject:                  "com.example.Address-value",
+					Version:                  "1",
+					ApiCurioResponseFilename: "shared-address",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			//the shared subject is fetched once per reconcile
+			info := httpmock.GetCallCountInfo()
+			Expect(info["GET http://apicurio:1080/apis/ccompat/v6/subjects/com.example.Address-value/versions/1"]).
+				To(Equal(1))
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties["inventory"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			address := map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"street": map[string]interface{}{"type": "keyword"},
+					"geo": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"lat": map[string]interface{}{"type": "double"},
+							"lon": map[string]interface{}{"type": "double"},
+						},
+					},
+				},
+			}
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":             map[string]interface{}{"type": "keyword"},
+				"home_address":   address,
+				"work_addresses": address,
+			}))
+
+			//the shared record type is defined once in the schema passed to xjoin-core
+			deployment := &v1.Deployment{}
+			deploymentLookupKey := types.NamespacedName{
+				Name: "xjoin-core-xjoinindexpipeline-test-index-pipeline-1234", Namespace: namespace}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), deploymentLookupKey, deployment)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			var sinkSchema string
+			for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "SINK_SCHEMA" {
+					sinkSchema = env.Value
+				}
+			}
+			Expect(strings.Count(sinkSchema, `"name":"com.example.Address"`)).To(Equal(1))
+			Expect(sinkSchema).To(ContainSubstring(`{"items":"com.example.Address","type":"array"}`))
+		})
+
+		It("Should record the data source version chosen by the version policy", func() {
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+				DataSourceSpecs: []v1alpha1.DataSourceVersionSpec{{
+					Name:   dataSourceName,
+					Policy: v1alpha1.DataSourceVersionPolicyActiveOnly,
+				}},
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			Expect(createdIndexPipeline.Status.DataSources).To(Equal(map[string]v1alpha1.DataSourceVersion{
+				dataSourceName: {
+					Version:       createdDataSource.Status.ActiveVersion,
+					SchemaVersion: 1,
+					Policy:        v1alpha1.DataSourceVersionPolicyActiveOnly,
+				},
+			}))
+			Expect(createdIndexPipeline.GetDataSources()).To(Equal(map[string]string{
+				dataSourceName: createdDataSource.Status.ActiveVersion,
+			}))
+		})
+
+		It("Should map unions and arrays of records to elasticsearch types", func() {
+			setIndexTemplate(namespace, `{"mappings":{"properties":{{.ElasticSearchProperties}}}}`)
+
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-with-unions-and-arrays",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			var index map[string]interface{}
+			err := json.Unmarshal([]byte(reconciler.indexRequestBody), &index)
+			checkError(err)
+			properties := index["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			properties = properties[dataSourceName].(map[string]interface{})["properties"].(map[string]interface{})
+
+			Expect(properties).To(Equal(map[string]interface{}{
+				"id":        map[string]interface{}{"type": "keyword"},
+				"reference": map[string]interface{}{"type": "keyword"},
+				"tags":      map[string]interface{}{"type": "keyword"},
+				"systems": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name":    map[string]interface{}{"type": "keyword"},
+						"profile": map[string]interface{}{"type": "object"},
+					},
+				},
+			}))
+
+			//json fields inside arrays are parsed for each item
+			var pipeline map[string]interface{}
+			err = json.Unmarshal([]byte(reconciler.pipelineRequestBody), &pipeline)
+			checkError(err)
+			Expect(pipeline["processors"]).To(Equal([]interface{}{
+				map[string]interface{}{
+					"foreach": map[string]interface{}{
+						"field":          "testdatasource.systems",
+						"ignore_missing": true,
+						"processor": map[string]interface{}{
+							"json": map[string]interface{}{
+								"field":          "_ingest._value.profile",
+								"ignore_failure": true,
+							},
+						},
+					},
+				},
+			}))
+		})
+
+		It("Should create an XJoinIndexValidation resource", func() {
+			configFileName := "xjoinindex"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				CustomSubgraphImages: []v1alpha1.CustomSubgraphImage{{
+					Name:  "test-custom-image",
+					Image: "quay.io/cloudservices/host-inventory-subgraph:latest",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			validatorName := "xjoinindexpipeline.test-index-pipeline.1234"
+			validatorLookupKey := types.NamespacedName{Name: validatorName, Namespace: namespace}
+			validator := &v1alpha1.XJoinIndexValidator{}
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), validatorLookupKey, validator)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(validator.Name).To(Equal(validatorName))
+			Expect(validator.Namespace).To(Equal(namespace))
+			Expect(validator.GetLabels()).To(Equal(map[string]string{
+				"app":                  "xjoin-validator",
+				"xjoin.component.name": "xjoinindexpipeline.test-index-pipeline",
+			}))
+			Expect(validator.OwnerReferences).To(HaveLen(1))
+
+			truePtr := true
+			expectedOwnerRef := metav1.OwnerReference{
+				APIVersion:         "v1alpha1",
+				Kind:               "XJoinIndexPipeline",
+				Name:               "test-index-pipeline.1234",
+				UID:                validator.OwnerReferences[0].UID,
+				Controller:         &truePtr,
+				BlockOwnerDeletion: &truePtr,
+			}
+			Expect(validator.OwnerReferences[0]).To(Equal(expectedOwnerRef))
+			Expect(validator.Spec.Version).To(Equal("1234"))
+			Expect(validator.Spec.IndexName).To(Equal("xjoinindexpipeline.test-index-pipeline.1234"))
+
+			indexAvroSchema, err := os.ReadFile("./test/data/avro/" + configFileName + ".json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(validator.Spec.AvroSchema).To(Equal(string(indexAvroSchema)))
+		})
+
+		It("Should pass the validation spec to the XJoinIndexValidation resource", func() {
+			validation := &v1alpha1.ValidationSpec{
+				Strategy:   v1alpha1.ValidationStrategySampling,
+				SampleSize: 500,
+				Confidence: 99,
+			}
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				Validation:     validation,
+			}
+			reconciler.ReconcileNew()
+
+			validatorLookupKey := types.NamespacedName{
+				Name: "xjoinindexpipeline.test-index-pipeline.1234", Namespace: namespace}
+			validator := &v1alpha1.XJoinIndexValidator{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), validatorLookupKey, validator)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(validator.Spec.Validation).To(Equal(validation))
+		})
+	})
+
+	Context("Reconcile Deletion", func() {
+		It("Should delete the Elasticsearch index", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			err := k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			info := httpmock.GetCallCountInfo()
+			count := info["HEAD http://localhost:9200/xjoinindexpipeline."+name+".1234"]
+			Expect(count).To(Equal(1))
+
+			count = info["DELETE http://localhost:9200/xjoinindexpipeline."+name+".1234"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should delete the Elasticsearch connector", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			connectors := &v1beta2.KafkaConnectorList{}
+			err := k8sClient.List(context.Background(), connectors, client.InNamespace(namespace))
+			checkError(err)
+			Expect(connectors.Items).To(HaveLen(1))
+
+			err = k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://connect-connect-api."+namespace+".svc:8083/connectors/xjoinindexpipeline."+name+".1234"]
+			Expect(count).To(Equal(6))
+
+			connectors = &v1beta2.KafkaConnectorList{}
+			err = k8sClient.List(context.Background(), connectors, client.InNamespace(namespace))
+			checkError(err)
+			Expect(connectors.Items).To(HaveLen(0))
+		})
+
+		It("Should delete the Kafka topic", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			topics := &v1beta2.KafkaTopicList{}
+			err := k8sClient.List(context.Background(), topics, client.InNamespace(namespace))
+			checkError(err)
+			Expect(topics.Items).To(HaveLen(1))
+
+			err = k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			topics = &v1beta2.KafkaTopicList{}
+			err = k8sClient.List(context.Background(), topics, client.InNamespace(namespace))
+			checkError(err)
+			Expect(topics.Items).To(HaveLen(0))
+		})
+
+		It("Should delete the Avro schema", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			err := k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			info := httpmock.GetCallCountInfo()
+			count := info["DELETE http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+name+".1234-value"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should delete the GraphQL schema", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			err := k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			info := httpmock.GetCallCountInfo()
+			count := info["DELETE http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+name+".1234"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should delete the xjoin-core deployment", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			deployment := &unstructured.Unstructured{}
+			deployment.SetGroupVersionKind(common.DeploymentGVK)
+			deploymentName := "xjoin-core-xjoinindexpipeline-" + name + "-1234"
+			deploymentLookup := types.NamespacedName{Name: deploymentName, Namespace: namespace}
+			err := k8sClient.Get(context.Background(), deploymentLookup, deployment)
+			checkError(err)
+			Expect(deployment.GetName()).To(Equal(deploymentName))
+
+			err = k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			deployments := &unstructured.UnstructuredList{}
+			deployments.SetGroupVersionKind(common.DeploymentGVK)
+			err = k8sClient.List(context.Background(), deployments, client.InNamespace(namespace))
+			checkError(err)
+			Expect(deployments.Items).To(HaveLen(0))
+		})
+
+		It("Should delete the xjoin-api-subgraph deployment", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			deployment := &unstructured.Unstructured{}
+			deployment.SetGroupVersionKind(common.DeploymentGVK)
+			deploymentName := name + "-1234"
+			deploymentLookup := types.NamespacedName{Name: deploymentName, Namespace: namespace}
+			err := k8sClient.Get(context.Background(), deploymentLookup, deployment)
+			checkError(err)
+			Expect(deployment.GetName()).To(Equal(deploymentName))
+
+			err = k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			deployments := &unstructured.UnstructuredList{}
+			deployments.SetGroupVersionKind(common.DeploymentGVK)
+			err = k8sClient.List(context.Background(), deployments, client.InNamespace(namespace))
+			checkError(err)
+			Expect(deployments.Items).To(HaveLen(0))
+		})
+
+		It("Should delete the custom image's xjoin-api-subgraph deployment", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				CustomSubgraphImages: []v1alpha1.CustomSubgraphImage{
+					{
+						Name:  "test-custom-image",
+						Image: "quay.io/test/custom-image",
+					},
+				},
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			deployment := &unstructured.Unstructured{}
+			deployment.SetGroupVersionKind(common.DeploymentGVK)
+			deploymentName := "test-index-pipeline-test-custom-image-1234"
+			deploymentLookup := types.NamespacedName{Name: deploymentName, Namespace: namespace}
+			err := k8sClient.Get(context.Background(), deploymentLookup, deployment)
+			checkError(err)
+			Expect(deployment.GetName()).To(Equal(deploymentName))
+
+			err = k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			deployments := &unstructured.UnstructuredList{}
+			deployments.SetGroupVersionKind(common.DeploymentGVK)
+			err = k8sClient.List(context.Background(), deployments, client.InNamespace(namespace))
+			checkError(err)
+			Expect(deployments.Items).To(HaveLen(0))
+		})
+
+		It("Should delete the custom image's graphql schema", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				CustomSubgraphImages: []v1alpha1.CustomSubgraphImage{
+					{
+						Name:  "test-custom-image",
+						Image: "quay.io/test/custom-image",
+					},
+				},
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			err := k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-test-custom-image.1234/versions"]
+			Expect(count).To(Equal(1))
+
+			count = info["DELETE http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-test-custom-image.1234"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should delete the Elasticsearch pipeline", func() {
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-json-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			err := k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://localhost:9200/_ingest/pipeline/xjoinindexpipeline.test-index-pipeline.1234"]
+			Expect(count).To(Equal(1))
+
+			count = info["DELETE http://localhost:9200/_ingest/pipeline/xjoinindexpipeline.test-index-pipeline.1234"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Should delete the XJoinIndexValidation resource", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			createdIndexPipeline := reconciler.ReconcileNew()
+
+			validators := &v1alpha1.XJoinIndexValidatorList{}
+			err := k8sClient.List(context.Background(), validators, client.InNamespace(namespace))
+			checkError(err)
+			Expect(validators.Items).To(HaveLen(1))
+
+			err = k8sClient.Delete(context.Background(), &createdIndexPipeline)
+			checkError(err)
+			reconciler.ReconcileDelete()
+
+			validators = &v1alpha1.XJoinIndexValidatorList{}
+			err = k8sClient.List(context.Background(), validators, client.InNamespace(namespace))
+			checkError(err)
+			Expect(validators.Items).To(HaveLen(0))
+		})
+	})
+
+	Context("Validation", func() {
+		It("Sets ValidationResult to invalid when at least one DataSourcePipeline is invalid", func() {
+			//create a valid datasource
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			//create the indexpipeline that references the valid datasource
+			indexPipelineReconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			indexPipelineReconciler.ReconcileNew()
+
+			//assert the indexpipeline's ValidationResponse status is valid
+			indexPipelineLookup := types.NamespacedName{Name: indexPipelineReconciler.GetName(), Namespace: namespace}
+			createdIndexPipeline := &v1alpha1.XJoinIndexPipeline{}
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), indexPipelineLookup, createdIndexPipeline)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(createdIndexPipeline.Status.ValidationResponse.Result).To(Equal(index.Valid))
+
+			//set the DatasourcePipeline to invalid
+			datasourcePipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName + "." + createdDataSource.Status.ActiveVersion,
+				K8sClient: k8sClient,
+			}
+			datasourcePipelineReconciler.ReconcileInvalid()
+
+			//assert the indexpipeline becomes invalid
+			updatedIndexPipeline := indexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "ENABLED",
+				GraphQLSchemaNewState:      "DISABLED",
+			})
+			Expect(updatedIndexPipeline.Status.ValidationResponse.Result).To(Equal(index.Invalid))
+		})
+
+		It("Sets ValidationResult to valid when all DataSourcePipelines are valid", func() {
+			//create a valid datasource
+			dataSourceName := "testdatasource"
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      dataSourceName,
+				K8sClient: k8sClient,
+			}
+			datasourceReconciler.ReconcileNew()
+			createdDataSource := datasourceReconciler.ReconcileValid()
+
+			//create the indexpipeline that references the valid datasource
+			indexPipelineReconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-pipeline",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     dataSourceName,
+					Version:                  createdDataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			indexPipelineReconciler.ReconcileNew()
+
+			//assert the indexpipeline's ValidationResponse status is valid
+			indexPipelineLookup := types.NamespacedName{Name: indexPipelineReconciler.GetName(), Namespace: namespace}
+			createdIndexPipeline := &v1alpha1.XJoinIndexPipeline{}
+
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), indexPipelineLookup, createdIndexPipeline)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+			Expect(createdIndexPipeline.Status.ValidationResponse.Result).To(Equal(index.Valid))
+		})
+
+		It("Initially disables the GraphQL schema", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			//assert the REST API call to disable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline.1234/meta"]
+			Expect(count).To(Equal(2))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline.1234/state <DisabledState>"]
+			Expect(count).To(Equal(2))
+		})
+
+		It("Enables the GraphQL schema when the pipeline becomes valid", func() {
+			resources := CreateValidIndexPipeline(namespace, nil)
+
+			//assert the indexpipeline is now active
+			Expect(resources.IndexPipeline.Status.Active).To(Equal(true))
+
+			//assert the REST API call to enable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+resources.IndexPipeline.Name+"/meta"]
+			Expect(count).To(Equal(1))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+resources.IndexPipeline.Name+"/state <EnabledState>"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Disables the GraphQL schema after refreshing", func() {
+			resources := CreateValidIndexPipeline(namespace, nil)
+			Expect(resources.IndexPipeline.Status.Active).To(Equal(true))
+
+			//set the datasource to invalid
+			datasourcePipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      resources.DataSource.Name + "." + resources.DataSource.Status.ActiveVersion,
+				K8sClient: k8sClient,
+			}
+			datasourcePipelineReconciler.ReconcileInvalid()
+			resources.DatasourceReconciler.reconcile()
+
+			//reconcile the index to trigger a refresh
+			resources.IndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "ENABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			updatedIndex := resources.IndexReconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.ActiveVersionIsValid).To(Equal(false))
+			Expect(updatedIndex.Status.RefreshingVersion).ToNot(Equal(""))
+
+			//set the refreshing version to valid
+			refreshingIndexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			indexPipelineLookup := types.NamespacedName{
+				Namespace: namespace,
+				Name:      updatedIndex.Name + "." + updatedIndex.Status.RefreshingVersion,
+			}
+			err := k8sClient.Get(context.Background(), indexPipelineLookup, refreshingIndexPipeline)
+			checkError(err)
+
+			refreshingIndexPipeline.Status.ValidationResponse.Result = index.Valid
+
+			err = k8sClient.Status().Update(context.Background(), refreshingIndexPipeline)
+			checkError(err)
+
+			//reconcile the index to set the refreshing version to active
+			updatedIndex = resources.IndexReconciler.ReconcileUpdated()
+
+			//reconcile the indexPipeline to enable the graphql schemas
+			newIndexPipelineReconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           updatedIndex.Name,
+				Version:        updatedIndex.Status.ActiveVersion,
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     resources.DataSource.Name,
+					Version:                  resources.DataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			newIndexPipeline := newIndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "DISABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			Expect(newIndexPipeline.Status.Active).To(Equal(true))
+
+			//assert the REST API call to enable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+newIndexPipeline.Name+"/meta"]
+			Expect(count).To(Equal(1))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+newIndexPipeline.Name+"/state <EnabledState>"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Initially disables the Custom GraphQL schemas", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				CustomSubgraphImages: []v1alpha1.CustomSubgraphImage{{
+					Name:  "test-custom-image",
+					Image: "quay.io/cloudservices/host-inventory-subgraph:latest",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			//assert the REST API call to disable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-test-custom-image.1234/meta"]
+			Expect(count).To(Equal(2))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-test-custom-image.1234/state <DisabledState>"]
+			Expect(count).To(Equal(2))
+		})
+
+		It("Enables the custom GraphQL schemas when the pipeline becomes valid", func() {
+			customImageName := "test-custom-image"
+			customSubgraphImages := []v1alpha1.CustomSubgraphImage{{
+				Name:  customImageName,
+				Image: "quay.io/cloudservices/host-inventory-subgraph:latest",
+			}}
+			resources := CreateValidIndexPipeline(namespace, customSubgraphImages)
+
+			//assert the indexpipeline is now active
+			Expect(resources.IndexPipeline.Status.Active).To(Equal(true))
+
+			//assert the REST API call to enable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+
+				resources.Index.Name+"-"+customImageName+"."+resources.Index.Status.RefreshingVersion+"/meta"]
+			Expect(count).To(Equal(1))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+
+				resources.Index.Name+"-"+customImageName+"."+resources.Index.Status.RefreshingVersion+"/state <EnabledState>"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Disables the Custom GraphQL schema after becoming invalid", func() {
+			customImageName := "test-custom-image"
+			customSubgraphImages := []v1alpha1.CustomSubgraphImage{{
+				Name:  customImageName,
+				Image: "quay.io/cloudservices/host-inventory-subgraph:latest",
+			}}
+			resources := CreateValidIndexPipeline(namespace, customSubgraphImages)
+			Expect(resources.IndexPipeline.Status.Active).To(Equal(true))
+
+			//set the datasource to invalid
+			datasourcePipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      resources.DataSource.Name + "." + resources.DataSource.Status.ActiveVersion,
+				K8sClient: k8sClient,
+			}
+			datasourcePipelineReconciler.ReconcileInvalid()
+			resources.DatasourceReconciler.reconcile()
+
+			//reconcile the index to trigger a refresh
+			resources.IndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "ENABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			updatedIndex := resources.IndexReconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.ActiveVersionIsValid).To(Equal(false))
+			Expect(updatedIndex.Status.RefreshingVersion).ToNot(Equal(""))
+
+			//set the refreshing version to valid
+			refreshingIndexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			indexPipelineLookup := types.NamespacedName{
+				Namespace: namespace,
+				Name:      updatedIndex.Name + "." + updatedIndex.Status.RefreshingVersion,
+			}
+			err := k8sClient.Get(context.Background(), indexPipelineLookup, refreshingIndexPipeline)
+			checkError(err)
+
+			refreshingIndexPipeline.Status.ValidationResponse.Result = index.Valid
+
+			err = k8sClient.Status().Update(context.Background(), refreshingIndexPipeline)
+			checkError(err)
+
+			//reconcile the index to set the refreshing version to active
+			updatedIndex = resources.IndexReconciler.ReconcileUpdated()
+
+			//reconcile the indexPipeline to enable the graphql schemas
+			newIndexPipelineReconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:            namespace,
+				Name:                 updatedIndex.Name,
+				Version:              updatedIndex.Status.ActiveVersion,
+				ConfigFileName:       "xjoinindex-with-referenced-field",
+				K8sClient:            k8sClient,
+				CustomSubgraphImages: customSubgraphImages,
+				DataSources: []DataSource{{
+					Name:                     resources.DataSource.Name,
+					Version:                  resources.DataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			newIndexPipeline := newIndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "DISABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			Expect(newIndexPipeline.Status.Active).To(Equal(true))
+
+			//assert the REST API call to enable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+
+				updatedIndex.Name+"-"+customImageName+"."+updatedIndex.Status.ActiveVersion+"/meta"]
+			Expect(count).To(Equal(1))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+
+				updatedIndex.Name+"-"+customImageName+"."+updatedIndex.Status.ActiveVersion+"/state <EnabledState>"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Initially disables the GraphQL schema", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.ReconcileNew()
+
+			//assert the REST API call to disable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline.1234/meta"]
+			Expect(count).To(Equal(2))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline.1234/state <DisabledState>"]
+			Expect(count).To(Equal(2))
+		})
+
+		It("Enables the GraphQL schema when the pipeline becomes valid", func() {
+			resources := CreateValidIndexPipeline(namespace, nil)
+
+			//assert the indexpipeline is now active
+			Expect(resources.IndexPipeline.Status.Active).To(Equal(true))
+
+			//assert the REST API call to enable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+resources.IndexPipeline.Name+"/meta"]
+			Expect(count).To(Equal(1))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+resources.IndexPipeline.Name+"/state <EnabledState>"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Disables the GraphQL schema after refreshing", func() {
+			resources := CreateValidIndexPipeline(namespace, nil)
+			Expect(resources.IndexPipeline.Status.Active).To(Equal(true))
+
+			//set the datasource to invalid
+			datasourcePipelineReconciler := DatasourcePipelineTestReconciler{
+				Namespace: namespace,
+				Name:      resources.DataSource.Name + "." + resources.DataSource.Status.ActiveVersion,
+				K8sClient: k8sClient,
+			}
+			datasourcePipelineReconciler.ReconcileInvalid()
+			resources.DatasourceReconciler.reconcile()
+
+			//reconcile the index to trigger a refresh
+			resources.IndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "ENABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			updatedIndex := resources.IndexReconciler.ReconcileUpdated()
+			Expect(updatedIndex.Status.ActiveVersionIsValid).To(Equal(false))
+			Expect(updatedIndex.Status.RefreshingVersion).ToNot(Equal(""))
+
+			//set the refreshing version to valid
+			refreshingIndexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			indexPipelineLookup := types.NamespacedName{
+				Namespace: namespace,
+				Name:      updatedIndex.Name + "." + updatedIndex.Status.RefreshingVersion,
+			}
+			err := k8sClient.Get(context.Background(), indexPipelineLookup, refreshingIndexPipeline)
+			checkError(err)
+
+			refreshingIndexPipeline.Status.ValidationResponse.Result = index.Valid
+
+			err = k8sClient.Status().Update(context.Background(), refreshingIndexPipeline)
+			checkError(err)
+
+			//reconcile the index to set the refreshing version to active
+			updatedIndex = resources.IndexReconciler.ReconcileUpdated()
+
+			//reconcile the indexPipeline to enable the graphql schemas
+			newIndexPipelineReconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           updatedIndex.Name,
+				Version:        updatedIndex.Status.ActiveVersion,
+				ConfigFileName: "xjoinindex-with-referenced-field",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     resources.DataSource.Name,
+					Version:                  resources.DataSource.Status.ActiveVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			newIndexPipeline := newIndexPipelineReconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "DISABLED",
+				GraphQLSchemaNewState:      "ENABLED",
+			})
+			Expect(newIndexPipeline.Status.Active).To(Equal(true))
+
+			//assert the REST API call to enable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+newIndexPipeline.Name+"/meta"]
+			Expect(count).To(Equal(1))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+newIndexPipeline.Name+"/state <EnabledState>"]
+			Expect(count).To(Equal(1))
+		})
+
+		It("Initially disables the Custom GraphQL schemas", func() {
+			name := "test-index-pipeline"
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				CustomSubgraphImages: []v1alpha1.CustomSubgraphImage{{
+					Name:  "test-custom-image",
+					Image: "quay.io/cloudservices/host-inventory-subgraph:latest",
+				}},
+			}
+			reconciler.ReconcileNew()
+
+			//assert the REST API call to disable the schema was made
+			info := httpmock.GetCallCountInfo()
+			count := info["GET http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-test-custom-image.1234/meta"]
+			Expect(count).To(Equal(2))
+			count = info["PUT http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-test-custom-image.1234/state <DisabledState>"]
+			Expect(count).To(Equal(2))
+		})
+	})
+})
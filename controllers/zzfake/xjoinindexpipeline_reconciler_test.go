@@ -0,0 +1,817 @@
+package zzfake
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/schemaregistry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type XJoinIndexPipelineTestReconciler struct {
+	Namespace            string
+	Name                 string
+	Version              string
+	ConfigFileName       string
+	CustomSubgraphImages []v1alpha1.CustomSubgraphImage
+	XJoinCore            *v1alpha1.DeploymentSpec
+	XJoinAPISubGraph     *v1alpha1.XJoinAPISubGraphSpec
+	Sink                 *v1alpha1.SinkSpec
+	Validation           *v1alpha1.ValidationSpec
+	K8sClient            client.Client
+	DataSources          []DataSource
+	DataSourceSpecs      []v1alpha1.DataSourceVersionSpec
+	RegistrySubjects     []RegistrySubject
+	createdIndexPipeline v1alpha1.XJoinIndexPipeline
+	indexRequestBody     string
+	indexCreated         bool
+	pipelineRequestBody  string
+	graphQLSchemaBody    string
+	avroSchemaBody       string
+	graphQLSchemaLabels  map[string][]string
+	graphQLSchemaPushed  string //the latest version of each graphql schema pushed by the subgraph pods
+}
+
+type DataSource struct {
+	Name                     string
+	Version                  string
+	ApiCurioResponseFilename string
+}
+
+// RegistrySubject is a schema registry subject referenced by a data source schema
+type RegistrySubject struct {
+	Subject                  string
+	Version                  string
+	ApiCurioResponseFilename string
+}
+
+func (x *XJoinIndexPipelineTestReconciler) GetName() string {
+	return x.Name + "." + x.Version
+}
+
+func (x *XJoinIndexPipelineTestReconciler) ReconcileNew() v1alpha1.XJoinIndexPipeline {
+	x.createValidIndexPipeline()
+	return x.ReconcileCreate()
+}
+
+// ReconcileCreate reconciles an XJoinIndexPipeline whose components don't exist yet e.g. a pipeline created by its
+// XJoinIndex. The mocks record the created resources so later reconciles read them back.
+func (x *XJoinIndexPipelineTestReconciler) ReconcileCreate() v1alpha1.XJoinIndexPipeline {
+	x.registerNewMocks()
+	result := x.reconcile()
+	Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 30000000000}))
+	indexPipelineLookupKey := types.NamespacedName{Name: x.GetName(), Namespace: x.Namespace}
+	Eventually(func() bool {
+		err := x.K8sClient.Get(context.Background(), indexPipelineLookupKey, &x.createdIndexPipeline)
+		return err == nil
+	}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+	return x.createdIndexPipeline
+}
+
+func (x *XJoinIndexPipelineTestReconciler) ReconcileUpdated(params UpdatedMocksParams) v1alpha1.XJoinIndexPipeline {
+	x.registerUpdatedMocks(params)
+	result := x.reconcile()
+	Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 30000000000}))
+	indexPipelineLookup := types.NamespacedName{Name: x.GetName(), Namespace: x.Namespace}
+	Eventually(func() bool {
+		err := x.K8sClient.Get(context.Background(), indexPipelineLookup, &x.createdIndexPipeline)
+		return err == nil
+	}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+	return x.createdIndexPipeline
+}
+
+func (x *XJoinIndexPipelineTestReconciler) ReconcileDelete() {
+	x.registerDeleteMocks()
+	result := x.reconcile()
+	Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 0}))
+
+	indexPipelineList := v1alpha1.XJoinIndexPipelineList{}
+	err := x.K8sClient.List(context.Background(), &indexPipelineList, client.InNamespace(x.Namespace))
+	checkError(err)
+	Expect(indexPipelineList.Items).To(HaveLen(0))
+}
+
+func (x *XJoinIndexPipelineTestReconciler) reconcile() reconcile.Result {
+	ctx := context.Background()
+	xjoinIndexPipelineReconciler := x.newXJoinIndexPipelineReconciler()
+	indexLookupKey := types.NamespacedName{Name: x.GetName(), Namespace: x.Namespace}
+	result, err := xjoinIndexPipelineReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: indexLookupKey})
+	checkError(err)
+	return result
+}
+
+func (x *XJoinIndexPipelineTestReconciler) registerDeleteMocks() {
+	httpmock.Reset()
+	httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip) //disable mocks for unregistered http requests
+
+	//connector mocks
+	httpmock.RegisterResponder(
+		"GET",
+		"http://connect-connect-api."+x.Namespace+".svc:8083/connectors/xjoinindexpipeline."+x.GetName(),
+		httpmock.NewStringResponder(404, `{}`))
+
+	//gql schema mocks
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"/versions/1",
+		httpmock.NewStringResponder(200, `{}`))
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"/versions/latest",
+		httpmock.NewStringResponder(200, `{}`))
+	httpmock.RegisterResponder(
+		"DELETE",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName(),
+		httpmock.NewStringResponder(200, `{}`))
+
+	//graphql schema state update mocks
+	getMetaResponder, err := httpmock.NewJsonResponder(200, map[string]string{"state": "ENABLED"})
+	checkError(err)
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
+		getMetaResponder)
+
+	//elasticsearch index mocks
+	httpmock.RegisterResponder(
+		"HEAD",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		httpmock.NewStringResponder(200, `{}`))
+	httpmock.RegisterResponder(
+		"DELETE",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		httpmock.NewStringResponder(200, `{}`))
+
+	//avro schema mocks
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/1",
+		httpmock.NewStringResponder(200, `{}`))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/latest",
+		httpmock.NewStringResponder(200, `{}`))
+
+	httpmock.RegisterResponder(
+		"DELETE",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value",
+		httpmock.NewStringResponder(200, `{}`))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/versions",
+		httpmock.NewStringResponder(404, `{}`))
+
+	for _, customImage := range x.CustomSubgraphImages {
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-"+customImage.Name+"."+x.Version+"/versions",
+			httpmock.NewStringResponder(200, `{}`).Once())
+
+		httpmock.RegisterResponder(
+			"DELETE",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline.test-index-pipeline-"+customImage.Name+"."+x.Version,
+			httpmock.NewStringResponder(200, `{}`).Once())
+	}
+
+	x.registerSubjectMocks()
+
+	for _, dataSource := range x.DataSources {
+		response, err := os.ReadFile("./test/data/apicurio/" + dataSource.ApiCurioResponseFilename + ".json")
+		checkError(err)
+		//later reconciles fetch the schema version recorded in the status
+		for _, version := range []string{"latest", "1"} {
+			httpmock.RegisterResponder(
+				"GET",
+				"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/"+version,
+				httpmock.NewStringResponder(200, string(response)))
+		}
+
+		httpmock.RegisterResponder(
+			"GET",
+			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
+			httpmock.NewStringResponder(200, "{}").Once())
+
+		httpmock.RegisterResponder(
+			"DELETE",
+			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
+			httpmock.NewStringResponder(200, "{}").Once())
+	}
+}
+
+func (x *XJoinIndexPipelineTestReconciler) registerNewMocks() {
+	httpmock.Reset()
+	httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip) //disable mocks for unregistered http requests
+
+	//elasticsearch index mocks
+	httpmock.RegisterResponder(
+		"HEAD",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		x.elasticsearchIndexExistsResponder())
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		x.elasticsearchIndexResponder())
+
+	httpmock.RegisterResponder(
+		"PUT",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		recordRequestBody(&x.indexRequestBody, func(req *http.Request) (*http.Response, error) {
+			x.indexCreated = true
+			return httpmock.NewStringResponse(201, `{}`), nil
+		}))
+
+	//avro schema mocks
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/1",
+		httpmock.NewStringResponder(404, `{"message":"No version '1' found for artifact with ID 'xjoinindexpipelinepipeline.`+x.GetName()+`-value' in group 'null'.","error_code":40402}`))
+
+	httpmock.RegisterResponder(
+		"POST",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions",
+		recordRequestBody(&x.avroSchemaBody, httpmock.NewStringResponder(200, `{"createdBy":"","createdOn":"2022-07-27T17:28:11+0000","modifiedBy":"","modifiedOn":"2022-07-27T17:28:11+0000","id":1,"version":1,"type":"AVRO","globalId":1,"state":"ENABLED","groupId":"null","contentId":1,"references":[]}`)))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/schemas/ids/1",
+		httpmock.NewStringResponder(200, `{"schema":"{\"name\":\"Value\",\"namespace\":\"xjoindatasourcepipeline.`+x.GetName()+`\"}","schemaType":"AVRO","references":[]}`))
+
+	//graphql schema state update mocks
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
+		x.graphQLSchemaMetaResponder("xjoinindexpipeline."+x.GetName(), "ENABLED"))
+
+	httpmock.RegisterMatcherResponder(
+		"PUT",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/state",
+		httpmock.BodyContainsString(`"state":"DISABLED"`).WithName("DisabledState"),
+		httpmock.NewStringResponder(200, `{}`))
+
+	//graphql schema mocks
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/versions",
+		httpmock.NewStringResponder(404, `{}`))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName(),
+		x.graphQLSchemaResponder())
+
+	httpmock.RegisterResponder(
+		"POST",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts",
+		x.recordGraphQLSchema(httpmock.NewStringResponder(201, `{}`)))
+
+	httpmock.RegisterResponder(
+		"PUT",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
+		x.recordGraphQLSchemaLabels("xjoinindexpipeline."+x.GetName(), httpmock.NewStringResponder(200, `{}`)))
+
+	httpmock.RegisterResponder(
+		"POST",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
+		x.graphQLSchemaVersionResponder("xjoinindexpipeline."+x.GetName()))
+
+	for _, customImage := range x.CustomSubgraphImages {
+		//custom subgraph graphql schema mocks
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/versions",
+			httpmock.NewStringResponder(404, `{}`))
+
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version,
+			x.customGraphQLSchemaResponder())
+
+		httpmock.RegisterResponder(
+			"POST",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts",
+			x.recordGraphQLSchema(httpmock.NewStringResponder(201, `{}`)))
+
+		customSchemaName := "xjoinindexpipeline." + x.Name + "-" + customImage.Name + "." + x.Version
+		httpmock.RegisterResponder(
+			"PUT",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/"+customSchemaName+"/meta",
+			x.recordGraphQLSchemaLabels(customSchemaName, httpmock.NewStringResponder(200, `{}`)))
+
+		httpmock.RegisterResponder(
+			"POST",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/"+customSchemaName+"/meta",
+			x.graphQLSchemaVersionResponder(customSchemaName))
+
+		//graphql schema state update mocks
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/"+customSchemaName+"/meta",
+			x.graphQLSchemaMetaResponder(customSchemaName, "ENABLED"))
+
+		httpmock.RegisterMatcherResponder(
+			"PUT",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/state",
+			httpmock.BodyContainsString(`"state":"DISABLED"`).WithName("DisabledState"),
+			httpmock.NewStringResponder(200, `{}`))
+	}
+
+	x.registerSubjectMocks()
+
+	for _, dataSource := range x.DataSources {
+		response, err := os.ReadFile("./test/data/apicurio/" + dataSource.ApiCurioResponseFilename + ".json")
+		checkError(err)
+		//later reconciles fetch the schema version recorded in the status
+		for _, version := range []string{"latest", "1"} {
+			httpmock.RegisterResponder(
+				"GET",
+				"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/"+version,
+				httpmock.NewStringResponder(200, string(response)))
+		}
+
+		httpmock.RegisterResponder(
+			"GET",
+			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
+			x.elasticsearchPipelineResponder())
+
+		httpmock.RegisterResponder(
+			"PUT",
+			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
+			recordRequestBody(&x.pipelineRequestBody, httpmock.NewStringResponder(200, "{}")))
+	}
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/latest",
+		x.avroSchemaResponder())
+}
+
+func (x *XJoinIndexPipelineTestReconciler) registerUpdatedMocks(params UpdatedMocksParams) {
+	httpmock.Reset()
+	httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip) //disable mocks for unregistered http requests
+
+	//elasticsearch mocks
+	httpmock.RegisterResponder(
+		"HEAD",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		httpmock.NewStringResponder(200, `{}`))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://localhost:9200/xjoinindexpipeline."+x.GetName(),
+		x.elasticsearchIndexResponder())
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://localhost:9200/_cat/indices/"+x.Name+".%2A",
+		httpmock.NewStringResponder(200, "[]"))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://localhost:9200/_ingest/pipeline/"+x.Name+"%2A",
+		httpmock.NewStringResponder(200, "{}"))
+
+	//graphql schema state update mocks
+	var getMetaResponder httpmock.Responder
+	var err error
+	if params.GraphQLSchemaExistingState == "ENABLED" {
+		getMetaResponder, err = httpmock.NewJsonResponder(200, map[string]string{"state": "ENABLED"})
+		checkError(err)
+	} else {
+		getMetaResponder, err = httpmock.NewJsonResponder(200, map[string]string{"state": "DISABLED"})
+		checkError(err)
+	}
+
+	var matcher httpmock.Matcher
+	if params.GraphQLSchemaNewState == "ENABLED" {
+		matcher = httpmock.BodyContainsString(`"state":"ENABLED"`).WithName("EnabledState")
+	} else {
+		matcher = httpmock.BodyContainsString(`"state":"DISABLED"`).WithName("DisabledState")
+	}
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
+		x.graphQLSchemaMetaResponder("xjoinindexpipeline."+x.GetName(), params.GraphQLSchemaExistingState))
+
+	httpmock.RegisterResponder(
+		"POST",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/meta",
+		x.graphQLSchemaVersionResponder("xjoinindexpipeline."+x.GetName()))
+
+	httpmock.RegisterMatcherResponder(
+		"PUT",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/state",
+		matcher,
+		httpmock.NewStringResponder(200, `{}`))
+
+	//avro schema mocks
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/1",
+		httpmock.NewStringResponder(200, `{}`))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects",
+		httpmock.NewStringResponder(200, `[]`))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName()+"/versions",
+		httpmock.NewStringResponder(200, `{}`))
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.GetName(),
+		x.graphQLSchemaResponder())
+
+	responder, err := httpmock.NewJsonResponder(200, httpmock.File("./test/data/apicurio/empty-response.json"))
+	checkError(err)
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/registry/v2/search/artifacts?limit=500&labels=graphql",
+		responder)
+
+	for _, customImage := range x.CustomSubgraphImages {
+		//custom subgraph graphql schema mocks
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/versions",
+			getMetaResponder)
+
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version,
+			x.customGraphQLSchemaResponder())
+
+		httpmock.RegisterResponder(
+			"POST",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts",
+			x.recordGraphQLSchema(httpmock.NewStringResponder(201, `{}`)))
+
+		httpmock.RegisterResponder(
+			"PUT",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/meta",
+			httpmock.NewStringResponder(200, `{}`))
+
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/meta",
+			x.graphQLSchemaMetaResponder(
+				"xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version, params.GraphQLSchemaExistingState))
+
+		httpmock.RegisterResponder(
+			"POST",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/meta",
+			x.graphQLSchemaVersionResponder("xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version))
+
+		httpmock.RegisterMatcherResponder(
+			"PUT",
+			"http://apicurio:1080/apis/registry/v2/groups/default/artifacts/xjoinindexpipeline."+x.Name+"-"+customImage.Name+"."+x.Version+"/state",
+			matcher,
+			httpmock.NewStringResponder(200, `{}`))
+	}
+
+	x.registerSubjectMocks()
+
+	for _, dataSource := range x.DataSources {
+		response, err := os.ReadFile("./test/data/apicurio/" + dataSource.ApiCurioResponseFilename + ".json")
+		checkError(err)
+		//later reconciles fetch the schema version recorded in the status
+		for _, version := range []string{"latest", "1"} {
+			httpmock.RegisterResponder(
+				"GET",
+				"http://apicurio:1080/apis/ccompat/v6/subjects/xjoindatasourcepipeline."+dataSource.Name+"."+dataSource.Version+"-value/versions/"+version,
+				httpmock.NewStringResponder(200, string(response)))
+		}
+
+		httpmock.RegisterResponder(
+			"GET",
+			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
+			x.elasticsearchPipelineResponder())
+
+		httpmock.RegisterResponder(
+			"PUT",
+			"http://localhost:9200/_ingest/pipeline/xjoinindexpipeline."+x.GetName(),
+			recordRequestBody(&x.pipelineRequestBody, httpmock.NewStringResponder(200, "{}")))
+	}
+
+	httpmock.RegisterResponder(
+		"GET",
+		"http://apicurio:1080/apis/ccompat/v6/subjects/xjoinindexpipeline."+x.GetName()+"-value/versions/latest",
+		x.avroSchemaResponder())
+}
+
+func (x *XJoinIndexPipelineTestReconciler) newXJoinIndexPipelineReconciler() *controllers.XJoinIndexPipelineReconciler {
+	return controllers.NewXJoinIndexPipelineReconciler(
+		x.K8sClient,
+		scheme.Scheme,
+		testLogger,
+		record.NewFakeRecorder(10),
+		x.Namespace,
+		true)
+}
+
+func (x *XJoinIndexPipelineTestReconciler) createValidIndexPipeline() {
+	ctx := context.Background()
+	indexAvroSchema, err := os.ReadFile("./test/data/avro/" + x.ConfigFileName + ".json")
+	checkError(err)
+	xjoinIndexName := "test-xjoin-index"
+
+	//XjoinIndexPipeline requires an XJoinIndex owner. Create one here
+	indexSpec := v1alpha1.XJoinIndexSpec{
+		AvroSchema:           string(indexAvroSchema),
+		Pause:                false,
+		CustomSubgraphImages: x.CustomSubgraphImages,
+		XJoinCore:            x.XJoinCore,
+		XJoinAPISubGraph:     x.XJoinAPISubGraph,
+		Sink:                 x.Sink,
+		DataSources:          x.DataSourceSpecs,
+		Validation:           x.Validation,
+	}
+
+	index := &v1alpha1.XJoinIndex{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      xjoinIndexName,
+			Namespace: x.Namespace,
+		},
+		Spec: indexSpec,
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "xjoin.cloud.redhat.com/v1alpha1",
+			Kind:       "XJoinIndex",
+		},
+	}
+
+	Expect(x.K8sClient.Create(ctx, index)).Should(Succeed())
+
+	//create the XJoinIndexPipeline
+	indexPipelineSpec := v1alpha1.XJoinIndexPipelineSpec{
+		Name:                 x.Name,
+		Version:              x.Version,
+		AvroSchema:           string(indexAvroSchema),
+		Pause:                false,
+		CustomSubgraphImages: x.CustomSubgraphImages,
+		XJoinCore:            x.XJoinCore,
+		XJoinAPISubGraph:     x.XJoinAPISubGraph,
+		Sink:                 x.Sink,
+		DataSources:          x.DataSourceSpecs,
+		Validation:           x.Validation,
+	}
+
+	blockOwnerDeletion := true
+	controller := true
+	indexPipeline := &v1alpha1.XJoinIndexPipeline{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      x.GetName(),
+			Namespace: x.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         common.IndexGVK.Version,
+					Kind:               common.IndexGVK.Kind,
+					Name:               xjoinIndexName,
+					Controller:         &controller,
+					BlockOwnerDeletion: &blockOwnerDeletion,
+					UID:                "a6778b9b-dfed-4d41-af53-5ebbcddb7535",
+				},
+			},
+		},
+		Spec: indexPipelineSpec,
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "xjoin.cloud.redhat.com/v1alpha1",
+			Kind:       "XJoinIndexPipeline",
+		},
+	}
+
+	Expect(x.K8sClient.Create(ctx, indexPipeline)).Should(Succeed())
+
+	//validate indexPipeline spec is created correctly
+	indexPipelineLookupKey := types.NamespacedName{Name: x.GetName(), Namespace: x.Namespace}
+	createdIndexPipeline := &v1alpha1.XJoinIndexPipeline{}
+
+	Eventually(func() bool {
+		err := x.K8sClient.Get(ctx, indexPipelineLookupKey, createdIndexPipeline)
+		return err == nil
+	}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+
+	Expect(createdIndexPipeline.Spec.Name).Should(Equal(x.Name))
+	Expect(createdIndexPipeline.Spec.Version).Should(Equal(x.Version))
+	Expect(createdIndexPipeline.Spec.Pause).Should(Equal(false))
+	Expect(createdIndexPipeline.Spec.AvroSchema).Should(Equal(string(indexAvroSchema)))
+	Expect(createdIndexPipeline.Spec.CustomSubgraphImages).Should(Equal(x.CustomSubgraphImages))
+}
+
+func (x *XJoinIndexPipelineTestReconciler) registerSubjectMocks() {
+	for _, subject := range x.RegistrySubjects {
+		response, err := os.ReadFile("./test/data/apicurio/" + subject.ApiCurioResponseFilename + ".json")
+		checkError(err)
+		httpmock.RegisterResponder(
+			"GET",
+			"http://apicurio:1080/apis/ccompat/v6/subjects/"+subject.Subject+"/versions/"+subject.Version,
+			httpmock.NewStringResponder(200, string(response)))
+	}
+}
+
+// setIndexTemplate sets the elasticsearch.index.template of the namespace's xjoin-generic ConfigMap
+func setIndexTemplate(namespace string, template string) {
+	configMap := &corev1.ConfigMap{}
+	err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "xjoin-generic", Namespace: namespace}, configMap)
+	checkError(err)
+	configMap.Data["elasticsearch.index.template"] = template
+	err = k8sClient.Update(context.Background(), configMap)
+	checkError(err)
+}
+
+// setTypeMapping sets the elasticsearch.type.mapping of the namespace's xjoin-generic ConfigMap
+func setTypeMapping(namespace string, typeMapping string) {
+	configMap := &corev1.ConfigMap{}
+	err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "xjoin-generic", Namespace: namespace}, configMap)
+	checkError(err)
+	configMap.Data["elasticsearch.type.mapping"] = typeMapping
+	err = k8sClient.Update(context.Background(), configMap)
+	checkError(err)
+}
+
+// recordGraphQLSchema records the content of the graphql schema registered for the pipeline, the schemas of the custom
+// subgraphs are registered with the same request
+func (x *XJoinIndexPipelineTestReconciler) recordGraphQLSchema(responder httpmock.Responder) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Registry-ArtifactId") == "xjoinindexpipeline."+x.GetName() {
+			requestBody, err := io.ReadAll(req.Body)
+			checkError(err)
+			x.graphQLSchemaBody = string(requestBody)
+		}
+		return responder(req)
+	}
+}
+
+// graphQLSchemaResponder returns the content of the latest version of the graphql schema
+func (x *XJoinIndexPipelineTestReconciler) graphQLSchemaResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if x.graphQLSchemaPushed != "" {
+			return httpmock.NewStringResponse(200, x.graphQLSchemaPushed), nil
+		}
+		if x.graphQLSchemaBody == "" {
+			return httpmock.NewStringResponse(200, schemaregistry.DefaultGraphQLSchema), nil
+		}
+		return httpmock.NewStringResponse(200, x.graphQLSchemaBody), nil
+	}
+}
+
+// customGraphQLSchemaResponder returns the content of the latest version of the graphql schema of a custom subgraph,
+// the operator registers the placeholder
+func (x *XJoinIndexPipelineTestReconciler) customGraphQLSchemaResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if x.graphQLSchemaPushed != "" {
+			return httpmock.NewStringResponse(200, x.graphQLSchemaPushed), nil
+		}
+		return httpmock.NewStringResponse(200, schemaregistry.DefaultGraphQLSchema), nil
+	}
+}
+
+// graphQLSchemaVersionResponder finds the version of a graphql schema that has the content of the request, the
+// operator registers the generated schema for the pipeline and the placeholder for the custom subgraphs
+func (x *XJoinIndexPipelineTestReconciler) graphQLSchemaVersionResponder(name string) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		content, err := io.ReadAll(req.Body)
+		checkError(err)
+
+		registered := schemaregistry.DefaultGraphQLSchema
+		if name == "xjoinindexpipeline."+x.GetName() && x.graphQLSchemaBody != "" {
+			registered = x.graphQLSchemaBody
+		}
+		if string(content) != registered {
+			return httpmock.NewStringResponse(404, `{"error_code":404,"message":"No version found"}`), nil
+		}
+		return httpmock.NewJsonResponse(200, map[string]interface{}{
+			"version": "1",
+			"labels":  x.graphQLSchemaLabels[name],
+		})
+	}
+}
+
+// recordGraphQLSchemaLabels records the labels put on the metadata of a graphql schema
+func (x *XJoinIndexPipelineTestReconciler) recordGraphQLSchemaLabels(
+	name string, responder httpmock.Responder) httpmock.Responder {
+
+	return func(req *http.Request) (*http.Response, error) {
+		var meta struct {
+			Labels []string `json:"labels"`
+		}
+		err := json.NewDecoder(req.Body).Decode(&meta)
+		checkError(err)
+		if x.graphQLSchemaLabels == nil {
+			x.graphQLSchemaLabels = make(map[string][]string)
+		}
+		x.graphQLSchemaLabels[name] = meta.Labels
+		return responder(req)
+	}
+}
+
+// graphQLSchemaMetaResponder returns the metadata of a graphql schema with the recorded labels
+func (x *XJoinIndexPipelineTestReconciler) graphQLSchemaMetaResponder(name string, state string) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewJsonResponse(200, map[string]interface{}{
+			"state":  state,
+			"labels": x.graphQLSchemaLabels[name],
+		})
+	}
+}
+
+// avroSchemaResponder returns the latest version of the registered avro schema
+func (x *XJoinIndexPipelineTestReconciler) avroSchemaResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if x.avroSchemaBody == "" {
+			return httpmock.NewStringResponse(200, `{}`), nil
+		}
+
+		var registered map[string]interface{}
+		err := json.Unmarshal([]byte(x.avroSchemaBody), &registered)
+		checkError(err)
+		registered["subject"] = "xjoinindexpipeline." + x.GetName() + "-value"
+		registered["version"] = 1
+		registered["id"] = 1
+		return httpmock.NewJsonResponse(200, registered)
+	}
+}
+
+// elasticsearchIndexExistsResponder responds to the existence check of the index once it is created
+func (x *XJoinIndexPipelineTestReconciler) elasticsearchIndexExistsResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if !x.indexCreated {
+			return httpmock.NewStringResponse(404, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{}`), nil
+	}
+}
+
+// elasticsearchIndexResponder returns the mappings and settings of the created index, the settings are nested under
+// index like Elasticsearch does
+func (x *XJoinIndexPipelineTestReconciler) elasticsearchIndexResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		var created struct {
+			Mappings map[string]interface{} `json:"mappings"`
+			Settings map[string]interface{} `json:"settings"`
+		}
+		if x.indexRequestBody != "" {
+			err := json.Unmarshal([]byte(x.indexRequestBody), &created)
+			checkError(err)
+		}
+
+		indexSettings := make(map[string]interface{})
+		for key, value := range created.Settings {
+			if nested, ok := value.(map[string]interface{}); ok && key == "index" {
+				for nestedKey, nestedValue := range nested {
+					indexSettings[nestedKey] = nestedValue
+				}
+			} else {
+				indexSettings[key] = value
+			}
+		}
+
+		return httpmock.NewJsonResponse(200, map[string]interface{}{
+			"xjoinindexpipeline." + x.GetName(): map[string]interface{}{
+				"mappings": created.Mappings,
+				"settings": map[string]interface{}{"index": indexSettings},
+			},
+		})
+	}
+}
+
+// elasticsearchPipelineResponder returns the created elasticsearch pipeline
+func (x *XJoinIndexPipelineTestReconciler) elasticsearchPipelineResponder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if x.pipelineRequestBody == "" {
+			return httpmock.NewStringResponse(404, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200,
+			`{"xjoinindexpipeline.`+x.GetName()+`":`+x.pipelineRequestBody+`}`), nil
+	}
+}
+
+// recordRequestBody stores the body of the request in body before responding
+func recordRequestBody(body *string, responder httpmock.Responder) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		requestBody, err := io.ReadAll(req.Body)
+		checkError(err)
+		*body = string(requestBody)
+		return responder(req)
+	}
+}
@@ -0,0 +1,580 @@
+package zzfake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	validation "github.com/redhatinsights/xjoin-go-lib/pkg/validation"
+	"github.com/redhatinsights/xjoin-operator/api/v1alpha1"
+	"github.com/redhatinsights/xjoin-operator/controllers/common"
+	"github.com/redhatinsights/xjoin-operator/controllers/index"
+	"github.com/redhatinsights/xjoin-operator/controllers/parameters"
+	"github.com/redhatinsights/xjoin-operator/controllers/validator"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+	"time"
+	//+kubebuilder:scaffold:imports
+)
+
+var _ = Describe("XJoinIndexValidator", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		httpmock.Activate()
+		httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip) //disable mocks for unregistered http requests
+
+		var err error
+		namespace, err = NewNamespace()
+		checkError(err)
+		setValidationMode(namespace, index.ValidationModePod)
+	})
+
+	AfterEach(func() {
+		httpmock.DeactivateAndReset()
+	})
+
+	Context("Reconcile Creation", func() {
+		It("Should add a finalizer to the XJoinIndexValidator", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-validator",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			createdIndexValidator, _ := reconciler.ReconcileCreate()
+			Expect(createdIndexValidator.Finalizers).To(HaveLen(1))
+			Expect(createdIndexValidator.Finalizers).To(ContainElement(index.XJoinIndexValidatorFinalizer))
+		})
+
+		It("Should create an xjoin-validation pod", func() {
+			configFileName := "xjoinindex-with-referenced-field"
+			name := "test-index-validator"
+
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           name,
+				Version:        "1234",
+				ConfigFileName: configFileName,
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+
+			pods := reconciler.ListValidatorPods()
+			Expect(len(pods.Items)).To(Equal(1))
+			pod := pods.Items[0]
+
+			Expect(pod.Name).To(Equal(strings.ReplaceAll(reconciler.GetName(), ".", "-")))
+			Expect(pod.Status.Phase).To(Equal(corev1.PodPending))
+			Expect(pod.ObjectMeta.Labels).To(Equal(map[string]string{
+				common.COMPONENT_NAME_LABEL: "XJoinIndexValidator",
+				"xjoin.index":               reconciler.GetName(),
+			}))
+
+			Expect(pod.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+			credentialsSecretName := strings.ReplaceAll(reconciler.GetName(), ".", "-") + "-credentials"
+
+			Expect(pod.Spec.Containers).To(HaveLen(1))
+			Expect(pod.Spec.Containers[0].Name).To(Equal(strings.ReplaceAll(reconciler.GetName(), ".", "-")))
+			Expect(pod.Spec.Containers[0].Image).To(Equal("quay.io/cloudservices/xjoin-validation:latest"))
+			Expect(pod.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullAlways))
+
+			//elasticsearch connection environment variables
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "ELASTICSEARCH_HOST_URL",
+				Value: "",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "xjoin-elasticsearch",
+						},
+						Key:      "endpoint",
+						Optional: nil,
+					},
+				},
+			}))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "ELASTICSEARCH_USERNAME",
+				Value: "",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "xjoin-elasticsearch",
+						},
+						Key:      "username",
+						Optional: nil,
+					},
+				},
+			}))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "ELASTICSEARCH_PASSWORD",
+				Value: "",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "xjoin-elasticsearch",
+						},
+						Key:      "password",
+						Optional: nil,
+					},
+				},
+			}))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "VALIDATION_OUTPUT_MAX_BYTES",
+				Value: "4096",
+			}))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "VALIDATION_OUTPUT_MAX_IDS",
+				Value: "1000",
+			}))
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:      "ELASTICSEARCH_INDEX",
+				Value:     "xjoinindexpipeline." + reconciler.GetName(),
+				ValueFrom: nil,
+			}))
+
+			//avro environment variables
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:      "FULL_AVRO_SCHEMA",
+				Value:     "{\"type\":\"record\",\"name\":\"Value\",\"namespace\":\"test-index-validator.1234\",\"fields\":[{\"name\":\"testdatasource\",\"type\":{\"name\":\"testdatasource.Value\",\"xjoin.type\":\"reference\"}}]}",
+				ValueFrom: nil,
+			}))
+
+			//database environment variables
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:      "testdatasource_DB_TABLE",
+				Value:     "dbTable",
+				ValueFrom: nil,
+			}))
+
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:      "testdatasource_DB_PORT",
+				Value:     "8080",
+				ValueFrom: nil,
+			}))
+
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:      "testdatasource_DB_NAME",
+				Value:     "dbName",
+				ValueFrom: nil,
+			}))
+
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "testdatasource_DB_PASSWORD",
+				Value: "",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: credentialsSecretName,
+						},
+						Key: "testdatasource_DB_PASSWORD",
+					},
+				},
+			}))
+
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "testdatasource_DB_USERNAME",
+				Value: "",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: credentialsSecretName,
+						},
+						Key: "testdatasource_DB_USERNAME",
+					},
+				},
+			}))
+
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:      "testdatasource_DB_HOSTNAME",
+				Value:     "dbHost",
+				ValueFrom: nil,
+			}))
+
+			//database credentials are read from a secret owned by the validator
+			credentialsSecret := &corev1.Secret{}
+			err := k8sClient.Get(context.Background(),
+				client.ObjectKey{Name: credentialsSecretName, Namespace: namespace}, credentialsSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(credentialsSecret.Data).To(Equal(map[string][]byte{
+				"testdatasource_DB_USERNAME": []byte("dbUsername"),
+				"testdatasource_DB_PASSWORD": []byte("dbPassword"),
+			}))
+			Expect(credentialsSecret.OwnerReferences).To(HaveLen(1))
+			Expect(credentialsSecret.OwnerReferences[0].Kind).To(Equal("XJoinIndexValidator"))
+		})
+
+		It("Should requeue after ValidationPodStatusInterval", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-validator",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			_, result := reconciler.ReconcileCreate()
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 5 * time.Second}))
+		})
+	})
+
+	Context("Reconcile Pod Running", func() {
+		It("Should requeue after ValidationPodStatusInterval", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-validator",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			validator, result := reconciler.ReconcileRunning()
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 5 * time.Second}))
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodRunning))
+		})
+
+		It("Should NOT create more pods while a pod is already running", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-validator",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			validator, _ := reconciler.ReconcileRunning()
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodRunning))
+
+			pods := reconciler.ListValidatorPods()
+			Expect(len(pods.Items)).To(Equal(1))
+		})
+	})
+
+	Context("Reconcile Native Validation", func() {
+		BeforeEach(func() {
+			setValidationMode(namespace, index.ValidationModeNative)
+		})
+
+		It("Should be the default validation mode", func() {
+			Expect(parameters.BuildIndexParameters().ValidationMode.DefaultValue).To(Equal(index.ValidationModeNative))
+		})
+
+		It("Should validate in the background without a pod and requeue after ValidationPodStatusInterval", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-validator",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			validator, result := reconciler.ReconcileCreate()
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 5 * time.Second}))
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodRunning))
+
+			pods := reconciler.ListValidatorPods()
+			Expect(pods.Items).To(BeEmpty())
+		})
+
+		It("Should apply the result once the validation completes and requeue after ValidationInterval", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:      namespace,
+				Name:           "test-index-validator",
+				Version:        "1234",
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			validator, result := reconciler.ReconcileNativeResult()
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 60 * time.Second}))
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodSuccess))
+			Expect(validator.Status.ValidationError).To(BeEmpty())
+
+			pods := reconciler.ListValidatorPods()
+			Expect(pods.Items).To(BeEmpty())
+		})
+	})
+
+	Context("Reconcile Pod Success", func() {
+		It("Should requeue after ValidationInterval", func() {
+			name := "test-index-validator"
+			version := "1234"
+
+			terminationMessage, err := os.ReadFile("./test/data/validator/success.json")
+			checkError(err)
+
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               name,
+				Version:            version,
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			validator, result := reconciler.ReconcileSuccess()
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodSuccess))
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 60 * time.Second}))
+		})
+
+		It("Should delete the validator pod", func() {
+			name := "test-index-validator"
+			version := "1234"
+
+			terminationMessage, err := os.ReadFile("./test/data/validator/success.json")
+			checkError(err)
+
+			indexValidatorReconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               name,
+				Version:            version,
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
+			}
+			indexValidatorReconciler.CreateDatasource()
+			indexValidatorReconciler.ReconcileCreate()
+			indexValidatorReconciler.ReconcileRunning()
+
+			pods := &corev1.PodList{}
+			err = k8sClient.List(context.Background(), pods, client.InNamespace(namespace))
+			checkError(err)
+			Expect(pods.Items[0].Name).To(Equal(strings.ReplaceAll(indexValidatorReconciler.GetName(), ".", "-")))
+
+			indexValidatorReconciler.ReconcileSuccess()
+
+			pods = &corev1.PodList{}
+			err = k8sClient.List(context.Background(), pods, client.InNamespace(namespace))
+			checkError(err)
+
+			Expect(len(pods.Items)).To(Equal(0))
+		})
+
+		It("Should write the mismatch report and link it from the XJoinIndexPipeline status", func() {
+			terminationMessage, err := os.ReadFile("./test/data/validator/invalid.json")
+			checkError(err)
+
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index-validator",
+				Version:            "1234",
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			reconciler.ReconcileSuccess()
+
+			indexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			err = k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: "test-index-pipeline", Namespace: namespace}, indexPipeline)
+			checkError(err)
+			reportStatus := indexPipeline.Status.ValidationReport
+			Expect(reportStatus).ToNot(BeNil())
+			Expect(reportStatus.ConfigMap).To(Equal(index.ValidationReportConfigMapName(reconciler.GetName())))
+			Expect(reportStatus.Result).To(Equal(index.Invalid))
+			Expect(reportStatus.Mismatched).To(Equal(3))
+			Expect(reportStatus.Entries).To(Equal(3))
+
+			configMap := &corev1.ConfigMap{}
+			err = k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: reportStatus.ConfigMap, Namespace: namespace}, configMap)
+			checkError(err)
+
+			var report validator.Report
+			err = json.Unmarshal([]byte(configMap.Data["report.json"]), &report)
+			checkError(err)
+			Expect(report.Entries).To(Equal([]validator.ReportEntry{
+				{ID: "1", Type: validator.MissingInSink},
+				{ID: "2", Type: validator.MissingInSource},
+				{ID: "3", Type: validator.DifferentFields, Fields: []string{"name"}},
+			}))
+		})
+
+		It("Should update each DataSourcePipeline's ValidationResponse status", func() {
+			//create datasource
+			datasourceReconciler := DatasourceTestReconciler{
+				Namespace: namespace,
+				Name:      "testdatasource",
+				K8sClient: k8sClient,
+			}
+			datasource := datasourceReconciler.ReconcileNew()
+
+			//create index
+			indexReconciler := IndexTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index",
+				AvroSchemaFileName: "xjoinindex-with-referenced-field",
+				K8sClient:          k8sClient,
+			}
+			createdIndex := indexReconciler.ReconcileNew()
+
+			//reconcile indexpipeline to create the indexpipelinevalidator resource
+			reconciler := XJoinIndexPipelineTestReconciler{
+				Namespace:      namespace,
+				Name:           createdIndex.Name,
+				Version:        createdIndex.Status.RefreshingVersion,
+				ConfigFileName: "xjoinindex",
+				K8sClient:      k8sClient,
+				DataSources: []DataSource{{
+					Name:                     datasource.Name,
+					Version:                  datasource.Status.RefreshingVersion,
+					ApiCurioResponseFilename: "datasource-latest-version",
+				}},
+			}
+			reconciler.ReconcileUpdated(UpdatedMocksParams{
+				GraphQLSchemaExistingState: "ENABLED",
+				GraphQLSchemaNewState:      "DISABLED",
+			})
+
+			//assert datasourcepipeline is invalid
+			datasourcePipelineLookup := types.NamespacedName{
+				Name: datasource.Name + "." + datasource.Status.RefreshingVersion, Namespace: namespace}
+			datasourcePipeline := &v1alpha1.XJoinDataSourcePipeline{}
+			Eventually(func() bool {
+				err := k8sClient.Get(context.Background(), datasourcePipelineLookup, datasourcePipeline)
+				return err == nil
+			}, K8sGetTimeout, K8sGetInterval).Should(BeTrue())
+			Expect(datasourcePipeline.Status.ValidationResponse.Result).To(Equal(""))
+
+			//reconcile indexpipelinevalidator to successful validation
+			terminationMessage, err := os.ReadFile("./test/data/validator/success.json")
+			checkError(err)
+
+			validator := &v1alpha1.XJoinIndexValidatorList{}
+			err = k8sClient.List(context.Background(), validator, client.InNamespace(namespace))
+			checkError(err)
+
+			indexValidatorReconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "xjoinindexpipeline." + indexReconciler.Name,
+				Version:            createdIndex.Status.RefreshingVersion,
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
+			}
+			indexValidatorReconciler.ReconcileRunning()
+			indexValidatorReconciler.ReconcileSuccess()
+
+			//validate datasourcepipeline is valid
+			err = k8sClient.Get(context.Background(), datasourcePipelineLookup, datasourcePipeline)
+			checkError(err)
+			Expect(datasourcePipeline.Status.ValidationResponse.Result).To(Equal(index.Valid))
+		})
+	})
+
+	Context("Reconcile Invalid Pod Output", func() {
+		It("Should set the error phase and delete the pod when the output is partial", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index-validator",
+				Version:            "1234",
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: `{"schemaVersion":"v1","response":{"result":"val`,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			validator, result := reconciler.ReconcileSuccess()
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodError))
+			Expect(validator.Status.ValidationError).To(ContainSubstring("unable to parse the output"))
+			Expect(result).To(Equal(reconcile.Result{Requeue: false, RequeueAfter: 60 * time.Second}))
+			Expect(reconciler.ListValidatorPods().Items).To(BeEmpty())
+		})
+
+		It("Should set the error phase and not apply the result when the output was truncated", func() {
+			details := validation.ResponseDetails{TotalMismatch: 1000, IdsMissingFromElasticsearchCount: 1000}
+			for id := 0; id < 1000; id++ {
+				details.IdsMissingFromElasticsearch = append(details.IdsMissingFromElasticsearch, fmt.Sprint(id))
+			}
+			output, err := json.Marshal(index.ValidationOutput{
+				SchemaVersion: index.ValidationOutputSchemaVersion,
+				Response: &validation.ValidationResponse{
+					Result:  index.Invalid,
+					Reason:  "1000 records (100.00%) do not match",
+					Details: details,
+				},
+			})
+			checkError(err)
+			Expect(len(output)).To(BeNumerically(">", index.ValidationOutputMaxBytes))
+
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index-validator",
+				Version:            "1234",
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(output[:index.ValidationOutputMaxBytes]), //truncated like the kubelet does
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			indexValidator, _ := reconciler.ReconcileSuccess()
+			Expect(indexValidator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodError))
+			Expect(indexValidator.Status.ValidationError).To(Equal(index.ErrValidationOutputTruncated.Error()))
+			Expect(reconciler.ListValidatorPods().Items).To(BeEmpty())
+
+			indexPipeline := &v1alpha1.XJoinIndexPipeline{}
+			err = k8sClient.Get(context.Background(),
+				types.NamespacedName{Name: "test-index-pipeline", Namespace: namespace}, indexPipeline)
+			checkError(err)
+			Expect(indexPipeline.Status.ValidationReport).To(BeNil())
+		})
+
+		It("Should set the error phase when the output has an unsupported schema version", func() {
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               "test-index-validator",
+				Version:            "1234",
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: `{"schemaVersion":"v0","response":{"result":"valid"}}`,
+			}
+			reconciler.CreateDatasource()
+			reconciler.ReconcileCreate()
+			reconciler.ReconcileRunning()
+			validator, _ := reconciler.ReconcileSuccess()
+			Expect(validator.Status.ValidationPodPhase).To(Equal(index.ValidatorPodError))
+			Expect(validator.Status.ValidationError).To(ContainSubstring("unsupported schema version"))
+		})
+	})
+
+	Context("Reconcile Pod Failure", func() {
+		It("Should requeue immediately", func() {
+			name := "test-index-validator"
+			version := "1234"
+
+			terminationMessage, err := os.ReadFile("./test/data/validator/success.json")
+			checkError(err)
+
+			reconciler := XJoinIndexValidatorTestReconciler{
+				Namespace:          namespace,
+				Name:               name,
+				Version:            version,
+				ConfigFileName:     "xjoinindex",
+				K8sClient:          k8sClient,
+				TerminationMessage: string(terminationMessage),
+			}
+			reconciler.ReconcileCreate()
+			_, result := reconciler.ReconcileFailure()
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+		})
+	})
+})
@@ -18,7 +18,6 @@ package main
 
 import (
 	"flag"
-	"github.com/redhatinsights/xjoin-operator/controllers/k8s"
 	"os"
 	"time"
 
@@ -168,7 +167,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	clientset, err := kubernetes.NewForConfig(mgr.GetConfig()) //used to read logs from validation pod
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
 
 	if err != nil {
 		k8slog.Log.Error(err, "unable to load k8s config")
@@ -183,7 +182,6 @@ func main() {
 		mgr.GetEventRecorderFor("xjoinindexvalidator"),
 		namespace,
 		false,
-		k8s.PodLogReader{ClientSet: clientset},
 	).SetupWithManager(mgr); err != nil {
 		k8slog.Log.Error(err, "unable to create controller", "controller", "XJoinIndexValidator")
 		os.Exit(1)